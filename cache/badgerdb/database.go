@@ -192,6 +192,29 @@ func (d *Database) Exists(ctx context.Context, key string) (bool, error) {
 	return true, nil
 }
 
+// Keys returns the unexpired keys starting with the prefix, in byte order.
+func (d *Database) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := d.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		opts.Prefix = []byte(prefix)
+		it := txn.NewIterator(opts)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			keys = append(keys, string(it.Item().Key()))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
 func (d *Database) Close() error {
 	return d.db.Close()
 }
@@ -38,6 +38,19 @@ type TTL[S any] interface {
 	MultiSetWithTTL(ctx context.Context, valMap map[string]S, expiration time.Duration) error
 }
 
+// NX provides an atomic set-if-not-exists operation, commonly used for locks and deduplication.
+type NX[S any] interface {
+	// SetNX stores a key-value pair with a specified expiration duration only if the key does not exist.
+	// It returns true when the value was stored.
+	SetNX(ctx context.Context, key string, val S, expiration time.Duration) (bool, error)
+}
+
+// KeyLister provides enumeration of the keys stored in the cache.
+type KeyLister interface {
+	// Keys returns the unexpired keys starting with the prefix, in no particular order.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+}
+
 // Evictor provides a method to clear all entries from the cache.
 type Evictor interface {
 	// DelAll removes all keys from the cache.
@@ -2,6 +2,7 @@ package mcache
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 )
@@ -65,6 +66,24 @@ func (t *Map[K, S]) SetWithTTL(ctx context.Context, key K, val S, expiration tim
 	return nil
 }
 
+func (t *Map[K, S]) SetNX(ctx context.Context, key K, val S, expiration time.Duration) (bool, error) {
+	t.rw.Lock()
+	defer t.rw.Unlock()
+
+	if _, ok := t.store[key]; ok {
+		if exp, hasExp := t.expiration[key]; !hasExp || !time.Now().After(exp) {
+			return false, nil
+		}
+	}
+	t.store[key] = val
+	if expiration >= 0 {
+		t.expiration[key] = time.Now().Add(expiration)
+	} else {
+		delete(t.expiration, key)
+	}
+	return true, nil
+}
+
 func (t *Map[K, S]) MultiSet(ctx context.Context, valMap map[K]S) error {
 	t.rw.Lock()
 	defer t.rw.Unlock()
@@ -175,6 +194,24 @@ func (t *Map[K, S]) DelAll(ctx context.Context) error {
 	return nil
 }
 
+// Keys returns the unexpired string keys starting with the prefix. Keys of other types are skipped.
+func (t *Map[K, S]) Keys(ctx context.Context, prefix string) ([]string, error) {
+	t.rw.RLock()
+	defer t.rw.RUnlock()
+
+	now := time.Now()
+	keys := make([]string, 0, len(t.store))
+	for key := range t.store {
+		if exp, ok := t.expiration[key]; ok && now.After(exp) {
+			continue
+		}
+		if s, ok := any(key).(string); ok && strings.HasPrefix(s, prefix) {
+			keys = append(keys, s)
+		}
+	}
+	return keys, nil
+}
+
 func (t *Map[K, S]) Count() int {
 	t.rw.Lock()
 	defer t.rw.Unlock()
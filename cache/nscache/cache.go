@@ -2,6 +2,8 @@ package nscache
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/go-sphere/sphere/cache"
@@ -84,6 +86,10 @@ func (n *NSCache[S]) SetWithTTL(ctx context.Context, key string, val S, expirati
 	return n.cache.SetWithTTL(ctx, n.keygen(key), val, expiration)
 }
 
+func (n *NSCache[S]) SetNX(ctx context.Context, key string, val S, expiration time.Duration) (bool, error) {
+	return cache.SetNX[S](ctx, n.cache, n.keygen(key), val, expiration)
+}
+
 func (n *NSCache[S]) MultiSetWithTTL(ctx context.Context, valMap map[string]S, expiration time.Duration) error {
 	prefixedValMap := make(map[string]S, len(valMap))
 	for k, v := range valMap {
@@ -96,6 +102,23 @@ func (n *NSCache[S]) DelAll(ctx context.Context) error {
 	return n.cache.DelAll(ctx)
 }
 
+// Keys returns the keys of this namespace starting with the prefix, without the namespace.
+// The underlying cache must implement cache.KeyLister.
+func (n *NSCache[S]) Keys(ctx context.Context, prefix string) ([]string, error) {
+	lister, ok := n.cache.(cache.KeyLister)
+	if !ok {
+		return nil, errors.New("underlying cache does not support key listing")
+	}
+	keys, err := lister.Keys(ctx, n.keygen(prefix))
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, n.keygen(""))
+	}
+	return keys, nil
+}
+
 func (n *NSCache[S]) Close() error {
 	return n.cache.Close()
 }
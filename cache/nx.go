@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// SetNX stores a key-value pair with a specified expiration duration only if the key does not exist.
+// It uses the atomic NX implementation when the cache provides one; otherwise it falls back to
+// an Exists check followed by SetWithTTL, which is not atomic across concurrent writers.
+func SetNX[S any](ctx context.Context, c ExpirableCache[S], key string, val S, expiration time.Duration) (bool, error) {
+	if nx, ok := c.(NX[S]); ok {
+		return nx.SetNX(ctx, key, val, expiration)
+	}
+	exists, err := c.Exists(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+	err = c.SetWithTTL(ctx, key, val, expiration)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
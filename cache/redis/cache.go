@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -30,6 +31,10 @@ func (c *ByteCache) SetWithTTL(ctx context.Context, key string, val []byte, expi
 	return c.client.Set(ctx, key, val, expiration).Err()
 }
 
+func (c *ByteCache) SetNX(ctx context.Context, key string, val []byte, expiration time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, val, expiration).Result()
+}
+
 func (c *ByteCache) MultiSet(ctx context.Context, valMap map[string][]byte) error {
 	return c.MultiSetWithTTL(ctx, valMap, redis.KeepTTL)
 }
@@ -104,6 +109,35 @@ func (c *ByteCache) Exists(ctx context.Context, key string) (bool, error) {
 	return exists > 0, nil
 }
 
+// Keys returns the keys starting with the prefix using SCAN, so it does not block the server
+// the way KEYS would. Keys written or deleted during the scan may or may not be included.
+func (c *ByteCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	pattern := globEscaper.Replace(prefix) + "*"
+	seen := make(map[string]struct{})
+	keys := make([]string, 0)
+	var cursor uint64
+	for {
+		batch, next, err := c.client.Scan(ctx, cursor, pattern, 1000).Result()
+		if err != nil {
+			return nil, err
+		}
+		// SCAN may return a key more than once.
+		for _, key := range batch {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+		if next == 0 {
+			return keys, nil
+		}
+		cursor = next
+	}
+}
+
+// globEscaper escapes the glob metacharacters of a SCAN MATCH pattern.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
 func (c *ByteCache) Close() error {
 	return c.client.Close()
 }
@@ -3,6 +3,7 @@ package test
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -133,6 +134,53 @@ func TestByteCacheTTLContract(t *testing.T) {
 	}
 }
 
+func TestByteCacheSetNXContract(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range statefulByteCacheFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			c := factory.new(t)
+
+			stored, err := cache.SetNX(ctx, c, "nx_key", []byte("first"), time.Minute)
+			if err != nil {
+				t.Fatalf("SetNX first: %v", err)
+			}
+			if !stored {
+				t.Fatalf("SetNX first mismatch: expected value stored")
+			}
+			stored, err = cache.SetNX(ctx, c, "nx_key", []byte("second"), time.Minute)
+			if err != nil {
+				t.Fatalf("SetNX second: %v", err)
+			}
+			if stored {
+				t.Fatalf("SetNX second mismatch: expected existing key kept")
+			}
+			v, found, err := c.Get(ctx, "nx_key")
+			if err != nil {
+				t.Fatalf("Get after SetNX: %v", err)
+			}
+			if !found || string(v) != "first" {
+				t.Fatalf("Get after SetNX mismatch: found=%v value=%q", found, string(v))
+			}
+
+			if _, err = cache.SetNX(ctx, c, "nx_ttl", []byte("v"), 20*time.Millisecond); err != nil {
+				t.Fatalf("SetNX with ttl: %v", err)
+			}
+			assertEventuallyNotFound(t, c, "nx_ttl")
+			stored, err = cache.SetNX(ctx, c, "nx_ttl", []byte("v2"), time.Minute)
+			if err != nil {
+				t.Fatalf("SetNX after expiry: %v", err)
+			}
+			if !stored {
+				t.Fatalf("SetNX after expiry mismatch: expected value stored")
+			}
+		})
+	}
+}
+
 func TestByteCacheBoundaryContract(t *testing.T) {
 	t.Parallel()
 
@@ -261,6 +309,55 @@ func TestByteCacheConcurrentAccess(t *testing.T) {
 	}
 }
 
+func TestByteCacheKeyListerContract(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range statefulByteCacheFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			c := factory.new(t)
+			lister, ok := c.(cache.KeyLister)
+			if !ok {
+				t.Skip("cache does not implement KeyLister")
+			}
+			for _, key := range []string{"files/a", "files/b", "files*/c", "other"} {
+				if err := c.Set(ctx, key, []byte(key)); err != nil {
+					t.Fatalf("Set %s: %v", key, err)
+				}
+			}
+			if err := c.SetWithTTL(ctx, "files/expired", []byte("x"), 20*time.Millisecond); err != nil {
+				t.Fatalf("SetWithTTL: %v", err)
+			}
+			assertEventuallyNotFound(t, c, "files/expired")
+
+			keys, err := lister.Keys(ctx, "files/")
+			if err != nil {
+				t.Fatalf("Keys: %v", err)
+			}
+			slices.Sort(keys)
+			if !slices.Equal(keys, []string{"files/a", "files/b"}) {
+				t.Fatalf("Keys(files/) = %v, want [files/a files/b]", keys)
+			}
+			keys, err = lister.Keys(ctx, "files*")
+			if err != nil {
+				t.Fatalf("Keys: %v", err)
+			}
+			if !slices.Equal(keys, []string{"files*/c"}) {
+				t.Fatalf("Keys(files*) = %v, want the literal prefix match only", keys)
+			}
+			keys, err = lister.Keys(ctx, "")
+			if err != nil {
+				t.Fatalf("Keys: %v", err)
+			}
+			if len(keys) != 4 {
+				t.Fatalf("Keys(\"\") = %v, want 4 keys", keys)
+			}
+		})
+	}
+}
+
 func TestByteCacheClose(t *testing.T) {
 	t.Parallel()
 
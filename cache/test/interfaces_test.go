@@ -9,6 +9,7 @@ import (
 	"github.com/go-sphere/sphere/cache/mcache"
 	"github.com/go-sphere/sphere/cache/memory"
 	"github.com/go-sphere/sphere/cache/nocache"
+	"github.com/go-sphere/sphere/cache/nscache"
 	"github.com/go-sphere/sphere/cache/redis"
 )
 
@@ -27,6 +28,13 @@ var (
 	_ cache.ExpirableByteCache  = (*badgerdb.Database)(nil)
 	_ cache.ExpirableByteCache  = (*nocache.ByteNoCache)(nil)
 	_ cache.ExpirableCache[int] = (*memory.Cache[int])(nil)
+	_ cache.KeyLister           = (*mcache.Map[string, []byte])(nil)
+	_ cache.KeyLister           = (*badgerdb.Database)(nil)
+	_ cache.KeyLister           = (*redis.ByteCache)(nil)
+	_ cache.KeyLister           = (*nscache.NSCache[[]byte])(nil)
+	_ cache.NX[[]byte]          = (*mcache.Map[string, []byte])(nil)
+	_ cache.NX[[]byte]          = (*redis.ByteCache)(nil)
+	_ cache.NX[string]          = (*nscache.NSCache[string])(nil)
 )
 
 func TestCodecCacheImplementsContract(t *testing.T) {
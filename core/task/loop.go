@@ -0,0 +1,57 @@
+package task
+
+import (
+	"context"
+	"sync"
+)
+
+// Loop tracks the run of a background task that is started and stopped like a Task.
+// The zero value is ready to use, and a stopped loop can be run again.
+type Loop struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Run calls run with a context that is cancelled when ctx is or when Stop is called, and returns its error.
+// It fails with alreadyStarted if the loop is already running.
+func (l *Loop) Run(ctx context.Context, alreadyStarted error, run func(ctx context.Context) error) error {
+	l.mu.Lock()
+	if l.cancel != nil {
+		l.mu.Unlock()
+		return alreadyStarted
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	l.cancel = cancel
+	l.done = done
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		l.cancel = nil
+		l.done = nil
+		l.mu.Unlock()
+		close(done)
+	}()
+	defer cancel()
+	return run(ctx)
+}
+
+// Stop cancels the running loop and waits for it to return or for the context to be done.
+// Stopping a loop that is not running does nothing.
+func (l *Loop) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	cancel, done := l.cancel, l.done
+	l.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
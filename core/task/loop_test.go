@@ -0,0 +1,64 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLoopRunStopRestart(t *testing.T) {
+	errStarted := errors.New("worker already started")
+	var loop Loop
+
+	if err := loop.Stop(context.Background()); err != nil {
+		t.Fatalf("expected stop before run to succeed, got %v", err)
+	}
+
+	for round := range 2 {
+		started := make(chan struct{})
+		runErrCh := make(chan error, 1)
+		go func() {
+			runErrCh <- loop.Run(context.Background(), errStarted, func(ctx context.Context) error {
+				close(started)
+				<-ctx.Done()
+				return nil
+			})
+		}()
+		waitSignal(t, started, "loop started")
+
+		if err := loop.Run(context.Background(), errStarted, func(context.Context) error { return nil }); !errors.Is(err, errStarted) {
+			t.Fatalf("round %d: expected already started error, got %v", round, err)
+		}
+		if err := loop.Stop(context.Background()); err != nil {
+			t.Fatalf("round %d: expected stop to succeed, got %v", round, err)
+		}
+		if err := waitError(t, runErrCh, "loop run result"); err != nil {
+			t.Fatalf("round %d: expected nil run result, got %v", round, err)
+		}
+	}
+}
+
+func TestLoopStopHonoursContext(t *testing.T) {
+	var loop Loop
+	started := make(chan struct{})
+	release := make(chan struct{})
+	runErrCh := make(chan error, 1)
+	go func() {
+		runErrCh <- loop.Run(context.Background(), nil, func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	waitSignal(t, started, "loop started")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := loop.Stop(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+	close(release)
+	if err := waitError(t, runErrCh, "loop run result"); err != nil {
+		t.Fatalf("expected nil run result, got %v", err)
+	}
+}
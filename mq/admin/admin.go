@@ -0,0 +1,207 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/mq"
+	"github.com/go-sphere/sphere/server/httpz"
+)
+
+// QueueStat describes the backlog of a single topic queue.
+type QueueStat struct {
+	Topic  string `json:"topic"`
+	Length int64  `json:"length"`
+}
+
+// QueueMessages lists the oldest pending messages of a topic queue.
+type QueueMessages[T any] struct {
+	Topic    string `json:"topic"`
+	Length   int64  `json:"length"`
+	Messages []T    `json:"messages"`
+}
+
+// TopicStat describes the subscribers of a single pub/sub topic.
+type TopicStat struct {
+	Topic       string `json:"topic"`
+	Subscribers int64  `json:"subscribers"`
+}
+
+type options struct {
+	defaultPeek int
+	maxPeek     int
+}
+
+func newOptions(opts ...Option) *options {
+	defaults := &options{
+		defaultPeek: 10,
+		maxPeek:     100,
+	}
+	for _, opt := range opts {
+		opt(defaults)
+	}
+	return defaults
+}
+
+// Option is a functional option for configuring the admin handler.
+type Option func(*options)
+
+// WithPeekLimit sets how many messages are returned when the limit query parameter is omitted,
+// and the upper bound a request may ask for. The defaults are 10 and 100.
+func WithPeekLimit(defaultLimit, maxLimit int) Option {
+	return func(opts *options) {
+		opts.defaultPeek = defaultLimit
+		opts.maxPeek = maxLimit
+	}
+}
+
+// Handler exposes read-only queue and pub/sub introspection over HTTP.
+// It never consumes, purges or publishes messages.
+type Handler[T any] struct {
+	queue  mq.QueueInspector[T]
+	pubsub mq.PubSubInspector
+	opts   *options
+}
+
+// NewHandler creates an admin handler for the given inspectors.
+// Either inspector may be nil, in which case its routes are not registered, but not both.
+func NewHandler[T any](queue mq.QueueInspector[T], pubsub mq.PubSubInspector, opt ...Option) (*Handler[T], error) {
+	if queue == nil && pubsub == nil {
+		return nil, errors.New("queue or pubsub inspector is required")
+	}
+	opts := newOptions(opt...)
+	if opts.maxPeek <= 0 {
+		return nil, errors.New("max peek limit must be positive")
+	}
+	opts.defaultPeek = min(max(opts.defaultPeek, 1), opts.maxPeek)
+	return &Handler[T]{
+		queue:  queue,
+		pubsub: pubsub,
+		opts:   opts,
+	}, nil
+}
+
+// Register mounts the read-only endpoints on the router:
+//
+//	GET /queues               pending topics with their lengths
+//	GET /queue/*topic         length of a single topic queue
+//	GET /messages/*topic      oldest pending messages, limited by the limit query parameter
+//	GET /pubsub               topics that have subscribers with their subscriber counts
+//	GET /subscribers/*topic   subscriber count of a single topic
+//
+// Topics are wildcard parameters, so topic names may contain slashes.
+func (h *Handler[T]) Register(route httpx.Router) {
+	if h.queue != nil {
+		route.Handle(http.MethodGet, "/queues", httpz.WithJson(h.listQueues))
+		path, param := httpx.FixWildcardPathIfNeed(route, "/queue/*topic")
+		route.Handle(http.MethodGet, path, httpz.WithJson(h.getQueue(param)))
+		path, param = httpx.FixWildcardPathIfNeed(route, "/messages/*topic")
+		route.Handle(http.MethodGet, path, httpz.WithJson(h.peekQueue(param)))
+	}
+	if h.pubsub != nil {
+		route.Handle(http.MethodGet, "/pubsub", httpz.WithJson(h.listTopics))
+		path, param := httpx.FixWildcardPathIfNeed(route, "/subscribers/*topic")
+		route.Handle(http.MethodGet, path, httpz.WithJson(h.getTopic(param)))
+	}
+}
+
+// topicParam returns the topic of a wildcard route parameter.
+func topicParam(ctx httpx.Context, param string) (string, error) {
+	topic := strings.TrimPrefix(ctx.Param(param), "/")
+	if topic == "" {
+		return "", httpx.NewBadRequestError("topic is required")
+	}
+	return topic, nil
+}
+
+func (h *Handler[T]) listQueues(ctx httpx.Context) ([]QueueStat, error) {
+	topics, err := h.queue.Topics(ctx.Context())
+	if err != nil {
+		return nil, httpx.InternalServerError(err)
+	}
+	stats := make([]QueueStat, 0, len(topics))
+	for _, topic := range topics {
+		length, lErr := h.queue.Len(ctx.Context(), topic)
+		if lErr != nil {
+			return nil, httpx.InternalServerError(lErr)
+		}
+		stats = append(stats, QueueStat{Topic: topic, Length: length})
+	}
+	return stats, nil
+}
+
+func (h *Handler[T]) getQueue(param string) func(ctx httpx.Context) (QueueStat, error) {
+	return func(ctx httpx.Context) (QueueStat, error) {
+		topic, err := topicParam(ctx, param)
+		if err != nil {
+			return QueueStat{}, err
+		}
+		length, err := h.queue.Len(ctx.Context(), topic)
+		if err != nil {
+			return QueueStat{}, httpx.InternalServerError(err)
+		}
+		return QueueStat{Topic: topic, Length: length}, nil
+	}
+}
+
+func (h *Handler[T]) peekQueue(param string) func(ctx httpx.Context) (QueueMessages[T], error) {
+	return func(ctx httpx.Context) (QueueMessages[T], error) {
+		topic, err := topicParam(ctx, param)
+		if err != nil {
+			return QueueMessages[T]{}, err
+		}
+		limit := h.opts.defaultPeek
+		if raw := ctx.Query("limit"); raw != "" {
+			n, aErr := strconv.Atoi(raw)
+			if aErr != nil || n <= 0 {
+				return QueueMessages[T]{}, httpx.NewBadRequestError("limit must be a positive integer")
+			}
+			limit = min(n, h.opts.maxPeek)
+		}
+		length, err := h.queue.Len(ctx.Context(), topic)
+		if err != nil {
+			return QueueMessages[T]{}, httpx.InternalServerError(err)
+		}
+		messages, err := h.queue.Peek(ctx.Context(), topic, limit)
+		if err != nil {
+			return QueueMessages[T]{}, httpx.InternalServerError(err)
+		}
+		if messages == nil {
+			messages = []T{}
+		}
+		return QueueMessages[T]{Topic: topic, Length: length, Messages: messages}, nil
+	}
+}
+
+func (h *Handler[T]) listTopics(ctx httpx.Context) ([]TopicStat, error) {
+	topics, err := h.pubsub.ActiveTopics(ctx.Context())
+	if err != nil {
+		return nil, httpx.InternalServerError(err)
+	}
+	counts, err := h.pubsub.SubscriberCounts(ctx.Context(), topics...)
+	if err != nil {
+		return nil, httpx.InternalServerError(err)
+	}
+	stats := make([]TopicStat, 0, len(topics))
+	for _, topic := range topics {
+		stats = append(stats, TopicStat{Topic: topic, Subscribers: counts[topic]})
+	}
+	return stats, nil
+}
+
+func (h *Handler[T]) getTopic(param string) func(ctx httpx.Context) (TopicStat, error) {
+	return func(ctx httpx.Context) (TopicStat, error) {
+		topic, err := topicParam(ctx, param)
+		if err != nil {
+			return TopicStat{}, err
+		}
+		counts, err := h.pubsub.SubscriberCounts(ctx.Context(), topic)
+		if err != nil {
+			return TopicStat{}, httpx.InternalServerError(err)
+		}
+		return TopicStat{Topic: topic, Subscribers: counts[topic]}, nil
+	}
+}
@@ -0,0 +1,137 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/mq/memory"
+	"github.com/go-sphere/sphere/server/httpz"
+)
+
+// testRouter records registered handlers; the remaining Router methods are unused.
+type testRouter struct {
+	httpx.Router
+	handlers map[string]httpx.Handler
+}
+
+func (r *testRouter) Handle(method, pattern string, h httpx.Handler) {
+	r.handlers[method+" "+pattern] = h
+}
+
+// unimplementedContext is embedded under its own name so testContext can define Context().
+type unimplementedContext = httpx.Context
+
+// testContext implements the few Context methods the handlers touch.
+type testContext struct {
+	unimplementedContext
+	params map[string]string
+	query  map[string]string
+	status int
+	body   []byte
+}
+
+func (c *testContext) Context() context.Context { return context.Background() }
+func (c *testContext) Param(key string) string  { return c.params[key] }
+func (c *testContext) Query(key string) string  { return c.query[key] }
+func (c *testContext) JSON(code int, v any) error {
+	c.status = code
+	var err error
+	c.body, err = json.Marshal(v)
+	return err
+}
+
+func serve[T any](t *testing.T, router *testRouter, route string, params, query map[string]string) (int, T) {
+	t.Helper()
+	handler, ok := router.handlers[http.MethodGet+" "+route]
+	if !ok {
+		t.Fatalf("route %s not registered", route)
+	}
+	ctx := &testContext{params: params, query: query}
+	if err := handler(ctx); err != nil {
+		t.Fatalf("handler %s error = %v", route, err)
+	}
+	var resp httpz.DataResponse[T]
+	if ctx.status == http.StatusOK {
+		if err := json.Unmarshal(ctx.body, &resp); err != nil {
+			t.Fatalf("decode %s response: %v", route, err)
+		}
+	}
+	return ctx.status, resp.Data
+}
+
+func TestHandlerRoutes(t *testing.T) {
+	ctx := context.Background()
+	mq := memory.NewMessageQueue[string]()
+	t.Cleanup(func() { _ = mq.Close() })
+	for _, msg := range []string{"a", "b", "c"} {
+		if err := mq.Publish(ctx, "tenant/jobs", msg); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	if err := mq.Subscribe(ctx, "events", func(string) error { return nil }); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	handler, err := NewHandler[string](mq, mq, WithPeekLimit(1, 2))
+	if err != nil {
+		t.Fatalf("NewHandler() error = %v", err)
+	}
+	router := &testRouter{handlers: map[string]httpx.Handler{}}
+	handler.Register(router)
+
+	status, queues := serve[[]QueueStat](t, router, "/queues", nil, nil)
+	if status != http.StatusOK || len(queues) != 1 || queues[0] != (QueueStat{Topic: "tenant/jobs", Length: 3}) {
+		t.Fatalf("/queues = %d %+v", status, queues)
+	}
+
+	status, peeked := serve[QueueMessages[string]](t, router, "/messages/*topic", map[string]string{"topic": "/tenant/jobs"}, nil)
+	if status != http.StatusOK || peeked.Length != 3 || len(peeked.Messages) != 1 || peeked.Messages[0] != "a" {
+		t.Fatalf("default peek = %d %+v", status, peeked)
+	}
+	_, peeked = serve[QueueMessages[string]](t, router, "/messages/*topic", map[string]string{"topic": "/tenant/jobs"}, map[string]string{"limit": "50"})
+	if len(peeked.Messages) != 2 {
+		t.Fatalf("peek limit not capped: %+v", peeked)
+	}
+	status, _ = serve[QueueMessages[string]](t, router, "/messages/*topic", map[string]string{"topic": "/tenant/jobs"}, map[string]string{"limit": "zero"})
+	if status != http.StatusBadRequest {
+		t.Fatalf("invalid limit status = %d, want %d", status, http.StatusBadRequest)
+	}
+	status, stat := serve[QueueStat](t, router, "/queue/*topic", map[string]string{"topic": "/tenant/jobs"}, nil)
+	if status != http.StatusOK || stat != (QueueStat{Topic: "tenant/jobs", Length: 3}) {
+		t.Fatalf("/queue/*topic = %d %+v", status, stat)
+	}
+	status, _ = serve[QueueStat](t, router, "/queue/*topic", map[string]string{"topic": "/"}, nil)
+	if status != http.StatusBadRequest {
+		t.Fatalf("empty topic status = %d, want %d", status, http.StatusBadRequest)
+	}
+	if n, _ := mq.Len(ctx, "tenant/jobs"); n != 3 {
+		t.Fatalf("admin endpoints consumed messages: len = %d", n)
+	}
+
+	status, topics := serve[[]TopicStat](t, router, "/pubsub", nil, nil)
+	if status != http.StatusOK || len(topics) != 1 || topics[0] != (TopicStat{Topic: "events", Subscribers: 1}) {
+		t.Fatalf("/pubsub = %d %+v", status, topics)
+	}
+	_, topic := serve[TopicStat](t, router, "/subscribers/*topic", map[string]string{"topic": "/idle/events"}, nil)
+	if topic != (TopicStat{Topic: "idle/events", Subscribers: 0}) {
+		t.Fatalf("/subscribers/*topic = %+v", topic)
+	}
+}
+
+func TestNewHandlerValidation(t *testing.T) {
+	if _, err := NewHandler[string](nil, nil); err == nil {
+		t.Fatal("expected NewHandler without inspectors to fail")
+	}
+	router := &testRouter{handlers: map[string]httpx.Handler{}}
+	handler, err := NewHandler[string](memory.NewQueue[string](), nil)
+	if err != nil {
+		t.Fatalf("NewHandler() error = %v", err)
+	}
+	handler.Register(router)
+	if _, ok := router.handlers[http.MethodGet+" /pubsub"]; ok {
+		t.Fatal("pubsub routes registered without a pubsub inspector")
+	}
+}
@@ -0,0 +1,140 @@
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-sphere/sphere/cache"
+	"github.com/go-sphere/sphere/mq"
+)
+
+var (
+	// ErrInProgress indicates that another consumer is currently processing the same message.
+	// The message has not been completed yet, so it should be redelivered later rather than dropped.
+	ErrInProgress = errors.New("mq dedup: message is being processed by another consumer")
+
+	stateProcessing = []byte("processing")
+	stateDone       = []byte("done")
+)
+
+// KeyFunc derives the deduplication key of a message.
+// Messages that yield the same key are considered duplicates.
+type KeyFunc[T any] func(topic string, data T) (string, error)
+
+// PayloadHashKey derives the key from the topic and the SHA-256 hash of the JSON-encoded payload.
+// Prefer a business identifier (payment ID, event ID) when one is available.
+func PayloadHashKey[T any]() KeyFunc[T] {
+	return func(topic string, data T) (string, error) {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(raw)
+		return topic + ":" + hex.EncodeToString(sum[:]), nil
+	}
+}
+
+type options struct {
+	prefix        string
+	retention     time.Duration
+	processingTTL time.Duration
+	onDuplicate   func(ctx context.Context, topic string, key string)
+}
+
+func newOptions(opts ...Option) *options {
+	defaults := &options{
+		prefix:        "mq:dedup:",
+		retention:     24 * time.Hour,
+		processingTTL: 5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(defaults)
+	}
+	return defaults
+}
+
+// Option is a functional option for configuring the deduplication middleware.
+type Option func(*options)
+
+// WithPrefix sets the cache key prefix used for deduplication records.
+// The default prefix is "mq:dedup:".
+func WithPrefix(prefix string) Option {
+	return func(opts *options) {
+		opts.prefix = prefix
+	}
+}
+
+// WithRetention sets how long a successfully processed message is remembered.
+// Duplicates arriving after the retention window are processed again. The default is 24 hours.
+func WithRetention(retention time.Duration) Option {
+	return func(opts *options) {
+		opts.retention = retention
+	}
+}
+
+// WithProcessingTTL sets how long a claim survives while the handler is running.
+// It bounds how long a message stays blocked if the consumer crashes mid-processing.
+// The default is five minutes.
+func WithProcessingTTL(ttl time.Duration) Option {
+	return func(opts *options) {
+		opts.processingTTL = ttl
+	}
+}
+
+// WithOnDuplicate sets a callback invoked whenever an already processed message is skipped.
+func WithOnDuplicate(fn func(ctx context.Context, topic string, key string)) Option {
+	return func(opts *options) {
+		opts.onDuplicate = fn
+	}
+}
+
+// NewMiddleware creates a middleware that processes each message key at most once within the retention window.
+// Before invoking the handler it claims the key with set-if-not-exists semantics; caches implementing
+// cache.NX (such as the redis and mcache backends) make the claim atomic across consumers.
+// If the handler fails the claim is released so a redelivery can retry the message.
+// Duplicates of completed messages are skipped and reported as success, while duplicates of
+// messages still in flight return ErrInProgress.
+func NewMiddleware[T any](store cache.ByteCache, key KeyFunc[T], options ...Option) (mq.Middleware[T], error) {
+	if store == nil {
+		return nil, errors.New("cache is required")
+	}
+	if key == nil {
+		return nil, errors.New("key func is required")
+	}
+	opts := newOptions(options...)
+	return func(next mq.Handler[T]) mq.Handler[T] {
+		return func(ctx context.Context, topic string, data T) error {
+			k, err := key(topic, data)
+			if err != nil {
+				return err
+			}
+			k = opts.prefix + k
+			claimed, err := cache.SetNX(ctx, store, k, stateProcessing, opts.processingTTL)
+			if err != nil {
+				return err
+			}
+			if !claimed {
+				state, found, gErr := store.Get(ctx, k)
+				if gErr != nil {
+					return gErr
+				}
+				if !found || bytes.Equal(state, stateProcessing) {
+					return ErrInProgress
+				}
+				if opts.onDuplicate != nil {
+					opts.onDuplicate(ctx, topic, k)
+				}
+				return nil
+			}
+			if hErr := next(ctx, topic, data); hErr != nil {
+				return errors.Join(hErr, store.Del(context.WithoutCancel(ctx), k))
+			}
+			return store.SetWithTTL(context.WithoutCancel(ctx), k, stateDone, opts.retention)
+		}
+	}, nil
+}
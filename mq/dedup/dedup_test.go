@@ -0,0 +1,168 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/cache"
+	"github.com/go-sphere/sphere/cache/mcache"
+	"github.com/go-sphere/sphere/cache/redis"
+	"github.com/go-sphere/sphere/mq"
+	"github.com/go-sphere/sphere/test/redistest"
+)
+
+type payment struct {
+	ID     int `json:"id"`
+	Amount int `json:"amount"`
+}
+
+func paymentKey(topic string, data payment) (string, error) {
+	return topic + ":" + strconv.Itoa(data.ID), nil
+}
+
+func cacheFactories(t *testing.T) map[string]cache.ByteCache {
+	return map[string]cache.ByteCache{
+		"mcache": mcache.NewByteCache(),
+		"redis":  redis.NewByteCache(redistest.NewTestRedisClient(t)),
+	}
+}
+
+func TestMiddlewareSkipsDuplicates(t *testing.T) {
+	for name, store := range cacheFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var calls atomic.Int32
+			var skipped atomic.Int32
+			middleware, err := NewMiddleware(store, paymentKey, WithOnDuplicate(func(ctx context.Context, topic string, key string) {
+				skipped.Add(1)
+			}))
+			if err != nil {
+				t.Fatalf("NewMiddleware() error = %v", err)
+			}
+			handler := mq.Chain(func(ctx context.Context, topic string, data payment) error {
+				calls.Add(1)
+				return nil
+			}, middleware)
+
+			for range 3 {
+				if err = handler(ctx, "payments", payment{ID: 1, Amount: 10}); err != nil {
+					t.Fatalf("handler() error = %v", err)
+				}
+			}
+			if err = handler(ctx, "payments", payment{ID: 2, Amount: 10}); err != nil {
+				t.Fatalf("handler() error = %v", err)
+			}
+			if got := calls.Load(); got != 2 {
+				t.Fatalf("handler calls = %d, want 2", got)
+			}
+			if got := skipped.Load(); got != 2 {
+				t.Fatalf("skipped duplicates = %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestMiddlewareReleasesClaimOnFailure(t *testing.T) {
+	for name, store := range cacheFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			middleware, err := NewMiddleware(store, paymentKey)
+			if err != nil {
+				t.Fatalf("NewMiddleware() error = %v", err)
+			}
+			failure := errors.New("gateway timeout")
+			attempts := 0
+			handler := middleware(func(ctx context.Context, topic string, data payment) error {
+				attempts++
+				if attempts == 1 {
+					return failure
+				}
+				return nil
+			})
+
+			if err = handler(ctx, "payments", payment{ID: 3}); !errors.Is(err, failure) {
+				t.Fatalf("first handler() error = %v, want %v", err, failure)
+			}
+			if err = handler(ctx, "payments", payment{ID: 3}); err != nil {
+				t.Fatalf("retry handler() error = %v", err)
+			}
+			if err = handler(ctx, "payments", payment{ID: 3}); err != nil {
+				t.Fatalf("duplicate handler() error = %v", err)
+			}
+			if attempts != 2 {
+				t.Fatalf("handler attempts = %d, want 2", attempts)
+			}
+		})
+	}
+}
+
+func TestMiddlewareReportsInFlightDuplicates(t *testing.T) {
+	ctx := context.Background()
+	middleware, err := NewMiddleware(mcache.NewByteCache(), paymentKey)
+	if err != nil {
+		t.Fatalf("NewMiddleware() error = %v", err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := middleware(func(ctx context.Context, topic string, data payment) error {
+		close(started)
+		<-release
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		if hErr := handler(ctx, "payments", payment{ID: 4}); hErr != nil {
+			t.Errorf("first handler() error = %v", hErr)
+		}
+	})
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for handler to start")
+	}
+	if err = handler(ctx, "payments", payment{ID: 4}); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("concurrent handler() error = %v, want %v", err, ErrInProgress)
+	}
+	close(release)
+	wg.Wait()
+}
+
+func TestMiddlewareRetentionExpiry(t *testing.T) {
+	ctx := context.Background()
+	middleware, err := NewMiddleware(mcache.NewByteCache(), PayloadHashKey[payment](), WithRetention(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewMiddleware() error = %v", err)
+	}
+	calls := 0
+	handler := middleware(func(ctx context.Context, topic string, data payment) error {
+		calls++
+		return nil
+	})
+	for range 2 {
+		if err = handler(ctx, "payments", payment{ID: 5, Amount: 1}); err != nil {
+			t.Fatalf("handler() error = %v", err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err = handler(ctx, "payments", payment{ID: 5, Amount: 1}); err != nil {
+		t.Fatalf("handler() after retention error = %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("handler calls = %d, want 2", calls)
+	}
+}
+
+func TestNewMiddlewareValidation(t *testing.T) {
+	if _, err := NewMiddleware[payment](nil, paymentKey); err == nil {
+		t.Fatal("expected NewMiddleware without cache to fail")
+	}
+	if _, err := NewMiddleware[payment](mcache.NewByteCache(), nil); err == nil {
+		t.Fatal("expected NewMiddleware without key func to fail")
+	}
+}
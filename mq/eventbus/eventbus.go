@@ -0,0 +1,290 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/go-sphere/sphere/log"
+	"github.com/go-sphere/sphere/mq"
+)
+
+var ErrBusClosed = errors.New("eventbus: bus is closed")
+
+type options struct {
+	workers      int
+	queueSize    int
+	errorHandler func(ctx context.Context, event any, err error)
+}
+
+func newOptions(opts ...Option) *options {
+	defaults := &options{
+		workers:   4,
+		queueSize: 100,
+		errorHandler: func(ctx context.Context, event any, err error) {
+			log.Warn("eventbus: async handler failed", log.String("event", fmt.Sprintf("%T", event)), log.Err(err))
+		},
+	}
+	for _, opt := range opts {
+		opt(defaults)
+	}
+	return defaults
+}
+
+// Option is a functional option for configuring the event bus.
+type Option func(*options)
+
+// WithWorkers sets the number of workers running async handlers. The default is 4.
+func WithWorkers(n int) Option {
+	return func(opts *options) {
+		opts.workers = n
+	}
+}
+
+// WithQueueSize sets how many async deliveries each worker buffers before Publish blocks.
+// The default is 100.
+func WithQueueSize(size int) Option {
+	return func(opts *options) {
+		opts.queueSize = size
+	}
+}
+
+// WithErrorHandler sets the callback receiving errors returned by async handlers.
+// By default they are logged.
+func WithErrorHandler(fn func(ctx context.Context, event any, err error)) Option {
+	return func(opts *options) {
+		opts.errorHandler = fn
+	}
+}
+
+type handlerOptions struct {
+	priority int
+}
+
+// HandlerOption is a functional option for configuring a single handler registration.
+type HandlerOption func(*handlerOptions)
+
+// WithPriority sets the handler priority. Handlers with a higher priority run first;
+// handlers with equal priority run in registration order. The default is 0.
+func WithPriority(priority int) HandlerOption {
+	return func(opts *handlerOptions) {
+		opts.priority = priority
+	}
+}
+
+// registration is a handler bound to one event type.
+type registration struct {
+	id       uint64
+	priority int
+	async    bool
+	worker   int
+	call     func(ctx context.Context, event any) error
+}
+
+// delivery is an event queued for an async handler.
+type delivery struct {
+	ctx   context.Context
+	event any
+	reg   *registration
+}
+
+// Bus dispatches in-process events to the handlers registered for their Go type.
+//
+// Synchronous handlers run on the publishing goroutine in priority order, and every one of them
+// runs even if an earlier one fails; their errors are joined and returned by Publish.
+// Async handlers run on a fixed worker pool. Each async handler is pinned to one worker, so it
+// observes events in publish order; their errors go to the configured error handler.
+type Bus struct {
+	opts    *options
+	workers []chan delivery
+	wg      sync.WaitGroup
+	// closing is closed by Close to release publishers waiting on a full queue, and senders
+	// counts those publishers so that the worker channels are closed only once they have left.
+	closing chan struct{}
+	senders sync.WaitGroup
+
+	mu       sync.RWMutex
+	handlers map[reflect.Type][]*registration
+	nextID   uint64
+	closed   bool
+}
+
+// New creates an event bus and starts its async workers.
+func New(opt ...Option) *Bus {
+	opts := newOptions(opt...)
+	b := &Bus{
+		opts:     opts,
+		workers:  make([]chan delivery, max(opts.workers, 1)),
+		closing:  make(chan struct{}),
+		handlers: make(map[reflect.Type][]*registration),
+	}
+	for i := range b.workers {
+		ch := make(chan delivery, max(opts.queueSize, 0))
+		b.workers[i] = ch
+		b.wg.Go(func() {
+			for d := range ch {
+				if err := invoke(d.ctx, d.reg, d.event); err != nil && b.opts.errorHandler != nil {
+					b.opts.errorHandler(d.ctx, d.event, err)
+				}
+			}
+		})
+	}
+	return b
+}
+
+// Subscribe registers a synchronous handler for events of type E and returns a function that removes it.
+// Events are matched on the exact type argument used with Publish, so E and *E are distinct events.
+func Subscribe[E any](b *Bus, handler func(ctx context.Context, event E) error, opt ...HandlerOption) func() {
+	return b.register(reflect.TypeFor[E](), false, wrap(handler), opt...)
+}
+
+// SubscribeAsync registers a handler for events of type E that runs on the bus worker pool,
+// and returns a function that removes it.
+func SubscribeAsync[E any](b *Bus, handler func(ctx context.Context, event E) error, opt ...HandlerOption) func() {
+	return b.register(reflect.TypeFor[E](), true, wrap(handler), opt...)
+}
+
+// Forward registers a synchronous handler broadcasting every event of type E to the topic
+// of an external mq.PubSub, and returns a function that stops forwarding.
+// Broadcast failures are reported by Publish like any other handler error.
+func Forward[E any](b *Bus, pubsub mq.PubSub[E], topic string, opt ...HandlerOption) func() {
+	return Subscribe(b, func(ctx context.Context, event E) error {
+		return pubsub.Broadcast(ctx, topic, event)
+	}, opt...)
+}
+
+// Publish dispatches the event to the handlers registered for type E.
+// It runs the synchronous handlers, returning their joined errors, and then queues the event for
+// the async handlers, blocking while a worker queue is full until the context is cancelled.
+// Async handlers receive a context that keeps the values of ctx but not its cancellation.
+func Publish[E any](ctx context.Context, b *Bus, event E) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrBusClosed
+	}
+	regs := b.handlers[reflect.TypeFor[E]()]
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, reg := range regs {
+		if !reg.async {
+			errs = append(errs, invoke(ctx, reg, event))
+		}
+	}
+	asyncCtx := context.WithoutCancel(ctx)
+	for _, reg := range regs {
+		if reg.async {
+			if err := b.enqueue(ctx, delivery{ctx: asyncCtx, event: event, reg: reg}); err != nil {
+				errs = append(errs, err)
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops accepting events and waits until the async workers have drained their queues
+// or the context is done. Publishers waiting on a full queue fail with ErrBusClosed.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.closing)
+		go func() {
+			b.senders.Wait()
+			for _, ch := range b.workers {
+				close(ch)
+			}
+		}()
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Bus) register(eventType reflect.Type, async bool, call func(ctx context.Context, event any) error, opt ...HandlerOption) func() {
+	opts := &handlerOptions{}
+	for _, o := range opt {
+		o(opts)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	reg := &registration{
+		id:       b.nextID,
+		priority: opts.priority,
+		async:    async,
+		worker:   int(b.nextID % uint64(len(b.workers))),
+		call:     call,
+	}
+	// Handler slices are copied on write so that Publish can iterate a snapshot without locking.
+	regs := append(slices.Clone(b.handlers[eventType]), reg)
+	slices.SortStableFunc(regs, func(x, y *registration) int {
+		return y.priority - x.priority
+	})
+	b.handlers[eventType] = regs
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			b.handlers[eventType] = slices.DeleteFunc(slices.Clone(b.handlers[eventType]), func(r *registration) bool {
+				return r.id == reg.id
+			})
+			if len(b.handlers[eventType]) == 0 {
+				delete(b.handlers, eventType)
+			}
+		})
+	}
+}
+
+func (b *Bus) enqueue(ctx context.Context, d delivery) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return ErrBusClosed
+	}
+	b.senders.Add(1)
+	b.mu.RUnlock()
+	defer b.senders.Done()
+	select {
+	case b.workers[d.reg.worker] <- d:
+		return nil
+	case <-b.closing:
+		return ErrBusClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func wrap[E any](handler func(ctx context.Context, event E) error) func(ctx context.Context, event any) error {
+	return func(ctx context.Context, event any) error {
+		return handler(ctx, event.(E))
+	}
+}
+
+// invoke runs a handler, converting a panic into an error.
+func invoke(ctx context.Context, reg *registration, event any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("eventbus: handler panic: %v", r)
+		}
+	}()
+	return reg.call(ctx, event)
+}
@@ -0,0 +1,239 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/mq/memory"
+)
+
+type userRegistered struct {
+	ID int `json:"id"`
+}
+
+type orderPaid struct {
+	ID int `json:"id"`
+}
+
+func newBus(t *testing.T, opt ...Option) *Bus {
+	t.Helper()
+	bus := New(opt...)
+	t.Cleanup(func() { _ = bus.Close(context.Background()) })
+	return bus
+}
+
+func TestSyncHandlersRunByPriority(t *testing.T) {
+	bus := newBus(t)
+	var order []string
+	record := func(name string) func(context.Context, userRegistered) error {
+		return func(ctx context.Context, event userRegistered) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	Subscribe(bus, record("default-1"))
+	Subscribe(bus, record("low"), WithPriority(-10))
+	Subscribe(bus, record("high"), WithPriority(10))
+	Subscribe(bus, record("default-2"))
+	Subscribe(bus, func(ctx context.Context, event orderPaid) error {
+		order = append(order, "other-type")
+		return nil
+	})
+
+	if err := Publish(context.Background(), bus, userRegistered{ID: 1}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	want := []string{"high", "default-1", "default-2", "low"}
+	if !slices.Equal(order, want) {
+		t.Fatalf("handler order = %v, want %v", order, want)
+	}
+}
+
+func TestSyncHandlerErrorsAreAggregated(t *testing.T) {
+	bus := newBus(t)
+	errFirst := errors.New("send welcome mail")
+	errSecond := errors.New("grant coupon")
+	calls := 0
+	Subscribe(bus, func(ctx context.Context, event userRegistered) error {
+		calls++
+		return errFirst
+	})
+	Subscribe(bus, func(ctx context.Context, event userRegistered) error {
+		calls++
+		panic("boom")
+	})
+	Subscribe(bus, func(ctx context.Context, event userRegistered) error {
+		calls++
+		return errSecond
+	})
+
+	err := Publish(context.Background(), bus, userRegistered{ID: 1})
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Fatalf("Publish() error = %v, want both handler errors", err)
+	}
+	if calls != 3 {
+		t.Fatalf("handler calls = %d, want 3", calls)
+	}
+}
+
+func TestAsyncHandlersPreserveOrderAndDrainOnClose(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+	bus := New(WithWorkers(3), WithQueueSize(1), WithErrorHandler(func(ctx context.Context, event any, err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}))
+
+	received := make([][]int, 3)
+	for i := range received {
+		SubscribeAsync(bus, func(ctx context.Context, event orderPaid) error {
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			received[i] = append(received[i], event.ID)
+			mu.Unlock()
+			if event.ID == 5 && i == 0 {
+				return errors.New("ledger unavailable")
+			}
+			return nil
+		})
+	}
+
+	var want []int
+	for id := range 10 {
+		want = append(want, id)
+		if err := Publish(context.Background(), bus, orderPaid{ID: id}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	for i, got := range received {
+		if !slices.Equal(got, want) {
+			t.Fatalf("async handler %d received %v, want %v", i, got, want)
+		}
+	}
+	if len(errs) != 1 {
+		t.Fatalf("async errors = %v, want exactly one", errs)
+	}
+	if err := Publish(context.Background(), bus, orderPaid{ID: 11}); !errors.Is(err, ErrBusClosed) {
+		t.Fatalf("Publish() after Close error = %v, want %v", err, ErrBusClosed)
+	}
+}
+
+func TestCloseReleasesRepublishingHandler(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+	bus := New(WithWorkers(1), WithQueueSize(1), WithErrorHandler(func(ctx context.Context, event any, err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}))
+	started := make(chan struct{})
+	SubscribeAsync(bus, func(ctx context.Context, event orderPaid) error {
+		if event.ID != 0 {
+			return nil
+		}
+		close(started)
+		// The second event does not fit the queue of the worker running this handler.
+		for id := 1; id <= 2; id++ {
+			if err := Publish(ctx, bus, orderPaid{ID: id}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err := Publish(context.Background(), bus, orderPaid{ID: 0}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	<-started
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := bus.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrBusClosed) {
+		t.Fatalf("async errors = %v, want %v", errs, ErrBusClosed)
+	}
+}
+
+func TestCloseHonoursContext(t *testing.T) {
+	bus := newBus(t, WithWorkers(1))
+	release := make(chan struct{})
+	SubscribeAsync(bus, func(ctx context.Context, event orderPaid) error {
+		<-release
+		return nil
+	})
+	if err := Publish(context.Background(), bus, orderPaid{ID: 1}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Close() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(release)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	bus := newBus(t)
+	calls := 0
+	unsubscribe := Subscribe(bus, func(ctx context.Context, event userRegistered) error {
+		calls++
+		return nil
+	})
+	ctx := context.Background()
+	_ = Publish(ctx, bus, userRegistered{ID: 1})
+	unsubscribe()
+	unsubscribe()
+	_ = Publish(ctx, bus, userRegistered{ID: 2})
+	if calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls)
+	}
+}
+
+func TestForwardToPubSub(t *testing.T) {
+	ctx := context.Background()
+	bus := newBus(t)
+	pubsub := memory.NewPubSub[orderPaid]()
+	t.Cleanup(func() { _ = pubsub.Close() })
+
+	received := make(chan orderPaid, 1)
+	if err := pubsub.Subscribe(ctx, "orders.paid", func(event orderPaid) error {
+		received <- event
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	stop := Forward(bus, pubsub, "orders.paid")
+
+	if err := Publish(ctx, bus, orderPaid{ID: 7}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	select {
+	case event := <-received:
+		if event.ID != 7 {
+			t.Fatalf("forwarded event = %+v, want ID 7", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for forwarded event")
+	}
+
+	stop()
+	if err := Publish(ctx, bus, orderPaid{ID: 8}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	select {
+	case event := <-received:
+		t.Fatalf("event forwarded after stop: %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,35 @@
+package mq
+
+import "context"
+
+// Handler processes a single message delivered on a topic.
+// It is the common shape consumed by message middlewares such as deduplication or rate limiting.
+type Handler[T any] func(ctx context.Context, topic string, data T) error
+
+// Middleware decorates a Handler with cross-cutting behavior.
+type Middleware[T any] func(next Handler[T]) Handler[T]
+
+// Chain wraps the handler with the given middlewares.
+// The first middleware becomes the outermost one and runs first.
+func Chain[T any](handler Handler[T], middlewares ...Middleware[T]) Handler[T] {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// SubscribeFunc adapts the handler to the callback accepted by PubSub.Subscribe.
+// Every message is processed with ctx and attributed to topic.
+func (h Handler[T]) SubscribeFunc(ctx context.Context, topic string) func(data T) error {
+	return func(data T) error {
+		return h(ctx, topic, data)
+	}
+}
+
+// PSubscribeFunc adapts the handler to the callback accepted by PubSub.PSubscribe.
+// Every message is processed with ctx and attributed to its concrete topic.
+func (h Handler[T]) PSubscribeFunc(ctx context.Context) func(topic string, data T) error {
+	return func(topic string, data T) error {
+		return h(ctx, topic, data)
+	}
+}
@@ -0,0 +1,280 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-sphere/confstore/codec"
+)
+
+// buffer is a bounded FIFO of messages for a single topic.
+// Unlike a channel it can be inspected without consuming messages.
+// Waiters block on signal channels that are closed and replaced whenever the buffer changes.
+//
+// When a spill log is attached, messages that do not fit in memory are appended to disk instead
+// of applying the overflow policy, and are moved back into memory as consumers make room.
+// Once anything has spilled, new messages also go to disk until it is drained, preserving FIFO order.
+type buffer[T any] struct {
+	mu       sync.Mutex
+	items    []T
+	size     int
+	overflow OverflowPolicy
+	closed   bool
+	readable chan struct{}
+	writable chan struct{}
+
+	spill *spillLog
+	codec codec.Codec
+}
+
+// newBuffer creates a buffer holding at most size messages in memory. Sizes below one are treated as one.
+func newBuffer[T any](size int, overflow OverflowPolicy) *buffer[T] {
+	return &buffer[T]{
+		size:     max(size, 1),
+		overflow: overflow,
+		readable: make(chan struct{}),
+		writable: make(chan struct{}),
+	}
+}
+
+// openSpillBuffer creates a buffer backed by the spill log in dir, restoring the messages
+// persisted by a previous close.
+func openSpillBuffer[T any](dir string, size int, segmentSize int64, c codec.Codec) (*buffer[T], error) {
+	log, head, err := openSpillLog(dir, segmentSize)
+	if err != nil {
+		return nil, err
+	}
+	b := newBuffer[T](size, OverflowBlock)
+	b.spill = log
+	b.codec = c
+	for _, raw := range head {
+		var data T
+		if err = c.Unmarshal(raw, &data); err != nil {
+			return nil, errors.Join(err, log.closeFiles())
+		}
+		b.items = append(b.items, data)
+	}
+	return b, nil
+}
+
+// push appends a message according to the overflow policy.
+func (b *buffer[T]) push(ctx context.Context, data T) error {
+	return b.pushAll(ctx, []T{data})
+}
+
+// pushAll appends the messages in order according to the overflow policy.
+// If the context is cancelled or an error is returned midway, the messages appended so far remain buffered.
+func (b *buffer[T]) pushAll(ctx context.Context, items []T) error {
+	for {
+		b.mu.Lock()
+		n, err := b.appendLocked(items)
+		if n > 0 {
+			b.notify(&b.readable)
+		}
+		items = items[n:]
+		wait := b.writable
+		b.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// appendLocked stores as many messages as possible without blocking and reports how many were
+// handled, including dropped ones. Callers must hold mu.
+func (b *buffer[T]) appendLocked(items []T) (int, error) {
+	if b.closed {
+		return 0, ErrQueueClosed
+	}
+	for i, data := range items {
+		switch {
+		case b.spill != nil && (b.spill.count > 0 || len(b.items) >= b.size):
+			raw, err := b.codec.Marshal(data)
+			if err != nil {
+				return i, err
+			}
+			if err = b.spill.append(raw); err != nil {
+				return i, err
+			}
+		case len(b.items) < b.size:
+			b.items = append(b.items, data)
+		case b.overflow == OverflowDropNewest:
+		case b.overflow == OverflowDropOldest:
+			var zero T
+			b.items[0] = zero
+			b.items = append(b.items[1:], data)
+		case b.overflow == OverflowError:
+			return i, ErrQueueFull
+		default:
+			return i, nil
+		}
+	}
+	return len(items), nil
+}
+
+// pop removes the oldest message, blocking while the buffer is empty.
+// Messages still buffered when the buffer is closed can be drained before ErrQueueClosed is returned.
+func (b *buffer[T]) pop(ctx context.Context) (T, error) {
+	var zero T
+	for {
+		items, ready, err := b.take(1)
+		if err != nil {
+			return zero, err
+		}
+		if len(items) > 0 {
+			return items[0], nil
+		}
+		select {
+		case <-ready:
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// tryPop removes the oldest message without blocking.
+func (b *buffer[T]) tryPop() (T, bool, error) {
+	var zero T
+	items, _, err := b.take(1)
+	if err != nil || len(items) == 0 {
+		return zero, false, err
+	}
+	return items[0], true, nil
+}
+
+// take removes up to n of the oldest messages without blocking. When none are buffered it
+// returns a channel that is closed once new messages arrive.
+func (b *buffer[T]) take(n int) ([]T, <-chan struct{}, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.refillLocked(); err != nil {
+		return nil, nil, err
+	}
+	if len(b.items) == 0 {
+		if b.closed {
+			return nil, nil, ErrQueueClosed
+		}
+		return nil, b.readable, nil
+	}
+	n = min(n, len(b.items))
+	items := append([]T(nil), b.items[:n]...)
+	clear(b.items[:n])
+	b.items = b.items[n:]
+	b.notify(&b.writable)
+	return items, nil, nil
+}
+
+// refillLocked moves spilled messages back into memory while there is room. Callers must hold mu.
+func (b *buffer[T]) refillLocked() error {
+	if b.closed || b.spill == nil || b.spill.count == 0 || len(b.items) >= b.size {
+		return nil
+	}
+	for b.spill.count > 0 && len(b.items) < b.size {
+		raw, err := b.spill.next()
+		if err != nil {
+			return err
+		}
+		var data T
+		if err = b.codec.Unmarshal(raw, &data); err != nil {
+			return errors.Join(err, b.spill.commit())
+		}
+		b.items = append(b.items, data)
+	}
+	return b.spill.commit()
+}
+
+// purge drops all buffered messages, including spilled ones.
+func (b *buffer[T]) purge() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.items = nil
+	b.notify(&b.writable)
+	if b.spill != nil {
+		return b.spill.reset()
+	}
+	return nil
+}
+
+// len returns the number of buffered messages, including spilled ones.
+func (b *buffer[T]) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(b.items)
+	if b.spill != nil {
+		n += b.spill.count
+	}
+	return n
+}
+
+// peek returns copies of up to n of the oldest messages without removing them.
+func (b *buffer[T]) peek(n int) ([]T, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n = max(n, 0)
+	items := append([]T(nil), b.items[:min(n, len(b.items))]...)
+	if b.spill == nil || len(items) == n {
+		return items, nil
+	}
+	raws, err := b.spill.peek(n - len(items))
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range raws {
+		var data T
+		if err = b.codec.Unmarshal(raw, &data); err != nil {
+			return nil, err
+		}
+		items = append(items, data)
+	}
+	return items, nil
+}
+
+// close wakes all waiters; pending and future pushes fail with ErrQueueClosed.
+// With a spill log, the messages still in memory are persisted for the next open instead of
+// remaining available to consumers.
+func (b *buffer[T]) close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	close(b.readable)
+	close(b.writable)
+	if b.spill == nil {
+		return nil
+	}
+
+	head := make([][]byte, 0, len(b.items))
+	var err error
+	for _, data := range b.items {
+		raw, mErr := b.codec.Marshal(data)
+		if mErr != nil {
+			err = errors.Join(err, mErr)
+			continue
+		}
+		head = append(head, raw)
+	}
+	b.items = nil
+	return errors.Join(err, b.spill.close(head))
+}
+
+// notify wakes every goroutine waiting on the signal and arms a fresh one. Callers must hold mu.
+func (b *buffer[T]) notify(signal *chan struct{}) {
+	if b.closed {
+		return
+	}
+	close(*signal)
+	*signal = make(chan struct{})
+}
@@ -1,13 +1,40 @@
 package memory
 
+import (
+	"github.com/go-sphere/confstore/codec"
+	"github.com/go-sphere/sphere/mq"
+)
+
+// OverflowPolicy decides what Queue.Publish does when a topic queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the publisher until a consumer makes room or the context is cancelled.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest silently discards the message being published.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest pending message to make room for the new one.
+	OverflowDropOldest
+	// OverflowError rejects the message with ErrQueueFull.
+	OverflowError
+)
+
 // options holds configuration parameters for memory-based message queue implementations.
 type options struct {
-	queueSize int
+	queueSize        int
+	priorityWeights  [mq.PriorityLevels]int
+	overflow         OverflowPolicy
+	spillSegmentSize int64
+	codec            codec.Codec
 }
 
 func newOptions(opts ...Option) *options {
 	o := &options{
-		queueSize: 100, // default queue size
+		queueSize:        100,               // default queue size
+		priorityWeights:  [...]int{4, 2, 1}, // default high/normal/low weights
+		overflow:         OverflowBlock,
+		spillSegmentSize: 64 << 20, // 64 MiB per spill segment
+		codec:            codec.JsonCodec(),
 	}
 	for _, opt := range opts {
 		opt(o)
@@ -18,10 +45,43 @@ func newOptions(opts ...Option) *options {
 // Option defines a function type for configuring memory message queue options.
 type Option func(*options)
 
-// WithQueueSize sets the buffer size for message channels in the memory queue.
-// A larger size allows more messages to be buffered before blocking publishers.
+// WithQueueSize sets how many messages each topic buffers in memory.
+// A larger size allows more messages to be buffered before the overflow policy applies.
 func WithQueueSize(size int) Option {
 	return func(o *options) {
 		o.queueSize = size
 	}
 }
+
+// WithPriorityWeights sets the relative share of deliveries each priority level receives
+// in a PriorityQueue while several levels have pending messages.
+// A weight of zero only serves that level when all other levels are empty.
+func WithPriorityWeights(high, normal, low int) Option {
+	return func(o *options) {
+		o.priorityWeights = [...]int{high, normal, low}
+	}
+}
+
+// WithOverflowPolicy sets what Queue does when a topic queue is full. The default is OverflowBlock.
+// The policy does not apply to queues created with OpenQueue, which spill to disk instead.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflow = policy
+	}
+}
+
+// WithSpillSegmentSize sets the size at which OpenQueue starts a new spill segment file.
+// The default is 64 MiB.
+func WithSpillSegmentSize(size int64) Option {
+	return func(o *options) {
+		o.spillSegmentSize = size
+	}
+}
+
+// WithCodec sets the codec OpenQueue uses to write messages to disk.
+// If not specified, JSON codec is used by default.
+func WithCodec(codec codec.Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
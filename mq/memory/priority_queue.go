@@ -0,0 +1,257 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-sphere/sphere/mq"
+)
+
+// priorityTopic holds the per-level channels of a single topic and the weighted selection state.
+type priorityTopic[T any] struct {
+	levels [mq.PriorityLevels]chan T
+	// ready wakes a blocked consumer when messages may be pending.
+	ready chan struct{}
+
+	mu      sync.Mutex
+	current [mq.PriorityLevels]int
+}
+
+// PriorityQueue implements an in-memory point-to-point queue with per-message priorities.
+// Each topic keeps one buffered channel per priority level. While several levels have pending
+// messages, consumers pick among them with smooth weighted round-robin, so higher priorities
+// are served more often without starving lower ones.
+type PriorityQueue[T any] struct {
+	queueSize int
+	weights   [mq.PriorityLevels]int
+	topics    map[string]*priorityTopic[T]
+	done      chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPriorityQueue creates a new memory-based priority queue with the specified options.
+// The default queue size is 100 messages per priority level and the default weights are 4:2:1.
+func NewPriorityQueue[T any](opt ...Option) *PriorityQueue[T] {
+	opts := newOptions(opt...)
+	weights := opts.priorityWeights
+	for i := range weights {
+		weights[i] = max(weights[i], 0)
+	}
+	return &PriorityQueue[T]{
+		queueSize: opts.queueSize,
+		weights:   weights,
+		topics:    make(map[string]*priorityTopic[T]),
+		done:      make(chan struct{}),
+	}
+}
+
+func (q *PriorityQueue[T]) Publish(ctx context.Context, topic string, data T) error {
+	return q.PublishWithPriority(ctx, topic, mq.PriorityNormal, data)
+}
+
+func (q *PriorityQueue[T]) PublishWithPriority(ctx context.Context, topic string, priority mq.Priority, data T) error {
+	if !priority.Valid() {
+		return mq.ErrInvalidPriority
+	}
+	pt, err := q.getOrCreateTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case pt.levels[priority] <- data:
+		pt.notify()
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *PriorityQueue[T]) Consume(ctx context.Context, topic string) (T, error) {
+	pt, err := q.getOrCreateTopic(topic)
+	var zero T
+	if err != nil {
+		return zero, err
+	}
+
+	// Wake-ups only signal that messages may be pending; each attempt picks the level with
+	// weighted round-robin, so a consumer that was already waiting still honors priorities.
+	for {
+		data, found, rErr := q.tryReceive(pt)
+		if rErr != nil {
+			return zero, rErr
+		}
+		if found {
+			return data, nil
+		}
+		select {
+		case <-pt.ready:
+		case <-q.done:
+			return zero, ErrQueueClosed
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+func (q *PriorityQueue[T]) TryConsume(ctx context.Context, topic string) (T, bool, error) {
+	var zero T
+	q.mu.RLock()
+	pt, exists := q.topics[topic]
+	closed := q.closed
+	q.mu.RUnlock()
+	if closed {
+		return zero, false, ErrQueueClosed
+	}
+	if !exists {
+		return zero, false, nil
+	}
+	select {
+	case <-ctx.Done():
+		return zero, false, ctx.Err()
+	default:
+	}
+	return q.tryReceive(pt)
+}
+
+func (q *PriorityQueue[T]) PurgeQueue(ctx context.Context, topic string) error {
+	q.mu.RLock()
+	pt, exists := q.topics[topic]
+	closed := q.closed
+	q.mu.RUnlock()
+
+	if closed {
+		return ErrQueueClosed
+	}
+	if !exists {
+		return nil
+	}
+
+	for _, ch := range pt.levels {
+	drain:
+		for {
+			select {
+			case _, ok := <-ch:
+				if !ok {
+					return ErrQueueClosed
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+				break drain
+			}
+		}
+	}
+	return nil
+}
+
+func (q *PriorityQueue[T]) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	close(q.done)
+	for _, pt := range q.topics {
+		for _, ch := range pt.levels {
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// tryReceive performs a non-blocking receive, choosing the level with weighted round-robin.
+// If the chosen level was drained concurrently, the remaining levels are tried in priority order.
+func (q *PriorityQueue[T]) tryReceive(pt *priorityTopic[T]) (T, bool, error) {
+	var zero T
+	preferred, ok := q.pickLevel(pt)
+	if !ok {
+		return zero, false, nil
+	}
+	order := make([]int, 0, mq.PriorityLevels)
+	order = append(order, preferred)
+	for level := range mq.PriorityLevels {
+		if level != preferred {
+			order = append(order, level)
+		}
+	}
+	for _, level := range order {
+		select {
+		case data, open := <-pt.levels[level]:
+			if !open {
+				return zero, false, ErrQueueClosed
+			}
+			if pt.pending() {
+				// Pass the wake-up on to another blocked consumer.
+				pt.notify()
+			}
+			return data, true, nil
+		default:
+		}
+	}
+	return zero, false, nil
+}
+
+// notify wakes one blocked consumer, or the next one to block.
+func (pt *priorityTopic[T]) notify() {
+	select {
+	case pt.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (pt *priorityTopic[T]) pending() bool {
+	for _, ch := range pt.levels {
+		if len(ch) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pickLevel runs one round of smooth weighted round-robin over the levels that have pending messages.
+func (q *PriorityQueue[T]) pickLevel(pt *priorityTopic[T]) (int, bool) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	best, total := -1, 0
+	for level, ch := range pt.levels {
+		if len(ch) == 0 {
+			continue
+		}
+		if best < 0 {
+			best = level
+		}
+		pt.current[level] += q.weights[level]
+		total += q.weights[level]
+		if pt.current[level] > pt.current[best] {
+			best = level
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	pt.current[best] -= total
+	return best, true
+}
+
+func (q *PriorityQueue[T]) getOrCreateTopic(topic string) (*priorityTopic[T], error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+	pt, exists := q.topics[topic]
+	if !exists {
+		pt = &priorityTopic[T]{ready: make(chan struct{}, 1)}
+		for level := range pt.levels {
+			pt.levels[level] = make(chan T, q.queueSize)
+		}
+		q.topics[topic] = pt
+	}
+	return pt, nil
+}
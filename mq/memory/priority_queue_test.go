@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/mq"
+)
+
+func TestPriorityQueueBlockedConsumerHonorsPriority(t *testing.T) {
+	ctx := context.Background()
+	for range 20 {
+		q := NewPriorityQueue[int]()
+		pt, err := q.getOrCreateTopic("jobs")
+		if err != nil {
+			t.Fatalf("getOrCreateTopic: %v", err)
+		}
+		got := make(chan int, 1)
+		go func() {
+			msg, _ := q.Consume(ctx, "jobs")
+			got <- msg
+		}()
+		time.Sleep(time.Millisecond)
+
+		// Fill every level before waking the consumer.
+		pt.levels[mq.PriorityLow] <- 3
+		pt.levels[mq.PriorityNormal] <- 2
+		pt.levels[mq.PriorityHigh] <- 1
+		pt.notify()
+
+		select {
+		case msg := <-got:
+			if msg != 1 {
+				t.Fatalf("blocked consumer received %d, want the high priority message", msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("blocked consumer was not woken")
+		}
+		_ = q.Close()
+	}
+}
+
+func TestPriorityQueueCloseWakesConsumer(t *testing.T) {
+	q := NewPriorityQueue[int]()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := q.Consume(context.Background(), "jobs")
+		errs <- err
+	}()
+	time.Sleep(time.Millisecond)
+	_ = q.Close()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrQueueClosed) {
+			t.Fatalf("Consume error = %v, want %v", err, ErrQueueClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not wake the consumer")
+	}
+}
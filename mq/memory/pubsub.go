@@ -3,23 +3,32 @@ package memory
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sync"
 
 	"github.com/go-sphere/sphere/log"
+	"github.com/go-sphere/sphere/mq"
 )
 
+// message pairs a payload with the concrete topic it was broadcast on.
+type message[T any] struct {
+	topic string
+	data  T
+}
+
 // Subscription represents an active subscription to a topic with its associated handler and channels.
 type Subscription[T any] struct {
-	handler func(data T) error
-	ch      chan T
+	handler func(topic string, data T) error
+	ch      chan message[T]
 	done    chan struct{}
 }
 
 // PubSub implements an in-memory publish-subscribe message system with typed message support.
-// It broadcasts messages to all active subscribers of a topic.
+// It broadcasts messages to all active subscribers of a topic and of every matching pattern.
 type PubSub[T any] struct {
 	queueSize int
 	topics    map[string][]*Subscription[T]
+	patterns  map[string][]*Subscription[T]
 
 	mu     sync.RWMutex
 	closed bool
@@ -32,12 +41,18 @@ func NewPubSub[T any](opt ...Option) *PubSub[T] {
 	return &PubSub[T]{
 		queueSize: opts.queueSize,
 		topics:    make(map[string][]*Subscription[T]),
+		patterns:  make(map[string][]*Subscription[T]),
 	}
 }
 
 func (p *PubSub[T]) Broadcast(ctx context.Context, topic string, data T) error {
 	p.mu.RLock()
-	subscribers, exists := p.topics[topic]
+	subscribers := append([]*Subscription[T](nil), p.topics[topic]...)
+	for pattern, subs := range p.patterns {
+		if mq.MatchTopic(pattern, topic) {
+			subscribers = append(subscribers, subs...)
+		}
+	}
 	closed := p.closed
 	p.mu.RUnlock()
 
@@ -45,17 +60,18 @@ func (p *PubSub[T]) Broadcast(ctx context.Context, topic string, data T) error {
 		return fmt.Errorf("pubsub is closed")
 	}
 
-	if !exists || len(subscribers) == 0 {
+	if len(subscribers) == 0 {
 		return nil
 	}
 
+	msg := message[T]{topic: topic, data: data}
 	var wg sync.WaitGroup
 	for _, sub := range subscribers {
 		wg.Add(1)
 		go func(s *Subscription[T]) {
 			defer wg.Done()
 			select {
-			case s.ch <- data:
+			case s.ch <- msg:
 			case <-ctx.Done():
 			case <-s.done:
 			}
@@ -67,6 +83,18 @@ func (p *PubSub[T]) Broadcast(ctx context.Context, topic string, data T) error {
 }
 
 func (p *PubSub[T]) Subscribe(ctx context.Context, topic string, handler func(data T) error) error {
+	return p.subscribe(p.topics, topic, func(_ string, data T) error {
+		return handler(data)
+	})
+}
+
+// PSubscribe registers a handler for every topic matching the glob pattern.
+// Patterns are resolved with mq.MatchTopic on each Broadcast.
+func (p *PubSub[T]) PSubscribe(ctx context.Context, pattern string, handler func(topic string, data T) error) error {
+	return p.subscribe(p.patterns, pattern, handler)
+}
+
+func (p *PubSub[T]) subscribe(registry map[string][]*Subscription[T], name string, handler func(topic string, data T) error) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -76,10 +104,10 @@ func (p *PubSub[T]) Subscribe(ctx context.Context, topic string, handler func(da
 
 	sub := &Subscription[T]{
 		handler: handler,
-		ch:      make(chan T, p.queueSize),
+		ch:      make(chan message[T], p.queueSize),
 		done:    make(chan struct{}),
 	}
-	p.topics[topic] = append(p.topics[topic], sub)
+	registry[name] = append(registry[name], sub)
 
 	go func() {
 		defer func() {
@@ -96,8 +124,8 @@ func (p *PubSub[T]) Subscribe(ctx context.Context, topic string, handler func(da
 func (p *PubSub[T]) handleSubscription(sub *Subscription[T]) {
 	for {
 		select {
-		case data := <-sub.ch:
-			if err := sub.handler(data); err != nil {
+		case msg := <-sub.ch:
+			if err := sub.handler(msg.topic, msg.data); err != nil {
 				fmt.Printf("handler error: %v\n", err)
 			}
 		case <-sub.done:
@@ -107,6 +135,14 @@ func (p *PubSub[T]) handleSubscription(sub *Subscription[T]) {
 }
 
 func (p *PubSub[T]) UnsubscribeAll(ctx context.Context, topic string) error {
+	return p.unsubscribeAll(p.topics, topic)
+}
+
+func (p *PubSub[T]) PUnsubscribeAll(ctx context.Context, pattern string) error {
+	return p.unsubscribeAll(p.patterns, pattern)
+}
+
+func (p *PubSub[T]) unsubscribeAll(registry map[string][]*Subscription[T], name string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -114,16 +150,49 @@ func (p *PubSub[T]) UnsubscribeAll(ctx context.Context, topic string) error {
 		return fmt.Errorf("pubsub is closed")
 	}
 
-	if subscribers, exists := p.topics[topic]; exists {
+	if subscribers, exists := registry[name]; exists {
 		for _, sub := range subscribers {
 			close(sub.done)
 		}
-		delete(p.topics, topic)
+		delete(registry, name)
 	}
 
 	return nil
 }
 
+// ActiveTopics returns the sorted names of the topics that currently have at least one subscriber.
+func (p *PubSub[T]) ActiveTopics(ctx context.Context) ([]string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil, fmt.Errorf("pubsub is closed")
+	}
+	topics := make([]string, 0, len(p.topics))
+	for topic, subscribers := range p.topics {
+		if len(subscribers) > 0 {
+			topics = append(topics, topic)
+		}
+	}
+	slices.Sort(topics)
+	return topics, nil
+}
+
+// SubscriberCounts returns the number of subscribers of each specified topic in this process.
+func (p *PubSub[T]) SubscriberCounts(ctx context.Context, topics ...string) (map[string]int64, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return nil, fmt.Errorf("pubsub is closed")
+	}
+	counts := make(map[string]int64, len(topics))
+	for _, topic := range topics {
+		counts[topic] = int64(len(p.topics[topic]))
+	}
+	return counts, nil
+}
+
 func (p *PubSub[T]) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -132,9 +201,11 @@ func (p *PubSub[T]) Close() error {
 	}
 	p.closed = true
 
-	for _, subscribers := range p.topics {
-		for _, sub := range subscribers {
-			close(sub.done)
+	for _, registry := range []map[string][]*Subscription[T]{p.topics, p.patterns} {
+		for _, subscribers := range registry {
+			for _, sub := range subscribers {
+				close(sub.done)
+			}
 		}
 	}
 	return nil
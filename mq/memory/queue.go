@@ -2,64 +2,166 @@ package memory
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 )
 
 var (
 	ErrNoMessage   = errors.New("memory mq: no message available")
 	ErrQueueClosed = errors.New("memory mq: queue is closed")
+	ErrQueueFull   = errors.New("memory mq: queue is full")
 )
 
+// topicDirPrefix marks the per-topic directories created by OpenQueue.
+const topicDirPrefix = "topic-"
+
 // Queue implements an in-memory point-to-point message queue with typed message support.
 // It provides FIFO message delivery to exactly one consumer per topic.
 type Queue[T any] struct {
-	queueSize int
-	queues    map[string]chan T
+	opts     *options
+	spillDir string
+	queues   map[string]*buffer[T]
 
 	mu     sync.RWMutex
 	closed bool
 }
 
 // NewQueue creates a new memory-based queue with the specified options.
-// The default queue size is 100 messages per topic.
+// The default queue size is 100 messages per topic, and publishers block while a topic is full
+// unless another policy is set with WithOverflowPolicy.
 func NewQueue[T any](opt ...Option) *Queue[T] {
-	opts := newOptions(opt...)
 	return &Queue[T]{
-		queueSize: opts.queueSize,
-		queues:    make(map[string]chan T),
+		opts:   newOptions(opt...),
+		queues: make(map[string]*buffer[T]),
 	}
 }
 
+// OpenQueue creates a queue that spills to append-only segment files under dir instead of
+// blocking or dropping messages when a topic exceeds the queue size, so bursts are bounded by
+// disk rather than memory. Close persists the messages still held in memory, and the next
+// OpenQueue on the same directory restores every topic in its original order.
+// Messages held in memory are lost if the process crashes; spilled messages are not.
+// The directory must not be shared by several open queues.
+func OpenQueue[T any](dir string, opt ...Option) (*Queue[T], error) {
+	if dir == "" {
+		return nil, errors.New("spill directory is required")
+	}
+	opts := newOptions(opt...)
+	if opts.codec == nil {
+		return nil, errors.New("codec is required")
+	}
+	if opts.spillSegmentSize <= 0 {
+		return nil, errors.New("spill segment size must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	q := &Queue[T]{
+		opts:     opts,
+		spillDir: dir,
+		queues:   make(map[string]*buffer[T]),
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name, found := strings.CutPrefix(entry.Name(), topicDirPrefix)
+		if !entry.IsDir() || !found {
+			continue
+		}
+		topic, dErr := base64.RawURLEncoding.DecodeString(name)
+		if dErr != nil {
+			continue
+		}
+		queue, oErr := q.newBuffer(string(topic))
+		if oErr != nil {
+			return nil, errors.Join(oErr, q.Close())
+		}
+		q.queues[string(topic)] = queue
+	}
+	return q, nil
+}
+
 func (q *Queue[T]) Publish(ctx context.Context, topic string, data T) error {
 	queue, err := q.getOrCreateQueue(topic)
 	if err != nil {
 		return err
 	}
+	return queue.push(ctx, data)
+}
 
-	select {
-	case queue <- data:
+func (q *Queue[T]) Consume(ctx context.Context, topic string) (T, error) {
+	queue, err := q.getOrCreateQueue(topic)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return queue.pop(ctx)
+}
+
+// PublishBatch appends all messages to the topic queue in order, blocking while the queue is full.
+// If the context is cancelled midway, the messages appended so far stay queued.
+func (q *Queue[T]) PublishBatch(ctx context.Context, topic string, data []T) error {
+	if len(data) == 0 {
 		return nil
-	case <-ctx.Done():
-		return ctx.Err()
 	}
+	queue, err := q.getOrCreateQueue(topic)
+	if err != nil {
+		return err
+	}
+	return queue.pushAll(ctx, data)
 }
 
-func (q *Queue[T]) Consume(ctx context.Context, topic string) (T, error) {
+// ConsumeBatch drains up to limit messages from the topic queue, lingering for up to wait
+// while the batch is not full.
+func (q *Queue[T]) ConsumeBatch(ctx context.Context, topic string, limit int, wait time.Duration) ([]T, error) {
+	if limit <= 0 {
+		return []T{}, nil
+	}
 	queue, err := q.getOrCreateQueue(topic)
-	var zero T
 	if err != nil {
-		return zero, err
+		return nil, err
 	}
 
-	select {
-	case data, ok := <-queue:
-		if !ok {
-			return zero, ErrQueueClosed
+	var expired <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	batch := make([]T, 0, min(limit, q.opts.queueSize))
+	for {
+		items, ready, tErr := queue.take(limit - len(batch))
+		batch = append(batch, items...)
+		if tErr != nil {
+			if len(batch) > 0 {
+				return batch, nil
+			}
+			return nil, tErr
+		}
+		if len(batch) == limit || wait <= 0 {
+			return batch, nil
+		}
+		if len(items) > 0 {
+			continue
+		}
+		select {
+		case <-ready:
+		case <-expired:
+			return batch, nil
+		case <-ctx.Done():
+			if len(batch) > 0 {
+				return batch, nil
+			}
+			return nil, ctx.Err()
 		}
-		return data, nil
-	case <-ctx.Done():
-		return zero, ctx.Err()
 	}
 }
 
@@ -77,43 +179,57 @@ func (q *Queue[T]) TryConsume(ctx context.Context, topic string) (T, bool, error
 		return zero, false, ctx.Err()
 	default:
 	}
-
-	select {
-	case data, ok := <-queue:
-		if !ok {
-			return zero, false, ErrQueueClosed
-		}
-		return data, true, nil
-	default:
-		return zero, false, nil
-	}
+	return queue.tryPop()
 }
 
 func (q *Queue[T]) PurgeQueue(ctx context.Context, topic string) error {
-	q.mu.RLock()
-	queue, exists := q.queues[topic]
-	closed := q.closed
-	q.mu.RUnlock()
-
-	if closed {
-		return ErrQueueClosed
+	queue, exists, err := q.getQueue(topic)
+	if err != nil {
+		return err
 	}
 	if !exists {
 		return nil
 	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return queue.purge()
+}
 
-	for {
-		select {
-		case _, ok := <-queue:
-			if !ok {
-				return ErrQueueClosed
-			}
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			return nil
+// Len returns the number of messages waiting in the topic queue.
+func (q *Queue[T]) Len(ctx context.Context, topic string) (int64, error) {
+	queue, exists, err := q.getQueue(topic)
+	if err != nil || !exists {
+		return 0, err
+	}
+	return int64(queue.len()), nil
+}
+
+// Peek returns up to n of the oldest pending messages of the topic queue without consuming them.
+func (q *Queue[T]) Peek(ctx context.Context, topic string, n int) ([]T, error) {
+	queue, exists, err := q.getQueue(topic)
+	if err != nil || !exists || n <= 0 {
+		return nil, err
+	}
+	return queue.peek(n)
+}
+
+// Topics returns the sorted names of all topics that currently hold pending messages.
+func (q *Queue[T]) Topics(ctx context.Context) ([]string, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+	topics := make([]string, 0, len(q.queues))
+	for topic, queue := range q.queues {
+		if queue.len() > 0 {
+			topics = append(topics, topic)
 		}
 	}
+	slices.Sort(topics)
+	return topics, nil
 }
 
 func (q *Queue[T]) Close() error {
@@ -123,13 +239,14 @@ func (q *Queue[T]) Close() error {
 		return nil
 	}
 	q.closed = true
-	for _, ch := range q.queues {
-		close(ch)
+	var errs []error
+	for _, queue := range q.queues {
+		errs = append(errs, queue.close())
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
-func (q *Queue[T]) getOrCreateQueue(topic string) (chan T, error) {
+func (q *Queue[T]) getOrCreateQueue(topic string) (*buffer[T], error) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
@@ -138,13 +255,26 @@ func (q *Queue[T]) getOrCreateQueue(topic string) (chan T, error) {
 	}
 	queue, exists := q.queues[topic]
 	if !exists {
-		queue = make(chan T, q.queueSize)
+		var err error
+		queue, err = q.newBuffer(topic)
+		if err != nil {
+			return nil, err
+		}
 		q.queues[topic] = queue
 	}
 	return queue, nil
 }
 
-func (q *Queue[T]) getQueue(topic string) (chan T, bool, error) {
+func (q *Queue[T]) newBuffer(topic string) (*buffer[T], error) {
+	if q.spillDir == "" {
+		return newBuffer[T](q.opts.queueSize, q.opts.overflow), nil
+	}
+	// Topic names are encoded so that any string maps to a single safe directory name.
+	dir := filepath.Join(q.spillDir, topicDirPrefix+base64.RawURLEncoding.EncodeToString([]byte(topic)))
+	return openSpillBuffer[T](dir, q.opts.queueSize, q.opts.spillSegmentSize, q.opts.codec)
+}
+
+func (q *Queue[T]) getQueue(topic string) (*buffer[T], bool, error) {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
 
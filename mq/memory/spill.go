@@ -0,0 +1,411 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	segmentExt     = ".seg"
+	headFileName   = "head.dat"
+	cursorFileName = "cursor"
+	recordHeader   = 4
+)
+
+var errSpillEmpty = errors.New("memory mq: spill log is empty")
+
+// spillLog is an append-only log of length-prefixed records split across numbered segment files.
+// It holds the messages of a single topic that did not fit in memory. A cursor file records how far
+// the log has been read, and fully read segments are deleted.
+type spillLog struct {
+	dir            string
+	maxSegmentSize int64
+
+	segments  []uint64 // ascending sequence numbers of the segments still on disk
+	writer    *os.File
+	writeSize int64
+
+	reader     *bufio.Reader
+	readerFile *os.File
+	readOffset int64
+
+	count int // records written but not read yet
+}
+
+// openSpillLog opens or creates the log stored in dir. It returns the records saved by a previous
+// close, which precede everything else in the log, and removes them from disk.
+func openSpillLog(dir string, maxSegmentSize int64) (*spillLog, [][]byte, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+	l := &spillLog{
+		dir:            dir,
+		maxSegmentSize: maxSegmentSize,
+	}
+
+	head, err := readRecordsFile(filepath.Join(dir, headFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, pErr := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if pErr != nil {
+			continue
+		}
+		l.segments = append(l.segments, seq)
+	}
+	slices.Sort(l.segments)
+
+	cursorSeq, cursorOffset, err := l.readCursor()
+	if err != nil {
+		return nil, nil, err
+	}
+	for len(l.segments) > 0 && l.segments[0] < cursorSeq {
+		if err = os.Remove(l.segmentPath(l.segments[0])); err != nil {
+			return nil, nil, err
+		}
+		l.segments = l.segments[1:]
+	}
+	if len(l.segments) > 0 && l.segments[0] == cursorSeq {
+		l.readOffset = cursorOffset
+	}
+
+	for i, seq := range l.segments {
+		offset := int64(0)
+		if i == 0 {
+			offset = l.readOffset
+		}
+		n, rErr := l.recoverSegment(seq, offset)
+		if rErr != nil {
+			return nil, nil, rErr
+		}
+		l.count += n
+	}
+	if err = os.Remove(filepath.Join(dir, headFileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	return l, head, nil
+}
+
+// append writes a record to the end of the log, rotating to a new segment when the current one is full.
+func (l *spillLog) append(record []byte) error {
+	size := int64(recordHeader + len(record))
+	if l.writer == nil || (l.writeSize > 0 && l.writeSize+size > l.maxSegmentSize) {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	buf := make([]byte, recordHeader, size)
+	binary.BigEndian.PutUint32(buf, uint32(len(record)))
+	buf = append(buf, record...)
+	if _, err := l.writer.Write(buf); err != nil {
+		return err
+	}
+	l.writeSize += size
+	l.count++
+	return nil
+}
+
+// next reads the oldest unread record. Call commit to persist the read position.
+func (l *spillLog) next() ([]byte, error) {
+	for {
+		if l.count == 0 || len(l.segments) == 0 {
+			return nil, errSpillEmpty
+		}
+		if l.reader == nil {
+			file, err := os.Open(l.segmentPath(l.segments[0]))
+			if err != nil {
+				return nil, err
+			}
+			if _, err = file.Seek(l.readOffset, io.SeekStart); err != nil {
+				_ = file.Close()
+				return nil, err
+			}
+			l.readerFile = file
+			l.reader = bufio.NewReader(file)
+		}
+		record, err := readRecord(l.reader)
+		if errors.Is(err, io.EOF) && len(l.segments) > 1 {
+			if err = l.dropHeadSegment(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		l.readOffset += int64(recordHeader + len(record))
+		l.count--
+		return record, nil
+	}
+}
+
+// commit persists the read position. Once every record has been read the log is reset,
+// deleting all of its segments.
+func (l *spillLog) commit() error {
+	if l.count == 0 {
+		return l.reset()
+	}
+	if len(l.segments) == 0 {
+		return nil
+	}
+	buf := binary.BigEndian.AppendUint64(nil, l.segments[0])
+	buf = binary.BigEndian.AppendUint64(buf, uint64(l.readOffset))
+	return writeFileAtomic(filepath.Join(l.dir, cursorFileName), buf)
+}
+
+// peek reads up to n unread records without moving the read position.
+func (l *spillLog) peek(n int) ([][]byte, error) {
+	var records [][]byte
+	offset := l.readOffset
+	for _, seq := range l.segments {
+		if len(records) >= n || len(records) >= l.count {
+			break
+		}
+		file, err := os.Open(l.segmentPath(seq))
+		if err != nil {
+			return nil, err
+		}
+		if _, err = file.Seek(offset, io.SeekStart); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		reader := bufio.NewReader(file)
+		for len(records) < n && len(records) < l.count {
+			record, rErr := readRecord(reader)
+			if errors.Is(rErr, io.EOF) {
+				break
+			}
+			if rErr != nil {
+				_ = file.Close()
+				return nil, rErr
+			}
+			records = append(records, record)
+		}
+		_ = file.Close()
+		offset = 0
+	}
+	return records, nil
+}
+
+// reset deletes every segment and the cursor.
+func (l *spillLog) reset() error {
+	err := l.closeFiles()
+	for _, seq := range l.segments {
+		err = errors.Join(err, removeIfExists(l.segmentPath(seq)))
+	}
+	l.segments = nil
+	l.readOffset = 0
+	l.writeSize = 0
+	l.count = 0
+	return errors.Join(err, removeIfExists(filepath.Join(l.dir, cursorFileName)))
+}
+
+// close saves head as the records to restore first on the next open, persists the read position
+// and releases the segment files.
+func (l *spillLog) close(head [][]byte) error {
+	var err error
+	if len(head) > 0 {
+		var buf []byte
+		for _, record := range head {
+			buf = binary.BigEndian.AppendUint32(buf, uint32(len(record)))
+			buf = append(buf, record...)
+		}
+		err = writeFileAtomic(filepath.Join(l.dir, headFileName), buf)
+	}
+	if l.writer != nil {
+		err = errors.Join(err, l.writer.Sync())
+	}
+	if l.count > 0 {
+		err = errors.Join(err, l.commit())
+	} else {
+		err = errors.Join(err, l.reset())
+	}
+	return errors.Join(err, l.closeFiles())
+}
+
+func (l *spillLog) rotate() error {
+	if l.writer != nil {
+		if err := l.writer.Close(); err != nil {
+			return err
+		}
+		l.writer = nil
+	}
+	seq := uint64(1)
+	if len(l.segments) > 0 {
+		seq = l.segments[len(l.segments)-1] + 1
+	}
+	file, err := os.OpenFile(l.segmentPath(seq), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	l.segments = append(l.segments, seq)
+	l.writer = file
+	l.writeSize = 0
+	return nil
+}
+
+func (l *spillLog) dropHeadSegment() error {
+	if err := l.readerFile.Close(); err != nil {
+		return err
+	}
+	l.reader, l.readerFile = nil, nil
+	if err := os.Remove(l.segmentPath(l.segments[0])); err != nil {
+		return err
+	}
+	l.segments = l.segments[1:]
+	l.readOffset = 0
+	return nil
+}
+
+// recoverSegment counts the complete records of a segment from offset, truncating a torn record
+// left at the end by a crash. The last segment is reopened for appending.
+func (l *spillLog) recoverSegment(seq uint64, offset int64) (int, error) {
+	path := l.segmentPath(seq)
+	file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			_ = file.Close()
+		}
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	reader := bufio.NewReader(file)
+	count, end := 0, offset
+	for {
+		record, rErr := readRecord(reader)
+		if rErr != nil {
+			break
+		}
+		count++
+		end += int64(recordHeader + len(record))
+	}
+	if end < info.Size() {
+		if err = file.Truncate(end); err != nil {
+			return 0, err
+		}
+	}
+	if seq == l.segments[len(l.segments)-1] {
+		if _, err = file.Seek(0, io.SeekEnd); err != nil {
+			return 0, err
+		}
+		keep = true
+		l.writer = file
+		l.writeSize = max(end, 0)
+	}
+	return count, nil
+}
+
+func (l *spillLog) readCursor() (uint64, int64, error) {
+	raw, err := os.ReadFile(filepath.Join(l.dir, cursorFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(raw) != 16 {
+		return 0, 0, fmt.Errorf("memory mq: corrupted spill cursor in %s", l.dir)
+	}
+	return binary.BigEndian.Uint64(raw), int64(binary.BigEndian.Uint64(raw[8:])), nil
+}
+
+func (l *spillLog) closeFiles() error {
+	var err error
+	if l.readerFile != nil {
+		err = l.readerFile.Close()
+		l.reader, l.readerFile = nil, nil
+	}
+	if l.writer != nil {
+		err = errors.Join(err, l.writer.Close())
+		l.writer = nil
+	}
+	return err
+}
+
+func (l *spillLog) segmentPath(seq uint64) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+func readRecord(r *bufio.Reader) ([]byte, error) {
+	var header [recordHeader]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	record := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err := io.ReadFull(r, record); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+func readRecordsFile(path string) ([][]byte, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	reader := bufio.NewReader(file)
+	var records [][]byte
+	for {
+		record, rErr := readRecord(reader)
+		if errors.Is(rErr, io.EOF) {
+			return records, nil
+		}
+		if rErr != nil {
+			return nil, rErr
+		}
+		records = append(records, record)
+	}
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
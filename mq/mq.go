@@ -2,9 +2,14 @@ package mq
 
 import (
 	"context"
+	"errors"
 	"io"
+	"time"
 )
 
+// ErrInvalidPriority indicates that a priority outside the supported levels was requested.
+var ErrInvalidPriority = errors.New("mq: invalid priority")
+
 // Queue provides point-to-point messaging capabilities with typed message support.
 // Messages are delivered to exactly one consumer, following FIFO ordering.
 type Queue[T any] interface {
@@ -27,6 +32,34 @@ type Queue[T any] interface {
 	io.Closer
 }
 
+// Priority ranks messages published to a PriorityQueue. Lower values are delivered first.
+type Priority uint8
+
+const (
+	PriorityHigh Priority = iota
+	PriorityNormal
+	PriorityLow
+)
+
+// PriorityLevels is the number of supported priority levels.
+const PriorityLevels = int(PriorityLow) + 1
+
+// Valid reports whether the priority is one of the supported levels.
+func (p Priority) Valid() bool {
+	return int(p) < PriorityLevels
+}
+
+// PriorityQueue extends Queue with per-message priorities on the same topic.
+// Messages published with Publish use PriorityNormal.
+// Within a single priority level messages keep FIFO ordering.
+type PriorityQueue[T any] interface {
+	Queue[T]
+
+	// PublishWithPriority sends a message to the specified topic queue at the given priority.
+	// Returns ErrInvalidPriority if the priority is not one of the supported levels.
+	PublishWithPriority(ctx context.Context, topic string, priority Priority, data T) error
+}
+
 // PubSub provides publish-subscribe messaging capabilities with typed message support.
 // Messages are broadcast to all active subscribers of a topic.
 type PubSub[T any] interface {
@@ -41,9 +74,62 @@ type PubSub[T any] interface {
 	// UnsubscribeAll removes all subscriptions for the specified topic.
 	UnsubscribeAll(ctx context.Context, topic string) error
 
+	PatternSubscriber[T]
+
 	io.Closer
 }
 
+// PatternSubscriber provides wildcard subscriptions across many topics.
+// Patterns use the glob syntax described by MatchTopic.
+type PatternSubscriber[T any] interface {
+	// PSubscribe registers a handler function to receive messages from every topic matching the pattern.
+	// The handler receives the concrete topic each message was broadcast on.
+	PSubscribe(ctx context.Context, pattern string, handler func(topic string, data T) error) error
+
+	// PUnsubscribeAll removes all subscriptions registered with the specified pattern.
+	PUnsubscribeAll(ctx context.Context, pattern string) error
+}
+
+// BatchQueue provides batched publishing and consumption for high-volume queues.
+// Backends implement it optionally; use a type assertion to detect support.
+type BatchQueue[T any] interface {
+	// PublishBatch sends all messages to the specified topic queue, preserving their order.
+	// Publishing an empty batch is a no-op.
+	PublishBatch(ctx context.Context, topic string, data []T) error
+
+	// ConsumeBatch collects up to limit messages from the specified topic queue, in FIFO order.
+	// It returns as soon as limit messages are collected or wait has elapsed since the call,
+	// whichever comes first; a non-positive wait only drains the messages already pending.
+	// The returned batch may be empty. If the context is cancelled after some messages were
+	// collected, they are returned without an error so that no message is lost.
+	ConsumeBatch(ctx context.Context, topic string, limit int, wait time.Duration) ([]T, error)
+}
+
+// QueueInspector provides read-only introspection of pending queue messages.
+// Backends implement it optionally; use a type assertion to detect support.
+type QueueInspector[T any] interface {
+	// Len returns the number of messages waiting in the specified topic queue.
+	Len(ctx context.Context, topic string) (int64, error)
+
+	// Peek returns up to n of the oldest pending messages of the specified topic queue without consuming them.
+	Peek(ctx context.Context, topic string, n int) ([]T, error)
+
+	// Topics returns the names of the topic queues that currently hold pending messages.
+	Topics(ctx context.Context) ([]string, error)
+}
+
+// PubSubInspector provides read-only introspection of publish-subscribe subscriptions.
+// Backends implement it optionally; use a type assertion to detect support.
+type PubSubInspector interface {
+	// ActiveTopics returns the names of the topics that currently have at least one subscriber.
+	// Pattern subscriptions are not included.
+	ActiveTopics(ctx context.Context) ([]string, error)
+
+	// SubscriberCounts returns the number of subscribers of each specified topic.
+	// Pattern subscriptions are not counted.
+	SubscriberCounts(ctx context.Context, topics ...string) (map[string]int64, error)
+}
+
 // MessageQueue combines both queue and publish-subscribe messaging patterns.
 // This interface provides maximum flexibility for messaging architectures.
 type MessageQueue[T any] interface {
@@ -0,0 +1,163 @@
+package outbox
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/go-sphere/confstore/codec"
+	"github.com/go-sphere/sphere/mq"
+)
+
+// options holds configuration parameters for the outbox store.
+type options struct {
+	table       string
+	codec       codec.Codec
+	placeholder func(n int) string
+}
+
+func newOptions(opt ...Option) *options {
+	opts := &options{
+		table:       "mq_outbox",
+		codec:       codec.JsonCodec(),
+		placeholder: QuestionPlaceholder,
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	return opts
+}
+
+// Option defines a function type for configuring the outbox store.
+type Option func(*options)
+
+// WithTable sets the table name used to persist outbox messages.
+// The default table name is "mq_outbox".
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithCodec sets the codec used to serialize message payloads into the outbox table.
+// If not specified, JSON codec is used by default.
+func WithCodec(codec codec.Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithPlaceholder sets the bind parameter style used when building SQL statements.
+// n is the 1-based position of the parameter within the statement.
+func WithPlaceholder(placeholder func(n int) string) Option {
+	return func(o *options) {
+		o.placeholder = placeholder
+	}
+}
+
+// QuestionPlaceholder renders "?" bind parameters as used by SQLite and MySQL.
+func QuestionPlaceholder(int) string {
+	return "?"
+}
+
+// DollarPlaceholder renders "$n" bind parameters as used by PostgreSQL.
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// relayOptions holds configuration parameters for the outbox relay task.
+type relayOptions[T any] struct {
+	queue           mq.Queue[T]
+	pubSub          mq.PubSub[T]
+	interval        time.Duration
+	batchSize       int
+	maxAttempts     int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+	claimTimeout    time.Duration
+	retention       time.Duration
+	cleanupInterval time.Duration
+}
+
+func newRelayOptions[T any](opt ...RelayOption[T]) *relayOptions[T] {
+	opts := &relayOptions[T]{
+		interval:        time.Second,
+		batchSize:       100,
+		maxAttempts:     10,
+		retryBackoff:    time.Second,
+		maxRetryBackoff: 5 * time.Minute,
+		claimTimeout:    30 * time.Second,
+		retention:       7 * 24 * time.Hour,
+		cleanupInterval: time.Hour,
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	return opts
+}
+
+// RelayOption defines a function type for configuring the outbox relay.
+type RelayOption[T any] func(*relayOptions[T])
+
+// WithQueue sets the queue that receives messages enqueued with Outbox.Publish.
+func WithQueue[T any](queue mq.Queue[T]) RelayOption[T] {
+	return func(o *relayOptions[T]) {
+		o.queue = queue
+	}
+}
+
+// WithPubSub sets the pub/sub system that receives messages enqueued with Outbox.Broadcast.
+func WithPubSub[T any](pubSub mq.PubSub[T]) RelayOption[T] {
+	return func(o *relayOptions[T]) {
+		o.pubSub = pubSub
+	}
+}
+
+// WithPollInterval sets how often the relay polls the outbox table for pending messages.
+// The default interval is one second.
+func WithPollInterval[T any](interval time.Duration) RelayOption[T] {
+	return func(o *relayOptions[T]) {
+		o.interval = interval
+	}
+}
+
+// WithBatchSize sets the maximum number of messages relayed per poll.
+// The default batch size is 100.
+func WithBatchSize[T any](size int) RelayOption[T] {
+	return func(o *relayOptions[T]) {
+		o.batchSize = size
+	}
+}
+
+// WithMaxAttempts sets how many times delivery of a message is attempted before it is left
+// in the table as dead. A non-positive value retries forever. The default is 10 attempts.
+func WithMaxAttempts[T any](attempts int) RelayOption[T] {
+	return func(o *relayOptions[T]) {
+		o.maxAttempts = attempts
+	}
+}
+
+// WithRetryBackoff sets the initial and maximum delay between delivery attempts.
+// The delay doubles after every failed attempt until it reaches max.
+func WithRetryBackoff[T any](initial, max time.Duration) RelayOption[T] {
+	return func(o *relayOptions[T]) {
+		o.retryBackoff = initial
+		o.maxRetryBackoff = max
+	}
+}
+
+// WithClaimTimeout sets how long a message claimed by one relay replica stays invisible to others.
+// It must exceed the time needed to publish a single message. The default is 30 seconds.
+func WithClaimTimeout[T any](timeout time.Duration) RelayOption[T] {
+	return func(o *relayOptions[T]) {
+		o.claimTimeout = timeout
+	}
+}
+
+// WithRetention sets how long sent messages are kept before cleanup and how often cleanup runs.
+// A non-positive retention disables cleanup. The defaults are seven days and one hour.
+func WithRetention[T any](retention, cleanupInterval time.Duration) RelayOption[T] {
+	return func(o *relayOptions[T]) {
+		o.retention = retention
+		o.cleanupInterval = cleanupInterval
+	}
+}
@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sphere/confstore/codec"
+	"github.com/google/uuid"
+)
+
+// Kind identifies which messaging pattern an outbox message is relayed through.
+type Kind string
+
+const (
+	// KindQueue messages are relayed with mq.Queue.Publish.
+	KindQueue Kind = "queue"
+	// KindPubSub messages are relayed with mq.PubSub.Broadcast.
+	KindPubSub Kind = "pubsub"
+)
+
+// Schema returns the DDL that creates the outbox table and its polling index for SQLite,
+// including databases opened through the infra/sqlite driver.
+// Other databases can use it as a template: only the payload column type (BLOB) and the
+// statement separator usually need adjusting.
+func Schema(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s (
+	id VARCHAR(36) NOT NULL PRIMARY KEY,
+	topic VARCHAR(255) NOT NULL,
+	kind VARCHAR(16) NOT NULL,
+	payload BLOB NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	created_at BIGINT NOT NULL,
+	available_at BIGINT NOT NULL,
+	sent_at BIGINT
+);
+CREATE INDEX IF NOT EXISTS %[1]s_pending_idx ON %[1]s (sent_at, available_at);`, table)
+}
+
+// Outbox records messages in a database table within the caller's transaction,
+// so they are published if and only if the surrounding business writes commit.
+// Messages are delivered later by a Relay with at-least-once semantics.
+type Outbox[T any] struct {
+	table       string
+	codec       codec.Codec
+	placeholder func(n int) string
+
+	insertStmt string
+}
+
+// New creates a new outbox store with the specified options.
+// The table must already exist; see Schema and Migrate.
+func New[T any](opt ...Option) (*Outbox[T], error) {
+	opts := newOptions(opt...)
+	if opts.table == "" {
+		return nil, errors.New("table is required")
+	}
+	if opts.codec == nil {
+		return nil, errors.New("codec is required")
+	}
+	if opts.placeholder == nil {
+		return nil, errors.New("placeholder is required")
+	}
+	o := &Outbox[T]{
+		table:       opts.table,
+		codec:       opts.codec,
+		placeholder: opts.placeholder,
+	}
+	o.insertStmt = o.format(
+		"INSERT INTO %s (id, topic, kind, payload, attempts, created_at, available_at) VALUES (%s, %s, %s, %s, 0, %s, %s)",
+		6,
+	)
+	return o, nil
+}
+
+// Table returns the name of the table backing this outbox.
+func (o *Outbox[T]) Table() string {
+	return o.table
+}
+
+// Migrate creates the outbox table using the SQLite-compatible Schema.
+func (o *Outbox[T]) Migrate(ctx context.Context, db *sql.DB) error {
+	for stmt := range strings.SplitSeq(Schema(o.table), ";") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Publish records a message for point-to-point delivery to the topic queue.
+// It returns the generated message ID.
+func (o *Outbox[T]) Publish(ctx context.Context, tx *sql.Tx, topic string, data T) (string, error) {
+	return o.enqueue(ctx, tx, KindQueue, topic, data)
+}
+
+// Broadcast records a message for broadcast delivery to all subscribers of the topic.
+// It returns the generated message ID.
+func (o *Outbox[T]) Broadcast(ctx context.Context, tx *sql.Tx, topic string, data T) (string, error) {
+	return o.enqueue(ctx, tx, KindPubSub, topic, data)
+}
+
+func (o *Outbox[T]) enqueue(ctx context.Context, tx *sql.Tx, kind Kind, topic string, data T) (string, error) {
+	if tx == nil {
+		return "", errors.New("outbox: transaction is required")
+	}
+	if topic == "" {
+		return "", errors.New("outbox: topic is required")
+	}
+	raw, err := o.codec.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	id := uuid.NewString()
+	now := time.Now().UnixMilli()
+	_, err = tx.ExecContext(ctx, o.insertStmt, id, topic, string(kind), raw, now, now)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// format substitutes the table name followed by n bind parameters into the statement template.
+func (o *Outbox[T]) format(template string, n int) string {
+	args := make([]any, 0, n+1)
+	args = append(args, o.table)
+	for i := 1; i <= n; i++ {
+		args = append(args, o.placeholder(i))
+	}
+	return fmt.Sprintf(template, args...)
+}
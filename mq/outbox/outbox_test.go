@@ -0,0 +1,357 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/infra/sqlite"
+	"github.com/go-sphere/sphere/mq"
+	"github.com/go-sphere/sphere/mq/memory"
+)
+
+const testDriverName = "sqlite_outbox_test"
+
+var registerDriverOnce sync.Once
+
+type event struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func newTestDB(t *testing.T) (*sql.DB, *Outbox[event]) {
+	t.Helper()
+	registerDriverOnce.Do(func() {
+		sqlite.Register(testDriverName)
+	})
+	db, err := sql.Open(testDriverName, filepath.Join(t.TempDir(), "outbox.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	box, err := New[event]()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err = box.Migrate(context.Background(), db); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	return db, box
+}
+
+func enqueueInTx(t *testing.T, db *sql.DB, commit bool, fn func(tx *sql.Tx) error) {
+	t.Helper()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin tx: %v", err)
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		t.Fatalf("enqueue in tx: %v", err)
+	}
+	if commit {
+		err = tx.Commit()
+	} else {
+		err = tx.Rollback()
+	}
+	if err != nil {
+		t.Fatalf("finish tx: %v", err)
+	}
+}
+
+func countRows(t *testing.T, db *sql.DB, where string) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM mq_outbox WHERE " + where).Scan(&n); err != nil {
+		t.Fatalf("count rows: %v", err)
+	}
+	return n
+}
+
+func TestRelayPublishesOnlyCommittedMessages(t *testing.T) {
+	ctx := context.Background()
+	db, box := newTestDB(t)
+	queue := memory.NewQueue[event]()
+	t.Cleanup(func() { _ = queue.Close() })
+
+	enqueueInTx(t, db, true, func(tx *sql.Tx) error {
+		_, err := box.Publish(ctx, tx, "orders", event{ID: 1, Name: "committed"})
+		return err
+	})
+	enqueueInTx(t, db, false, func(tx *sql.Tx) error {
+		_, err := box.Publish(ctx, tx, "orders", event{ID: 2, Name: "rolled back"})
+		return err
+	})
+
+	relay, err := NewRelay(db, box, WithQueue[event](queue))
+	if err != nil {
+		t.Fatalf("NewRelay() error = %v", err)
+	}
+	sent, err := relay.RelayOnce(ctx)
+	if err != nil {
+		t.Fatalf("RelayOnce() error = %v", err)
+	}
+	if sent != 1 {
+		t.Fatalf("RelayOnce() sent = %d, want 1", sent)
+	}
+
+	got, found, err := queue.TryConsume(ctx, "orders")
+	if err != nil || !found {
+		t.Fatalf("TryConsume() found = %v, err = %v", found, err)
+	}
+	if got.ID != 1 || got.Name != "committed" {
+		t.Fatalf("relayed message = %+v, want committed event", got)
+	}
+	if _, found, _ = queue.TryConsume(ctx, "orders"); found {
+		t.Fatal("rolled back message must not be relayed")
+	}
+
+	sent, err = relay.RelayOnce(ctx)
+	if err != nil {
+		t.Fatalf("second RelayOnce() error = %v", err)
+	}
+	if sent != 0 {
+		t.Fatalf("second RelayOnce() sent = %d, want 0", sent)
+	}
+	if n := countRows(t, db, "sent_at IS NOT NULL"); n != 1 {
+		t.Fatalf("sent rows = %d, want 1", n)
+	}
+}
+
+type flakyQueue struct {
+	mq.Queue[event]
+	failures int
+}
+
+func (q *flakyQueue) Publish(ctx context.Context, topic string, data event) error {
+	if q.failures > 0 {
+		q.failures--
+		return errors.New("broker unavailable")
+	}
+	return q.Queue.Publish(ctx, topic, data)
+}
+
+func TestRelayRetriesFailedMessages(t *testing.T) {
+	ctx := context.Background()
+	db, box := newTestDB(t)
+	inner := memory.NewQueue[event]()
+	t.Cleanup(func() { _ = inner.Close() })
+	queue := &flakyQueue{Queue: inner, failures: 2}
+
+	enqueueInTx(t, db, true, func(tx *sql.Tx) error {
+		_, err := box.Publish(ctx, tx, "payments", event{ID: 7})
+		return err
+	})
+
+	relay, err := NewRelay(db, box,
+		WithQueue[event](queue),
+		WithRetryBackoff[event](0, 0),
+		WithMaxAttempts[event](3),
+	)
+	if err != nil {
+		t.Fatalf("NewRelay() error = %v", err)
+	}
+
+	for i := range 2 {
+		sent, rErr := relay.RelayOnce(ctx)
+		if rErr != nil {
+			t.Fatalf("RelayOnce(%d) error = %v", i, rErr)
+		}
+		if sent != 0 {
+			t.Fatalf("RelayOnce(%d) sent = %d, want 0", i, sent)
+		}
+	}
+	if n := countRows(t, db, "attempts = 2 AND last_error = 'broker unavailable'"); n != 1 {
+		t.Fatalf("failed rows = %d, want 1", n)
+	}
+
+	sent, err := relay.RelayOnce(ctx)
+	if err != nil {
+		t.Fatalf("RelayOnce() error = %v", err)
+	}
+	if sent != 1 {
+		t.Fatalf("RelayOnce() sent = %d, want 1", sent)
+	}
+	if got, found, _ := inner.TryConsume(ctx, "payments"); !found || got.ID != 7 {
+		t.Fatalf("TryConsume() = %+v, %v; want event 7", got, found)
+	}
+}
+
+func TestRelayStopsRetryingAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	db, box := newTestDB(t)
+	inner := memory.NewQueue[event]()
+	t.Cleanup(func() { _ = inner.Close() })
+	queue := &flakyQueue{Queue: inner, failures: 5}
+
+	enqueueInTx(t, db, true, func(tx *sql.Tx) error {
+		_, err := box.Publish(ctx, tx, "payments", event{ID: 8})
+		return err
+	})
+
+	relay, err := NewRelay(db, box,
+		WithQueue[event](queue),
+		WithRetryBackoff[event](0, 0),
+		WithMaxAttempts[event](2),
+	)
+	if err != nil {
+		t.Fatalf("NewRelay() error = %v", err)
+	}
+	for range 4 {
+		if _, err = relay.RelayOnce(ctx); err != nil {
+			t.Fatalf("RelayOnce() error = %v", err)
+		}
+	}
+	if queue.failures != 3 {
+		t.Fatalf("delivery attempts = %d, want 2", 5-queue.failures)
+	}
+	if n := countRows(t, db, "attempts = 2 AND sent_at IS NULL"); n != 1 {
+		t.Fatalf("dead rows = %d, want 1", n)
+	}
+}
+
+func TestRelayCleanupRemovesOldSentMessages(t *testing.T) {
+	ctx := context.Background()
+	db, box := newTestDB(t)
+	queue := memory.NewQueue[event]()
+	t.Cleanup(func() { _ = queue.Close() })
+
+	enqueueInTx(t, db, true, func(tx *sql.Tx) error {
+		_, err := box.Publish(ctx, tx, "orders", event{ID: 1})
+		return err
+	})
+
+	relay, err := NewRelay(db, box,
+		WithQueue[event](queue),
+		WithRetention[event](time.Millisecond, time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("NewRelay() error = %v", err)
+	}
+	if _, err = relay.RelayOnce(ctx); err != nil {
+		t.Fatalf("RelayOnce() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	removed, err := relay.Cleanup(ctx)
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("Cleanup() removed = %d, want 1", removed)
+	}
+	if n := countRows(t, db, "1 = 1"); n != 0 {
+		t.Fatalf("remaining rows = %d, want 0", n)
+	}
+}
+
+func TestRelayTaskBroadcasts(t *testing.T) {
+	ctx := context.Background()
+	db, box := newTestDB(t)
+	pubSub := memory.NewPubSub[event]()
+	t.Cleanup(func() { _ = pubSub.Close() })
+
+	recv := make(chan event, 1)
+	if err := pubSub.Subscribe(ctx, "users", func(data event) error {
+		recv <- data
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	relay, err := NewRelay(db, box,
+		WithPubSub[event](pubSub),
+		WithPollInterval[event](10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("NewRelay() error = %v", err)
+	}
+	startErr := make(chan error, 1)
+	go func() { startErr <- relay.Start(ctx) }()
+
+	enqueueInTx(t, db, true, func(tx *sql.Tx) error {
+		_, err := box.Broadcast(ctx, tx, "users", event{ID: 3, Name: "registered"})
+		return err
+	})
+
+	select {
+	case got := <-recv:
+		if got.ID != 3 {
+			t.Fatalf("broadcast event = %+v, want ID 3", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for relayed broadcast")
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err = relay.Stop(stopCtx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err = <-startErr; err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+}
+
+func TestNewRelayValidation(t *testing.T) {
+	db, box := newTestDB(t)
+	if _, err := NewRelay[event](db, box); err == nil {
+		t.Fatal("expected NewRelay without queue or pubsub to fail")
+	}
+	if _, err := NewRelay(nil, box, WithQueue[event](memory.NewQueue[event]())); err == nil {
+		t.Fatal("expected NewRelay without db to fail")
+	}
+}
+
+func TestDollarPlaceholder(t *testing.T) {
+	box, err := New[event](WithTable("events_outbox"), WithPlaceholder(DollarPlaceholder))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	want := "INSERT INTO events_outbox (id, topic, kind, payload, attempts, created_at, available_at) VALUES ($1, $2, $3, $4, 0, $5, $6)"
+	if box.insertStmt != want {
+		t.Fatalf("insert statement = %q, want %q", box.insertStmt, want)
+	}
+}
+
+func TestRelayRestartsAfterStop(t *testing.T) {
+	ctx := context.Background()
+	db, box := newTestDB(t)
+	relay, err := NewRelay(db, box,
+		WithQueue[event](memory.NewQueue[event]()),
+		WithPollInterval[event](10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("NewRelay() error = %v", err)
+	}
+	for i := range 2 {
+		startErr := make(chan error, 1)
+		go func() { startErr <- relay.Start(ctx) }()
+		// Stop is a no-op until Start has registered the loop, so retry until Start returns.
+		stopped := false
+		for deadline := time.Now().Add(2 * time.Second); !stopped && time.Now().Before(deadline); {
+			stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+			err = relay.Stop(stopCtx)
+			cancel()
+			if err != nil {
+				t.Fatalf("Stop() #%d error = %v", i+1, err)
+			}
+			select {
+			case err = <-startErr:
+				if err != nil {
+					t.Fatalf("Start() #%d error = %v", i+1, err)
+				}
+				stopped = true
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		if !stopped {
+			t.Fatalf("relay #%d did not stop", i+1)
+		}
+	}
+}
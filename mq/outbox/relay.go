@@ -0,0 +1,258 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-sphere/sphere/core/task"
+	"github.com/go-sphere/sphere/log"
+)
+
+var ErrRelayAlreadyStarted = errors.New("outbox relay already started")
+
+// pendingMessage is a row read from the outbox table that is due for delivery.
+type pendingMessage struct {
+	id          string
+	topic       string
+	kind        Kind
+	payload     []byte
+	attempts    int
+	availableAt int64
+}
+
+// Relay is a task that polls the outbox table and delivers pending messages to the
+// configured mq.Queue and mq.PubSub. Delivery is at-least-once: a message is marked as
+// sent only after publishing succeeds, so consumers should be idempotent.
+// Several relay replicas may poll the same table; each message is claimed before delivery
+// so that concurrent replicas do not publish it at the same time.
+type Relay[T any] struct {
+	db     *sql.DB
+	outbox *Outbox[T]
+	opts   *relayOptions[T]
+
+	selectStmt  string
+	claimStmt   string
+	sentStmt    string
+	failStmt    string
+	cleanupStmt string
+
+	loop        task.Loop
+	lastCleanup time.Time
+}
+
+// NewRelay creates a relay task delivering messages recorded by the outbox.
+// At least one of WithQueue or WithPubSub must be provided.
+func NewRelay[T any](db *sql.DB, outbox *Outbox[T], opt ...RelayOption[T]) (*Relay[T], error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	if outbox == nil {
+		return nil, errors.New("outbox is required")
+	}
+	opts := newRelayOptions(opt...)
+	if opts.queue == nil && opts.pubSub == nil {
+		return nil, errors.New("queue or pubsub is required")
+	}
+	if opts.interval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+	if opts.batchSize <= 0 {
+		return nil, errors.New("batch size must be positive")
+	}
+	return &Relay[T]{
+		db:     db,
+		outbox: outbox,
+		opts:   opts,
+		selectStmt: outbox.format(
+			"SELECT id, topic, kind, payload, attempts, available_at FROM %s WHERE sent_at IS NULL AND available_at <= %s AND attempts < %s ORDER BY created_at, id LIMIT %s",
+			3,
+		),
+		claimStmt: outbox.format(
+			"UPDATE %s SET available_at = %s WHERE id = %s AND available_at = %s AND sent_at IS NULL",
+			3,
+		),
+		sentStmt: outbox.format(
+			"UPDATE %s SET sent_at = %s, last_error = NULL WHERE id = %s",
+			2,
+		),
+		failStmt: outbox.format(
+			"UPDATE %s SET attempts = attempts + 1, last_error = %s, available_at = %s WHERE id = %s",
+			3,
+		),
+		cleanupStmt: outbox.format(
+			"DELETE FROM %s WHERE sent_at IS NOT NULL AND sent_at < %s",
+			1,
+		),
+	}, nil
+}
+
+// Identifier returns the relay's identifier for logging and debugging purposes.
+func (r *Relay[T]) Identifier() string {
+	return "outbox_relay"
+}
+
+// Start polls the outbox table until the context is cancelled or Stop is called.
+// Delivery and cleanup errors are logged and retried on the next poll.
+// A stopped relay can be started again.
+func (r *Relay[T]) Start(ctx context.Context) error {
+	return r.loop.Run(ctx, ErrRelayAlreadyStarted, func(ctx context.Context) error {
+		ticker := time.NewTicker(r.opts.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := r.RelayOnce(ctx); err != nil && ctx.Err() == nil {
+				log.Warn("outbox relay failed", log.String("table", r.outbox.table), log.Err(err))
+			}
+			if err := r.cleanupIfDue(ctx); err != nil && ctx.Err() == nil {
+				log.Warn("outbox cleanup failed", log.String("table", r.outbox.table), log.Err(err))
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop signals the polling loop to exit and waits for the in-flight batch to finish.
+func (r *Relay[T]) Stop(ctx context.Context) error {
+	return r.loop.Stop(ctx)
+}
+
+// RelayOnce delivers a single batch of due messages and returns how many were sent.
+// A failed message is rescheduled with exponential backoff; the error of the batch
+// only reports failures to read or update the outbox table.
+func (r *Relay[T]) RelayOnce(ctx context.Context) (int, error) {
+	messages, err := r.fetchPending(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, msg := range messages {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		claimed, cErr := r.claim(ctx, msg)
+		if cErr != nil {
+			return sent, cErr
+		}
+		if !claimed {
+			continue
+		}
+		if dErr := r.deliver(ctx, msg); dErr != nil {
+			if fErr := r.markFailed(ctx, msg, dErr); fErr != nil {
+				return sent, fErr
+			}
+			continue
+		}
+		if _, sErr := r.db.ExecContext(ctx, r.sentStmt, time.Now().UnixMilli(), msg.id); sErr != nil {
+			return sent, sErr
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// Cleanup deletes sent messages older than the configured retention and returns the number removed.
+// Messages that exhausted their attempts are kept for inspection.
+func (r *Relay[T]) Cleanup(ctx context.Context) (int64, error) {
+	if r.opts.retention <= 0 {
+		return 0, nil
+	}
+	before := time.Now().Add(-r.opts.retention).UnixMilli()
+	res, err := r.db.ExecContext(ctx, r.cleanupStmt, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *Relay[T]) cleanupIfDue(ctx context.Context) error {
+	if r.opts.retention <= 0 || r.opts.cleanupInterval <= 0 {
+		return nil
+	}
+	if !r.lastCleanup.IsZero() && time.Since(r.lastCleanup) < r.opts.cleanupInterval {
+		return nil
+	}
+	r.lastCleanup = time.Now()
+	_, err := r.Cleanup(ctx)
+	return err
+}
+
+func (r *Relay[T]) fetchPending(ctx context.Context) ([]pendingMessage, error) {
+	maxAttempts := r.opts.maxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = math.MaxInt32
+	}
+	rows, err := r.db.QueryContext(ctx, r.selectStmt, time.Now().UnixMilli(), maxAttempts, r.opts.batchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	messages := make([]pendingMessage, 0, r.opts.batchSize)
+	for rows.Next() {
+		var msg pendingMessage
+		var kind string
+		if err = rows.Scan(&msg.id, &msg.topic, &kind, &msg.payload, &msg.attempts, &msg.availableAt); err != nil {
+			return nil, err
+		}
+		msg.kind = Kind(kind)
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// claim hides the message from other replicas for the claim timeout.
+// It reports false when another replica claimed the message first.
+func (r *Relay[T]) claim(ctx context.Context, msg pendingMessage) (bool, error) {
+	until := time.Now().Add(r.opts.claimTimeout).UnixMilli()
+	res, err := r.db.ExecContext(ctx, r.claimStmt, until, msg.id, msg.availableAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (r *Relay[T]) deliver(ctx context.Context, msg pendingMessage) error {
+	var data T
+	if err := r.outbox.codec.Unmarshal(msg.payload, &data); err != nil {
+		return err
+	}
+	switch msg.kind {
+	case KindQueue:
+		if r.opts.queue == nil {
+			return errors.New("no queue configured for outbox relay")
+		}
+		return r.opts.queue.Publish(ctx, msg.topic, data)
+	case KindPubSub:
+		if r.opts.pubSub == nil {
+			return errors.New("no pubsub configured for outbox relay")
+		}
+		return r.opts.pubSub.Broadcast(ctx, msg.topic, data)
+	default:
+		return fmt.Errorf("unknown outbox message kind %q", msg.kind)
+	}
+}
+
+func (r *Relay[T]) markFailed(ctx context.Context, msg pendingMessage, cause error) error {
+	backoff := r.opts.retryBackoff
+	for i := 0; i < msg.attempts && backoff < r.opts.maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if r.opts.maxRetryBackoff > 0 && backoff > r.opts.maxRetryBackoff {
+		backoff = r.opts.maxRetryBackoff
+	}
+	next := time.Now().Add(backoff).UnixMilli()
+	_, err := r.db.ExecContext(ctx, r.failStmt, cause.Error(), next, msg.id)
+	return err
+}
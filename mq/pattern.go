@@ -0,0 +1,103 @@
+package mq
+
+// MatchTopic reports whether topic matches the glob-style pattern.
+// The syntax follows Redis PSUBSCRIBE so that every backend resolves patterns identically:
+//
+//   - "*" matches any sequence of characters, including the empty sequence
+//   - "?" matches exactly one character
+//   - "[abc]" matches one character from the set, "[^abc]" negates it and "[a-z]" matches a range
+//   - "\x" matches the character x literally
+func MatchTopic(pattern, topic string) bool {
+	p, t := 0, 0
+	starP, starT := -1, 0
+	for t < len(topic) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				starP, starT = p, t
+				p++
+				continue
+			case '?':
+				p++
+				t++
+				continue
+			case '[':
+				width, matched := matchClass(pattern[p:], topic[t])
+				if matched {
+					p += width
+					t++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) {
+					if pattern[p+1] == topic[t] {
+						p += 2
+						t++
+						continue
+					}
+					break
+				}
+				if topic[t] == '\\' {
+					p++
+					t++
+					continue
+				}
+			default:
+				if pattern[p] == topic[t] {
+					p++
+					t++
+					continue
+				}
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		starT++
+		p, t = starP+1, starT
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass matches c against the character class at the start of pattern.
+// It returns the width of the class expression and whether c belongs to it.
+// An unterminated class consumes the rest of the pattern, as Redis does.
+func matchClass(pattern string, c byte) (int, bool) {
+	i := 1
+	negate := false
+	if i < len(pattern) && pattern[i] == '^' {
+		negate = true
+		i++
+	}
+	matched := false
+	for i < len(pattern) && pattern[i] != ']' {
+		switch {
+		case pattern[i] == '\\' && i+1 < len(pattern):
+			if pattern[i+1] == c {
+				matched = true
+			}
+			i += 2
+		case i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']':
+			lo, hi := pattern[i], pattern[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			i += 3
+		default:
+			if pattern[i] == c {
+				matched = true
+			}
+			i++
+		}
+	}
+	if i < len(pattern) {
+		i++ // closing bracket
+	}
+	return i, matched != negate
+}
@@ -9,13 +9,15 @@ import (
 
 // options holds configuration parameters for Redis-based message queue implementations.
 type options struct {
-	client *redis.Client
-	codec  codec.Codec
+	client        *redis.Client
+	codec         codec.Codec
+	topicRegistry string
 }
 
 func newOptions(opt ...Option) *options {
 	opts := &options{
-		codec: codec.JsonCodec(),
+		codec:         codec.JsonCodec(),
+		topicRegistry: "mq:topics",
 	}
 	for _, o := range opt {
 		o(opts)
@@ -42,6 +44,15 @@ func WithCodec(codec codec.Codec) Option {
 	}
 }
 
+// WithTopicRegistry sets the key of the Redis set in which a queue records the topics it publishes to,
+// so that Topics lists them without scanning the keyspace. The default is "mq:topics".
+// Queues sharing a database with different topic sets should use different registries.
+func WithTopicRegistry(key string) Option {
+	return func(o *options) {
+		o.topicRegistry = key
+	}
+}
+
 func (o *options) validate() error {
 	if o.client == nil {
 		return errors.New("redis client is required")
@@ -49,5 +60,8 @@ func (o *options) validate() error {
 	if o.codec == nil {
 		return errors.New("codec is required")
 	}
+	if o.topicRegistry == "" {
+		return errors.New("topic registry key is required")
+	}
 	return nil
 }
@@ -0,0 +1,108 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/go-sphere/confstore/codec"
+	"github.com/go-sphere/sphere/mq"
+	"github.com/redis/go-redis/v9"
+)
+
+// PriorityQueue implements a Redis-backed point-to-point queue with per-message priorities.
+// Each topic is stored as one Redis list per priority level. Consumers always drain higher
+// levels first: BLPOP checks the lists in priority order, so lower levels are only served
+// while all higher levels are empty.
+type PriorityQueue[T any] struct {
+	client *redis.Client
+	codec  codec.Codec
+}
+
+// NewPriorityQueue creates a new Redis-based priority queue with the specified options.
+// A Redis client must be provided via WithClient option.
+func NewPriorityQueue[T any](opt ...Option) (*PriorityQueue[T], error) {
+	opts := newOptions(opt...)
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	return &PriorityQueue[T]{
+		client: opts.client,
+		codec:  opts.codec,
+	}, nil
+}
+
+// priorityKeys returns the list keys of a topic ordered from the highest to the lowest priority.
+func priorityKeys(topic string) []string {
+	keys := make([]string, mq.PriorityLevels)
+	for level := range keys {
+		keys[level] = priorityKey(topic, mq.Priority(level))
+	}
+	return keys
+}
+
+func priorityKey(topic string, priority mq.Priority) string {
+	return topic + ":priority:" + strconv.Itoa(int(priority))
+}
+
+func (q *PriorityQueue[T]) Publish(ctx context.Context, topic string, data T) error {
+	return q.PublishWithPriority(ctx, topic, mq.PriorityNormal, data)
+}
+
+func (q *PriorityQueue[T]) PublishWithPriority(ctx context.Context, topic string, priority mq.Priority, data T) error {
+	if !priority.Valid() {
+		return mq.ErrInvalidPriority
+	}
+	raw, err := q.codec.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return q.client.RPush(ctx, priorityKey(topic, priority), raw).Err()
+}
+
+func (q *PriorityQueue[T]) Consume(ctx context.Context, topic string) (T, error) {
+	var zero T
+	resp, err := blpop(ctx, q.client, priorityKeys(topic)...)
+	if err != nil {
+		return zero, err
+	}
+	if len(resp) < 2 {
+		return zero, fmt.Errorf("%w: %v", errInvalidBLPopResponse, resp)
+	}
+	var data T
+	err = q.codec.Unmarshal([]byte(resp[1]), &data)
+	if err != nil {
+		return zero, err
+	}
+	return data, nil
+}
+
+func (q *PriorityQueue[T]) TryConsume(ctx context.Context, topic string) (T, bool, error) {
+	var zero T
+	for _, key := range priorityKeys(topic) {
+		raw, err := q.client.LPop(ctx, key).Bytes()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return zero, false, err
+		}
+		var data T
+		err = q.codec.Unmarshal(raw, &data)
+		if err != nil {
+			return zero, false, err
+		}
+		return data, true, nil
+	}
+	return zero, false, nil
+}
+
+func (q *PriorityQueue[T]) PurgeQueue(ctx context.Context, topic string) error {
+	return q.client.Del(ctx, priorityKeys(topic)...).Err()
+}
+
+func (q *PriorityQueue[T]) Close() error {
+	return q.client.Close()
+}
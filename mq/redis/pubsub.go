@@ -2,6 +2,8 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"sync"
 
 	"github.com/go-sphere/confstore/codec"
@@ -15,8 +17,9 @@ type PubSub[T any] struct {
 	client *redis.Client
 	codec  codec.Codec
 
-	subscriptions map[string]*redis.PubSub
-	mu            sync.Mutex
+	subscriptions        map[string][]*redis.PubSub
+	patternSubscriptions map[string][]*redis.PubSub
+	mu                   sync.Mutex
 }
 
 // NewPubSub creates a new Redis-based publish-subscribe system with the specified options.
@@ -28,9 +31,10 @@ func NewPubSub[T any](opt ...Option) (*PubSub[T], error) {
 		return nil, err
 	}
 	return &PubSub[T]{
-		client:        opts.client,
-		codec:         opts.codec,
-		subscriptions: make(map[string]*redis.PubSub),
+		client:               opts.client,
+		codec:                opts.codec,
+		subscriptions:        make(map[string][]*redis.PubSub),
+		patternSubscriptions: make(map[string][]*redis.PubSub),
 	}, nil
 }
 
@@ -51,51 +55,109 @@ func (p *PubSub[T]) Subscribe(ctx context.Context, topic string, handler func(da
 		_ = sub.Close()
 		return err
 	}
-	p.subscriptions[topic] = sub
-
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Error("recovered from panic in subscription handler", log.Any("error", r))
-			}
-		}()
-		for msg := range sub.Channel() {
-			var data T
-			err := p.codec.Unmarshal([]byte(msg.Payload), &data)
-			if err != nil {
-				continue
-			}
-			err = handler(data)
-			if err != nil {
-				continue
-			}
-		}
-	}()
+	p.subscriptions[topic] = append(p.subscriptions[topic], sub)
+
+	go p.handleSubscription(sub, func(_ string, data T) error {
+		return handler(data)
+	})
 
 	return nil
 }
 
-func (p *PubSub[T]) UnsubscribeAll(ctx context.Context, topic string) error {
+// PSubscribe registers a handler for every topic matching the glob pattern using Redis PSUBSCRIBE.
+// The handler receives the concrete channel name the message was published to.
+func (p *PubSub[T]) PSubscribe(ctx context.Context, pattern string, handler func(topic string, data T) error) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if sub, ok := p.subscriptions[topic]; ok {
-		delete(p.subscriptions, topic)
-		return sub.Close()
+	sub := p.client.PSubscribe(ctx, pattern)
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return err
 	}
+	p.patternSubscriptions[pattern] = append(p.patternSubscriptions[pattern], sub)
+
+	go p.handleSubscription(sub, handler)
 
 	return nil
 }
 
+func (p *PubSub[T]) handleSubscription(sub *redis.PubSub, handler func(topic string, data T) error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("recovered from panic in subscription handler", log.Any("error", r))
+		}
+	}()
+	for msg := range sub.Channel() {
+		var data T
+		err := p.codec.Unmarshal([]byte(msg.Payload), &data)
+		if err != nil {
+			continue
+		}
+		err = handler(msg.Channel, data)
+		if err != nil {
+			continue
+		}
+	}
+}
+
+func (p *PubSub[T]) UnsubscribeAll(ctx context.Context, topic string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return closeSubscriptions(p.subscriptions, topic)
+}
+
+func (p *PubSub[T]) PUnsubscribeAll(ctx context.Context, pattern string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return closeSubscriptions(p.patternSubscriptions, pattern)
+}
+
+// ActiveTopics returns the sorted names of the channels with at least one subscriber using PUBSUB CHANNELS.
+// The result covers every client connected to the Redis server, not only this instance.
+func (p *PubSub[T]) ActiveTopics(ctx context.Context) ([]string, error) {
+	topics, err := p.client.PubSubChannels(ctx, "*").Result()
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(topics)
+	return topics, nil
+}
+
+// SubscriberCounts returns the number of subscribers of each specified topic using PUBSUB NUMSUB.
+// The counts cover every client connected to the Redis server, not only this instance.
+func (p *PubSub[T]) SubscriberCounts(ctx context.Context, topics ...string) (map[string]int64, error) {
+	if len(topics) == 0 {
+		return map[string]int64{}, nil
+	}
+	return p.client.PubSubNumSub(ctx, topics...).Result()
+}
+
 func (p *PubSub[T]) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	var err error
-	for topic, sub := range p.subscriptions {
-		err = sub.Close()
-		delete(p.subscriptions, topic)
+	var errs []error
+	for _, registry := range []map[string][]*redis.PubSub{p.subscriptions, p.patternSubscriptions} {
+		for name := range registry {
+			errs = append(errs, closeSubscriptions(registry, name))
+		}
 	}
 
-	return err
+	return errors.Join(errs...)
+}
+
+func closeSubscriptions(registry map[string][]*redis.PubSub, name string) error {
+	subs, ok := registry[name]
+	if !ok {
+		return nil
+	}
+	delete(registry, name)
+	var errs []error
+	for _, sub := range subs {
+		errs = append(errs, sub.Close())
+	}
+	return errors.Join(errs...)
 }
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"time"
 
 	"github.com/go-sphere/confstore/codec"
 	"github.com/redis/go-redis/v9"
@@ -12,10 +15,12 @@ import (
 var errInvalidBLPopResponse = errors.New("redis mq: invalid BLPOP response")
 
 // Queue implements a Redis-backed point-to-point message queue with typed message support.
-// It uses Redis lists to provide FIFO message delivery semantics.
+// It uses Redis lists to provide FIFO message delivery semantics, and records the topics
+// it publishes to in a registry set for Topics.
 type Queue[T any] struct {
-	client *redis.Client
-	codec  codec.Codec
+	client   *redis.Client
+	codec    codec.Codec
+	registry string
 }
 
 // NewQueue creates a new Redis-based queue with the specified options.
@@ -27,8 +32,9 @@ func NewQueue[T any](opt ...Option) (*Queue[T], error) {
 		return nil, err
 	}
 	return &Queue[T]{
-		client: opts.client,
-		codec:  opts.codec,
+		client:   opts.client,
+		codec:    opts.codec,
+		registry: opts.topicRegistry,
 	}, nil
 }
 
@@ -37,12 +43,23 @@ func (q *Queue[T]) Publish(ctx context.Context, topic string, data T) error {
 	if err != nil {
 		return err
 	}
-	return q.client.RPush(ctx, topic, raw).Err()
+	return q.push(ctx, topic, raw)
 }
 
+// push appends the messages to the topic list and registers the topic in one transaction.
+func (q *Queue[T]) push(ctx context.Context, topic string, raws ...any) error {
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, topic, raws...)
+		pipe.SAdd(ctx, q.registry, topic)
+		return nil
+	})
+	return err
+}
+
+// Consume blocks until a message arrives on the topic, or until the deadline of the context.
 func (q *Queue[T]) Consume(ctx context.Context, topic string) (T, error) {
 	var zero T
-	resp, err := q.client.BLPop(ctx, 0, topic).Result()
+	resp, err := blpop(ctx, q.client, topic)
 	if err != nil {
 		return zero, err
 	}
@@ -58,6 +75,114 @@ func (q *Queue[T]) Consume(ctx context.Context, topic string) (T, error) {
 	return data, nil
 }
 
+// PublishBatch appends all messages to the topic list with a single RPUSH.
+func (q *Queue[T]) PublishBatch(ctx context.Context, topic string, data []T) error {
+	if len(data) == 0 {
+		return nil
+	}
+	raws := make([]any, 0, len(data))
+	for _, item := range data {
+		raw, err := q.codec.Marshal(item)
+		if err != nil {
+			return err
+		}
+		raws = append(raws, raw)
+	}
+	return q.push(ctx, topic, raws...)
+}
+
+// ConsumeBatch pops up to max messages with LPOP count, lingering for up to wait while the
+// batch is not full. Lingering blocks on BLPOP with the remaining time, at most one second
+// per round trip, so no message popped by the server is ever abandoned by a client timeout.
+// Payloads that fail to decode are dropped, ending the batch with the messages decoded so far;
+// the decode error is returned only when there are none.
+func (q *Queue[T]) ConsumeBatch(ctx context.Context, topic string, limit int, wait time.Duration) ([]T, error) {
+	batch := make([]T, 0, max(limit, 0))
+	if limit <= 0 {
+		return batch, nil
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		raws, err := q.client.LPopCount(ctx, topic, limit-len(batch)).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return partialBatch(batch, err)
+		}
+		var decodeErr error
+		for _, raw := range raws {
+			var data T
+			if err = q.codec.Unmarshal([]byte(raw), &data); err != nil {
+				decodeErr = err
+				continue
+			}
+			batch = append(batch, data)
+		}
+		if decodeErr != nil {
+			return partialBatch(batch, decodeErr)
+		}
+		remaining := time.Until(deadline)
+		if len(batch) == limit || remaining < time.Millisecond {
+			return batch, nil
+		}
+
+		// BLPOP accepts fractional seconds; zero would block forever, hence the millisecond floor above.
+		timeout := strconv.FormatFloat(min(remaining, time.Second).Seconds(), 'f', 3, 64)
+		resp, err := q.client.Do(ctx, "blpop", topic, timeout).StringSlice()
+		if err != nil {
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			return partialBatch(batch, err)
+		}
+		if len(resp) < 2 {
+			return partialBatch(batch, fmt.Errorf("%w: %v", errInvalidBLPopResponse, resp))
+		}
+		var data T
+		if err = q.codec.Unmarshal([]byte(resp[1]), &data); err != nil {
+			return partialBatch(batch, err)
+		}
+		batch = append(batch, data)
+	}
+}
+
+// blpop pops the first message of the keys, blocking until one arrives. BLPOP does not observe the
+// context, so with a deadline it blocks for at most a second at a time and fails with
+// context.DeadlineExceeded once the deadline is reached, like a cancelled context between rounds.
+func blpop(ctx context.Context, client *redis.Client, keys ...string) ([]string, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return client.BLPop(ctx, 0, keys...).Result()
+	}
+	args := make([]any, 0, len(keys)+2)
+	args = append(args, "blpop")
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		remaining := time.Until(deadline)
+		if remaining < time.Millisecond {
+			return nil, context.DeadlineExceeded
+		}
+		timeout := strconv.FormatFloat(min(remaining, time.Second).Seconds(), 'f', 3, 64)
+		resp, err := client.Do(ctx, append(args, timeout)...).StringSlice()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		return resp, err
+	}
+}
+
+// partialBatch returns the messages already popped instead of the error, since they are no longer
+// in Redis and would otherwise be lost. A persistent failure surfaces on the next call.
+func partialBatch[T any](batch []T, err error) ([]T, error) {
+	if len(batch) > 0 {
+		return batch, nil
+	}
+	return nil, err
+}
+
 func (q *Queue[T]) TryConsume(ctx context.Context, topic string) (T, bool, error) {
 	var zero T
 	raw, err := q.client.LPop(ctx, topic).Bytes()
@@ -76,7 +201,64 @@ func (q *Queue[T]) TryConsume(ctx context.Context, topic string) (T, bool, error
 }
 
 func (q *Queue[T]) PurgeQueue(ctx context.Context, topic string) error {
-	return q.client.Del(ctx, topic).Err()
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, topic)
+		pipe.SRem(ctx, q.registry, topic)
+		return nil
+	})
+	return err
+}
+
+// Len returns the number of messages waiting in the topic queue using LLEN.
+func (q *Queue[T]) Len(ctx context.Context, topic string) (int64, error) {
+	return q.client.LLen(ctx, topic).Result()
+}
+
+// Peek returns up to n of the oldest pending messages of the topic queue using LRANGE, without consuming them.
+func (q *Queue[T]) Peek(ctx context.Context, topic string, n int) ([]T, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	raws, err := q.client.LRange(ctx, topic, 0, int64(n-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	items := make([]T, 0, len(raws))
+	for _, raw := range raws {
+		var data T
+		if err = q.codec.Unmarshal([]byte(raw), &data); err != nil {
+			return nil, err
+		}
+		items = append(items, data)
+	}
+	return items, nil
+}
+
+// Topics returns the sorted names of the registered topic queues that currently hold pending messages.
+// Only topics published to through a queue with the same registry are reported.
+func (q *Queue[T]) Topics(ctx context.Context) ([]string, error) {
+	registered, err := q.client.SMembers(ctx, q.registry).Result()
+	if err != nil {
+		return nil, err
+	}
+	lengths := make([]*redis.IntCmd, len(registered))
+	_, err = q.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, topic := range registered {
+			lengths[i] = pipe.LLen(ctx, topic)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	topics := make([]string, 0, len(registered))
+	for i, topic := range registered {
+		if lengths[i].Val() > 0 {
+			topics = append(topics, topic)
+		}
+	}
+	slices.Sort(topics)
+	return topics, nil
 }
 
 func (q *Queue[T]) Close() error {
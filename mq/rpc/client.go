@@ -0,0 +1,182 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-sphere/sphere/log"
+	"github.com/go-sphere/sphere/mq"
+	"github.com/google/uuid"
+)
+
+// pendingCall is a call waiting for its response until its deadline, if any.
+type pendingCall[Resp any] struct {
+	ch       chan Response[Resp]
+	deadline time.Time
+}
+
+// Client issues requests over an mq.Queue and waits for the correlated responses.
+// All responses for a client arrive on a single reply topic and are dispatched to the
+// waiting calls by correlation ID; responses arriving after their call gave up are dropped.
+type Client[Req any, Resp any] struct {
+	requests mq.Queue[Request[Req]]
+	replies  mq.Queue[Response[Resp]]
+	opts     *options
+
+	mu      sync.Mutex
+	pending map[string]pendingCall[Resp]
+	closed  bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewClient creates a client publishing requests to the requests queue and consuming
+// responses from its reply topic on the replies queue. The queues may share a backend
+// but are not closed by the client.
+func NewClient[Req any, Resp any](requests mq.Queue[Request[Req]], replies mq.Queue[Response[Resp]], opt ...Option) (*Client[Req, Resp], error) {
+	if requests == nil {
+		return nil, errors.New("requests queue is required")
+	}
+	if replies == nil {
+		return nil, errors.New("replies queue is required")
+	}
+	opts := newOptions(opt...)
+	if opts.pollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+	if opts.replyTopic == "" {
+		id, err := uuid.NewRandom()
+		if err != nil {
+			return nil, err
+		}
+		opts.replyTopic = "rpc:reply:" + id.String()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client[Req, Resp]{
+		requests: requests,
+		replies:  replies,
+		opts:     opts,
+		pending:  make(map[string]pendingCall[Resp]),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(c.done)
+		poll(ctx, replies, opts.replyTopic, opts.pollInterval, c.dispatch, func(err error) {
+			log.Warn("mq rpc: consume replies failed", log.String("topic", opts.replyTopic), log.Err(err))
+		})
+	}()
+	return c, nil
+}
+
+// ReplyTopic returns the topic this client consumes responses from.
+func (c *Client[Req, Resp]) ReplyTopic() string {
+	return c.opts.replyTopic
+}
+
+// Call publishes req to the topic and waits for the matching response.
+// If ctx has no deadline the client's default timeout applies; the deadline is forwarded to
+// the server so that expired requests are skipped. Handler failures are returned as *RemoteError.
+func (c *Client[Req, Resp]) Call(ctx context.Context, topic string, req Req) (Resp, error) {
+	var zero Resp
+	if _, ok := ctx.Deadline(); !ok && c.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.timeout)
+		defer cancel()
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return zero, err
+	}
+	request := Request[Req]{
+		ID:      id.String(),
+		ReplyTo: c.opts.replyTopic,
+		Payload: req,
+	}
+	deadline, _ := ctx.Deadline()
+	if !deadline.IsZero() {
+		request.Deadline = deadline.UnixMilli()
+	}
+
+	ch := make(chan Response[Resp], 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return zero, ErrClientClosed
+	}
+	c.pending[request.ID] = pendingCall[Resp]{ch: ch, deadline: deadline}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, request.ID)
+		c.mu.Unlock()
+	}()
+
+	if err = c.requests.Publish(ctx, topic, request); err != nil {
+		return zero, err
+	}
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return zero, ErrClientClosed
+		}
+		if resp.Error != "" {
+			return zero, &RemoteError{Message: resp.Error}
+		}
+		return resp.Payload, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// Close stops consuming responses, fails the calls still waiting with ErrClientClosed
+// and purges the reply topic. Servers may still reply to those calls until their deadlines,
+// so the reply topic is purged again once the last deadline has passed.
+func (c *Client[Req, Resp]) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	var last time.Time
+	for id, call := range c.pending {
+		close(call.ch)
+		delete(c.pending, id)
+		if call.deadline.After(last) {
+			last = call.deadline
+		}
+	}
+	c.mu.Unlock()
+
+	c.cancel()
+	<-c.done
+	if wait := time.Until(last); wait > 0 {
+		time.AfterFunc(wait, func() {
+			if err := c.purge(); err != nil {
+				log.Warn("mq rpc: purge reply topic failed", log.String("topic", c.opts.replyTopic), log.Err(err))
+			}
+		})
+	}
+	return c.purge()
+}
+
+// purge removes the responses left on the reply topic.
+func (c *Client[Req, Resp]) purge() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return c.replies.PurgeQueue(ctx, c.opts.replyTopic)
+}
+
+func (c *Client[Req, Resp]) dispatch(resp Response[Resp]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	call, ok := c.pending[resp.ID]
+	if !ok {
+		return
+	}
+	delete(c.pending, resp.ID)
+	call.ch <- resp
+}
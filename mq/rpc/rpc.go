@@ -0,0 +1,118 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-sphere/sphere/mq"
+)
+
+var (
+	ErrClientClosed         = errors.New("mq rpc: client is closed")
+	ErrServerAlreadyStarted = errors.New("mq rpc server already started")
+)
+
+// Request is the envelope published to the request topic.
+// ReplyTo names the topic the caller consumes responses from, and ID correlates the response.
+type Request[T any] struct {
+	ID       string `json:"id"`
+	ReplyTo  string `json:"reply_to"`
+	Deadline int64  `json:"deadline,omitempty"` // unix milliseconds, zero means no deadline
+	Payload  T      `json:"payload"`
+}
+
+// Response is the envelope published to the caller's reply topic.
+type Response[T any] struct {
+	ID      string `json:"id"`
+	Payload T      `json:"payload"`
+	Error   string `json:"error,omitempty"`
+}
+
+// RemoteError is returned by Client.Call when the server handler failed.
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return "mq rpc: remote error: " + e.Message
+}
+
+type options struct {
+	replyTopic   string
+	timeout      time.Duration
+	pollInterval time.Duration
+	concurrency  int
+}
+
+func newOptions(opts ...Option) *options {
+	defaults := &options{
+		timeout:      30 * time.Second,
+		pollInterval: time.Second,
+		concurrency:  1,
+	}
+	for _, opt := range opts {
+		opt(defaults)
+	}
+	return defaults
+}
+
+// Option is a functional option for configuring RPC clients and servers.
+type Option func(*options)
+
+// WithReplyTopic sets the topic a client consumes responses from.
+// It must be unique per client instance; the default is "rpc:reply:" followed by a random UUID.
+func WithReplyTopic(topic string) Option {
+	return func(opts *options) {
+		opts.replyTopic = topic
+	}
+}
+
+// WithTimeout sets the deadline applied by Client.Call when the context has none.
+// The default is 30 seconds.
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *options) {
+		opts.timeout = timeout
+	}
+}
+
+// WithPollInterval sets how long clients and servers block on an empty queue before consuming
+// again, so that stopping never waits on a backend that does not honour cancellation for longer.
+// It is also the delay before retrying a failed consume. The default is 1 second.
+func WithPollInterval(interval time.Duration) Option {
+	return func(opts *options) {
+		opts.pollInterval = interval
+	}
+}
+
+// WithConcurrency sets how many requests a server handles in parallel. The default is 1.
+func WithConcurrency(n int) Option {
+	return func(opts *options) {
+		opts.concurrency = n
+	}
+}
+
+// poll consumes messages from the topic until the context is cancelled, blocking for up to the
+// poll interval at a time. Consume errors are passed to onError, and consuming resumes after the interval.
+func poll[T any](ctx context.Context, queue mq.Queue[T], topic string, interval time.Duration, handle func(T), onError func(error)) {
+	for ctx.Err() == nil {
+		consumeCtx, cancel := context.WithTimeout(ctx, interval)
+		msg, err := queue.Consume(consumeCtx, topic)
+		expired := consumeCtx.Err() != nil
+		cancel()
+		if err == nil {
+			handle(msg)
+			continue
+		}
+		if ctx.Err() != nil || expired {
+			continue
+		}
+		onError(err)
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
@@ -0,0 +1,277 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/mq"
+	"github.com/go-sphere/sphere/mq/memory"
+	redismq "github.com/go-sphere/sphere/mq/redis"
+	"github.com/go-sphere/sphere/test/redistest"
+)
+
+type quote struct {
+	Symbol string `json:"symbol"`
+}
+
+type price struct {
+	Symbol string `json:"symbol"`
+	Cents  int    `json:"cents"`
+}
+
+type queues struct {
+	requests mq.Queue[Request[quote]]
+	replies  mq.Queue[Response[price]]
+}
+
+func queueFactories(t *testing.T) map[string]func() queues {
+	return map[string]func() queues{
+		"memory": func() queues {
+			requests := memory.NewQueue[Request[quote]]()
+			replies := memory.NewQueue[Response[price]]()
+			t.Cleanup(func() {
+				_ = requests.Close()
+				_ = replies.Close()
+			})
+			return queues{requests: requests, replies: replies}
+		},
+		"redis": func() queues {
+			client := redistest.NewTestRedisClient(t)
+			requests, err := redismq.NewQueue[Request[quote]](redismq.WithClient(client))
+			if err != nil {
+				t.Fatalf("NewQueue() error = %v", err)
+			}
+			replies, err := redismq.NewQueue[Response[price]](redismq.WithClient(client))
+			if err != nil {
+				t.Fatalf("NewQueue() error = %v", err)
+			}
+			return queues{requests: requests, replies: replies}
+		},
+	}
+}
+
+func startServer(t *testing.T, q queues, handler HandlerFunc[quote, price], opt ...Option) *Server[quote, price] {
+	t.Helper()
+	server, err := NewServer(q.requests, q.replies, "prices", handler, opt...)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	go func() {
+		if sErr := server.Start(context.Background()); sErr != nil {
+			t.Errorf("Start() error = %v", sErr)
+		}
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = server.Stop(ctx)
+	})
+	return server
+}
+
+func newClient(t *testing.T, q queues, opt ...Option) *Client[quote, price] {
+	t.Helper()
+	client, err := NewClient(q.requests, q.replies, opt...)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestCallRoundTrip(t *testing.T) {
+	for name, factory := range queueFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			q := factory()
+			startServer(t, q, func(ctx context.Context, req quote) (price, error) {
+				if req.Symbol == "" {
+					return price{}, errors.New("symbol is required")
+				}
+				cents, err := strconv.Atoi(req.Symbol[1:])
+				return price{Symbol: req.Symbol, Cents: cents}, err
+			}, WithConcurrency(4), WithPollInterval(5*time.Millisecond))
+			client := newClient(t, q, WithPollInterval(5*time.Millisecond))
+
+			var wg sync.WaitGroup
+			for i := range 10 {
+				wg.Go(func() {
+					ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+					defer cancel()
+					symbol := "s" + strconv.Itoa(i)
+					got, err := client.Call(ctx, "prices", quote{Symbol: symbol})
+					if err != nil {
+						t.Errorf("Call(%s) error = %v", symbol, err)
+						return
+					}
+					if got != (price{Symbol: symbol, Cents: i}) {
+						t.Errorf("Call(%s) = %+v", symbol, got)
+					}
+				})
+			}
+			wg.Wait()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err := client.Call(ctx, "prices", quote{})
+			var remote *RemoteError
+			if !errors.As(err, &remote) || remote.Message != "symbol is required" {
+				t.Fatalf("Call() error = %v, want remote error", err)
+			}
+		})
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	for name, factory := range queueFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			client := newClient(t, factory(), WithTimeout(50*time.Millisecond))
+			start := time.Now()
+			_, err := client.Call(context.Background(), "nobody-listens", quote{Symbol: "x1"})
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Call() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("Call() took %v, default timeout not applied", elapsed)
+			}
+		})
+	}
+}
+
+func TestServerSkipsExpiredRequests(t *testing.T) {
+	q := queueFactories(t)["memory"]()
+	var calls atomic.Int32
+	ctx := context.Background()
+	expired := Request[quote]{
+		ID:       "expired",
+		ReplyTo:  "replies",
+		Deadline: time.Now().Add(-time.Second).UnixMilli(),
+		Payload:  quote{Symbol: "s1"},
+	}
+	if err := q.requests.Publish(ctx, "prices", expired); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	live := Request[quote]{ID: "live", ReplyTo: "replies", Payload: quote{Symbol: "s2"}}
+	if err := q.requests.Publish(ctx, "prices", live); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	startServer(t, q, func(ctx context.Context, req quote) (price, error) {
+		calls.Add(1)
+		return price{Symbol: req.Symbol}, nil
+	})
+
+	waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	resp, err := q.replies.Consume(waitCtx, "replies")
+	if err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+	if resp.ID != "live" || resp.Payload.Symbol != "s2" {
+		t.Fatalf("reply = %+v, want live request", resp)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("handler calls = %d, want 1", got)
+	}
+}
+
+func TestClientCloseFailsPendingCalls(t *testing.T) {
+	client := newClient(t, queueFactories(t)["memory"]())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := client.Call(context.Background(), "prices", quote{Symbol: "s1"})
+		errCh <- err
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		client.mu.Lock()
+		waiting := len(client.pending)
+		client.mu.Unlock()
+		if waiting == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for pending call")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := <-errCh; !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("Call() error = %v, want %v", err, ErrClientClosed)
+	}
+	if _, err := client.Call(context.Background(), "prices", quote{}); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("Call() after Close error = %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestLateRepliesDoNotOutliveTheClient(t *testing.T) {
+	for name, factory := range queueFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			q := factory()
+			inspector, ok := q.replies.(mq.QueueInspector[Response[price]])
+			if !ok {
+				t.Skip("replies queue cannot be inspected")
+			}
+			handled := make(chan struct{}, 2)
+			startServer(t, q, func(ctx context.Context, req quote) (price, error) {
+				time.Sleep(100 * time.Millisecond)
+				handled <- struct{}{}
+				return price{Symbol: req.Symbol}, nil
+			}, WithPollInterval(10*time.Millisecond))
+			client := newClient(t, q, WithTimeout(300*time.Millisecond), WithPollInterval(10*time.Millisecond))
+
+			// The first call gives up before the server replies, so the reply is dropped.
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if _, err := client.Call(ctx, "prices", quote{Symbol: "late"}); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Call() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+			<-handled
+
+			// The second call is still pending when the client closes, so the reply arrives after Close.
+			go func() { _, _ = client.Call(context.Background(), "prices", quote{Symbol: "closed"}) }()
+			for {
+				client.mu.Lock()
+				waiting := len(client.pending)
+				client.mu.Unlock()
+				if waiting == 1 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if err := client.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			<-handled
+			time.Sleep(400 * time.Millisecond)
+
+			topics, err := inspector.Topics(context.Background())
+			if err != nil {
+				t.Fatalf("Topics() error = %v", err)
+			}
+			if slices.Contains(topics, client.ReplyTopic()) {
+				t.Fatalf("reply topic %s outlived the client", client.ReplyTopic())
+			}
+		})
+	}
+}
+
+func TestConstructorsValidation(t *testing.T) {
+	q := queueFactories(t)["memory"]()
+	handler := func(ctx context.Context, req quote) (price, error) { return price{}, nil }
+	if _, err := NewClient[quote, price](nil, q.replies); err == nil {
+		t.Fatal("expected NewClient without requests queue to fail")
+	}
+	if _, err := NewServer(q.requests, q.replies, "", handler); err == nil {
+		t.Fatal("expected NewServer without topic to fail")
+	}
+	if _, err := NewServer(q.requests, q.replies, "prices", handler, WithConcurrency(0)); err == nil {
+		t.Fatal("expected NewServer with zero concurrency to fail")
+	}
+}
@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-sphere/sphere/core/task"
+	"github.com/go-sphere/sphere/log"
+	"github.com/go-sphere/sphere/mq"
+)
+
+// HandlerFunc processes a request payload and returns the response payload.
+type HandlerFunc[Req any, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// Server is a task that consumes requests from a topic, invokes the handler and
+// publishes each response to the reply topic named by the request.
+// Requests whose deadline has already passed are dropped without invoking the handler, and
+// responses are dropped if the deadline passes while handling, since the caller gave up by then.
+type Server[Req any, Resp any] struct {
+	requests mq.Queue[Request[Req]]
+	replies  mq.Queue[Response[Resp]]
+	topic    string
+	handler  HandlerFunc[Req, Resp]
+	opts     *options
+
+	loop task.Loop
+}
+
+// NewServer creates a server handling requests published to the topic.
+func NewServer[Req any, Resp any](requests mq.Queue[Request[Req]], replies mq.Queue[Response[Resp]], topic string, handler HandlerFunc[Req, Resp], opt ...Option) (*Server[Req, Resp], error) {
+	if requests == nil {
+		return nil, errors.New("requests queue is required")
+	}
+	if replies == nil {
+		return nil, errors.New("replies queue is required")
+	}
+	if topic == "" {
+		return nil, errors.New("topic is required")
+	}
+	if handler == nil {
+		return nil, errors.New("handler is required")
+	}
+	opts := newOptions(opt...)
+	if opts.pollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+	if opts.concurrency <= 0 {
+		return nil, errors.New("concurrency must be positive")
+	}
+	return &Server[Req, Resp]{
+		requests: requests,
+		replies:  replies,
+		topic:    topic,
+		handler:  handler,
+		opts:     opts,
+	}, nil
+}
+
+// Identifier returns the server's identifier for logging and debugging purposes.
+func (s *Server[Req, Resp]) Identifier() string {
+	return "mq_rpc_server:" + s.topic
+}
+
+// Start consumes and handles requests until the context is cancelled or Stop is called.
+// A stopped server can be started again.
+func (s *Server[Req, Resp]) Start(ctx context.Context) error {
+	return s.loop.Run(ctx, ErrServerAlreadyStarted, func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		for range s.opts.concurrency {
+			wg.Go(func() {
+				poll(ctx, s.requests, s.topic, s.opts.pollInterval, func(req Request[Req]) {
+					s.serve(ctx, req)
+				}, func(err error) {
+					log.Warn("mq rpc: consume requests failed", log.String("topic", s.topic), log.Err(err))
+				})
+			})
+		}
+		wg.Wait()
+		return nil
+	})
+}
+
+// Stop signals the server to exit and waits for in-flight requests to finish.
+func (s *Server[Req, Resp]) Stop(ctx context.Context) error {
+	return s.loop.Stop(ctx)
+}
+
+func (s *Server[Req, Resp]) serve(ctx context.Context, req Request[Req]) {
+	if req.ID == "" || req.ReplyTo == "" {
+		log.Warn("mq rpc: dropping request without correlation id or reply topic", log.String("topic", s.topic))
+		return
+	}
+	// Handlers run to completion on shutdown; only the caller's deadline bounds them.
+	handlerCtx := context.WithoutCancel(ctx)
+	if req.Deadline > 0 {
+		deadline := time.UnixMilli(req.Deadline)
+		if !time.Now().Before(deadline) {
+			return
+		}
+		var cancel context.CancelFunc
+		handlerCtx, cancel = context.WithDeadline(handlerCtx, deadline)
+		defer cancel()
+	}
+
+	resp := Response[Resp]{ID: req.ID}
+	payload, err := s.handler(handlerCtx, req.Payload)
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Payload = payload
+	}
+	if req.Deadline > 0 && !time.Now().Before(time.UnixMilli(req.Deadline)) {
+		return
+	}
+	if err = s.replies.Publish(handlerCtx, req.ReplyTo, resp); err != nil {
+		log.Warn("mq rpc: publish reply failed", log.String("topic", s.topic), log.String("reply_to", req.ReplyTo), log.Err(err))
+	}
+}
@@ -0,0 +1,216 @@
+package test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/mq"
+	"github.com/go-sphere/sphere/mq/memory"
+	redismq "github.com/go-sphere/sphere/mq/redis"
+	"github.com/go-sphere/sphere/test/redistest"
+)
+
+func batchQueue(t *testing.T, factory queueFactory) (mq.Queue[int], mq.BatchQueue[int]) {
+	t.Helper()
+	q := factory.new(t)
+	batch, ok := q.(mq.BatchQueue[int])
+	if !ok {
+		t.Skip("queue does not implement mq.BatchQueue")
+	}
+	return q, batch
+}
+
+func TestBatchQueueMaxSize(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range queueFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			q, batch := batchQueue(t, factory)
+
+			if err := batch.PublishBatch(ctx, "logs", nil); err != nil {
+				t.Fatalf("PublishBatch empty: %v", err)
+			}
+			if err := batch.PublishBatch(ctx, "logs", []int{1, 2, 3, 4, 5}); err != nil {
+				t.Fatalf("PublishBatch: %v", err)
+			}
+			if err := q.Publish(ctx, "logs", 6); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+
+			got, err := batch.ConsumeBatch(ctx, "logs", 4, 0)
+			if err != nil {
+				t.Fatalf("ConsumeBatch first: %v", err)
+			}
+			if !slices.Equal(got, []int{1, 2, 3, 4}) {
+				t.Fatalf("ConsumeBatch first mismatch: got=%v want=[1 2 3 4]", got)
+			}
+			got, err = batch.ConsumeBatch(ctx, "logs", 10, 0)
+			if err != nil {
+				t.Fatalf("ConsumeBatch rest: %v", err)
+			}
+			if !slices.Equal(got, []int{5, 6}) {
+				t.Fatalf("ConsumeBatch rest mismatch: got=%v want=[5 6]", got)
+			}
+			got, err = batch.ConsumeBatch(ctx, "logs", 10, 0)
+			if err != nil || len(got) != 0 {
+				t.Fatalf("ConsumeBatch empty mismatch: got=%v err=%v", got, err)
+			}
+		})
+	}
+}
+
+func TestBatchQueueLinger(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range queueFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			q, batch := batchQueue(t, factory)
+
+			if err := q.Publish(ctx, "linger", 1); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			const wait = 200 * time.Millisecond
+			start := time.Now()
+			got, err := batch.ConsumeBatch(ctx, "linger", 3, wait)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Fatalf("ConsumeBatch: %v", err)
+			}
+			if !slices.Equal(got, []int{1}) {
+				t.Fatalf("ConsumeBatch partial mismatch: got=%v want=[1]", got)
+			}
+			if elapsed < wait-20*time.Millisecond {
+				t.Fatalf("ConsumeBatch returned a partial batch before linger: elapsed=%v", elapsed)
+			}
+			if elapsed > 2*time.Second {
+				t.Fatalf("ConsumeBatch lingered too long: elapsed=%v", elapsed)
+			}
+		})
+	}
+}
+
+func TestBatchQueueReturnsWhenFull(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range queueFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			q, batch := batchQueue(t, factory)
+
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				_ = q.Publish(ctx, "burst", 1)
+				_ = batch.PublishBatch(ctx, "burst", []int{2, 3})
+			}()
+
+			start := time.Now()
+			got, err := batch.ConsumeBatch(ctx, "burst", 2, 10*time.Second)
+			if err != nil {
+				t.Fatalf("ConsumeBatch: %v", err)
+			}
+			if !slices.Equal(got, []int{1, 2}) {
+				t.Fatalf("ConsumeBatch mismatch: got=%v want=[1 2]", got)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("ConsumeBatch waited for linger despite full batch: elapsed=%v", elapsed)
+			}
+			msg, found, err := q.TryConsume(ctx, "burst")
+			if err != nil || !found || msg != 3 {
+				t.Fatalf("remaining message mismatch: msg=%d found=%v err=%v", msg, found, err)
+			}
+		})
+	}
+}
+
+func TestBatchQueueCanceledContextKeepsCollected(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range queueFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			q, batch := batchQueue(t, factory)
+			if err := q.Publish(context.Background(), "cancel", 7); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			got, err := batch.ConsumeBatch(ctx, "cancel", 5, 10*time.Second)
+			if err != nil {
+				t.Fatalf("ConsumeBatch: %v", err)
+			}
+			if !slices.Equal(got, []int{7}) {
+				t.Fatalf("ConsumeBatch mismatch: got=%v want=[7]", got)
+			}
+		})
+	}
+}
+
+func TestMemoryBatchPublishLargerThanQueue(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	q := memory.NewQueue[int](memory.WithQueueSize(2))
+	t.Cleanup(func() { _ = q.Close() })
+	done := make(chan error, 1)
+	go func() {
+		done <- q.PublishBatch(ctx, "small", []int{1, 2, 3, 4, 5})
+	}()
+
+	var got []int
+	for len(got) < 5 {
+		items, err := q.ConsumeBatch(ctx, "small", 5, time.Second)
+		if err != nil {
+			t.Fatalf("ConsumeBatch: %v", err)
+		}
+		got = append(got, items...)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("PublishBatch: %v", err)
+	}
+	if !slices.Equal(got, []int{1, 2, 3, 4, 5}) {
+		t.Fatalf("batch order mismatch: got=%v", got)
+	}
+}
+
+func TestRedisBatchSkipsMalformedPayload(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := redistest.NewTestRedisClient(t)
+	q, err := redismq.NewQueue[int](redismq.WithClient(client))
+	if err != nil {
+		t.Fatalf("create redis queue: %v", err)
+	}
+	if err = client.RPush(ctx, "logs", "1", "not-json", "3").Err(); err != nil {
+		t.Fatalf("RPush: %v", err)
+	}
+
+	got, err := q.ConsumeBatch(ctx, "logs", 5, 0)
+	if err != nil {
+		t.Fatalf("ConsumeBatch: %v", err)
+	}
+	if !slices.Equal(got, []int{1, 3}) {
+		t.Fatalf("ConsumeBatch mismatch: got=%v want=[1 3]", got)
+	}
+
+	if err = client.RPush(ctx, "logs", "not-json").Err(); err != nil {
+		t.Fatalf("RPush: %v", err)
+	}
+	if got, err = q.ConsumeBatch(ctx, "logs", 5, 0); err == nil {
+		t.Fatalf("ConsumeBatch of malformed payload succeeded: %v", got)
+	}
+	if n, _ := q.Len(ctx, "logs"); n != 0 {
+		t.Fatalf("malformed payload left in queue: len = %d", n)
+	}
+}
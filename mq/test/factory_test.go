@@ -10,9 +10,14 @@ import (
 )
 
 type queueFactory struct {
-	name                 string
-	blockingConsumeCheck bool
-	new                  func(tb testing.TB) mq.Queue[int]
+	name string
+	new  func(tb testing.TB) mq.Queue[int]
+}
+
+type priorityQueueFactory struct {
+	name     string
+	weighted bool
+	new      func(tb testing.TB) mq.PriorityQueue[int]
 }
 
 type pubSubFactory struct {
@@ -24,8 +29,7 @@ type pubSubFactory struct {
 func queueFactories() []queueFactory {
 	return []queueFactory{
 		{
-			name:                 "memory",
-			blockingConsumeCheck: true,
+			name: "memory",
 			new: func(tb testing.TB) mq.Queue[int] {
 				tb.Helper()
 				q := memory.NewQueue[int]()
@@ -34,8 +38,7 @@ func queueFactories() []queueFactory {
 			},
 		},
 		{
-			name:                 "redis",
-			blockingConsumeCheck: false,
+			name: "redis",
 			new: func(tb testing.TB) mq.Queue[int] {
 				t, ok := tb.(*testing.T)
 				if !ok {
@@ -50,6 +53,50 @@ func queueFactories() []queueFactory {
 				return q
 			},
 		},
+		{
+			name: "memory-priority",
+			new: func(tb testing.TB) mq.Queue[int] {
+				return priorityQueueFactories()[0].new(tb)
+			},
+		},
+		{
+			name: "redis-priority",
+			new: func(tb testing.TB) mq.Queue[int] {
+				return priorityQueueFactories()[1].new(tb)
+			},
+		},
+	}
+}
+
+func priorityQueueFactories() []priorityQueueFactory {
+	return []priorityQueueFactory{
+		{
+			name:     "memory",
+			weighted: true,
+			new: func(tb testing.TB) mq.PriorityQueue[int] {
+				tb.Helper()
+				q := memory.NewPriorityQueue[int]()
+				tb.Cleanup(func() { _ = q.Close() })
+				return q
+			},
+		},
+		{
+			name:     "redis",
+			weighted: false,
+			new: func(tb testing.TB) mq.PriorityQueue[int] {
+				t, ok := tb.(*testing.T)
+				if !ok {
+					tb.Fatalf("redis priority queue factory requires *testing.T")
+				}
+				client := redistest.NewTestRedisClient(t)
+				q, err := redismq.NewPriorityQueue[int](redismq.WithClient(client))
+				if err != nil {
+					tb.Fatalf("create redis priority queue: %v", err)
+				}
+				tb.Cleanup(func() { _ = q.Close() })
+				return q
+			},
+		},
 	}
 }
 
@@ -0,0 +1,39 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-sphere/sphere/mq"
+)
+
+func TestHandlerChainOrder(t *testing.T) {
+	t.Parallel()
+
+	var order []string
+	trace := func(name string) mq.Middleware[int] {
+		return func(next mq.Handler[int]) mq.Handler[int] {
+			return func(ctx context.Context, topic string, data int) error {
+				order = append(order, name)
+				return next(ctx, topic, data)
+			}
+		}
+	}
+	handler := mq.Chain(func(ctx context.Context, topic string, data int) error {
+		order = append(order, topic)
+		return nil
+	}, trace("outer"), trace("inner"))
+
+	if err := handler.SubscribeFunc(context.Background(), "topic")(1); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	want := []string{"outer", "inner", "topic"}
+	if len(order) != len(want) {
+		t.Fatalf("chain order mismatch: got=%v want=%v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("chain order mismatch: got=%v want=%v", order, want)
+		}
+	}
+}
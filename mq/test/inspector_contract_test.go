@@ -0,0 +1,184 @@
+package test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/mq"
+	redismq "github.com/go-sphere/sphere/mq/redis"
+	"github.com/go-sphere/sphere/test/redistest"
+)
+
+func TestQueueInspectorContract(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range queueFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			q := factory.new(t)
+			inspector, ok := q.(mq.QueueInspector[int])
+			if !ok {
+				t.Skip("queue does not implement mq.QueueInspector")
+			}
+
+			if n, err := inspector.Len(ctx, "missing"); err != nil || n != 0 {
+				t.Fatalf("Len missing mismatch: n=%d err=%v", n, err)
+			}
+			for i := 1; i <= 3; i++ {
+				if err := q.Publish(ctx, "orders", i); err != nil {
+					t.Fatalf("Publish orders: %v", err)
+				}
+			}
+			if err := q.Publish(ctx, "emails", 9); err != nil {
+				t.Fatalf("Publish emails: %v", err)
+			}
+
+			if n, err := inspector.Len(ctx, "orders"); err != nil || n != 3 {
+				t.Fatalf("Len orders mismatch: n=%d err=%v", n, err)
+			}
+			peeked, err := inspector.Peek(ctx, "orders", 2)
+			if err != nil {
+				t.Fatalf("Peek orders: %v", err)
+			}
+			if !slices.Equal(peeked, []int{1, 2}) {
+				t.Fatalf("Peek orders mismatch: got=%v want=[1 2]", peeked)
+			}
+			peeked, err = inspector.Peek(ctx, "orders", 10)
+			if err != nil || !slices.Equal(peeked, []int{1, 2, 3}) {
+				t.Fatalf("Peek beyond length mismatch: got=%v err=%v", peeked, err)
+			}
+
+			topics, err := inspector.Topics(ctx)
+			if err != nil {
+				t.Fatalf("Topics: %v", err)
+			}
+			if !slices.Equal(topics, []string{"emails", "orders"}) {
+				t.Fatalf("Topics mismatch: got=%v", topics)
+			}
+
+			msg, err := q.Consume(ctx, "orders")
+			if err != nil || msg != 1 {
+				t.Fatalf("Consume after Peek mismatch: msg=%d err=%v", msg, err)
+			}
+			if n, err := inspector.Len(ctx, "orders"); err != nil || n != 2 {
+				t.Fatalf("Len after Consume mismatch: n=%d err=%v", n, err)
+			}
+
+			if err := q.PurgeQueue(ctx, "emails"); err != nil {
+				t.Fatalf("PurgeQueue emails: %v", err)
+			}
+			topics, err = inspector.Topics(ctx)
+			if err != nil || !slices.Equal(topics, []string{"orders"}) {
+				t.Fatalf("Topics after purge mismatch: got=%v err=%v", topics, err)
+			}
+		})
+	}
+}
+
+func TestPubSubInspectorContract(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range pubSubFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			p := factory.newInt(t)
+			inspector, ok := p.(mq.PubSubInspector)
+			if !ok {
+				t.Fatalf("pubsub does not implement mq.PubSubInspector")
+			}
+
+			noop := func(int) error { return nil }
+			for range 2 {
+				if err := p.Subscribe(ctx, "alerts", noop); err != nil {
+					t.Fatalf("Subscribe alerts: %v", err)
+				}
+			}
+			if err := p.Subscribe(ctx, "audit", noop); err != nil {
+				t.Fatalf("Subscribe audit: %v", err)
+			}
+			if err := p.PSubscribe(ctx, "a*", func(string, int) error { return nil }); err != nil {
+				t.Fatalf("PSubscribe: %v", err)
+			}
+
+			topics, err := inspector.ActiveTopics(ctx)
+			if err != nil {
+				t.Fatalf("ActiveTopics: %v", err)
+			}
+			if !slices.Equal(topics, []string{"alerts", "audit"}) {
+				t.Fatalf("ActiveTopics mismatch: got=%v", topics)
+			}
+
+			counts, err := inspector.SubscriberCounts(ctx, "alerts", "audit", "idle")
+			if err != nil {
+				t.Fatalf("SubscriberCounts: %v", err)
+			}
+			want := map[string]int64{"alerts": 2, "audit": 1, "idle": 0}
+			for topic, n := range want {
+				if counts[topic] != n {
+					t.Fatalf("SubscriberCounts[%s] mismatch: got=%d want=%d", topic, counts[topic], n)
+				}
+			}
+
+			if err := p.UnsubscribeAll(ctx, "alerts"); err != nil {
+				t.Fatalf("UnsubscribeAll: %v", err)
+			}
+			// Redis drops the subscription once the connection is closed, which happens asynchronously.
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				topics, err = inspector.ActiveTopics(ctx)
+				if err != nil {
+					t.Fatalf("ActiveTopics after unsubscribe: %v", err)
+				}
+				if slices.Equal(topics, []string{"audit"}) {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("ActiveTopics after unsubscribe mismatch: got=%v", topics)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestRedisQueueTopicsIgnoreUnrelatedLists(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := redistest.NewTestRedisClient(t)
+	q, err := redismq.NewQueue[int](redismq.WithClient(client), redismq.WithTopicRegistry("jobs:topics"))
+	if err != nil {
+		t.Fatalf("create redis queue: %v", err)
+	}
+	priority, err := redismq.NewPriorityQueue[int](redismq.WithClient(client))
+	if err != nil {
+		t.Fatalf("create redis priority queue: %v", err)
+	}
+	if err = priority.PublishWithPriority(ctx, "reports", mq.PriorityHigh, 1); err != nil {
+		t.Fatalf("PublishWithPriority: %v", err)
+	}
+	if err = client.RPush(ctx, "sessions", "unrelated").Err(); err != nil {
+		t.Fatalf("RPush: %v", err)
+	}
+	if err = q.Publish(ctx, "tenant/orders", 1); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	topics, err := q.Topics(ctx)
+	if err != nil || !slices.Equal(topics, []string{"tenant/orders"}) {
+		t.Fatalf("Topics mismatch: got=%v err=%v", topics, err)
+	}
+	if _, err = q.Consume(ctx, "tenant/orders"); err != nil {
+		t.Fatalf("Consume: %v", err)
+	}
+	topics, err = q.Topics(ctx)
+	if err != nil || len(topics) != 0 {
+		t.Fatalf("Topics of drained queue mismatch: got=%v err=%v", topics, err)
+	}
+}
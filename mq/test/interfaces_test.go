@@ -16,6 +16,23 @@ var (
 	_ mq.Queue[int]        = (*redismq.Queue[int])(nil)
 	_ mq.PubSub[int]       = (*redismq.PubSub[int])(nil)
 	_ mq.MessageQueue[int] = (*redismq.MessageQueue[int])(nil)
+
+	_ mq.BatchQueue[int] = (*memory.Queue[int])(nil)
+	_ mq.BatchQueue[int] = (*redismq.Queue[int])(nil)
+	_ mq.BatchQueue[int] = (*memory.MessageQueue[int])(nil)
+	_ mq.BatchQueue[int] = (*redismq.MessageQueue[int])(nil)
+
+	_ mq.QueueInspector[int] = (*memory.Queue[int])(nil)
+	_ mq.QueueInspector[int] = (*redismq.Queue[int])(nil)
+	_ mq.QueueInspector[int] = (*memory.MessageQueue[int])(nil)
+	_ mq.QueueInspector[int] = (*redismq.MessageQueue[int])(nil)
+	_ mq.PubSubInspector     = (*memory.PubSub[int])(nil)
+	_ mq.PubSubInspector     = (*redismq.PubSub[int])(nil)
+	_ mq.PubSubInspector     = (*memory.MessageQueue[int])(nil)
+	_ mq.PubSubInspector     = (*redismq.MessageQueue[int])(nil)
+
+	_ mq.PriorityQueue[int] = (*memory.PriorityQueue[int])(nil)
+	_ mq.PriorityQueue[int] = (*redismq.PriorityQueue[int])(nil)
 )
 
 func TestRedisConstructorsValidation(t *testing.T) {
@@ -30,6 +47,9 @@ func TestRedisConstructorsValidation(t *testing.T) {
 	if _, err := redismq.NewMessageQueue[int](); err == nil {
 		t.Fatalf("expected NewMessageQueue without client to fail")
 	}
+	if _, err := redismq.NewPriorityQueue[int](); err == nil {
+		t.Fatalf("expected NewPriorityQueue without client to fail")
+	}
 }
 
 func TestMessageQueueConstruction(t *testing.T) {
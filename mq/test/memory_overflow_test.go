@@ -0,0 +1,202 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/mq/memory"
+)
+
+func consumeAll(t *testing.T, q *memory.Queue[int], topic string) []int {
+	t.Helper()
+	var got []int
+	for {
+		msg, found, err := q.TryConsume(context.Background(), topic)
+		if err != nil {
+			t.Fatalf("TryConsume %s: %v", topic, err)
+		}
+		if !found {
+			return got
+		}
+		got = append(got, msg)
+	}
+}
+
+func TestMemoryQueueOverflowPolicies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		policy  memory.OverflowPolicy
+		wantErr error
+		want    []int
+	}{
+		{name: "drop-newest", policy: memory.OverflowDropNewest, want: []int{1, 2}},
+		{name: "drop-oldest", policy: memory.OverflowDropOldest, want: []int{2, 3}},
+		{name: "error", policy: memory.OverflowError, wantErr: memory.ErrQueueFull, want: []int{1, 2}},
+		{name: "block", policy: memory.OverflowBlock, wantErr: context.DeadlineExceeded, want: []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := memory.NewQueue[int](memory.WithQueueSize(2), memory.WithOverflowPolicy(tt.policy))
+			t.Cleanup(func() { _ = q.Close() })
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			for i := 1; i <= 2; i++ {
+				if err := q.Publish(ctx, "burst", i); err != nil {
+					t.Fatalf("Publish %d: %v", i, err)
+				}
+			}
+			if err := q.Publish(ctx, "burst", 3); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Publish overflow error mismatch: got=%v want=%v", err, tt.wantErr)
+			}
+			if got := consumeAll(t, q, "burst"); !slices.Equal(got, tt.want) {
+				t.Fatalf("queued messages mismatch: got=%v want=%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryQueueSpillRecoversAfterRestart(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	opts := []memory.Option{memory.WithQueueSize(3), memory.WithSpillSegmentSize(32)}
+	q, err := memory.OpenQueue[int](dir, opts...)
+	if err != nil {
+		t.Fatalf("OpenQueue: %v", err)
+	}
+
+	var want []int
+	for i := 1; i <= 20; i++ {
+		want = append(want, i)
+	}
+	if err = q.PublishBatch(ctx, "logs/app", want); err != nil {
+		t.Fatalf("PublishBatch beyond queue size: %v", err)
+	}
+	if err = q.Publish(ctx, "audit", 100); err != nil {
+		t.Fatalf("Publish audit: %v", err)
+	}
+	if n, lErr := q.Len(ctx, "logs/app"); lErr != nil || n != 20 {
+		t.Fatalf("Len with spill mismatch: n=%d err=%v", n, lErr)
+	}
+	if peeked, pErr := q.Peek(ctx, "logs/app", 6); pErr != nil || !slices.Equal(peeked, want[:6]) {
+		t.Fatalf("Peek across spill mismatch: got=%v err=%v", peeked, pErr)
+	}
+	batch, err := q.ConsumeBatch(ctx, "logs/app", 5, 0)
+	if err != nil || !slices.Equal(batch, want[:3]) {
+		t.Fatalf("ConsumeBatch mismatch: got=%v err=%v", batch, err)
+	}
+	if err = q.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	q, err = memory.OpenQueue[int](dir, opts...)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(func() { _ = q.Close() })
+	topics, err := q.Topics(ctx)
+	if err != nil || !slices.Equal(topics, []string{"audit", "logs/app"}) {
+		t.Fatalf("Topics after restart mismatch: got=%v err=%v", topics, err)
+	}
+	if got := consumeAll(t, q, "logs/app"); !slices.Equal(got, want[3:]) {
+		t.Fatalf("recovered messages mismatch: got=%v want=%v", got, want[3:])
+	}
+	if got := consumeAll(t, q, "audit"); !slices.Equal(got, []int{100}) {
+		t.Fatalf("recovered audit mismatch: got=%v", got)
+	}
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*", "*.seg"))
+	if err != nil || len(segments) != 0 {
+		t.Fatalf("drained spill should remove segments: %v err=%v", segments, err)
+	}
+}
+
+func TestMemoryQueueSpillSurvivesCrash(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	crashed, err := memory.OpenQueue[int](dir, memory.WithQueueSize(2))
+	if err != nil {
+		t.Fatalf("OpenQueue: %v", err)
+	}
+	for i := 1; i <= 10; i++ {
+		if err = crashed.Publish(ctx, "jobs", i); err != nil {
+			t.Fatalf("Publish %d: %v", i, err)
+		}
+	}
+	if msg, cErr := crashed.Consume(ctx, "jobs"); cErr != nil || msg != 1 {
+		t.Fatalf("Consume mismatch: msg=%d err=%v", msg, cErr)
+	}
+
+	// Simulate a torn write left by the crash at the end of the active segment.
+	segments, err := filepath.Glob(filepath.Join(dir, "*", "*.seg"))
+	if err != nil || len(segments) == 0 {
+		t.Fatalf("expected spill segments: %v err=%v", segments, err)
+	}
+	f, err := os.OpenFile(segments[len(segments)-1], os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open segment: %v", err)
+	}
+	if _, err = f.Write([]byte{0, 0, 0}); err != nil {
+		t.Fatalf("write torn record: %v", err)
+	}
+	_ = f.Close()
+
+	// The crashed queue is abandoned without Close: message 2, held in memory, is lost while
+	// the spilled messages are recovered.
+	q, err := memory.OpenQueue[int](dir, memory.WithQueueSize(2))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(func() { _ = q.Close() })
+	want := []int{3, 4, 5, 6, 7, 8, 9, 10}
+	if got := consumeAll(t, q, "jobs"); !slices.Equal(got, want) {
+		t.Fatalf("recovered messages mismatch: got=%v want=%v", got, want)
+	}
+	if err = q.Publish(ctx, "jobs", 11); err != nil {
+		t.Fatalf("Publish after recovery: %v", err)
+	}
+	if got := consumeAll(t, q, "jobs"); !slices.Equal(got, []int{11}) {
+		t.Fatalf("publish after recovery mismatch: got=%v", got)
+	}
+}
+
+func TestMemoryQueueSpillPurge(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	q, err := memory.OpenQueue[int](dir, memory.WithQueueSize(1))
+	if err != nil {
+		t.Fatalf("OpenQueue: %v", err)
+	}
+	if err = q.PublishBatch(ctx, "jobs", []int{1, 2, 3}); err != nil {
+		t.Fatalf("PublishBatch: %v", err)
+	}
+	if err = q.PurgeQueue(ctx, "jobs"); err != nil {
+		t.Fatalf("PurgeQueue: %v", err)
+	}
+	if err = q.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	q, err = memory.OpenQueue[int](dir, memory.WithQueueSize(1))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(func() { _ = q.Close() })
+	if n, lErr := q.Len(ctx, "jobs"); lErr != nil || n != 0 {
+		t.Fatalf("Len after purge and restart mismatch: n=%d err=%v", n, lErr)
+	}
+}
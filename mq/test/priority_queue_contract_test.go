@@ -0,0 +1,187 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-sphere/sphere/mq"
+	"github.com/go-sphere/sphere/mq/memory"
+)
+
+func TestPriorityQueueOrdering(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range priorityQueueFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			q := factory.new(t)
+
+			publish := []struct {
+				priority mq.Priority
+				value    int
+			}{
+				{priority: mq.PriorityLow, value: 30},
+				{priority: mq.PriorityNormal, value: 20},
+				{priority: mq.PriorityLow, value: 31},
+				{priority: mq.PriorityHigh, value: 10},
+				{priority: mq.PriorityHigh, value: 11},
+			}
+			for _, p := range publish {
+				if err := q.PublishWithPriority(ctx, "jobs", p.priority, p.value); err != nil {
+					t.Fatalf("PublishWithPriority(%d): %v", p.value, err)
+				}
+			}
+
+			first, err := q.Consume(ctx, "jobs")
+			if err != nil {
+				t.Fatalf("Consume first: %v", err)
+			}
+			if first != 10 {
+				t.Fatalf("first message mismatch: got=%d want=10", first)
+			}
+
+			got := []int{first}
+			for {
+				msg, found, tErr := q.TryConsume(ctx, "jobs")
+				if tErr != nil {
+					t.Fatalf("TryConsume: %v", tErr)
+				}
+				if !found {
+					break
+				}
+				got = append(got, msg)
+			}
+			if len(got) != len(publish) {
+				t.Fatalf("consumed count mismatch: got=%v", got)
+			}
+
+			// FIFO must hold within every priority level.
+			indexOf := func(v int) int {
+				for i, g := range got {
+					if g == v {
+						return i
+					}
+				}
+				return -1
+			}
+			if indexOf(10) > indexOf(11) || indexOf(30) > indexOf(31) {
+				t.Fatalf("FIFO within level violated: got=%v", got)
+			}
+			if !factory.weighted {
+				want := []int{10, 11, 20, 30, 31}
+				for i := range want {
+					if got[i] != want[i] {
+						t.Fatalf("strict priority order mismatch: got=%v want=%v", got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPriorityQueueDefaultPublishIsNormal(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range priorityQueueFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			q := factory.new(t)
+
+			if err := q.PublishWithPriority(ctx, "mail", mq.PriorityLow, 3); err != nil {
+				t.Fatalf("PublishWithPriority low: %v", err)
+			}
+			if err := q.Publish(ctx, "mail", 2); err != nil {
+				t.Fatalf("Publish: %v", err)
+			}
+			if err := q.PublishWithPriority(ctx, "mail", mq.PriorityHigh, 1); err != nil {
+				t.Fatalf("PublishWithPriority high: %v", err)
+			}
+
+			for _, want := range []int{1, 2, 3} {
+				msg, err := q.Consume(ctx, "mail")
+				if err != nil {
+					t.Fatalf("Consume: %v", err)
+				}
+				if msg != want {
+					t.Fatalf("Consume mismatch: got=%d want=%d", msg, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPriorityQueueInvalidPriority(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range priorityQueueFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			q := factory.new(t)
+			err := q.PublishWithPriority(context.Background(), "jobs", mq.Priority(mq.PriorityLevels), 1)
+			if !errors.Is(err, mq.ErrInvalidPriority) {
+				t.Fatalf("invalid priority mismatch: err=%v", err)
+			}
+		})
+	}
+}
+
+func TestPriorityQueuePurge(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range priorityQueueFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			q := factory.new(t)
+			for level := range mq.PriorityLevels {
+				if err := q.PublishWithPriority(ctx, "purge", mq.Priority(level), level); err != nil {
+					t.Fatalf("PublishWithPriority: %v", err)
+				}
+			}
+			if err := q.PurgeQueue(ctx, "purge"); err != nil {
+				t.Fatalf("PurgeQueue: %v", err)
+			}
+			if _, found, err := q.TryConsume(ctx, "purge"); err != nil || found {
+				t.Fatalf("TryConsume after purge mismatch: found=%v err=%v", found, err)
+			}
+		})
+	}
+}
+
+func TestMemoryPriorityQueueWeightedSelection(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	q := memory.NewPriorityQueue[int](memory.WithPriorityWeights(3, 0, 1))
+	t.Cleanup(func() { _ = q.Close() })
+
+	for i := range 8 {
+		if err := q.PublishWithPriority(ctx, "bulk", mq.PriorityHigh, i); err != nil {
+			t.Fatalf("PublishWithPriority high: %v", err)
+		}
+		if err := q.PublishWithPriority(ctx, "bulk", mq.PriorityLow, 100+i); err != nil {
+			t.Fatalf("PublishWithPriority low: %v", err)
+		}
+	}
+
+	lows := 0
+	for range 8 {
+		msg, found, err := q.TryConsume(ctx, "bulk")
+		if err != nil || !found {
+			t.Fatalf("TryConsume mismatch: found=%v err=%v", found, err)
+		}
+		if msg >= 100 {
+			lows++
+		}
+	}
+	if lows != 2 {
+		t.Fatalf("weighted selection mismatch: low deliveries=%d want=2 of 8 with weights 3:1", lows)
+	}
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/mq"
+)
+
+type topicMessage struct {
+	topic string
+	data  int
+}
+
+func TestMatchTopic(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{pattern: "order.*.created", topic: "order.acme.created", want: true},
+		{pattern: "order.*.created", topic: "order.acme.eu.created", want: true},
+		{pattern: "order.*.created", topic: "order.acme.paid", want: false},
+		{pattern: "order.*", topic: "order.", want: true},
+		{pattern: "order.*", topic: "order", want: false},
+		{pattern: "*", topic: "", want: true},
+		{pattern: "user.?", topic: "user.1", want: true},
+		{pattern: "user.?", topic: "user.12", want: false},
+		{pattern: "user.[ab]", topic: "user.b", want: true},
+		{pattern: "user.[^ab]", topic: "user.b", want: false},
+		{pattern: "user.[a-c]x", topic: "user.bx", want: true},
+		{pattern: "user.[a-c]x", topic: "user.dx", want: false},
+		{pattern: `user.\*`, topic: "user.*", want: true},
+		{pattern: `user.\*`, topic: "user.1", want: false},
+		{pattern: "exact", topic: "exact", want: true},
+		{pattern: "exact", topic: "exactly", want: false},
+		{pattern: "a*b*c", topic: "axxbyyc", want: true},
+		{pattern: "a*b*c", topic: "axxbyy", want: false},
+	}
+	for _, tt := range tests {
+		if got := mq.MatchTopic(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestPubSubPatternSubscribe(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range pubSubFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			p := factory.newInt(t)
+
+			const pattern = "order.*.created"
+			recv := make(chan topicMessage, 4)
+			if err := p.PSubscribe(ctx, pattern, func(topic string, data int) error {
+				recv <- topicMessage{topic: topic, data: data}
+				return nil
+			}); err != nil {
+				t.Fatalf("PSubscribe: %v", err)
+			}
+
+			if err := p.Broadcast(ctx, "order.acme.created", 1); err != nil {
+				t.Fatalf("Broadcast acme: %v", err)
+			}
+			assertReceiveTopic(t, recv, topicMessage{topic: "order.acme.created", data: 1})
+
+			if err := p.Broadcast(ctx, "order.acme.paid", 2); err != nil {
+				t.Fatalf("Broadcast paid: %v", err)
+			}
+			if err := p.Broadcast(ctx, "order.globex.created", 3); err != nil {
+				t.Fatalf("Broadcast globex: %v", err)
+			}
+			assertReceiveTopic(t, recv, topicMessage{topic: "order.globex.created", data: 3})
+
+			if err := p.PUnsubscribeAll(ctx, pattern); err != nil {
+				t.Fatalf("PUnsubscribeAll: %v", err)
+			}
+			if err := p.Broadcast(ctx, "order.acme.created", 4); err != nil {
+				t.Fatalf("Broadcast after punsubscribe should not fail: %v", err)
+			}
+			assertNoReceiveTopic(t, recv)
+		})
+	}
+}
+
+func TestPubSubPatternAndTopicSubscribers(t *testing.T) {
+	t.Parallel()
+
+	for _, factory := range pubSubFactories() {
+		t.Run(factory.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			p := factory.newInt(t)
+
+			exact := make(chan int, 1)
+			wildcard := make(chan topicMessage, 1)
+			if err := p.Subscribe(ctx, "user.registered", func(data int) error {
+				exact <- data
+				return nil
+			}); err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			if err := p.PSubscribe(ctx, "user.*", func(topic string, data int) error {
+				wildcard <- topicMessage{topic: topic, data: data}
+				return nil
+			}); err != nil {
+				t.Fatalf("PSubscribe: %v", err)
+			}
+
+			if err := p.Broadcast(ctx, "user.registered", 5); err != nil {
+				t.Fatalf("Broadcast: %v", err)
+			}
+			assertReceiveInt(t, exact, 5)
+			assertReceiveTopic(t, wildcard, topicMessage{topic: "user.registered", data: 5})
+
+			if err := p.UnsubscribeAll(ctx, "user.registered"); err != nil {
+				t.Fatalf("UnsubscribeAll: %v", err)
+			}
+			if err := p.Broadcast(ctx, "user.registered", 6); err != nil {
+				t.Fatalf("Broadcast after unsubscribe: %v", err)
+			}
+			assertReceiveTopic(t, wildcard, topicMessage{topic: "user.registered", data: 6})
+			assertNoReceiveInt(t, exact)
+		})
+	}
+}
+
+func assertReceiveTopic(t *testing.T, ch <-chan topicMessage, want topicMessage) {
+	t.Helper()
+
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Fatalf("received message mismatch: got=%+v want=%+v", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for message=%+v", want)
+	}
+}
+
+func assertNoReceiveTopic(t *testing.T, ch <-chan topicMessage) {
+	t.Helper()
+
+	select {
+	case got := <-ch:
+		t.Fatalf("unexpected message after unsubscribe: got=%+v", got)
+	case <-time.After(150 * time.Millisecond):
+	}
+}
@@ -95,10 +95,6 @@ func TestQueuePurgeQueue(t *testing.T) {
 
 func TestQueueBlockingConsume(t *testing.T) {
 	for _, factory := range queueFactories() {
-		if !factory.blockingConsumeCheck {
-			continue
-		}
-
 		t.Run(factory.name, func(t *testing.T) {
 			ctx := context.Background()
 			q := factory.new(t)
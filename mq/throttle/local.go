@@ -0,0 +1,81 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// LocalRateLimiter limits each topic with its own rate.Limiter inside this process.
+type LocalRateLimiter struct {
+	newLimiter func(topic string) *rate.Limiter
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// NewLocalRateLimiter creates a limiter that calls newLimiter the first time a topic is seen.
+// Returning nil leaves that topic unlimited.
+func NewLocalRateLimiter(newLimiter func(topic string) *rate.Limiter) *LocalRateLimiter {
+	return &LocalRateLimiter{
+		newLimiter: newLimiter,
+		limiters:   make(map[string]*rate.Limiter),
+	}
+}
+
+func (l *LocalRateLimiter) Wait(ctx context.Context, topic string) error {
+	l.mu.Lock()
+	limiter, ok := l.limiters[topic]
+	if !ok {
+		limiter = l.newLimiter(topic)
+		l.limiters[topic] = limiter
+	}
+	l.mu.Unlock()
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Wait(ctx)
+}
+
+// LocalSemaphore caps the messages of each topic processed at the same time inside this process.
+type LocalSemaphore struct {
+	limit func(topic string) int
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// NewLocalSemaphore creates a semaphore that calls limit the first time a topic is seen.
+// A non-positive limit leaves that topic uncapped.
+func NewLocalSemaphore(limit func(topic string) int) *LocalSemaphore {
+	return &LocalSemaphore{
+		limit: limit,
+		slots: make(map[string]chan struct{}),
+	}
+}
+
+func (s *LocalSemaphore) Acquire(ctx context.Context, topic string) (func(), error) {
+	s.mu.Lock()
+	slots, ok := s.slots[topic]
+	if !ok {
+		if n := s.limit(topic); n > 0 {
+			slots = make(chan struct{}, n)
+		}
+		s.slots[topic] = slots
+	}
+	s.mu.Unlock()
+	if slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case slots <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() { <-slots })
+		}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,232 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+// gcraScript implements the generic cell rate algorithm. It stores the theoretical arrival time
+// of the next message and returns 0 when a message may proceed, or the milliseconds to wait.
+var gcraScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local tolerance = tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then
+	tat = now
+end
+local next_tat = tat + interval
+local wait = next_tat - now - tolerance
+if wait > 0 then
+	return math.ceil(wait)
+end
+redis.call('SET', KEYS[1], string.format('%.3f', next_tat), 'PX', math.ceil(next_tat - now))
+return 0
+`)
+
+// acquireScript adds a lease to the sorted set when fewer than the limit are active.
+// Expired leases, left by replicas that crashed while processing, are removed first.
+var acquireScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZCARD', KEYS[1]) < tonumber(ARGV[2]) then
+	redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], ARGV[5])
+	return 1
+end
+return 0
+`)
+
+// Limit describes a token bucket: Rate tokens per second with bursts of up to Burst messages.
+type Limit struct {
+	Rate  rate.Limit
+	Burst int
+}
+
+type redisOptions struct {
+	prefix       string
+	pollInterval time.Duration
+	leaseTTL     time.Duration
+}
+
+func newRedisOptions(opts ...RedisOption) *redisOptions {
+	defaults := &redisOptions{
+		prefix:       "mq:throttle:",
+		pollInterval: 50 * time.Millisecond,
+		leaseTTL:     time.Minute,
+	}
+	for _, opt := range opts {
+		opt(defaults)
+	}
+	return defaults
+}
+
+// RedisOption is a functional option for configuring the Redis-backed limiters.
+type RedisOption func(*redisOptions)
+
+// WithKeyPrefix sets the prefix of the Redis keys holding limiter state.
+// Replicas sharing a limit must use the same prefix. The default is "mq:throttle:".
+func WithKeyPrefix(prefix string) RedisOption {
+	return func(opts *redisOptions) {
+		opts.prefix = prefix
+	}
+}
+
+// WithPollInterval sets how often RedisSemaphore retries while all slots are taken.
+// The default is 50 milliseconds.
+func WithPollInterval(interval time.Duration) RedisOption {
+	return func(opts *redisOptions) {
+		opts.pollInterval = interval
+	}
+}
+
+// WithLeaseTTL sets how long a RedisSemaphore slot stays taken if its holder never releases it,
+// for example because the replica crashed. It should exceed the longest handler run.
+// The default is one minute.
+func WithLeaseTTL(ttl time.Duration) RedisOption {
+	return func(opts *redisOptions) {
+		opts.leaseTTL = ttl
+	}
+}
+
+// RedisRateLimiter enforces a per-topic rate shared by every replica using the same Redis and key prefix.
+// The state is computed from the replicas' clocks, which should be reasonably synchronized.
+type RedisRateLimiter struct {
+	client *redis.Client
+	limit  func(topic string) Limit
+	opts   *redisOptions
+}
+
+// NewRedisRateLimiter creates a global rate limiter. limit is called for every message;
+// a non-positive or infinite rate leaves the topic unlimited.
+func NewRedisRateLimiter(client *redis.Client, limit func(topic string) Limit, opt ...RedisOption) (*RedisRateLimiter, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if limit == nil {
+		return nil, errors.New("limit func is required")
+	}
+	return &RedisRateLimiter{
+		client: client,
+		limit:  limit,
+		opts:   newRedisOptions(opt...),
+	}, nil
+}
+
+func (l *RedisRateLimiter) Wait(ctx context.Context, topic string) error {
+	limit := l.limit(topic)
+	if limit.Rate <= 0 || limit.Rate == rate.Inf {
+		return nil
+	}
+	interval := 1000 / float64(limit.Rate)
+	tolerance := interval * float64(max(limit.Burst, 1)-1)
+	key := l.opts.prefix + "rate:" + topic
+	for {
+		now := float64(time.Now().UnixNano()) / float64(time.Millisecond)
+		wait, err := gcraScript.Run(ctx, l.client, []string{key},
+			formatFloat(now), formatFloat(interval), formatFloat(tolerance),
+		).Int64()
+		if err != nil {
+			return err
+		}
+		if wait <= 0 {
+			return nil
+		}
+		if err = sleep(ctx, time.Duration(wait)*time.Millisecond); err != nil {
+			return err
+		}
+	}
+}
+
+// RedisSemaphore caps the in-flight messages of each topic across every replica using the same
+// Redis and key prefix. Slots are leases that expire if not released.
+type RedisSemaphore struct {
+	client *redis.Client
+	limit  func(topic string) int
+	opts   *redisOptions
+}
+
+// NewRedisSemaphore creates a global concurrency cap. limit is called for every message;
+// a non-positive limit leaves the topic uncapped.
+func NewRedisSemaphore(client *redis.Client, limit func(topic string) int, opt ...RedisOption) (*RedisSemaphore, error) {
+	if client == nil {
+		return nil, errors.New("redis client is required")
+	}
+	if limit == nil {
+		return nil, errors.New("limit func is required")
+	}
+	opts := newRedisOptions(opt...)
+	if opts.pollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+	if opts.leaseTTL <= 0 {
+		return nil, errors.New("lease ttl must be positive")
+	}
+	return &RedisSemaphore{
+		client: client,
+		limit:  limit,
+		opts:   opts,
+	}, nil
+}
+
+func (s *RedisSemaphore) Acquire(ctx context.Context, topic string) (func(), error) {
+	n := s.limit(topic)
+	if n <= 0 {
+		return func() {}, nil
+	}
+	token, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	key := s.opts.prefix + "inflight:" + topic
+	for {
+		now := time.Now()
+		acquired, rErr := acquireScript.Run(ctx, s.client, []string{key},
+			now.UnixMilli(), n, now.Add(s.opts.leaseTTL).UnixMilli(), token.String(), s.opts.leaseTTL.Milliseconds(),
+		).Int()
+		if rErr != nil {
+			return nil, rErr
+		}
+		if acquired == 1 {
+			break
+		}
+		if rErr = sleep(ctx, s.opts.pollInterval); rErr != nil {
+			return nil, rErr
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// Release even if the handler's context was cancelled; an unreleased lease blocks a slot until it expires.
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			_ = s.client.ZRem(ctx, key, token.String()).Err()
+		})
+	}, nil
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return "0"
+	}
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
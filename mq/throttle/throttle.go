@@ -0,0 +1,77 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-sphere/sphere/mq"
+)
+
+// RateLimiter paces how fast messages of a topic are processed.
+type RateLimiter interface {
+	// Wait blocks until a message of the topic may be processed or the context is done.
+	Wait(ctx context.Context, topic string) error
+}
+
+// Semaphore bounds how many messages of a topic are processed at the same time.
+type Semaphore interface {
+	// Acquire blocks until a processing slot for the topic is free or the context is done.
+	// The returned function releases the slot and must be called exactly once.
+	Acquire(ctx context.Context, topic string) (release func(), err error)
+}
+
+type options struct {
+	limiters   []RateLimiter
+	semaphores []Semaphore
+}
+
+// Option is a functional option for configuring the throttling middleware.
+type Option func(*options)
+
+// WithRateLimiter adds a rate limiter the middleware waits on before each message.
+// It may be given several times, for example a local limiter together with a global one.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(opts *options) {
+		opts.limiters = append(opts.limiters, limiter)
+	}
+}
+
+// WithSemaphore adds a concurrency cap the middleware holds while each message is processed.
+// It may be given several times, for example a local cap together with a global one.
+func WithSemaphore(semaphore Semaphore) Option {
+	return func(opts *options) {
+		opts.semaphores = append(opts.semaphores, semaphore)
+	}
+}
+
+// NewMiddleware creates a middleware throttling message handlers per topic.
+// For each message it first acquires a slot from every semaphore, then waits on every rate
+// limiter, so the rate applies to handler starts rather than to messages waiting for a slot.
+// If the context is cancelled while waiting, the handler is not called and the context error
+// is returned so the message can be redelivered.
+func NewMiddleware[T any](opt ...Option) (mq.Middleware[T], error) {
+	opts := &options{}
+	for _, o := range opt {
+		o(opts)
+	}
+	if len(opts.limiters) == 0 && len(opts.semaphores) == 0 {
+		return nil, errors.New("rate limiter or semaphore is required")
+	}
+	return func(next mq.Handler[T]) mq.Handler[T] {
+		return func(ctx context.Context, topic string, data T) error {
+			for _, semaphore := range opts.semaphores {
+				release, err := semaphore.Acquire(ctx, topic)
+				if err != nil {
+					return err
+				}
+				defer release()
+			}
+			for _, limiter := range opts.limiters {
+				if err := limiter.Wait(ctx, topic); err != nil {
+					return err
+				}
+			}
+			return next(ctx, topic, data)
+		}
+	}, nil
+}
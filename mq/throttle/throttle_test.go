@@ -0,0 +1,221 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/mq"
+	"github.com/go-sphere/sphere/test/redistest"
+	"golang.org/x/time/rate"
+)
+
+// run invokes the throttled handler for n messages in parallel and returns the peak number of
+// handlers running at the same time together with the total duration.
+func run(t *testing.T, middleware mq.Middleware[int], topic string, n int, work time.Duration) (int32, time.Duration) {
+	t.Helper()
+	var running, peak atomic.Int32
+	handler := middleware(func(ctx context.Context, topic string, data int) error {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			old := peak.Load()
+			if current <= old || peak.CompareAndSwap(old, current) {
+				break
+			}
+		}
+		time.Sleep(work)
+		return nil
+	})
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			if err := handler(context.Background(), topic, i); err != nil {
+				t.Errorf("handler() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	return peak.Load(), time.Since(start)
+}
+
+func newMiddleware(t *testing.T, opt ...Option) mq.Middleware[int] {
+	t.Helper()
+	middleware, err := NewMiddleware[int](opt...)
+	if err != nil {
+		t.Fatalf("NewMiddleware() error = %v", err)
+	}
+	return middleware
+}
+
+func TestLocalSemaphoreCapsInFlightPerTopic(t *testing.T) {
+	semaphore := NewLocalSemaphore(func(topic string) int {
+		if topic == "vendor" {
+			return 2
+		}
+		return 0
+	})
+	middleware := newMiddleware(t, WithSemaphore(semaphore))
+
+	if peak, _ := run(t, middleware, "vendor", 8, 20*time.Millisecond); peak != 2 {
+		t.Fatalf("vendor peak in-flight = %d, want 2", peak)
+	}
+	if peak, _ := run(t, middleware, "internal", 8, 20*time.Millisecond); peak < 3 {
+		t.Fatalf("uncapped peak in-flight = %d, want more than the vendor cap", peak)
+	}
+}
+
+func TestLocalRateLimiterPacesTopic(t *testing.T) {
+	limiter := NewLocalRateLimiter(func(topic string) *rate.Limiter {
+		return rate.NewLimiter(rate.Every(20*time.Millisecond), 1)
+	})
+	middleware := newMiddleware(t, WithRateLimiter(limiter))
+
+	// Six messages at one per 20ms need at least five intervals after the first.
+	if _, elapsed := run(t, middleware, "vendor", 6, 0); elapsed < 90*time.Millisecond {
+		t.Fatalf("rate limited run took %v, want at least 100ms", elapsed)
+	}
+}
+
+func TestMiddlewareReturnsContextErrorWhileWaiting(t *testing.T) {
+	semaphore := NewLocalSemaphore(func(string) int { return 1 })
+	middleware := newMiddleware(t, WithSemaphore(semaphore))
+	release, err := semaphore.Acquire(context.Background(), "vendor")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer release()
+
+	called := false
+	handler := middleware(func(ctx context.Context, topic string, data int) error {
+		called = true
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err = handler(ctx, "vendor", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("handler() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if called {
+		t.Fatal("handler ran without a slot")
+	}
+}
+
+func TestRedisSemaphoreSharedAcrossReplicas(t *testing.T) {
+	client := redistest.NewTestRedisClient(t)
+	limit := func(string) int { return 2 }
+	var replicas []mq.Middleware[int]
+	for range 2 {
+		semaphore, err := NewRedisSemaphore(client, limit, WithPollInterval(5*time.Millisecond))
+		if err != nil {
+			t.Fatalf("NewRedisSemaphore() error = %v", err)
+		}
+		replicas = append(replicas, newMiddleware(t, WithSemaphore(semaphore)))
+	}
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := range 8 {
+		handler := replicas[i%2](func(ctx context.Context, topic string, data int) error {
+			current := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := peak.Load()
+				if current <= old || peak.CompareAndSwap(old, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		})
+		wg.Go(func() {
+			if err := handler(context.Background(), "vendor", i); err != nil {
+				t.Errorf("handler() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	if got := peak.Load(); got != 2 {
+		t.Fatalf("global peak in-flight = %d, want 2", got)
+	}
+}
+
+func TestRedisSemaphoreLeaseExpires(t *testing.T) {
+	client := redistest.NewTestRedisClient(t)
+	semaphore, err := NewRedisSemaphore(client, func(string) int { return 1 },
+		WithPollInterval(5*time.Millisecond), WithLeaseTTL(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewRedisSemaphore() error = %v", err)
+	}
+	if _, err = semaphore.Acquire(context.Background(), "vendor"); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	// The first holder never releases, as if its replica crashed.
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	release, err := semaphore.Acquire(ctx, "vendor")
+	if err != nil {
+		t.Fatalf("Acquire() after lease expiry error = %v", err)
+	}
+	release()
+}
+
+func TestRedisRateLimiterSharedAcrossReplicas(t *testing.T) {
+	client := redistest.NewTestRedisClient(t)
+	limit := func(topic string) Limit {
+		if topic == "vendor" {
+			return Limit{Rate: 50, Burst: 2}
+		}
+		return Limit{Rate: rate.Inf}
+	}
+	var limiters []*RedisRateLimiter
+	for range 2 {
+		limiter, err := NewRedisRateLimiter(client, limit)
+		if err != nil {
+			t.Fatalf("NewRedisRateLimiter() error = %v", err)
+		}
+		limiters = append(limiters, limiter)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range 7 {
+		wg.Go(func() {
+			if err := limiters[i%2].Wait(context.Background(), "vendor"); err != nil {
+				t.Errorf("Wait() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+	// A burst of two passes immediately; the other five are paced at 20ms each.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatalf("global rate limited run took %v, want at least 100ms", elapsed)
+	}
+
+	start = time.Now()
+	for range 20 {
+		if err := limiters[0].Wait(context.Background(), "internal"); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("unlimited topic was throttled: %v", elapsed)
+	}
+}
+
+func TestConstructorsValidation(t *testing.T) {
+	if _, err := NewMiddleware[int](); err == nil {
+		t.Fatal("expected NewMiddleware without limits to fail")
+	}
+	if _, err := NewRedisRateLimiter(nil, func(string) Limit { return Limit{} }); err == nil {
+		t.Fatal("expected NewRedisRateLimiter without client to fail")
+	}
+	if _, err := NewRedisSemaphore(nil, func(string) int { return 1 }); err == nil {
+		t.Fatal("expected NewRedisSemaphore without client to fail")
+	}
+}
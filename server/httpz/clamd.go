@@ -0,0 +1,83 @@
+package httpz
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// clamdChunkSize is the size of the chunks streamed to clamd, well below its default StreamMaxLength.
+const clamdChunkSize = 32 << 10
+
+// ClamdScanner is a FileScanner backed by a ClamAV daemon, which receives files
+// through its INSTREAM command.
+type ClamdScanner struct {
+	network string
+	address string
+}
+
+// NewClamdScanner creates a scanner connecting to clamd at the address,
+// such as "tcp" and "127.0.0.1:3310" or "unix" and "/run/clamav/clamd.ctl".
+func NewClamdScanner(network, address string) *ClamdScanner {
+	return &ClamdScanner{network: network, address: address}
+}
+
+// Scan streams the file to clamd. A detected signature is reported as ErrMaliciousFile;
+// the deadline of the context bounds the whole scan.
+func (s *ClamdScanner) Scan(ctx context.Context, file io.Reader, filename string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err = io.WriteString(conn, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, rErr := file.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, err = conn.Write(buf[:4+n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(rErr, io.EOF) {
+			break
+		}
+		if rErr != nil {
+			return rErr
+		}
+	}
+	binary.BigEndian.PutUint32(buf, 0)
+	if _, err = conn.Write(buf[:4]); err != nil {
+		return err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	reply = strings.TrimSpace(strings.TrimSuffix(reply, "\x00"))
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return nil
+	case strings.HasSuffix(result, " FOUND"):
+		return fmt.Errorf("%w: %s", ErrMaliciousFile, strings.TrimSuffix(result, " FOUND"))
+	default:
+		return fmt.Errorf("clamd: unexpected reply %q", reply)
+	}
+}
@@ -0,0 +1,307 @@
+package httpz
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	_ "image/gif"  // register GIF for image dimension checks
+	_ "image/jpeg" // register JPEG for image dimension checks
+	_ "image/png"  // register PNG for image dimension checks
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sphere/httpx"
+)
+
+// ErrMaliciousFile is returned, possibly wrapped, by a FileScanner that finds a threat in a file.
+var ErrMaliciousFile = errors.New("malicious file")
+
+// sniffLen is the number of leading bytes inspected to detect the content type.
+const sniffLen = 512
+
+// maxImageHeaderSize bounds the bytes read from a streamed upload to find the image dimensions.
+const maxImageHeaderSize = 1 << 20
+
+// FileScanner inspects uploaded content before it is handled, such as a virus scanner.
+// Scan returns an error wrapping ErrMaliciousFile to reject the file; any other error fails the upload.
+type FileScanner interface {
+	Scan(ctx context.Context, file io.Reader, filename string) error
+}
+
+// FileScannerFunc adapts a function to the FileScanner interface.
+type FileScannerFunc func(ctx context.Context, file io.Reader, filename string) error
+
+// Scan calls f(ctx, file, filename).
+func (f FileScannerFunc) Scan(ctx context.Context, file io.Reader, filename string) error {
+	return f(ctx, file, filename)
+}
+
+// ContentCheckOptions contains configuration for validating uploaded content.
+type ContentCheckOptions struct {
+	allowTypes     []string
+	matchExtension bool
+	maxWidth       int
+	maxHeight      int
+	maxPixels      int64
+	scanners       []FileScanner
+}
+
+// ContentCheckOption is a functional option for configuring content validation.
+type ContentCheckOption func(*ContentCheckOptions)
+
+// WithContentAllowTypes restricts uploads to content types sniffed from their leading bytes.
+// A pattern is a MIME type such as "application/pdf" or a wildcard such as "image/*".
+func WithContentAllowTypes(patterns ...string) ContentCheckOption {
+	return func(options *ContentCheckOptions) {
+		for _, pattern := range patterns {
+			options.allowTypes = append(options.allowTypes, strings.ToLower(pattern))
+		}
+	}
+}
+
+// WithContentMatchExtension rejects uploads whose sniffed content type contradicts the type
+// of their filename extension, such as an HTML page named "photo.jpg".
+func WithContentMatchExtension() ContentCheckOption {
+	return func(options *ContentCheckOptions) {
+		options.matchExtension = true
+	}
+}
+
+// WithContentMaxImageSize rejects JPEG, PNG and GIF images wider or taller than the limits.
+// A zero limit is not checked. Images that cannot be decoded are rejected.
+func WithContentMaxImageSize(width, height int) ContentCheckOption {
+	return func(options *ContentCheckOptions) {
+		options.maxWidth = width
+		options.maxHeight = height
+	}
+}
+
+// WithContentMaxImagePixels rejects JPEG, PNG and GIF images with more than pixels pixels,
+// which protects image processing from decompression bombs.
+func WithContentMaxImagePixels(pixels int64) ContentCheckOption {
+	return func(options *ContentCheckOptions) {
+		options.maxPixels = pixels
+	}
+}
+
+// WithContentScanner adds a scanner that inspects the whole upload before it is handled.
+// Scanners run in the order they are added.
+func WithContentScanner(scanner FileScanner) ContentCheckOption {
+	return func(options *ContentCheckOptions) {
+		options.scanners = append(options.scanners, scanner)
+	}
+}
+
+// ContentChecker validates uploaded content by what it is rather than what it is named.
+type ContentChecker struct {
+	opts *ContentCheckOptions
+}
+
+// NewContentChecker creates a content checker with the given options.
+func NewContentChecker(options ...ContentCheckOption) *ContentChecker {
+	opts := &ContentCheckOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	return &ContentChecker{opts: opts}
+}
+
+func (c *ContentChecker) checksImages() bool {
+	return c.opts.maxWidth > 0 || c.opts.maxHeight > 0 || c.opts.maxPixels > 0
+}
+
+// CheckSeeker validates a seekable upload and rewinds it. It returns the sniffed content type.
+func (c *ContentChecker) CheckSeeker(ctx context.Context, file io.ReadSeeker, filename string) (string, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	contentType, err := c.checkType(head[:n], filename)
+	if err != nil {
+		return "", err
+	}
+	if c.checksImages() && isCheckedImage(contentType) {
+		if err = rewind(file); err != nil {
+			return "", err
+		}
+		if err = c.checkImage(file); err != nil {
+			return "", err
+		}
+	}
+	for _, scanner := range c.opts.scanners {
+		if err = rewind(file); err != nil {
+			return "", err
+		}
+		if err = scan(ctx, scanner, file, filename); err != nil {
+			return "", err
+		}
+	}
+	return contentType, rewind(file)
+}
+
+// CheckStream validates a streamed upload and returns a reader of its complete content, which the
+// caller must close. Without scanners only the leading bytes are buffered; with scanners the
+// upload is spooled to a temporary file, removed on close, so it can be scanned before use.
+func (c *ContentChecker) CheckStream(ctx context.Context, stream io.Reader, filename string) (io.ReadCloser, string, error) {
+	if len(c.opts.scanners) > 0 {
+		spool, err := os.CreateTemp("", "upload-*")
+		if err != nil {
+			return nil, "", err
+		}
+		file := &tempFile{File: spool}
+		if _, err = io.Copy(spool, stream); err == nil {
+			_, err = spool.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			_ = file.Close()
+			return nil, "", err
+		}
+		contentType, err := c.CheckSeeker(ctx, spool, filename)
+		if err != nil {
+			_ = file.Close()
+			return nil, "", err
+		}
+		return file, contentType, nil
+	}
+
+	reader := bufio.NewReaderSize(stream, sniffLen)
+	head, err := reader.Peek(sniffLen)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", err
+	}
+	contentType, err := c.checkType(head, filename)
+	if err != nil {
+		return nil, "", err
+	}
+	var content io.Reader = reader
+	if c.checksImages() && isCheckedImage(contentType) {
+		// Decode the header from a copy of what is read, then replay it ahead of the rest.
+		var consumed bytes.Buffer
+		err = c.checkImage(io.TeeReader(io.LimitReader(reader, maxImageHeaderSize), &consumed))
+		if err != nil {
+			return nil, "", err
+		}
+		content = io.MultiReader(&consumed, reader)
+	}
+	return io.NopCloser(content), contentType, nil
+}
+
+// checkType sniffs the content type from the leading bytes and checks it against the options.
+func (c *ContentChecker) checkType(head []byte, filename string) (string, error) {
+	contentType := baseType(http.DetectContentType(head))
+	if len(c.opts.allowTypes) > 0 && !matchContentType(c.opts.allowTypes, contentType) {
+		return "", httpx.BadRequestError(
+			errors.New("FileError:CONTENT_TYPE_NOT_ALLOWED"),
+			"File content type not allowed: "+contentType,
+		)
+	}
+	if c.opts.matchExtension {
+		declared := baseType(mime.TypeByExtension(filepath.Ext(filename)))
+		if declared != "" && !contentTypeCompatible(declared, contentType) {
+			return "", httpx.BadRequestError(
+				errors.New("FileError:CONTENT_TYPE_MISMATCH"),
+				"File content does not match its extension: "+filename,
+			)
+		}
+	}
+	return contentType, nil
+}
+
+// checkImage reads the image header and checks the dimensions against the limits.
+func (c *ContentChecker) checkImage(r io.Reader) error {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return httpx.BadRequestError(errors.New("FileError:INVALID_IMAGE"), "File is not a valid image")
+	}
+	if (c.opts.maxWidth > 0 && config.Width > c.opts.maxWidth) ||
+		(c.opts.maxHeight > 0 && config.Height > c.opts.maxHeight) ||
+		(c.opts.maxPixels > 0 && int64(config.Width)*int64(config.Height) > c.opts.maxPixels) {
+		return httpx.BadRequestError(errors.New("FileError:IMAGE_TOO_LARGE"), "Image dimensions exceed the allowed size")
+	}
+	return nil
+}
+
+func scan(ctx context.Context, scanner FileScanner, file io.Reader, filename string) error {
+	err := scanner.Scan(ctx, file, filename)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, ErrMaliciousFile) {
+		return httpx.BadRequestError(errors.New("FileError:FILE_REJECTED"), "File rejected by scanner: "+filename)
+	}
+	return httpx.InternalServerError(err)
+}
+
+func rewind(file io.Seeker) error {
+	_, err := file.Seek(0, io.SeekStart)
+	return err
+}
+
+// isCheckedImage reports whether the dimensions of a content type can be checked.
+func isCheckedImage(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	default:
+		return false
+	}
+}
+
+func baseType(contentType string) string {
+	base, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(base))
+}
+
+func matchContentType(patterns []string, contentType string) bool {
+	for _, pattern := range patterns {
+		if pattern == contentType || pattern == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// contentTypeCompatible reports whether sniffed content may carry the type declared by its
+// extension. Sniffing recognizes a limited set of formats, so generic results are accepted for
+// types it cannot tell apart: plain text for textual types, ZIP for ZIP-based documents, and
+// unrecognized binary data for anything but images, PDF and text.
+func contentTypeCompatible(declared, sniffed string) bool {
+	if declared == sniffed {
+		return true
+	}
+	textual := strings.HasPrefix(declared, "text/") || strings.Contains(declared, "json") ||
+		strings.Contains(declared, "xml") || strings.Contains(declared, "javascript")
+	switch sniffed {
+	case "text/plain":
+		return textual
+	case "text/xml":
+		return strings.Contains(declared, "xml")
+	case "application/zip":
+		return strings.Contains(declared, "zip") || strings.HasPrefix(declared, "application/vnd.") ||
+			declared == "application/java-archive"
+	case "application/octet-stream":
+		return !textual && !strings.HasPrefix(declared, "image/") && declared != "application/pdf"
+	default:
+		return false
+	}
+}
+
+// tempFile is a spooled upload that is removed when closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	return errors.Join(err, os.Remove(f.Name()))
+}
@@ -0,0 +1,170 @@
+package httpz
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/go-sphere/httpx"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestContentChecker(t *testing.T) {
+	checker := NewContentChecker(
+		WithContentAllowTypes("image/*", "application/pdf", "text/plain"),
+		WithContentMatchExtension(),
+		WithContentMaxImagePixels(100*100),
+	)
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+		wantType string
+		wantErr  bool
+	}{
+		{"png", "a.png", encodePNG(t, 100, 100), "image/png", false},
+		{"png without extension", "upload", encodePNG(t, 10, 10), "image/png", false},
+		{"too many pixels", "a.png", encodePNG(t, 101, 100), "", true},
+		{"png named jpg", "a.jpg", encodePNG(t, 10, 10), "", true},
+		{"pdf", "doc.pdf", []byte("%PDF-1.7\n..."), "application/pdf", false},
+		{"text named pdf", "doc.pdf", []byte("just text"), "", true},
+		{"html", "page.txt", []byte("<!DOCTYPE html><html></html>"), "", true},
+		{"zip not allowed", "a.docx", []byte("PK\x03\x04rest of archive"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := bytes.NewReader(tt.content)
+			got, err := checker.CheckSeeker(context.Background(), file, tt.filename)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckSeeker() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, status, _ := httpx.ParseError(err); status != 400 {
+					t.Fatalf("CheckSeeker() error status = %d, want 400", status)
+				}
+				return
+			}
+			if got != tt.wantType {
+				t.Fatalf("CheckSeeker() type = %q, want %q", got, tt.wantType)
+			}
+			if rest, _ := io.ReadAll(file); !bytes.Equal(rest, tt.content) {
+				t.Fatal("CheckSeeker() did not rewind the file")
+			}
+
+			stream, streamType, err := checker.CheckStream(context.Background(), bytes.NewReader(tt.content), tt.filename)
+			if err != nil || streamType != tt.wantType {
+				t.Fatalf("CheckStream() = %q, %v, want %q", streamType, err, tt.wantType)
+			}
+			if replayed, _ := io.ReadAll(stream); !bytes.Equal(replayed, tt.content) {
+				t.Fatal("CheckStream() did not return the complete content")
+			}
+			_ = stream.Close()
+		})
+	}
+}
+
+func TestContentTypeCompatible(t *testing.T) {
+	tests := []struct {
+		declared, sniffed string
+		want              bool
+	}{
+		{"image/png", "image/png", true},
+		{"text/csv", "text/plain", true},
+		{"application/json", "text/plain", true},
+		{"image/svg+xml", "text/xml", true},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "application/zip", true},
+		{"application/msword", "application/octet-stream", true},
+		{"image/jpeg", "application/octet-stream", false},
+		{"text/plain", "text/html", false},
+		{"image/jpeg", "text/plain", false},
+		{"application/pdf", "application/zip", false},
+	}
+	for _, tt := range tests {
+		if got := contentTypeCompatible(tt.declared, tt.sniffed); got != tt.want {
+			t.Errorf("contentTypeCompatible(%q, %q) = %v, want %v", tt.declared, tt.sniffed, got, tt.want)
+		}
+	}
+}
+
+// serveClamd answers one INSTREAM command like clamd, reporting a signature for content containing "EICAR".
+func serveClamd(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, aErr := listener.Accept()
+			if aErr != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				command := make([]byte, len("zINSTREAM\x00"))
+				if _, rErr := io.ReadFull(conn, command); rErr != nil || string(command) != "zINSTREAM\x00" {
+					_, _ = io.WriteString(conn, "UNKNOWN COMMAND\x00")
+					return
+				}
+				var content []byte
+				for {
+					var size uint32
+					if rErr := binary.Read(conn, binary.BigEndian, &size); rErr != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					chunk := make([]byte, size)
+					if _, rErr := io.ReadFull(conn, chunk); rErr != nil {
+						return
+					}
+					content = append(content, chunk...)
+				}
+				if bytes.Contains(content, []byte("EICAR")) {
+					_, _ = io.WriteString(conn, "stream: Eicar-Test-Signature FOUND\x00")
+					return
+				}
+				_, _ = io.WriteString(conn, "stream: OK\x00")
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestClamdScanner(t *testing.T) {
+	scanner := NewClamdScanner("tcp", serveClamd(t))
+	ctx := context.Background()
+	if err := scanner.Scan(ctx, strings.NewReader(strings.Repeat("clean ", 20000)), "clean.txt"); err != nil {
+		t.Fatalf("Scan() of a clean file error = %v", err)
+	}
+	err := scanner.Scan(ctx, strings.NewReader("X5O!P%@AP EICAR"), "eicar.txt")
+	if !errors.Is(err, ErrMaliciousFile) || !strings.Contains(err.Error(), "Eicar-Test-Signature") {
+		t.Fatalf("Scan() of an infected file error = %v, want %v", err, ErrMaliciousFile)
+	}
+
+	checker := NewContentChecker(WithContentScanner(scanner))
+	if _, err = checker.CheckSeeker(ctx, strings.NewReader("EICAR"), "eicar.txt"); err == nil {
+		t.Fatal("CheckSeeker() accepted an infected file")
+	}
+	failing := NewContentChecker(WithContentScanner(NewClamdScanner("tcp", "127.0.0.1:1")))
+	if _, err = failing.CheckSeeker(ctx, strings.NewReader("data"), "a.txt"); err == nil || errors.Is(err, ErrMaliciousFile) {
+		t.Fatalf("CheckSeeker() with an unreachable scanner error = %v, want a scanner failure", err)
+	}
+}
@@ -14,6 +14,7 @@ type WithFormOptions struct {
 	maxSize         int64
 	fileFormKey     string
 	allowExtensions map[string]struct{}
+	contentChecker  *ContentChecker
 }
 
 // WithFormOption is a functional option for configuring file upload behavior.
@@ -64,8 +65,17 @@ func WithFormAllowExtensions(extensions ...string) WithFormOption {
 	}
 }
 
+// WithFormContentCheck validates the uploaded content itself before the handler runs:
+// its type sniffed from magic bytes, its image dimensions and the configured scanners.
+func WithFormContentCheck(options ...ContentCheckOption) WithFormOption {
+	return func(opts *WithFormOptions) {
+		opts.contentChecker = NewContentChecker(options...)
+	}
+}
+
 // WithFormFileReader creates a Gin handler that processes uploaded files as io.ReadSeekCloser.
-// It validates file size, extension constraints, and passes the file content to the handler function.
+// It validates file size, extension constraints and, with WithFormContentCheck, the file content,
+// and passes the file content to the handler function.
 // The handler receives the file as an io.Reader along with the original filename.
 func WithFormFileReader[T any](handler func(ctx httpx.Context, file io.ReadSeekCloser, filename string) (T, error), options ...WithFormOption) httpx.Handler {
 	return WithJson(func(ctx httpx.Context) (T, error) {
@@ -97,6 +107,11 @@ func WithFormFileReader[T any](handler func(ctx httpx.Context, file io.ReadSeekC
 		defer func() {
 			_ = read.Close()
 		}()
+		if opts.contentChecker != nil {
+			if _, err = opts.contentChecker.CheckSeeker(ctx.Context(), read, file.Filename); err != nil {
+				return zero, err
+			}
+		}
 		return handler(ctx, read, file.Filename)
 	})
 }
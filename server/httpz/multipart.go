@@ -0,0 +1,391 @@
+package httpz
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/storage"
+)
+
+// MultipartOptions contains configuration for streaming multipart uploads.
+type MultipartOptions struct {
+	maxFileSize     int64
+	maxTotalSize    int64
+	maxFiles        int
+	maxFieldSize    int64
+	fileKeys        map[string]struct{}
+	allowExtensions map[string]struct{}
+	contentChecker  *ContentChecker
+	createKey       func(ctx httpx.Context, field, filename string) (string, error)
+	customKey       bool
+}
+
+// MultipartOption is a functional option for configuring streaming multipart uploads.
+type MultipartOption func(*MultipartOptions)
+
+func newMultipartOptions(opts ...MultipartOption) *MultipartOptions {
+	defaults := &MultipartOptions{
+		maxFileSize:  10 * 1024 * 1024,  // 10MB
+		maxTotalSize: 100 * 1024 * 1024, // 100MB
+		maxFiles:     10,
+		maxFieldSize: 1024 * 1024, // 1MB
+		createKey: func(ctx httpx.Context, field, filename string) (string, error) {
+			return storage.BuildUploadFileName(filename, storage.UploadNamingStrategyRandomExt)
+		},
+	}
+	for _, opt := range opts {
+		opt(defaults)
+	}
+	return defaults
+}
+
+// WithMultipartMaxFileSize sets the maximum size of each file in bytes.
+// A file above the limit fails on its own without failing the request.
+func WithMultipartMaxFileSize(maxSize int64) MultipartOption {
+	return func(options *MultipartOptions) {
+		options.maxFileSize = maxSize
+	}
+}
+
+// WithMultipartMaxTotalSize sets the maximum size of the whole multipart body in bytes.
+// Exceeding it fails the request.
+func WithMultipartMaxTotalSize(maxSize int64) MultipartOption {
+	return func(options *MultipartOptions) {
+		options.maxTotalSize = maxSize
+	}
+}
+
+// WithMultipartMaxFiles sets the maximum number of files in a request. The default is 10.
+func WithMultipartMaxFiles(maxFiles int) MultipartOption {
+	return func(options *MultipartOptions) {
+		options.maxFiles = maxFiles
+	}
+}
+
+// WithMultipartMaxFieldSize sets the maximum size of each regular form field in bytes.
+func WithMultipartMaxFieldSize(maxSize int64) MultipartOption {
+	return func(options *MultipartOptions) {
+		options.maxFieldSize = maxSize
+	}
+}
+
+// WithMultipartFileKeys restricts files to the given form field names; files under other
+// fields fail on their own. If no field names are provided, files under any field are accepted.
+func WithMultipartFileKeys(keys ...string) MultipartOption {
+	return func(options *MultipartOptions) {
+		if len(keys) == 0 {
+			options.fileKeys = nil
+			return
+		}
+		options.fileKeys = make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			options.fileKeys[key] = struct{}{}
+		}
+	}
+}
+
+// WithMultipartAllowExtensions restricts files to specific file extensions, matched case-insensitively.
+func WithMultipartAllowExtensions(extensions ...string) MultipartOption {
+	return func(options *MultipartOptions) {
+		if len(extensions) == 0 {
+			options.allowExtensions = nil
+			return
+		}
+		options.allowExtensions = make(map[string]struct{}, len(extensions))
+		for _, ext := range extensions {
+			options.allowExtensions[strings.ToLower(ext)] = struct{}{}
+		}
+	}
+}
+
+// WithMultipartContentCheck validates the content of each file as it streams, like
+// WithFormContentCheck. Scanners need the whole file, so with scanners each file is
+// spooled to a temporary file before it is uploaded.
+func WithMultipartContentCheck(options ...ContentCheckOption) MultipartOption {
+	return func(opts *MultipartOptions) {
+		opts.contentChecker = NewContentChecker(options...)
+	}
+}
+
+// WithMultipartFileKey sets how the storage key of each file is created.
+// By default the key is a random name keeping the file extension.
+func WithMultipartFileKey(fn func(ctx httpx.Context, field, filename string) (string, error)) MultipartOption {
+	return func(options *MultipartOptions) {
+		if fn == nil {
+			return
+		}
+		options.createKey = fn
+		options.customKey = true
+	}
+}
+
+// MultipartFileResult describes the outcome of one file of a multipart upload.
+type MultipartFileResult struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	Key         string `json:"key,omitempty"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+	// Error is set if the file was rejected or could not be stored, and Key is then empty.
+	Error string `json:"error,omitempty"`
+}
+
+// MultipartUploadResult is the outcome of a streaming multipart upload.
+type MultipartUploadResult struct {
+	Fields map[string][]string   `json:"fields,omitempty"`
+	Files  []MultipartFileResult `json:"files"`
+}
+
+var (
+	errMultipartFileTooLarge  = errors.New("FileError:FILE_TOO_LARGE")
+	errMultipartFieldTooLarge = errors.New("FormError:FIELD_TOO_LARGE")
+	errMultipartTotalTooLarge = errors.New("FileError:TOTAL_SIZE_EXCEEDED")
+)
+
+// StreamMultipartUpload reads a multipart/form-data request body part by part and uploads each
+// file straight into the uploader while it is received, without buffering the body in memory or
+// on disk. Regular fields are collected in the result. A file that is too large, not allowed or
+// not stored is reported in its result while the remaining parts are still processed. The request
+// fails if the body is malformed or exceeds the total size, file count or field size limits, and the
+// files it stored are then deleted. Only files under keys that did not exist before are deleted,
+// when the uploader is a storage.FileDeleter: with WithMultipartFileKey, that takes an uploader
+// that also checks existence like storage.FileDownloader.
+func StreamMultipartUpload(ctx httpx.Context, uploader storage.FileUploader, options ...MultipartOption) (result MultipartUploadResult, err error) {
+	opts := newMultipartOptions(options...)
+	mediaType, params, err := mime.ParseMediaType(ctx.Header("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		return MultipartUploadResult{}, httpx.BadRequestError(
+			errors.New("FormError:NOT_MULTIPART"),
+			"Request body is not multipart/form-data",
+		)
+	}
+	body := ctx.BodyReader()
+	if body == nil {
+		return MultipartUploadResult{}, httpx.NewBadRequestError("empty request body")
+	}
+	total := &limitReader{reader: body, limit: opts.maxTotalSize, err: errMultipartTotalTooLarge}
+	reader := multipart.NewReader(total, params["boundary"])
+
+	var created []string
+	defer func() {
+		if err != nil {
+			deleteFiles(ctx, uploader, created)
+		}
+	}()
+	result = MultipartUploadResult{Files: []MultipartFileResult{}}
+	for {
+		part, pErr := reader.NextPart()
+		if errors.Is(pErr, io.EOF) {
+			return result, nil
+		}
+		if pErr != nil {
+			return result, multipartError(total, pErr, "")
+		}
+		if part.FileName() == "" {
+			value, fErr := readField(part, opts.maxFieldSize)
+			if fErr != nil {
+				return result, multipartError(total, fErr, part.FormName())
+			}
+			if result.Fields == nil {
+				result.Fields = make(map[string][]string)
+			}
+			result.Fields[part.FormName()] = append(result.Fields[part.FormName()], value)
+			continue
+		}
+		if opts.maxFiles > 0 && len(result.Files) >= opts.maxFiles {
+			return result, httpx.BadRequestError(
+				errors.New("FileError:TOO_MANY_FILES"),
+				"Too many files in request",
+			)
+		}
+		file, isNew := uploadPart(ctx, uploader, part, opts)
+		if isNew {
+			created = append(created, file.Key)
+		}
+		if total.exceeded {
+			return result, multipartError(total, nil, "")
+		}
+		result.Files = append(result.Files, file)
+	}
+}
+
+// WithMultipartUpload creates a handler that streams a multipart upload into the uploader with
+// StreamMultipartUpload and passes the result to the handler.
+func WithMultipartUpload[T any](uploader storage.FileUploader, handler func(ctx httpx.Context, result MultipartUploadResult) (T, error), options ...MultipartOption) httpx.Handler {
+	return WithJson(func(ctx httpx.Context) (T, error) {
+		var zero T
+		result, err := StreamMultipartUpload(ctx, uploader, options...)
+		if err != nil {
+			return zero, err
+		}
+		return handler(ctx, result)
+	})
+}
+
+// uploadPart stores one file part, reporting a rejected or failed file in its result.
+// It also reports whether the file was stored under a key that did not exist before.
+func uploadPart(ctx httpx.Context, uploader storage.FileUploader, part *multipart.Part, opts *MultipartOptions) (MultipartFileResult, bool) {
+	file := MultipartFileResult{
+		Field:    part.FormName(),
+		Filename: part.FileName(),
+	}
+	fail := func(err error) (MultipartFileResult, bool) {
+		file.Key = ""
+		file.Error = multipartFileError(err)
+		return file, false
+	}
+	if opts.fileKeys != nil {
+		if _, ok := opts.fileKeys[file.Field]; !ok {
+			return fail(httpx.BadRequestError(
+				errors.New("FileError:FILE_KEY_NOT_ALLOWED"),
+				"File field not allowed: "+file.Field,
+			))
+		}
+	}
+	if opts.allowExtensions != nil {
+		if _, ok := opts.allowExtensions[strings.ToLower(filepath.Ext(file.Filename))]; !ok {
+			return fail(httpx.BadRequestError(
+				errors.New("FileError:FILE_EXTENSION_NOT_ALLOWED"),
+				"File extension not allowed: "+file.Filename,
+			))
+		}
+	}
+	key, err := opts.createKey(ctx, file.Field, file.Filename)
+	if err != nil {
+		return fail(err)
+	}
+	isNew, err := isNewKey(ctx, uploader, key, opts)
+	if err != nil {
+		return fail(err)
+	}
+
+	limited := &limitReader{reader: part, limit: opts.maxFileSize, err: errMultipartFileTooLarge}
+	tooLarge := httpx.BadRequestError(
+		errMultipartFileTooLarge,
+		"File size exceeds maximum allowed size: "+file.Filename,
+	)
+	var content io.Reader = limited
+	if opts.contentChecker != nil {
+		checked, contentType, cErr := opts.contentChecker.CheckStream(ctx.Context(), limited, file.Filename)
+		if cErr != nil {
+			if limited.exceeded {
+				return fail(tooLarge)
+			}
+			return fail(cErr)
+		}
+		defer func() {
+			_ = checked.Close()
+		}()
+		content = checked
+		file.ContentType = contentType
+	}
+	counter := &countingReader{reader: content}
+	file.Key, err = uploader.UploadFile(ctx.Context(), counter, key)
+	file.Size = counter.size
+	if err != nil {
+		if isNew {
+			deleteFiles(ctx, uploader, []string{key})
+		}
+		if limited.exceeded {
+			return fail(tooLarge)
+		}
+		return fail(err)
+	}
+	return file, isNew
+}
+
+// isNewKey reports whether no file exists under key. Random default keys are taken as new, while
+// keys from WithMultipartFileKey are checked when the uploader can tell and taken as existing otherwise.
+func isNewKey(ctx httpx.Context, uploader storage.FileUploader, key string, opts *MultipartOptions) (bool, error) {
+	checker, ok := uploader.(interface {
+		IsFileExists(ctx context.Context, key string) (bool, error)
+	})
+	if !ok {
+		return !opts.customKey, nil
+	}
+	exists, err := checker.IsFileExists(ctx.Context(), key)
+	if err != nil {
+		return false, err
+	}
+	return !exists, nil
+}
+
+// deleteFiles deletes stored files when the uploader is a storage.FileDeleter.
+func deleteFiles(ctx httpx.Context, uploader storage.FileUploader, keys []string) {
+	deleter, ok := uploader.(storage.FileDeleter)
+	if !ok {
+		return
+	}
+	for _, key := range keys {
+		_ = deleter.DeleteFile(context.WithoutCancel(ctx.Context()), key)
+	}
+}
+
+// multipartFileError describes why a file failed. Client errors are reported as is, while
+// other failures are reported generically so that storage details are not exposed.
+func multipartFileError(err error) string {
+	_, status, message := defaultErrorParser(err)
+	if status >= 400 && status < 500 {
+		return message
+	}
+	return "Failed to store file"
+}
+
+// multipartError turns a failure to read the multipart body into a request error.
+func multipartError(total *limitReader, err error, field string) error {
+	switch {
+	case total.exceeded:
+		return httpx.BadRequestError(errMultipartTotalTooLarge, "Request body exceeds maximum allowed size")
+	case errors.Is(err, errMultipartFieldTooLarge):
+		return httpx.BadRequestError(errMultipartFieldTooLarge, "Form field exceeds maximum allowed size: "+field)
+	default:
+		return httpx.BadRequestError(err, "Malformed multipart body")
+	}
+}
+
+func readField(part *multipart.Part, maxSize int64) (string, error) {
+	value, err := io.ReadAll(&limitReader{reader: part, limit: maxSize, err: errMultipartFieldTooLarge})
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// limitReader fails with err once more than limit bytes are read. A non-positive limit means no limit.
+type limitReader struct {
+	reader   io.Reader
+	limit    int64
+	err      error
+	read     int64
+	exceeded bool
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	if r.exceeded {
+		return 0, r.err
+	}
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.limit > 0 && r.read > r.limit {
+		r.exceeded = true
+		return 0, r.err
+	}
+	return n, err
+}
+
+type countingReader struct {
+	reader io.Reader
+	size   int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.size += int64(n)
+	return n, err
+}
@@ -0,0 +1,240 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+// Entry is a stored file to add to an archive.
+type Entry struct {
+	Key string `json:"key" yaml:"key"`
+	// Name is the path of the file in the archive. Empty means the key.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+// Write streams the files into an archive written to w, without buffering whole files. Files are
+// downloaded ahead of the one being written, up to the concurrency, and written in entry order.
+// Entry names are cleaned into relative paths, and repeated names get a numbered suffix.
+// Tar archives rely on DownloadResult.Size matching the content.
+func Write(ctx context.Context, w io.Writer, downloader storage.FileDownloader, entries []Entry, opt ...Option) error {
+	opts := newOptions(opt...)
+	if opts.concurrency <= 0 {
+		return errors.New("archive concurrency must be positive")
+	}
+	archive, err := newWriter(w, opts.format)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	downloads := newPrefetcher(ctx, downloader, entries, opts.concurrency)
+	next := 0
+	defer func() {
+		cancel()
+		// Close the downloads opened ahead of a failed entry.
+		for ; next < len(entries); next++ {
+			if d := downloads.take(next); d.err == nil {
+				_ = d.result.Reader.Close()
+			}
+		}
+	}()
+
+	names := make(map[string]struct{}, len(entries))
+	for next < len(entries) {
+		entry := entries[next]
+		d := downloads.take(next)
+		next++
+		if d.err != nil {
+			if opts.skipMissing && errors.Is(d.err, storageerr.ErrorNotFound) {
+				continue
+			}
+			return d.err
+		}
+		name, nErr := uniqueName(names, entry)
+		if nErr == nil {
+			nErr = archive.add(name, d.result)
+		}
+		_ = d.result.Reader.Close()
+		if nErr != nil {
+			return nErr
+		}
+	}
+	return archive.Close()
+}
+
+// WriteResponse streams the files into an archive sent as an attachment with the file name.
+// Errors after the response has started abort the response body.
+func WriteResponse(ctx httpx.Context, downloader storage.FileDownloader, filename string, entries []Entry, opt ...Option) error {
+	format := newOptions(opt...).format
+	reader, writer := io.Pipe()
+	defer func() {
+		// Stop the writer if the response ended before the archive.
+		_ = reader.Close()
+	}()
+	go func() {
+		_ = writer.CloseWithError(Write(ctx.Context(), writer, downloader, entries, opt...))
+	}()
+	ctx.SetHeader("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	return ctx.DataFromReader(http.StatusOK, format.ContentType(), reader, -1)
+}
+
+type download struct {
+	result storage.DownloadResult
+	err    error
+	// slot is set if the download holds a prefetch slot.
+	slot bool
+}
+
+// prefetcher downloads files ahead of their use, with at most concurrency downloads
+// opened and not yet taken.
+type prefetcher struct {
+	downloads []chan download
+	slots     chan struct{}
+}
+
+// newPrefetcher starts downloading the entries. Every entry receives exactly one download,
+// which is an error if the context is cancelled before it starts.
+func newPrefetcher(ctx context.Context, downloader storage.FileDownloader, entries []Entry, concurrency int) *prefetcher {
+	p := &prefetcher{
+		downloads: make([]chan download, len(entries)),
+		slots:     make(chan struct{}, concurrency),
+	}
+	for i := range p.downloads {
+		p.downloads[i] = make(chan download, 1)
+	}
+	go func() {
+		for i, entry := range entries {
+			select {
+			case p.slots <- struct{}{}:
+			case <-ctx.Done():
+				for ; i < len(entries); i++ {
+					p.downloads[i] <- download{err: ctx.Err()}
+				}
+				return
+			}
+			go func() {
+				result, err := downloader.DownloadFile(ctx, entry.Key)
+				p.downloads[i] <- download{result: result, err: err, slot: true}
+			}()
+		}
+	}()
+	return p
+}
+
+// take waits for the download of the i-th entry and frees its slot for the next one.
+func (p *prefetcher) take(i int) download {
+	d := <-p.downloads[i]
+	if d.slot {
+		<-p.slots
+	}
+	return d
+}
+
+// uniqueName returns the cleaned archive path of an entry, numbered if it is already taken.
+func uniqueName(names map[string]struct{}, entry Entry) (string, error) {
+	name := entry.Name
+	if name == "" {
+		name = entry.Key
+	}
+	name = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+	if name == "" {
+		return "", storageerr.ErrorFileNameInvalid
+	}
+	unique := name
+	ext := path.Ext(name)
+	for i := 2; ; i++ {
+		if _, ok := names[unique]; !ok {
+			break
+		}
+		unique = strings.TrimSuffix(name, ext) + " (" + strconv.Itoa(i) + ")" + ext
+	}
+	names[unique] = struct{}{}
+	return unique, nil
+}
+
+// writer adds files to an archive of some format.
+type writer interface {
+	add(name string, file storage.DownloadResult) error
+	Close() error
+}
+
+func newWriter(w io.Writer, format Format) (writer, error) {
+	switch format {
+	case FormatZip:
+		return &zipWriter{zip: zip.NewWriter(w)}, nil
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarWriter{gzip: gz, tar: tar.NewWriter(gz)}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+type zipWriter struct {
+	zip *zip.Writer
+}
+
+func (z *zipWriter) add(name string, file storage.DownloadResult) error {
+	w, err := z.zip.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime(file),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file.Reader)
+	return err
+}
+
+func (z *zipWriter) Close() error {
+	return z.zip.Close()
+}
+
+type tarWriter struct {
+	gzip *gzip.Writer
+	tar  *tar.Writer
+}
+
+func (t *tarWriter) add(name string, file storage.DownloadResult) error {
+	if file.Size < 0 {
+		return errors.New("archive: size of " + name + " is unknown")
+	}
+	err := t.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     file.Size,
+		Mode:     0o644,
+		ModTime:  modTime(file),
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(t.tar, file.Reader)
+	return err
+}
+
+func (t *tarWriter) Close() error {
+	return errors.Join(t.tar.Close(), t.gzip.Close())
+}
+
+func modTime(file storage.DownloadResult) time.Time {
+	if file.ModTime.IsZero() {
+		return time.Now()
+	}
+	return file.ModTime
+}
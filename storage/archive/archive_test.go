@@ -0,0 +1,235 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-sphere/sphere/cache/memory"
+	"github.com/go-sphere/sphere/storage/kvcache"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+func newStorage(t *testing.T) *kvcache.Client {
+	t.Helper()
+	storeCache := memory.NewByteCache()
+	t.Cleanup(func() { _ = storeCache.Close() })
+	store, err := kvcache.NewClient(kvcache.Config{}, storeCache)
+	if err != nil {
+		t.Fatalf("new kvcache client: %v", err)
+	}
+	return store
+}
+
+func readStored(t *testing.T, store *kvcache.Client, key string) string {
+	t.Helper()
+	result, err := store.DownloadFile(context.Background(), key)
+	if err != nil {
+		t.Fatalf("DownloadFile(%q) error = %v", key, err)
+	}
+	defer func() { _ = result.Reader.Close() }()
+	data, _ := io.ReadAll(result.Reader)
+	return string(data)
+}
+
+func TestWriteAndExtract(t *testing.T) {
+	ctx := context.Background()
+	source := newStorage(t)
+	files := map[string]string{
+		"attachments/1/report.pdf": "%PDF-1.7 report",
+		"attachments/2/report.pdf": "%PDF-1.7 another report",
+		"attachments/2/notes.txt":  strings.Repeat("notes ", 10000),
+		"attachments/3/empty.txt":  "",
+	}
+	for key, content := range files {
+		if _, err := source.UploadFile(ctx, strings.NewReader(content), key); err != nil {
+			t.Fatalf("UploadFile(%q) error = %v", key, err)
+		}
+	}
+	entries := []Entry{
+		{Key: "attachments/1/report.pdf", Name: "report.pdf"},
+		{Key: "attachments/missing.txt"},
+		{Key: "attachments/2/report.pdf", Name: "report.pdf"},
+		{Key: "attachments/2/notes.txt", Name: "../../notes.txt"},
+		{Key: "attachments/3/empty.txt", Name: "/empty.txt"},
+	}
+	want := map[string]string{
+		"report.pdf":     files["attachments/1/report.pdf"],
+		"report (2).pdf": files["attachments/2/report.pdf"],
+		"notes.txt":      files["attachments/2/notes.txt"],
+		"empty.txt":      "",
+	}
+
+	for _, format := range []Format{FormatZip, FormatTarGz} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Write(ctx, &buf, source, entries, WithFormat(format), WithConcurrency(2), WithSkipMissing())
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			target := newStorage(t)
+			result, err := Extract(ctx, bytes.NewReader(buf.Bytes()), format, target, WithPrefix("/imports/"))
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if len(result.Files) != len(want) {
+				t.Fatalf("extracted files = %+v, want %d", result.Files, len(want))
+			}
+			for _, file := range result.Files {
+				if !strings.HasPrefix(file.Key, "imports/") || file.Size != int64(len(want[file.Name])) {
+					t.Fatalf("extracted file = %+v", file)
+				}
+				if got := readStored(t, target, file.Key); got != want[file.Name] {
+					t.Fatalf("content of %s differs", file.Name)
+				}
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		err := Write(ctx, io.Discard, source, entries, WithConcurrency(1))
+		if !errors.Is(err, storageerr.ErrorNotFound) {
+			t.Fatalf("Write() error = %v, want %v", err, storageerr.ErrorNotFound)
+		}
+	})
+
+	t.Run("streamed zip", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := Write(ctx, &buf, source, entries[:1]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		target := newStorage(t)
+		// A reader without random access is spooled before extraction.
+		result, err := Extract(ctx, io.MultiReader(&buf), FormatZip, target)
+		if err != nil || len(result.Files) != 1 || result.Files[0].Key != "report.pdf" {
+			t.Fatalf("Extract() = %+v, %v", result, err)
+		}
+	})
+}
+
+func buildZip(t *testing.T, files map[string]string, order []string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range order {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("zip Create(%q) error = %v", name, err)
+		}
+		_, _ = io.WriteString(f, files[name])
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("zip Close() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractRejectsUnsafeArchives(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("path traversal", func(t *testing.T) {
+		target := newStorage(t)
+		data := buildZip(t, map[string]string{"ok.txt": "fine", "../evil.txt": "evil"}, []string{"ok.txt", "../evil.txt"})
+		_, err := Extract(ctx, bytes.NewReader(data), FormatZip, target, WithPrefix("uploads"))
+		if !errors.Is(err, storageerr.ErrorFileNameInvalid) {
+			t.Fatalf("Extract() error = %v, want %v", err, storageerr.ErrorFileNameInvalid)
+		}
+		if exists, _ := target.IsFileExists(ctx, "uploads/ok.txt"); exists {
+			t.Fatal("files of a rejected archive were kept")
+		}
+	})
+
+	t.Run("existing keys", func(t *testing.T) {
+		target := newStorage(t)
+		if _, err := target.UploadFile(ctx, strings.NewReader("existing"), "uploads/b.txt"); err != nil {
+			t.Fatalf("UploadFile() error = %v", err)
+		}
+		data := buildZip(t, map[string]string{"a.txt": "a", "b.txt": "b"}, []string{"a.txt", "b.txt"})
+		_, err := Extract(ctx, bytes.NewReader(data), FormatZip, target, WithPrefix("uploads"))
+		if !errors.Is(err, storageerr.ErrorDistExisted) {
+			t.Fatalf("Extract() error = %v, want %v", err, storageerr.ErrorDistExisted)
+		}
+		if got := readStored(t, target, "uploads/b.txt"); got != "existing" {
+			t.Fatalf("existing file = %q, want it kept", got)
+		}
+		if exists, _ := target.IsFileExists(ctx, "uploads/a.txt"); exists {
+			t.Fatal("files of a rejected archive were kept")
+		}
+	})
+
+	t.Run("duplicate entries", func(t *testing.T) {
+		target := newStorage(t)
+		data := buildZip(t, map[string]string{"a.txt": "a", "./a.txt": "b"}, []string{"a.txt", "./a.txt"})
+		if _, err := Extract(ctx, bytes.NewReader(data), FormatZip, target); !errors.Is(err, ErrInvalidArchive) {
+			t.Fatalf("Extract() error = %v, want %v", err, ErrInvalidArchive)
+		}
+		if exists, _ := target.IsFileExists(ctx, "a.txt"); exists {
+			t.Fatal("files of a rejected archive were kept")
+		}
+	})
+
+	t.Run("links", func(t *testing.T) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "dir/", Mode: 0o755})
+		_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "dir/passwd", Linkname: "/etc/passwd"})
+		_ = tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "dir/a.txt", Size: 1, Mode: 0o644})
+		_, _ = tw.Write([]byte("a"))
+		_ = tw.Close()
+		_ = gz.Close()
+
+		target := newStorage(t)
+		result, err := Extract(ctx, &buf, FormatTarGz, target)
+		if err != nil {
+			t.Fatalf("Extract() error = %v", err)
+		}
+		if len(result.Files) != 1 || result.Files[0].Key != "dir/a.txt" || len(result.Skipped) != 1 || result.Skipped[0] != "dir/passwd" {
+			t.Fatalf("Extract() = %+v", result)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		files := map[string]string{"a.txt": "a", "b.txt": "b", "big.txt": strings.Repeat("x", 4096)}
+		data := buildZip(t, files, []string{"a.txt", "b.txt", "big.txt"})
+		if _, err := Extract(ctx, bytes.NewReader(data), FormatZip, newStorage(t), WithMaxEntries(2)); !errors.Is(err, ErrTooManyEntries) {
+			t.Fatalf("Extract() error = %v, want %v", err, ErrTooManyEntries)
+		}
+		if _, err := Extract(ctx, bytes.NewReader(data), FormatZip, newStorage(t), WithMaxSize(1024)); !errors.Is(err, ErrArchiveTooLarge) {
+			t.Fatalf("Extract() error = %v, want %v", err, ErrArchiveTooLarge)
+		}
+		if _, err := Extract(ctx, strings.NewReader("not an archive"), FormatZip, newStorage(t)); !errors.Is(err, ErrInvalidArchive) {
+			t.Fatalf("Extract() error = %v, want %v", err, ErrInvalidArchive)
+		}
+	})
+}
+
+func TestEntryKey(t *testing.T) {
+	tests := []struct {
+		prefix, name, want string
+		wantErr            bool
+	}{
+		{"uploads", "a/b.txt", "uploads/a/b.txt", false},
+		{"uploads", "/etc/passwd", "uploads/etc/passwd", false},
+		{"uploads", "a/../b.txt", "uploads/b.txt", false},
+		{"", "a\\b.txt", "a/b.txt", false},
+		{"uploads", "../b.txt", "", true},
+		{"uploads", "a/../../b.txt", "", true},
+		{"", "..\\..\\windows.ini", "", true},
+		{"uploads", "a\x00.txt", "", true},
+		{"uploads", ".", "", true},
+	}
+	for _, tt := range tests {
+		got, err := entryKey(tt.prefix, tt.name)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("entryKey(%q, %q) = %q, %v, want %q", tt.prefix, tt.name, got, err, tt.want)
+		}
+	}
+}
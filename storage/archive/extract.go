@@ -0,0 +1,316 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+// Archive errors with appropriate HTTP status codes.
+var (
+	// ErrUnsupportedFormat indicates that an archive format is not supported.
+	ErrUnsupportedFormat = httpx.BadRequestError(errors.New("archive format not supported"))
+
+	// ErrInvalidArchive indicates that an archive is malformed.
+	ErrInvalidArchive = httpx.BadRequestError(errors.New("archive invalid"))
+
+	// ErrTooManyEntries indicates that an archive holds more files than allowed.
+	ErrTooManyEntries = httpx.BadRequestError(errors.New("archive has too many entries"))
+
+	// ErrArchiveTooLarge indicates that the extracted files exceed the allowed total size.
+	ErrArchiveTooLarge = httpx.NewWithStatus(http.StatusRequestEntityTooLarge, "archive too large")
+)
+
+// ExtractedFile describes a file stored from an archive.
+type ExtractedFile struct {
+	Name string `json:"name" yaml:"name"`
+	Key  string `json:"key" yaml:"key"`
+	Size int64  `json:"size" yaml:"size"`
+}
+
+// ExtractResult describes the outcome of an extraction.
+type ExtractResult struct {
+	Files []ExtractedFile `json:"files" yaml:"files"`
+	// Skipped lists the entries that are neither regular files nor directories, such as links.
+	Skipped []string `json:"skipped,omitempty" yaml:"skipped,omitempty"`
+}
+
+// Extract unpacks the regular files of an archive into the uploader, under the keys of their paths
+// below the prefix; directories are implied by the keys and links are skipped. Like
+// local.Client.fixFilePath, paths resolving outside of the prefix fail with
+// storageerr.ErrorFileNameInvalid. A ZIP archive that is not an io.ReaderAt and io.Seeker is
+// spooled to a temporary file first, within the maximum size. Entries whose keys repeat an earlier
+// entry fail with ErrInvalidArchive. When the uploader also checks existence like storage.FileDownloader,
+// entries whose keys already exist fail with storageerr.ErrorDistExisted; if the extraction fails,
+// the files stored so far are then deleted when the uploader is a storage.FileDeleter.
+func Extract(ctx context.Context, r io.Reader, format Format, uploader storage.FileUploader, opt ...ExtractOption) (ExtractResult, error) {
+	opts := newExtractOptions(opt...)
+	e := &extractor{
+		uploader: uploader,
+		opts:     opts,
+		result:   ExtractResult{Files: []ExtractedFile{}},
+		keys:     make(map[string]struct{}),
+	}
+	var err error
+	switch format {
+	case FormatZip:
+		err = e.extractZip(ctx, r)
+	case FormatTarGz:
+		err = e.extractTarGz(ctx, r)
+	default:
+		return ExtractResult{}, ErrUnsupportedFormat
+	}
+	if err != nil {
+		e.cleanup(ctx)
+		return ExtractResult{}, err
+	}
+	return e.result, nil
+}
+
+type extractor struct {
+	uploader storage.FileUploader
+	opts     *extractOptions
+	result   ExtractResult
+	keys     map[string]struct{}
+	entries  int
+	size     int64
+}
+
+func (e *extractor) extractZip(ctx context.Context, r io.Reader) error {
+	file, size, closeFn, err := e.readerAt(r)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+	archive, err := zip.NewReader(file, size)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	for _, entry := range archive.File {
+		mode := entry.Mode()
+		if mode.IsDir() {
+			continue
+		}
+		if !mode.IsRegular() {
+			if err = e.skip(entry.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		content, oErr := entry.Open()
+		if oErr != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidArchive, oErr)
+		}
+		err = e.store(ctx, entry.Name, content)
+		_ = content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) extractTarGz(ctx context.Context, r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	defer func() {
+		_ = gz.Close()
+	}()
+	archive := tar.NewReader(gz)
+	for {
+		header, hErr := archive.Next()
+		if errors.Is(hErr, io.EOF) {
+			return nil
+		}
+		if hErr != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidArchive, hErr)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+		case tar.TypeReg:
+			if err = e.store(ctx, header.Name, archive); err != nil {
+				return err
+			}
+		default:
+			if err = e.skip(header.Name); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readerAt returns random access to a ZIP archive, spooling it to a temporary file if needed.
+func (e *extractor) readerAt(r io.Reader) (io.ReaderAt, int64, func(), error) {
+	if file, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	}); ok {
+		size, err := file.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		return file, size, func() {}, nil
+	}
+	spool, err := os.CreateTemp("", "archive-*")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	closeFn := func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}
+	size, err := io.Copy(spool, io.LimitReader(r, e.opts.maxSize+1))
+	if err != nil {
+		closeFn()
+		return nil, 0, nil, err
+	}
+	if size > e.opts.maxSize {
+		closeFn()
+		return nil, 0, nil, ErrArchiveTooLarge
+	}
+	return spool, size, closeFn, nil
+}
+
+// count counts an entry against the entry limit.
+func (e *extractor) count(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if e.entries >= e.opts.maxEntries {
+		return ErrTooManyEntries
+	}
+	e.entries++
+	return nil
+}
+
+// skip records an entry that is not extracted.
+func (e *extractor) skip(name string) error {
+	if e.entries >= e.opts.maxEntries {
+		return ErrTooManyEntries
+	}
+	e.entries++
+	e.result.Skipped = append(e.result.Skipped, name)
+	return nil
+}
+
+// store uploads an entry, counting its size against the total limit.
+func (e *extractor) store(ctx context.Context, name string, content io.Reader) error {
+	if err := e.count(ctx); err != nil {
+		return err
+	}
+	key, err := entryKey(e.opts.prefix, name)
+	if err != nil {
+		return err
+	}
+	if _, ok := e.keys[key]; ok {
+		return fmt.Errorf("%w: duplicate entry %q", ErrInvalidArchive, name)
+	}
+	e.keys[key] = struct{}{}
+	if err = e.checkNew(ctx, key); err != nil {
+		return err
+	}
+	counter := &sizeLimitReader{reader: content, remaining: e.opts.maxSize - e.size}
+	stored, err := e.uploader.UploadFile(ctx, counter, key)
+	e.size += counter.read
+	if err != nil {
+		e.delete(ctx, key)
+		if counter.exceeded {
+			return ErrArchiveTooLarge
+		}
+		return err
+	}
+	e.result.Files = append(e.result.Files, ExtractedFile{Name: name, Key: stored, Size: counter.read})
+	return nil
+}
+
+// existenceChecker is the part of storage.FileDownloader that checkNew needs.
+type existenceChecker interface {
+	IsFileExists(ctx context.Context, key string) (bool, error)
+}
+
+// checkNew rejects a key that already exists, when the uploader can tell.
+func (e *extractor) checkNew(ctx context.Context, key string) error {
+	checker, ok := e.uploader.(existenceChecker)
+	if !ok {
+		return nil
+	}
+	exists, err := checker.IsFileExists(ctx, key)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return storageerr.ErrorDistExisted
+	}
+	return nil
+}
+
+// delete removes a file stored by the extraction. Without an existence check the key may have
+// held a file before, so it is left alone.
+func (e *extractor) delete(ctx context.Context, key string) {
+	if _, ok := e.uploader.(existenceChecker); !ok {
+		return
+	}
+	if deleter, ok := e.uploader.(storage.FileDeleter); ok {
+		_ = deleter.DeleteFile(context.WithoutCancel(ctx), key)
+	}
+}
+
+// cleanup deletes the files stored by a failed extraction.
+func (e *extractor) cleanup(ctx context.Context) {
+	for _, file := range e.result.Files {
+		e.delete(ctx, file.Key)
+	}
+}
+
+// entryKey returns the storage key of an archive entry below the prefix. Like
+// local.Client.fixFilePath, absolute paths are taken as relative to the prefix
+// and paths resolving outside of it are rejected.
+func entryKey(prefix string, name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.ContainsRune(name, 0) {
+		return "", storageerr.ErrorFileNameInvalid
+	}
+	clean := path.Clean(name)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", storageerr.ErrorFileNameInvalid
+	}
+	clean = strings.TrimPrefix(clean, "/")
+	if clean == "" || clean == "." {
+		return "", storageerr.ErrorFileNameInvalid
+	}
+	return path.Join(prefix, clean), nil
+}
+
+var errSizeExceeded = errors.New("archive size exceeded")
+
+// sizeLimitReader fails once more than remaining bytes are read.
+type sizeLimitReader struct {
+	reader    io.Reader
+	remaining int64
+	read      int64
+	exceeded  bool
+}
+
+func (r *sizeLimitReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.remaining {
+		r.exceeded = true
+		return 0, errSizeExceeded
+	}
+	return n, err
+}
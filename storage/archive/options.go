@@ -0,0 +1,122 @@
+package archive
+
+import "strings"
+
+// Format is an archive file format.
+type Format string
+
+const (
+	// FormatZip is a ZIP archive with deflated entries.
+	FormatZip Format = "zip"
+	// FormatTarGz is a gzip-compressed tar archive.
+	FormatTarGz Format = "tar.gz"
+)
+
+// FormatFromName returns the format of an archive file name by its extension.
+func FormatFromName(name string) (Format, bool) {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return FormatZip, true
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return FormatTarGz, true
+	default:
+		return "", false
+	}
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	if f == FormatTarGz {
+		return "application/gzip"
+	}
+	return "application/zip"
+}
+
+// options holds configuration parameters for writing archives.
+type options struct {
+	format      Format
+	concurrency int
+	skipMissing bool
+}
+
+func newOptions(opt ...Option) *options {
+	opts := &options{
+		format:      FormatZip,
+		concurrency: 4,
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	return opts
+}
+
+// Option defines a function type for configuring archive writing.
+type Option func(*options)
+
+// WithFormat sets the format of the written archive. The default format is FormatZip.
+func WithFormat(format Format) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithConcurrency sets how many downloads are opened ahead of the entry being written.
+// The default concurrency is 4.
+func WithConcurrency(concurrency int) Option {
+	return func(o *options) {
+		o.concurrency = concurrency
+	}
+}
+
+// WithSkipMissing leaves files that do not exist out of the archive instead of failing it.
+func WithSkipMissing() Option {
+	return func(o *options) {
+		o.skipMissing = true
+	}
+}
+
+// extractOptions holds configuration parameters for extracting archives.
+type extractOptions struct {
+	prefix     string
+	maxEntries int
+	maxSize    int64
+}
+
+func newExtractOptions(opt ...ExtractOption) *extractOptions {
+	opts := &extractOptions{
+		maxEntries: 1000,
+		maxSize:    1 << 30, // 1GB
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	return opts
+}
+
+// ExtractOption defines a function type for configuring archive extraction.
+type ExtractOption func(*extractOptions)
+
+// WithPrefix sets the key prefix under which the extracted files are stored.
+// Entries cannot escape the prefix.
+func WithPrefix(prefix string) ExtractOption {
+	return func(o *extractOptions) {
+		o.prefix = strings.Trim(prefix, "/")
+	}
+}
+
+// WithMaxEntries sets the maximum number of files extracted from an archive.
+// The default maximum is 1000.
+func WithMaxEntries(maxEntries int) ExtractOption {
+	return func(o *extractOptions) {
+		o.maxEntries = maxEntries
+	}
+}
+
+// WithMaxSize sets the maximum total uncompressed size of the extracted files in bytes,
+// which protects against decompression bombs. The default maximum is 1GB.
+func WithMaxSize(maxSize int64) ExtractOption {
+	return func(o *extractOptions) {
+		o.maxSize = maxSize
+	}
+}
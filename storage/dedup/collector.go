@@ -0,0 +1,140 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-sphere/sphere/core/task"
+	"github.com/go-sphere/sphere/log"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+var ErrCollectorAlreadyStarted = errors.New("dedup collector already started")
+
+// Collector is a task that deletes blobs which have stayed unreferenced for the grace period,
+// and, when the underlying storage implements storage.FileLister, staged uploads abandoned
+// for as long. It excludes concurrent uploads of its Storage; when several processes share
+// an index, the grace period must outlast any upload.
+type Collector struct {
+	storage *Storage
+	opts    *collectorOptions
+
+	loop task.Loop
+}
+
+// NewCollector creates a garbage collection task for the storage.
+func NewCollector(s *Storage, opt ...CollectorOption) (*Collector, error) {
+	if s == nil {
+		return nil, errors.New("storage is required")
+	}
+	opts := newCollectorOptions(opt...)
+	if opts.interval <= 0 {
+		return nil, errors.New("collect interval must be positive")
+	}
+	if opts.gracePeriod < 0 {
+		return nil, errors.New("grace period must not be negative")
+	}
+	if opts.batchSize <= 0 {
+		return nil, errors.New("batch size must be positive")
+	}
+	return &Collector{
+		storage: s,
+		opts:    opts,
+	}, nil
+}
+
+// Identifier returns the collector's identifier for logging and debugging purposes.
+func (c *Collector) Identifier() string {
+	return "dedup_collector"
+}
+
+// Start collects garbage until the context is cancelled or Stop is called.
+// Errors are logged and retried on the next collection. A stopped collector can be started again.
+func (c *Collector) Start(ctx context.Context) error {
+	return c.loop.Run(ctx, ErrCollectorAlreadyStarted, func(ctx context.Context) error {
+		ticker := time.NewTicker(c.opts.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := c.CollectOnce(ctx); err != nil && ctx.Err() == nil {
+				log.Warn("dedup collection failed", log.String("blob_dir", c.storage.opts.blobDir), log.Err(err))
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop signals the collection loop to exit and waits for the in-flight collection to finish.
+func (c *Collector) Stop(ctx context.Context) error {
+	return c.loop.Stop(ctx)
+}
+
+// CollectOnce deletes a batch of orphaned blobs and the abandoned staged uploads,
+// and returns how many blobs were deleted.
+func (c *Collector) CollectOnce(ctx context.Context) (int, error) {
+	before := time.Now().Add(-c.opts.gracePeriod)
+	hashes, err := c.storage.index.Orphans(ctx, before, c.opts.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, hash := range hashes {
+		if ctx.Err() != nil {
+			return deleted, ctx.Err()
+		}
+		ok, dErr := c.collect(ctx, hash, before)
+		if dErr != nil {
+			return deleted, dErr
+		}
+		if ok {
+			deleted++
+		}
+	}
+	return deleted, c.cleanupUploads(ctx, before)
+}
+
+// collect deletes a blob if it is still an orphan from before the cutoff.
+func (c *Collector) collect(ctx context.Context, hash string, before time.Time) (bool, error) {
+	c.storage.mu.Lock()
+	defer c.storage.mu.Unlock()
+	forgotten, err := c.storage.index.Forget(ctx, hash, before)
+	if err != nil || !forgotten {
+		return false, err
+	}
+	err = c.storage.store.DeleteFile(ctx, c.storage.blobKey(hash))
+	if err != nil && !errors.Is(err, storageerr.ErrorNotFound) {
+		return false, err
+	}
+	return true, nil
+}
+
+// cleanupUploads deletes staged uploads last modified before the cutoff.
+func (c *Collector) cleanupUploads(ctx context.Context, before time.Time) error {
+	lister, ok := c.storage.store.(storage.FileLister)
+	if !ok {
+		return nil
+	}
+	req := storage.ListFilesRequest{Prefix: c.storage.tmpDir()}
+	for {
+		page, err := lister.ListFiles(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, file := range page.Files {
+			if file.ModTime.Before(before) {
+				if err = c.storage.store.DeleteFile(ctx, file.Key); err != nil && !errors.Is(err, storageerr.ErrorNotFound) {
+					return err
+				}
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		req.Cursor = page.NextCursor
+	}
+}
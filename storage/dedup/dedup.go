@@ -0,0 +1,231 @@
+// Package dedup stores files by content: uploads are hashed with SHA-256 while they stream to the
+// underlying storage, identical contents share a single blob, and logical keys are mapped to
+// blobs through a reference-counted Index.
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+	"github.com/google/uuid"
+)
+
+var _ interface {
+	storage.Storage
+	storage.FileStatter
+} = (*Storage)(nil)
+
+// Storage is a storage.Storage that keeps every distinct content once. Blobs are stored in the
+// underlying storage under "<blob dir>/ab/cd/<sha256>", and uploads are staged under
+// "<blob dir>/tmp/" while they are hashed. Copying and moving only update the index, and deleting
+// a key removes its reference; blobs are deleted by the Collector once unreferenced.
+// The ETag of a file is the hex SHA-256 of its content.
+type Storage struct {
+	store storage.Storage
+	index Index
+	opts  *options
+
+	// mu orders blob commits against garbage collection, so that a blob found to exist by an upload
+	// is not deleted before the upload references it.
+	mu sync.RWMutex
+}
+
+// NewStorage creates a deduplicating storage over the underlying store.
+func NewStorage(store storage.Storage, index Index, opt ...Option) (*Storage, error) {
+	if store == nil {
+		return nil, errors.New("store is required")
+	}
+	if index == nil {
+		return nil, errors.New("index is required")
+	}
+	opts := newOptions(opt...)
+	if opts.blobDir == "" {
+		return nil, errors.New("blob dir is required")
+	}
+	return &Storage{
+		store: store,
+		index: index,
+		opts:  opts,
+	}, nil
+}
+
+// UploadFile hashes the content while staging it in the underlying storage, then keeps the staged
+// blob only if no blob with the same content exists, and points the key at it. If the index update
+// fails, the blob is tracked as an orphan for the Collector.
+func (s *Storage) UploadFile(ctx context.Context, file io.Reader, key string) (string, error) {
+	hash := sha256.New()
+	var size byteCounter
+	tmpKey := path.Join(s.opts.blobDir, "tmp", uuid.NewString())
+	if _, err := s.store.UploadFile(ctx, io.TeeReader(file, io.MultiWriter(hash, &size)), tmpKey); err != nil {
+		return "", err
+	}
+	entry := Entry{
+		Hash:    hex.EncodeToString(hash.Sum(nil)),
+		Size:    int64(size),
+		MIME:    mimeType(key),
+		ModTime: time.Now(),
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if err := s.commitBlob(ctx, tmpKey, s.blobKey(entry.Hash)); err != nil {
+		_ = s.store.DeleteFile(ctx, tmpKey)
+		return "", err
+	}
+	if _, _, err := s.index.Put(ctx, key, entry, true); err != nil {
+		// Let the Collector reclaim the blob if nothing references it.
+		_ = s.index.Track(context.WithoutCancel(ctx), entry.Hash)
+		return "", err
+	}
+	return key, nil
+}
+
+// UploadLocalFile uploads a local file like UploadFile.
+func (s *Storage) UploadLocalFile(ctx context.Context, file string, key string) (string, error) {
+	raw, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = raw.Close()
+	}()
+	return s.UploadFile(ctx, raw, key)
+}
+
+// commitBlob moves a staged upload to its blob key, or discards it if the blob already exists.
+func (s *Storage) commitBlob(ctx context.Context, tmpKey, blobKey string) error {
+	exists, err := s.store.IsFileExists(ctx, blobKey)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err = s.store.MoveFile(ctx, tmpKey, blobKey, false)
+		if err == nil {
+			return nil
+		}
+		// A concurrent upload of the same content committed first.
+		if !errors.Is(err, storageerr.ErrorDistExisted) {
+			return err
+		}
+	}
+	return s.store.DeleteFile(ctx, tmpKey)
+}
+
+// IsFileExists checks whether the key is in the index.
+func (s *Storage) IsFileExists(ctx context.Context, key string) (bool, error) {
+	_, found, err := s.index.Get(ctx, key)
+	return found, err
+}
+
+// DownloadFile retrieves the blob the key points at.
+func (s *Storage) DownloadFile(ctx context.Context, key string) (storage.DownloadResult, error) {
+	entry, err := s.entry(ctx, key)
+	if err != nil {
+		return storage.DownloadResult{}, err
+	}
+	result, err := s.store.DownloadFile(ctx, s.blobKey(entry.Hash))
+	if err != nil {
+		return storage.DownloadResult{}, err
+	}
+	return storage.DownloadResult{
+		Reader:  result.Reader,
+		MIME:    entry.MIME,
+		Size:    entry.Size,
+		ETag:    entry.Hash,
+		ModTime: entry.ModTime,
+	}, nil
+}
+
+// StatFile returns the file information recorded in the index.
+func (s *Storage) StatFile(ctx context.Context, key string) (storage.FileStat, error) {
+	entry, err := s.entry(ctx, key)
+	if err != nil {
+		return storage.FileStat{}, err
+	}
+	return storage.FileStat{
+		FileInfo: storage.FileInfo{
+			Key:     key,
+			Size:    entry.Size,
+			ModTime: entry.ModTime,
+			ETag:    entry.Hash,
+			MIME:    entry.MIME,
+		},
+	}, nil
+}
+
+// DeleteFile removes the key and its reference to a blob.
+func (s *Storage) DeleteFile(ctx context.Context, key string) error {
+	_, _, err := s.index.Delete(ctx, key)
+	return err
+}
+
+// MoveFile points the destination key at the source blob and removes the source key.
+func (s *Storage) MoveFile(ctx context.Context, sourceKey string, destinationKey string, overwrite bool) error {
+	entry, err := s.entry(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	if sourceKey == destinationKey {
+		return nil
+	}
+	if _, _, err = s.index.Put(ctx, destinationKey, entry, overwrite); err != nil {
+		return err
+	}
+	_, _, err = s.index.Delete(ctx, sourceKey)
+	return err
+}
+
+// CopyFile points the destination key at the source blob.
+func (s *Storage) CopyFile(ctx context.Context, sourceKey string, destinationKey string, overwrite bool) error {
+	entry, err := s.entry(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.index.Put(ctx, destinationKey, entry, overwrite)
+	return err
+}
+
+func (s *Storage) entry(ctx context.Context, key string) (Entry, error) {
+	entry, found, err := s.index.Get(ctx, key)
+	if err != nil {
+		return Entry{}, err
+	}
+	if !found {
+		return Entry{}, storageerr.ErrorNotFound
+	}
+	return entry, nil
+}
+
+func (s *Storage) blobKey(hash string) string {
+	return path.Join(s.opts.blobDir, hash[:2], hash[2:4], hash)
+}
+
+func (s *Storage) tmpDir() string {
+	return s.opts.blobDir + "/tmp/"
+}
+
+func mimeType(key string) string {
+	if t := mime.TypeByExtension(filepath.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// byteCounter counts the bytes written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,320 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/cache/mcache"
+	"github.com/go-sphere/sphere/infra/sqlite"
+	"github.com/go-sphere/sphere/storage/local"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+const testDriverName = "sqlite_dedup_test"
+
+var registerDriverOnce sync.Once
+
+func newSQLiteIndex(t *testing.T) *SQLiteIndex {
+	t.Helper()
+	registerDriverOnce.Do(func() {
+		sqlite.Register(testDriverName)
+	})
+	db, err := sql.Open(testDriverName, filepath.Join(t.TempDir(), "dedup.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	index, err := NewSQLiteIndex(db, "dedup")
+	if err != nil {
+		t.Fatalf("NewSQLiteIndex() error = %v", err)
+	}
+	if err = index.Migrate(context.Background()); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	return index
+}
+
+func indexes(t *testing.T) map[string]Index {
+	return map[string]Index{
+		"cache":  NewCacheIndex(mcache.NewByteCache()),
+		"sqlite": newSQLiteIndex(t),
+	}
+}
+
+func newTestStorage(t *testing.T, index Index) (*Storage, string) {
+	t.Helper()
+	root := t.TempDir()
+	store, err := local.NewClient(local.Config{RootDir: root})
+	if err != nil {
+		t.Fatalf("local.NewClient() error = %v", err)
+	}
+	s, err := NewStorage(store, index)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	return s, root
+}
+
+// countBlobs returns the number of committed and staged blobs on disk.
+func countBlobs(t *testing.T, root string) (blobs int, staged int) {
+	t.Helper()
+	err := filepath.WalkDir(filepath.Join(root, "blobs"), func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if strings.Contains(filepath.ToSlash(p), "/blobs/tmp/") {
+			staged++
+		} else {
+			blobs++
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("walk blobs: %v", err)
+	}
+	return blobs, staged
+}
+
+func readFile(t *testing.T, s *Storage, key string) string {
+	t.Helper()
+	result, err := s.DownloadFile(context.Background(), key)
+	if err != nil {
+		t.Fatalf("DownloadFile(%q) error = %v", key, err)
+	}
+	defer func() { _ = result.Reader.Close() }()
+	data, err := io.ReadAll(result.Reader)
+	if err != nil {
+		t.Fatalf("read %q: %v", key, err)
+	}
+	if int64(len(data)) != result.Size {
+		t.Fatalf("DownloadFile(%q) size = %d, read %d bytes", key, result.Size, len(data))
+	}
+	return string(data)
+}
+
+func TestStorage(t *testing.T) {
+	for name, index := range indexes(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, root := newTestStorage(t, index)
+
+			for _, key := range []string{"a/one.txt", "b/two.txt"} {
+				if _, err := s.UploadFile(ctx, strings.NewReader("same content"), key); err != nil {
+					t.Fatalf("UploadFile(%q) error = %v", key, err)
+				}
+			}
+			if _, err := s.UploadFile(ctx, strings.NewReader("other content"), "c/three.bin"); err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+			if blobs, staged := countBlobs(t, root); blobs != 2 || staged != 0 {
+				t.Fatalf("blobs = %d, staged = %d, want 2 and 0", blobs, staged)
+			}
+			if got := readFile(t, s, "b/two.txt"); got != "same content" {
+				t.Fatalf("b/two.txt = %q", got)
+			}
+
+			stat, err := s.StatFile(ctx, "a/one.txt")
+			if err != nil {
+				t.Fatalf("StatFile() error = %v", err)
+			}
+			sum := sha256.Sum256([]byte("same content"))
+			if stat.ETag != hex.EncodeToString(sum[:]) || stat.Size != 12 || !strings.HasPrefix(stat.MIME, "text/plain") {
+				t.Fatalf("StatFile() = %+v", stat)
+			}
+			if other, _ := s.StatFile(ctx, "b/two.txt"); other.ETag != stat.ETag {
+				t.Fatalf("identical contents have ETags %q and %q", stat.ETag, other.ETag)
+			}
+			if bin, _ := s.StatFile(ctx, "c/three.bin"); bin.MIME != "application/octet-stream" {
+				t.Fatalf("c/three.bin MIME = %q", bin.MIME)
+			}
+
+			if err = s.CopyFile(ctx, "a/one.txt", "c/three.bin", false); !errors.Is(err, storageerr.ErrorDistExisted) {
+				t.Fatalf("CopyFile() without overwrite error = %v, want %v", err, storageerr.ErrorDistExisted)
+			}
+			if err = s.MoveFile(ctx, "a/one.txt", "d/four.txt", false); err != nil {
+				t.Fatalf("MoveFile() error = %v", err)
+			}
+			if exists, _ := s.IsFileExists(ctx, "a/one.txt"); exists {
+				t.Fatal("moved source still exists")
+			}
+			if got := readFile(t, s, "d/four.txt"); got != "same content" {
+				t.Fatalf("d/four.txt = %q", got)
+			}
+			if _, err = s.DownloadFile(ctx, "a/one.txt"); !errors.Is(err, storageerr.ErrorNotFound) {
+				t.Fatalf("DownloadFile() of a moved key error = %v, want %v", err, storageerr.ErrorNotFound)
+			}
+			if blobs, _ := countBlobs(t, root); blobs != 2 {
+				t.Fatalf("blobs after move = %d, want 2", blobs)
+			}
+		})
+	}
+}
+
+func TestCollector(t *testing.T) {
+	for name, index := range indexes(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, root := newTestStorage(t, index)
+			collector, err := NewCollector(s, WithGracePeriod(0))
+			if err != nil {
+				t.Fatalf("NewCollector() error = %v", err)
+			}
+
+			for _, key := range []string{"x.txt", "y.txt"} {
+				if _, err = s.UploadFile(ctx, strings.NewReader("shared"), key); err != nil {
+					t.Fatalf("UploadFile() error = %v", err)
+				}
+			}
+			if _, err = s.UploadFile(ctx, strings.NewReader("first"), "z.txt"); err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+			// Overwriting z.txt orphans the blob of its first content.
+			if _, err = s.UploadFile(ctx, strings.NewReader("second"), "z.txt"); err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+			if err = s.DeleteFile(ctx, "x.txt"); err != nil {
+				t.Fatalf("DeleteFile() error = %v", err)
+			}
+			// An abandoned staged upload.
+			if _, err = s.store.UploadFile(ctx, strings.NewReader("partial"), s.tmpDir()+"abandoned"); err != nil {
+				t.Fatalf("stage upload: %v", err)
+			}
+			time.Sleep(5 * time.Millisecond)
+
+			deleted, err := collector.CollectOnce(ctx)
+			if err != nil {
+				t.Fatalf("CollectOnce() error = %v", err)
+			}
+			if deleted != 1 {
+				t.Fatalf("CollectOnce() deleted %d blobs, want 1", deleted)
+			}
+			if blobs, staged := countBlobs(t, root); blobs != 2 || staged != 0 {
+				t.Fatalf("blobs = %d, staged = %d, want 2 and 0", blobs, staged)
+			}
+			if got := readFile(t, s, "y.txt"); got != "shared" {
+				t.Fatalf("y.txt = %q", got)
+			}
+			if got := readFile(t, s, "z.txt"); got != "second" {
+				t.Fatalf("z.txt = %q", got)
+			}
+
+			// Re-uploading collected content commits a new blob.
+			if _, err = s.UploadFile(ctx, strings.NewReader("first"), "w.txt"); err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+			if got := readFile(t, s, "w.txt"); got != "first" {
+				t.Fatalf("w.txt = %q", got)
+			}
+			if deleted, _ = collector.CollectOnce(ctx); deleted != 0 {
+				t.Fatalf("CollectOnce() deleted %d referenced blobs", deleted)
+			}
+		})
+	}
+}
+
+// failingIndex fails every Put after storing the blob.
+type failingIndex struct {
+	Index
+}
+
+func (failingIndex) Put(context.Context, string, Entry, bool) (Entry, bool, error) {
+	return Entry{}, false, errors.New("index unavailable")
+}
+
+func TestCollector_FailedIndexUpdate(t *testing.T) {
+	for name, index := range indexes(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, root := newTestStorage(t, failingIndex{Index: index})
+			if _, err := s.UploadFile(ctx, strings.NewReader("lost"), "a.txt"); err == nil {
+				t.Fatal("UploadFile() expected the index error")
+			}
+			if blobs, _ := countBlobs(t, root); blobs != 1 {
+				t.Fatalf("blobs = %d, want the committed blob", blobs)
+			}
+			time.Sleep(5 * time.Millisecond)
+
+			collector, err := NewCollector(s, WithGracePeriod(0))
+			if err != nil {
+				t.Fatalf("NewCollector() error = %v", err)
+			}
+			deleted, err := collector.CollectOnce(ctx)
+			if err != nil || deleted != 1 {
+				t.Fatalf("CollectOnce() = %d, %v, want the unreferenced blob deleted", deleted, err)
+			}
+			if blobs, _ := countBlobs(t, root); blobs != 0 {
+				t.Fatalf("blobs = %d after collection, want 0", blobs)
+			}
+		})
+	}
+}
+
+func TestCollector_GracePeriod(t *testing.T) {
+	ctx := context.Background()
+	s, root := newTestStorage(t, newSQLiteIndex(t))
+	collector, err := NewCollector(s, WithGracePeriod(time.Hour))
+	if err != nil {
+		t.Fatalf("NewCollector() error = %v", err)
+	}
+	if _, err = s.UploadFile(ctx, strings.NewReader("content"), "a.txt"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if err = s.DeleteFile(ctx, "a.txt"); err != nil {
+		t.Fatalf("DeleteFile() error = %v", err)
+	}
+	if deleted, err := collector.CollectOnce(ctx); err != nil || deleted != 0 {
+		t.Fatalf("CollectOnce() = %d, %v, want a recent orphan kept", deleted, err)
+	}
+	// A key referencing the orphan again before collection revives it.
+	if _, err = s.UploadFile(ctx, strings.NewReader("content"), "b.txt"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if orphans, _ := s.index.Orphans(ctx, time.Now().Add(time.Hour), 10); len(orphans) != 0 {
+		t.Fatalf("Orphans() = %v after the blob was referenced again", orphans)
+	}
+	if blobs, _ := countBlobs(t, root); blobs != 1 {
+		t.Fatalf("blobs = %d, want 1", blobs)
+	}
+}
+
+func TestCollector_StartStop(t *testing.T) {
+	s, _ := newTestStorage(t, NewCacheIndex(mcache.NewByteCache()))
+	collector, err := NewCollector(s, WithInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewCollector() error = %v", err)
+	}
+	started := make(chan error, 1)
+	go func() { started <- collector.Start(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	if err = collector.Start(context.Background()); !errors.Is(err, ErrCollectorAlreadyStarted) {
+		t.Fatalf("second Start() error = %v, want %v", err, ErrCollectorAlreadyStarted)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err = collector.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err = <-started; err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// A stopped collector can be started again.
+	go func() { started <- collector.Start(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	if err = collector.Stop(ctx); err != nil {
+		t.Fatalf("Stop() after restart error = %v", err)
+	}
+	if err = <-started; err != nil {
+		t.Fatalf("Start() after restart error = %v", err)
+	}
+}
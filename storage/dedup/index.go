@@ -0,0 +1,211 @@
+package dedup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sphere/sphere/cache"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+// Entry is the index record of a logical key: the blob holding its content and the file information.
+type Entry struct {
+	Hash    string    `json:"hash"`
+	Size    int64     `json:"size"`
+	MIME    string    `json:"mime"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Index maps logical keys to content blobs and counts the references to each blob.
+// A blob whose last reference is removed becomes an orphan, which the Collector deletes
+// once it has stayed unreferenced for the grace period.
+type Index interface {
+	// Get returns the entry of a key.
+	Get(ctx context.Context, key string) (Entry, bool, error)
+
+	// Put points the key at the entry's blob, adding a reference to it and removing the reference
+	// to the blob the key pointed at before, which it returns. Without overwrite, an existing key
+	// is rejected with storageerr.ErrorDistExisted.
+	Put(ctx context.Context, key string, entry Entry, overwrite bool) (Entry, bool, error)
+
+	// Delete removes the key and its reference to a blob, returning the removed entry.
+	Delete(ctx context.Context, key string) (Entry, bool, error)
+
+	// Orphans returns up to limit blobs that have been unreferenced since before the cutoff.
+	Orphans(ctx context.Context, before time.Time, limit int) ([]string, error)
+
+	// Forget removes the record of a blob if it is still an orphan from before the cutoff,
+	// reporting whether it did. Once forgotten, the blob may be deleted.
+	Forget(ctx context.Context, hash string, before time.Time) (bool, error)
+
+	// Track records a stored blob without references as an orphan from now, so that the Collector
+	// deletes it. Blobs that are already recorded are left unchanged.
+	Track(ctx context.Context, hash string) error
+}
+
+const (
+	cacheKeyPrefix  = "dedup:key:"
+	cacheBlobPrefix = "dedup:blob:"
+)
+
+// blobRecord is the reference count of a blob in a CacheIndex.
+type blobRecord struct {
+	Refs       int64 `json:"refs"`
+	OrphanedAt int64 `json:"orphaned_at,omitempty"`
+}
+
+// CacheIndex keeps the index in a cache.ByteCache, under the "dedup:key:" and "dedup:blob:" prefixes.
+// Updates are serialized by a mutex, so all writers must share one CacheIndex value.
+// Orphans requires a cache that implements cache.KeyLister.
+type CacheIndex struct {
+	cache cache.ByteCache
+	mu    sync.Mutex
+}
+
+// NewCacheIndex creates an index kept in the cache, which must not expire its entries.
+func NewCacheIndex(c cache.ByteCache) *CacheIndex {
+	return &CacheIndex{cache: c}
+}
+
+func (c *CacheIndex) Get(ctx context.Context, key string) (Entry, bool, error) {
+	raw, found, err := c.cache.Get(ctx, cacheKeyPrefix+key)
+	if err != nil || !found {
+		return Entry{}, false, err
+	}
+	var entry Entry
+	if err = json.Unmarshal(raw, &entry); err != nil {
+		return Entry{}, false, err
+	}
+	return entry, true, nil
+}
+
+func (c *CacheIndex) Put(ctx context.Context, key string, entry Entry, overwrite bool) (Entry, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous, found, err := c.Get(ctx, key)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	if found && !overwrite {
+		return Entry{}, false, storageerr.ErrorDistExisted
+	}
+	if !found || previous.Hash != entry.Hash {
+		if err = c.addRef(ctx, entry.Hash, 1); err != nil {
+			return Entry{}, false, err
+		}
+	}
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	if err = c.cache.Set(ctx, cacheKeyPrefix+key, raw); err != nil {
+		return Entry{}, false, err
+	}
+	if found && previous.Hash != entry.Hash {
+		if err = c.addRef(ctx, previous.Hash, -1); err != nil {
+			return Entry{}, false, err
+		}
+	}
+	return previous, found, nil
+}
+
+func (c *CacheIndex) Delete(ctx context.Context, key string) (Entry, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous, found, err := c.Get(ctx, key)
+	if err != nil || !found {
+		return Entry{}, false, err
+	}
+	if err = c.cache.Del(ctx, cacheKeyPrefix+key); err != nil {
+		return Entry{}, false, err
+	}
+	if err = c.addRef(ctx, previous.Hash, -1); err != nil {
+		return Entry{}, false, err
+	}
+	return previous, true, nil
+}
+
+func (c *CacheIndex) Orphans(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	lister, ok := c.cache.(cache.KeyLister)
+	if !ok {
+		return nil, errors.New("cache does not support key listing")
+	}
+	keys, err := lister.Keys(ctx, cacheBlobPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	for _, key := range keys {
+		if len(hashes) == limit {
+			break
+		}
+		hash := strings.TrimPrefix(key, cacheBlobPrefix)
+		record, found, rErr := c.blob(ctx, hash)
+		if rErr != nil {
+			return nil, rErr
+		}
+		if found && record.Refs <= 0 && record.OrphanedAt <= before.UnixMilli() {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, nil
+}
+
+func (c *CacheIndex) Forget(ctx context.Context, hash string, before time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	record, found, err := c.blob(ctx, hash)
+	if err != nil || !found || record.Refs > 0 || record.OrphanedAt > before.UnixMilli() {
+		return false, err
+	}
+	if err = c.cache.Del(ctx, cacheBlobPrefix+hash); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *CacheIndex) Track(ctx context.Context, hash string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, found, err := c.blob(ctx, hash)
+	if err != nil || found {
+		return err
+	}
+	return c.addRef(ctx, hash, 0)
+}
+
+func (c *CacheIndex) blob(ctx context.Context, hash string) (blobRecord, bool, error) {
+	raw, found, err := c.cache.Get(ctx, cacheBlobPrefix+hash)
+	if err != nil || !found {
+		return blobRecord{}, false, err
+	}
+	var record blobRecord
+	if err = json.Unmarshal(raw, &record); err != nil {
+		return blobRecord{}, false, err
+	}
+	return record, true, nil
+}
+
+// addRef changes the reference count of a blob, recording when it became an orphan.
+func (c *CacheIndex) addRef(ctx context.Context, hash string, delta int64) error {
+	record, _, err := c.blob(ctx, hash)
+	if err != nil {
+		return err
+	}
+	record.Refs += delta
+	if record.Refs <= 0 {
+		record.Refs = 0
+		record.OrphanedAt = time.Now().UnixMilli()
+	} else {
+		record.OrphanedAt = 0
+	}
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return c.cache.Set(ctx, cacheBlobPrefix+hash, raw)
+}
@@ -0,0 +1,78 @@
+package dedup
+
+import (
+	"strings"
+	"time"
+)
+
+// options holds configuration parameters for the deduplicating storage.
+type options struct {
+	blobDir string
+}
+
+func newOptions(opt ...Option) *options {
+	opts := &options{
+		blobDir: "blobs",
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	return opts
+}
+
+// Option defines a function type for configuring the deduplicating storage.
+type Option func(*options)
+
+// WithBlobDir sets the key prefix under which blobs are stored in the underlying storage.
+// The default prefix is "blobs".
+func WithBlobDir(dir string) Option {
+	return func(o *options) {
+		o.blobDir = strings.Trim(dir, "/")
+	}
+}
+
+// collectorOptions holds configuration parameters for the garbage collection task.
+type collectorOptions struct {
+	interval    time.Duration
+	gracePeriod time.Duration
+	batchSize   int
+}
+
+func newCollectorOptions(opt ...CollectorOption) *collectorOptions {
+	opts := &collectorOptions{
+		interval:    time.Hour,
+		gracePeriod: 24 * time.Hour,
+		batchSize:   100,
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	return opts
+}
+
+// CollectorOption defines a function type for configuring the garbage collection task.
+type CollectorOption func(*collectorOptions)
+
+// WithInterval sets how often the collector looks for orphaned blobs.
+// The default interval is one hour.
+func WithInterval(interval time.Duration) CollectorOption {
+	return func(o *collectorOptions) {
+		o.interval = interval
+	}
+}
+
+// WithGracePeriod sets how long a blob must stay unreferenced, and a temporary upload untouched,
+// before the collector deletes it. The default grace period is 24 hours.
+func WithGracePeriod(period time.Duration) CollectorOption {
+	return func(o *collectorOptions) {
+		o.gracePeriod = period
+	}
+}
+
+// WithBatchSize sets the maximum number of blobs deleted per collection.
+// The default batch size is 100.
+func WithBatchSize(size int) CollectorOption {
+	return func(o *collectorOptions) {
+		o.batchSize = size
+	}
+}
@@ -0,0 +1,196 @@
+package dedup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+// SQLiteSchema returns the DDL that creates the key and blob tables of a SQLiteIndex,
+// named after the table prefix with "_keys" and "_blobs" appended.
+func SQLiteSchema(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s_keys (
+	file_key VARCHAR(1024) NOT NULL PRIMARY KEY,
+	hash VARCHAR(64) NOT NULL,
+	size BIGINT NOT NULL,
+	mime VARCHAR(255) NOT NULL,
+	mod_time BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS %[1]s_blobs (
+	hash VARCHAR(64) NOT NULL PRIMARY KEY,
+	refs BIGINT NOT NULL,
+	orphaned_at BIGINT
+);
+CREATE INDEX IF NOT EXISTS %[1]s_blobs_orphan_idx ON %[1]s_blobs (refs, orphaned_at);`, table)
+}
+
+// SQLiteIndex keeps the index in SQLite tables, including databases opened through the
+// infra/sqlite driver. Every update runs in a transaction, so several processes may share it.
+type SQLiteIndex struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLiteIndex creates an index in the tables with the given prefix; see SQLiteSchema and Migrate.
+func NewSQLiteIndex(db *sql.DB, table string) (*SQLiteIndex, error) {
+	if db == nil {
+		return nil, errors.New("db is required")
+	}
+	if table == "" {
+		return nil, errors.New("table is required")
+	}
+	return &SQLiteIndex{db: db, table: table}, nil
+}
+
+// Migrate creates the index tables.
+func (s *SQLiteIndex) Migrate(ctx context.Context) error {
+	for stmt := range strings.SplitSeq(SQLiteSchema(s.table), ";") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteIndex) Get(ctx context.Context, key string) (Entry, bool, error) {
+	return s.get(ctx, s.db, key)
+}
+
+func (s *SQLiteIndex) Put(ctx context.Context, key string, entry Entry, overwrite bool) (previous Entry, found bool, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		previous, found, err = s.get(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		if found && !overwrite {
+			return storageerr.ErrorDistExisted
+		}
+		if found {
+			_, err = tx.ExecContext(ctx, s.format("UPDATE %s_keys SET hash = ?, size = ?, mime = ?, mod_time = ? WHERE file_key = ?"),
+				entry.Hash, entry.Size, entry.MIME, entry.ModTime.UnixMilli(), key)
+		} else {
+			_, err = tx.ExecContext(ctx, s.format("INSERT INTO %s_keys (file_key, hash, size, mime, mod_time) VALUES (?, ?, ?, ?, ?)"),
+				key, entry.Hash, entry.Size, entry.MIME, entry.ModTime.UnixMilli())
+		}
+		if err != nil {
+			return err
+		}
+		if found && previous.Hash == entry.Hash {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, s.format("INSERT INTO %s_blobs (hash, refs, orphaned_at) VALUES (?, 1, NULL) "+
+			"ON CONFLICT (hash) DO UPDATE SET refs = refs + 1, orphaned_at = NULL"), entry.Hash)
+		if err != nil || !found {
+			return err
+		}
+		return s.release(ctx, tx, previous.Hash)
+	})
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return previous, found, nil
+}
+
+func (s *SQLiteIndex) Delete(ctx context.Context, key string) (previous Entry, found bool, err error) {
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		previous, found, err = s.get(ctx, tx, key)
+		if err != nil || !found {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, s.format("DELETE FROM %s_keys WHERE file_key = ?"), key); err != nil {
+			return err
+		}
+		return s.release(ctx, tx, previous.Hash)
+	})
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return previous, found, nil
+}
+
+func (s *SQLiteIndex) Orphans(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, s.format("SELECT hash FROM %s_blobs WHERE refs <= 0 AND orphaned_at <= ? ORDER BY orphaned_at LIMIT ?"),
+		before.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err = rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+func (s *SQLiteIndex) Forget(ctx context.Context, hash string, before time.Time) (bool, error) {
+	result, err := s.db.ExecContext(ctx, s.format("DELETE FROM %s_blobs WHERE hash = ? AND refs <= 0 AND orphaned_at <= ?"),
+		hash, before.UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (s *SQLiteIndex) Track(ctx context.Context, hash string) error {
+	_, err := s.db.ExecContext(ctx, s.format("INSERT INTO %s_blobs (hash, refs, orphaned_at) VALUES (?, 0, ?) "+
+		"ON CONFLICT (hash) DO NOTHING"), hash, time.Now().UnixMilli())
+	return err
+}
+
+func (s *SQLiteIndex) get(ctx context.Context, q queryer, key string) (Entry, bool, error) {
+	var entry Entry
+	var modTime int64
+	err := q.QueryRowContext(ctx, s.format("SELECT hash, size, mime, mod_time FROM %s_keys WHERE file_key = ?"), key).
+		Scan(&entry.Hash, &entry.Size, &entry.MIME, &modTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+	entry.ModTime = time.UnixMilli(modTime)
+	return entry, true, nil
+}
+
+// release removes a reference to a blob, recording when it became an orphan.
+func (s *SQLiteIndex) release(ctx context.Context, tx *sql.Tx, hash string) error {
+	_, err := tx.ExecContext(ctx, s.format("UPDATE %s_blobs SET refs = refs - 1, "+
+		"orphaned_at = CASE WHEN refs - 1 <= 0 THEN ? ELSE NULL END WHERE hash = ?"), time.Now().UnixMilli(), hash)
+	return err
+}
+
+func (s *SQLiteIndex) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteIndex) format(template string) string {
+	return fmt.Sprintf(template, s.table)
+}
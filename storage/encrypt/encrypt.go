@@ -0,0 +1,225 @@
+// Package encrypt encrypts files at rest with AES-GCM before they reach an underlying storage.
+// Files are encrypted and decrypted in chunks while they stream, so large files are never held
+// in memory, and each file names the key that encrypted it, so keys can be rotated.
+package encrypt
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-sphere/sphere/storage"
+)
+
+var (
+	// ErrInvalidCiphertext indicates a file that is not in the encrypted format or fails authentication.
+	ErrInvalidCiphertext = errors.New("encrypt: invalid ciphertext")
+	// ErrUnknownKey indicates a file encrypted with a key that is not configured.
+	ErrUnknownKey = errors.New("encrypt: unknown key")
+)
+
+const (
+	defaultChunkSize = 64 << 10 // 64 KiB
+	maxChunkSize     = 16 << 20 // 16 MiB
+	hkdfInfo         = "sphere storage encryption"
+)
+
+// Key is an AES key of 16, 24 or 32 bytes, identified by an ID of up to 255 bytes
+// that is stored with every file it encrypts.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+func (k Key) validate() error {
+	if k.ID == "" || len(k.ID) > 255 {
+		return fmt.Errorf("encrypt: key ID %q must be 1 to 255 bytes", k.ID)
+	}
+	switch len(k.Secret) {
+	case 16, 24, 32:
+		return nil
+	default:
+		return fmt.Errorf("encrypt: key %q must be 16, 24 or 32 bytes", k.ID)
+	}
+}
+
+type options struct {
+	chunkSize    int
+	previousKeys []Key
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		chunkSize: defaultChunkSize,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Option configures a Storage.
+type Option func(*options)
+
+// WithChunkSize sets the plaintext size of the chunks that are encrypted separately.
+// The default is 64 KiB, and the size cannot exceed 16 MiB.
+func WithChunkSize(size int) Option {
+	return func(o *options) {
+		o.chunkSize = size
+	}
+}
+
+// WithPreviousKeys adds keys that only decrypt files, such as keys rotated out of use.
+func WithPreviousKeys(keys ...Key) Option {
+	return func(o *options) {
+		o.previousKeys = append(o.previousKeys, keys...)
+	}
+}
+
+var _ storage.Storage = (*Storage)(nil)
+
+// Storage is a storage.Storage that encrypts files before they reach the underlying storage and
+// decrypts them on download. New files are encrypted with the current key; files encrypted with a
+// previous key remain readable. Moving and copying work on the encrypted files directly.
+// The underlying storage sees the encrypted size, and its ETag identifies the encrypted content.
+type Storage struct {
+	store   storage.Storage
+	current Key
+	keys    map[string][]byte
+	opts    *options
+}
+
+// NewStorage creates a storage encrypting files with the current key.
+func NewStorage(store storage.Storage, current Key, opt ...Option) (*Storage, error) {
+	if store == nil {
+		return nil, errors.New("store is required")
+	}
+	opts := newOptions(opt...)
+	if opts.chunkSize <= 0 || opts.chunkSize > maxChunkSize {
+		return nil, fmt.Errorf("encrypt: chunk size must be between 1 and %d bytes", maxChunkSize)
+	}
+	keys := make(map[string][]byte, len(opts.previousKeys)+1)
+	for _, key := range append([]Key{current}, opts.previousKeys...) {
+		if err := key.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := keys[key.ID]; ok {
+			return nil, fmt.Errorf("encrypt: duplicate key ID %q", key.ID)
+		}
+		keys[key.ID] = key.Secret
+	}
+	return &Storage{
+		store:   store,
+		current: current,
+		keys:    keys,
+		opts:    opts,
+	}, nil
+}
+
+// UploadFile encrypts the data with the current key while uploading it.
+func (s *Storage) UploadFile(ctx context.Context, file io.Reader, key string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	h := newHeader(s.current.ID, s.opts.chunkSize, salt)
+	aead, err := fileCipher(s.current.Secret, salt)
+	if err != nil {
+		return "", err
+	}
+	return s.store.UploadFile(ctx, newEncryptReader(file, aead, h), key)
+}
+
+// UploadLocalFile encrypts and uploads a local file like UploadFile.
+func (s *Storage) UploadLocalFile(ctx context.Context, file string, key string) (string, error) {
+	raw, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = raw.Close()
+	}()
+	return s.UploadFile(ctx, raw, key)
+}
+
+// DownloadFile retrieves a file and decrypts it while it is read. Size is the plaintext size.
+// Reading fails with ErrInvalidCiphertext if the file was tampered with.
+func (s *Storage) DownloadFile(ctx context.Context, key string) (storage.DownloadResult, error) {
+	result, err := s.store.DownloadFile(ctx, key)
+	if err != nil {
+		return storage.DownloadResult{}, err
+	}
+	reader, size, err := s.decrypt(result.Reader, result.Size)
+	if err != nil {
+		_ = result.Reader.Close()
+		return storage.DownloadResult{}, err
+	}
+	result.Reader = reader
+	result.Size = size
+	return result, nil
+}
+
+// decrypt reads the header of an encrypted file and returns the decrypting reader and the plaintext
+// size, which is -1 if the encrypted size is unknown.
+func (s *Storage) decrypt(r io.ReadCloser, size int64) (io.ReadCloser, int64, error) {
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		return nil, 0, err
+	}
+	secret, ok := s.keys[h.keyID]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w %q", ErrUnknownKey, h.keyID)
+	}
+	aead, err := fileCipher(secret, h.salt)
+	if err != nil {
+		return nil, 0, err
+	}
+	if size >= 0 {
+		if size, err = h.plaintextSize(size); err != nil {
+			return nil, 0, err
+		}
+	}
+	return newDecryptReader(br, r, aead, h), size, nil
+}
+
+// IsFileExists checks whether the file exists in the underlying storage.
+func (s *Storage) IsFileExists(ctx context.Context, key string) (bool, error) {
+	return s.store.IsFileExists(ctx, key)
+}
+
+// DeleteFile removes the file from the underlying storage.
+func (s *Storage) DeleteFile(ctx context.Context, key string) error {
+	return s.store.DeleteFile(ctx, key)
+}
+
+// MoveFile moves the encrypted file in the underlying storage.
+func (s *Storage) MoveFile(ctx context.Context, sourceKey string, destinationKey string, overwrite bool) error {
+	return s.store.MoveFile(ctx, sourceKey, destinationKey, overwrite)
+}
+
+// CopyFile copies the encrypted file in the underlying storage.
+func (s *Storage) CopyFile(ctx context.Context, sourceKey string, destinationKey string, overwrite bool) error {
+	return s.store.CopyFile(ctx, sourceKey, destinationKey, overwrite)
+}
+
+// fileCipher derives the AES-GCM cipher of a file from a key and the file's salt.
+func fileCipher(secret, salt []byte) (cipher.AEAD, error) {
+	fileKey, err := hkdf.Key(sha256.New, secret, salt, hkdfInfo, len(secret))
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
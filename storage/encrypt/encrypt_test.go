@@ -0,0 +1,165 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/go-sphere/sphere/cache/mcache"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/kvcache"
+	"github.com/go-sphere/sphere/storage/local"
+)
+
+func testKey(id string) Key {
+	return Key{ID: id, Secret: bytes.Repeat([]byte(id[:1]), 32)}
+}
+
+func backends(t *testing.T) map[string]storage.Storage {
+	t.Helper()
+	memory, err := kvcache.NewClient(kvcache.Config{}, mcache.NewByteCache())
+	if err != nil {
+		t.Fatalf("new kvcache client: %v", err)
+	}
+	disk, err := local.NewClient(local.Config{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("local.NewClient() error = %v", err)
+	}
+	return map[string]storage.Storage{"kvcache": memory, "local": disk}
+}
+
+func download(t *testing.T, s storage.Storage, key string) ([]byte, storage.DownloadResult) {
+	t.Helper()
+	result, err := s.DownloadFile(context.Background(), key)
+	if err != nil {
+		t.Fatalf("DownloadFile(%q) error = %v", key, err)
+	}
+	defer func() { _ = result.Reader.Close() }()
+	data, err := io.ReadAll(result.Reader)
+	if err != nil {
+		t.Fatalf("read %q: %v", key, err)
+	}
+	return data, result
+}
+
+func TestStorage_RoundTrip(t *testing.T) {
+	const chunkSize = 1024
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, err := NewStorage(backend, testKey("k1"), WithChunkSize(chunkSize))
+			if err != nil {
+				t.Fatalf("NewStorage() error = %v", err)
+			}
+			for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3 * chunkSize, 5*chunkSize + 17} {
+				plain := make([]byte, size)
+				_, _ = rand.Read(plain)
+				if _, err = s.UploadFile(ctx, bytes.NewReader(plain), "doc.pdf"); err != nil {
+					t.Fatalf("UploadFile(%d bytes) error = %v", size, err)
+				}
+				data, result := download(t, s, "doc.pdf")
+				if !bytes.Equal(data, plain) {
+					t.Fatalf("round trip of %d bytes returned %d different bytes", size, len(data))
+				}
+				if result.Size != int64(size) {
+					t.Fatalf("DownloadFile() Size = %d, want %d", result.Size, size)
+				}
+				stored, _ := download(t, backend, "doc.pdf")
+				if size > 16 && bytes.Contains(stored, plain) {
+					t.Fatal("the underlying storage holds the plaintext")
+				}
+			}
+
+			if err = s.CopyFile(ctx, "doc.pdf", "copy.pdf", false); err != nil {
+				t.Fatalf("CopyFile() error = %v", err)
+			}
+			original, _ := download(t, s, "doc.pdf")
+			if copied, _ := download(t, s, "copy.pdf"); !bytes.Equal(copied, original) {
+				t.Fatal("copied file does not decrypt to the original")
+			}
+		})
+	}
+}
+
+func TestStorage_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	backend := backends(t)["kvcache"]
+	old, err := NewStorage(backend, testKey("old"))
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	if _, err = old.UploadFile(ctx, bytes.NewReader([]byte("before rotation")), "a.txt"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+
+	rotated, err := NewStorage(backend, testKey("new"), WithPreviousKeys(testKey("old")))
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	if _, err = rotated.UploadFile(ctx, bytes.NewReader([]byte("after rotation")), "b.txt"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if data, _ := download(t, rotated, "a.txt"); string(data) != "before rotation" {
+		t.Fatalf("a.txt = %q", data)
+	}
+	if data, _ := download(t, rotated, "b.txt"); string(data) != "after rotation" {
+		t.Fatalf("b.txt = %q", data)
+	}
+	if _, err = old.DownloadFile(ctx, "b.txt"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("DownloadFile() with a missing key error = %v, want %v", err, ErrUnknownKey)
+	}
+	if _, err = NewStorage(backend, testKey("new"), WithPreviousKeys(testKey("new"))); err == nil {
+		t.Fatal("NewStorage() accepted a duplicate key ID")
+	}
+	if _, err = NewStorage(backend, Key{ID: "short", Secret: []byte("too short")}); err == nil {
+		t.Fatal("NewStorage() accepted an invalid key")
+	}
+}
+
+func TestStorage_Tampering(t *testing.T) {
+	ctx := context.Background()
+	backend := backends(t)["kvcache"]
+	s, err := NewStorage(backend, testKey("k1"), WithChunkSize(64))
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	if _, err = s.UploadFile(ctx, bytes.NewReader(bytes.Repeat([]byte("secret "), 40)), "a.txt"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	stored, _ := download(t, backend, "a.txt")
+	headerSize := fixedHeaderSize + len("k1")
+	chunk := 64 + gcmTagSize
+
+	tests := map[string][]byte{
+		"flipped bit":     append(bytes.Clone(stored[:len(stored)-1]), stored[len(stored)-1]^1),
+		"truncated chunk": stored[:len(stored)-1],
+		"dropped chunk":   stored[:headerSize+2*chunk],
+		"swapped chunks": append(append(append(bytes.Clone(stored[:headerSize]),
+			stored[headerSize+chunk:headerSize+2*chunk]...), stored[headerSize:headerSize+chunk]...), stored[headerSize+2*chunk:]...),
+	}
+	for name, tampered := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err = backend.UploadFile(ctx, bytes.NewReader(tampered), "tampered.txt"); err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+			result, err := s.DownloadFile(ctx, "tampered.txt")
+			if err == nil {
+				_, err = io.ReadAll(result.Reader)
+				_ = result.Reader.Close()
+			}
+			if !errors.Is(err, ErrInvalidCiphertext) {
+				t.Fatalf("reading a file with a %s error = %v, want %v", name, err, ErrInvalidCiphertext)
+			}
+		})
+	}
+
+	if _, err = backend.UploadFile(ctx, bytes.NewReader([]byte("plain text")), "plain.txt"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if _, err = s.DownloadFile(ctx, "plain.txt"); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("DownloadFile() of a plaintext file error = %v, want %v", err, ErrInvalidCiphertext)
+	}
+}
@@ -0,0 +1,230 @@
+package encrypt
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// The encrypted format is a header followed by chunks:
+//
+//	magic "SPHE" | version (1 byte) | chunk size (uint32) | salt (16 bytes) | key ID length (1 byte) | key ID
+//	chunk...
+//
+// Every file is encrypted with its own AES key, derived from the key named by the key ID and the
+// random salt with HKDF-SHA256. Every chunk seals up to chunk size bytes of plaintext with AES-GCM.
+// Its nonce is the chunk index (uint32) followed by a byte set to 1 for the last chunk only, and
+// the header is its additional data. Every chunk but the last is full, and the last one is present
+// even for empty files, so truncating, reordering or splicing chunks fails authentication.
+const (
+	magic           = "SPHE"
+	formatVersion   = 1
+	saltSize        = 16
+	fixedHeaderSize = len(magic) + 1 + 4 + saltSize + 1
+	gcmTagSize      = 16
+)
+
+// header is the parsed header of an encrypted file.
+type header struct {
+	raw       []byte
+	keyID     string
+	chunkSize int
+	salt      []byte
+}
+
+func newHeader(keyID string, chunkSize int, salt []byte) header {
+	raw := make([]byte, 0, fixedHeaderSize+len(keyID))
+	raw = append(raw, magic...)
+	raw = append(raw, formatVersion)
+	raw = binary.BigEndian.AppendUint32(raw, uint32(chunkSize))
+	raw = append(raw, salt...)
+	raw = append(raw, byte(len(keyID)))
+	raw = append(raw, keyID...)
+	return header{raw: raw, keyID: keyID, chunkSize: chunkSize, salt: salt}
+}
+
+func readHeader(r io.Reader) (header, error) {
+	fixed := make([]byte, fixedHeaderSize)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return header{}, ErrInvalidCiphertext
+		}
+		return header{}, err
+	}
+	if string(fixed[:len(magic)]) != magic || fixed[len(magic)] != formatVersion {
+		return header{}, ErrInvalidCiphertext
+	}
+	chunkSize := binary.BigEndian.Uint32(fixed[len(magic)+1:])
+	if chunkSize == 0 || chunkSize > maxChunkSize {
+		return header{}, ErrInvalidCiphertext
+	}
+	keyID := make([]byte, fixed[fixedHeaderSize-1])
+	if _, err := io.ReadFull(r, keyID); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return header{}, ErrInvalidCiphertext
+		}
+		return header{}, err
+	}
+	return header{
+		raw:       append(fixed, keyID...),
+		keyID:     string(keyID),
+		chunkSize: int(chunkSize),
+		salt:      fixed[len(magic)+5 : len(magic)+5+saltSize],
+	}, nil
+}
+
+// plaintextSize returns the plaintext size of an encrypted file of the given total size.
+func (h header) plaintextSize(size int64) (int64, error) {
+	body := size - int64(len(h.raw))
+	if body < gcmTagSize {
+		return 0, ErrInvalidCiphertext
+	}
+	sealed := int64(h.chunkSize + gcmTagSize)
+	chunks := (body + sealed - 1) / sealed
+	return body - chunks*gcmTagSize, nil
+}
+
+// nonce returns the 12-byte nonce of a chunk.
+func nonce(index uint32, last bool) []byte {
+	b := make([]byte, 7, 12)
+	b = binary.BigEndian.AppendUint32(b, index)
+	if last {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+// encryptReader encrypts the plaintext read from src into the encrypted format.
+type encryptReader struct {
+	src    io.Reader
+	aead   cipher.AEAD
+	header header
+	index  uint32
+	plain  []byte
+	sealed []byte
+	out    []byte
+	done   bool
+}
+
+func newEncryptReader(src io.Reader, aead cipher.AEAD, h header) *encryptReader {
+	return &encryptReader{
+		src:    src,
+		aead:   aead,
+		header: h,
+		// One byte beyond the chunk tells whether a full chunk is the last one.
+		plain:  make([]byte, 0, h.chunkSize+1),
+		sealed: make([]byte, 0, h.chunkSize+gcmTagSize),
+		out:    h.raw,
+	}
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.seal(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// seal reads the next chunk of plaintext and seals it into the output.
+func (r *encryptReader) seal() error {
+	n, err := io.ReadFull(r.src, r.plain[len(r.plain):cap(r.plain)])
+	r.plain = r.plain[:len(r.plain)+n]
+	last := false
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case err != nil:
+		return err
+	}
+	if !last && r.index == math.MaxUint32 {
+		return errors.New("encrypt: file too large")
+	}
+	chunk := r.plain
+	if !last {
+		chunk = r.plain[:r.header.chunkSize]
+	}
+	r.out = r.aead.Seal(r.sealed[:0], nonce(r.index, last), chunk, r.header.raw)
+	if last {
+		r.done = true
+		return nil
+	}
+	r.index++
+	r.plain = r.plain[:copy(r.plain, r.plain[r.header.chunkSize:])]
+	return nil
+}
+
+// decryptReader decrypts the chunks following a header read from src.
+type decryptReader struct {
+	src    *bufio.Reader
+	closer io.Closer
+	aead   cipher.AEAD
+	header header
+	index  uint32
+	sealed []byte
+	plain  []byte
+	done   bool
+}
+
+func newDecryptReader(src *bufio.Reader, closer io.Closer, aead cipher.AEAD, h header) *decryptReader {
+	return &decryptReader{
+		src:    src,
+		closer: closer,
+		aead:   aead,
+		header: h,
+		sealed: make([]byte, h.chunkSize+gcmTagSize),
+	}
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// open reads and authenticates the next chunk.
+func (r *decryptReader) open() error {
+	n, err := io.ReadFull(r.src, r.sealed)
+	last := false
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, pErr := r.src.Peek(1); errors.Is(pErr, io.EOF) {
+			last = true
+		} else if pErr != nil {
+			return pErr
+		}
+	}
+	plain, err := r.aead.Open(r.sealed[:0], nonce(r.index, last), r.sealed[:n], r.header.raw)
+	if err != nil {
+		return ErrInvalidCiphertext
+	}
+	r.plain = plain
+	r.done = last
+	r.index++
+	return nil
+}
+
+func (r *decryptReader) Close() error {
+	return r.closer.Close()
+}
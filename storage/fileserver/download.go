@@ -0,0 +1,274 @@
+package fileserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/imageproc"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+// errRangeNotSatisfiable reports a Range header that does not overlap the file.
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// DownloadFileRange retrieves part of a file from the underlying store,
+// which must implement storage.RangeDownloader.
+func (a *FileServer) DownloadFileRange(ctx context.Context, key string, offset int64, length int64) (storage.DownloadResult, error) {
+	downloader, ok := a.store.(storage.RangeDownloader)
+	if !ok {
+		return storage.DownloadResult{}, errors.New("store does not support range download")
+	}
+	return downloader.DownloadFileRange(ctx, key, offset, length)
+}
+
+// serveFile answers GET and HEAD requests for a file. When the store implements storage.FileStatter,
+// conditional requests are answered from the metadata without downloading the file, and when it also
+// implements storage.RangeDownloader, a single byte range is served with 206 Partial Content.
+// Requests for several ranges get the whole file, as RFC 9110 allows.
+func (a *FileServer) serveFile(ctx httpx.Context, filename string, sharedHeaders map[string]string) error {
+	statter, ok := a.store.(storage.FileStatter)
+	if !ok {
+		return a.serveDownload(ctx, filename, sharedHeaders)
+	}
+	stat, err := statter.StatFile(ctx.Context(), filename)
+	if err != nil {
+		return downloadError(err)
+	}
+	ranger, canRange := a.store.(storage.RangeDownloader)
+	setFileHeaders(ctx, sharedHeaders, stat.ETag, stat.ModTime, stat.FileMetadata)
+	if canRange {
+		ctx.SetHeader("Accept-Ranges", "bytes")
+	}
+	if isNotModified(ctx, stat.ETag, stat.ModTime) {
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	head := ctx.Method() == http.MethodHead
+
+	if canRange {
+		offset, length, partial, rErr := requestRange(ctx, stat.ETag, stat.ModTime, stat.Size)
+		if rErr != nil {
+			ctx.SetHeader("Content-Range", fmt.Sprintf("bytes */%d", stat.Size))
+			return ctx.NoContent(http.StatusRequestedRangeNotSatisfiable)
+		}
+		if partial {
+			ctx.SetHeader("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, stat.Size))
+			if head {
+				return headResponse(ctx, http.StatusPartialContent, stat.MIME, length)
+			}
+			result, dErr := ranger.DownloadFileRange(ctx.Context(), filename, offset, length)
+			if dErr != nil {
+				return downloadError(dErr)
+			}
+			// result.Reader is expected to be closed by httpx.DataFromReader, so we don't close it here.
+			return ctx.DataFromReader(http.StatusPartialContent, stat.MIME, result.Reader, int(result.Size))
+		}
+	}
+
+	if head {
+		return headResponse(ctx, http.StatusOK, stat.MIME, stat.Size)
+	}
+	result, err := a.store.DownloadFile(ctx.Context(), filename)
+	if err != nil {
+		return downloadError(err)
+	}
+	return ctx.DataFromReader(http.StatusOK, result.MIME, result.Reader, int(result.Size))
+}
+
+// serveDownload answers requests for stores without StatFile, taking the metadata from the download.
+func (a *FileServer) serveDownload(ctx httpx.Context, filename string, sharedHeaders map[string]string) error {
+	result, err := a.store.DownloadFile(ctx.Context(), filename)
+	if err != nil {
+		return downloadError(err)
+	}
+	setFileHeaders(ctx, sharedHeaders, result.ETag, result.ModTime, result.FileMetadata)
+	if isNotModified(ctx, result.ETag, result.ModTime) {
+		_ = result.Reader.Close()
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	if ctx.Method() == http.MethodHead {
+		_ = result.Reader.Close()
+		return headResponse(ctx, http.StatusOK, result.MIME, result.Size)
+	}
+	// result.Reader is expected to be closed by httpx.DataFromReader, so we don't close it here.
+	return ctx.DataFromReader(http.StatusOK, result.MIME, result.Reader, int(result.Size))
+}
+
+// serveImage answers GET and HEAD requests for a processed image. Processed images have their own
+// ETag for conditional requests, and are served whole.
+func (a *FileServer) serveImage(ctx httpx.Context, filename string, opts imageproc.Options, sharedHeaders map[string]string) error {
+	result, err := a.opts.imageProcessor.Transform(ctx.Context(), a.store, filename, opts)
+	if err != nil {
+		if errors.Is(err, imageproc.ErrInvalidOptions) || errors.Is(err, imageproc.ErrImageTooLarge) ||
+			errors.Is(err, imageproc.ErrUnsupportedImage) {
+			return httpx.BadRequestError(err)
+		}
+		return downloadError(err)
+	}
+	setFileHeaders(ctx, sharedHeaders, result.ETag, time.Time{}, storage.FileMetadata{})
+	if isNotModified(ctx, result.ETag, time.Time{}) {
+		_ = result.Reader.Close()
+		return ctx.NoContent(http.StatusNotModified)
+	}
+	if ctx.Method() == http.MethodHead {
+		_ = result.Reader.Close()
+		return headResponse(ctx, http.StatusOK, result.MIME, result.Size)
+	}
+	// result.Reader is expected to be closed by httpx.DataFromReader, so we don't close it here.
+	return ctx.DataFromReader(http.StatusOK, result.MIME, result.Reader, int(result.Size))
+}
+
+func setFileHeaders(ctx httpx.Context, sharedHeaders map[string]string, etag string, modTime time.Time, metadata storage.FileMetadata) {
+	headers := maps.Clone(sharedHeaders)
+	if metadata.CacheControl != "" {
+		headers["Cache-Control"] = metadata.CacheControl
+	}
+	if metadata.ContentDisposition != "" {
+		headers["Content-Disposition"] = metadata.ContentDisposition
+	}
+	if etag != "" {
+		headers["ETag"] = quoteETag(etag)
+	}
+	if !modTime.IsZero() {
+		headers["Last-Modified"] = modTime.UTC().Format(http.TimeFormat)
+	}
+	for k, v := range headers {
+		ctx.SetHeader(k, v)
+	}
+}
+
+func headResponse(ctx httpx.Context, code int, contentType string, size int64) error {
+	if contentType != "" {
+		ctx.SetHeader("Content-Type", contentType)
+	}
+	if size >= 0 {
+		ctx.SetHeader("Content-Length", strconv.FormatInt(size, 10))
+	}
+	return ctx.NoContent(code)
+}
+
+func downloadError(err error) error {
+	switch {
+	case errors.Is(err, storageerr.ErrorNotFound):
+		return httpx.NotFoundError(err)
+	case errors.Is(err, storageerr.ErrorRangeInvalid):
+		return err
+	default:
+		return httpx.InternalServerError(err)
+	}
+}
+
+// isNotModified evaluates If-None-Match, or If-Modified-Since when it is absent, as RFC 9110 orders them.
+func isNotModified(ctx httpx.Context, etag string, modTime time.Time) bool {
+	if noneMatch := ctx.Header("If-None-Match"); noneMatch != "" {
+		return etag != "" && matchETag(noneMatch, etag, false)
+	}
+	since := ctx.Header("If-Modified-Since")
+	if since == "" || modTime.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(since)
+	if err != nil {
+		return false
+	}
+	return !modTime.Truncate(time.Second).After(t)
+}
+
+// matchETag reports whether a comma-separated list of entity tags, or "*", matches the ETag.
+// The weak comparison ignores W/ prefixes; the strong comparison never matches weak tags.
+func matchETag(list string, etag string, strong bool) bool {
+	etag = quoteETag(etag)
+	for candidate := range strings.SplitSeq(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strong {
+			if candidate == etag && !strings.HasPrefix(etag, "W/") {
+				return true
+			}
+			continue
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// requestRange returns the byte range to serve for the Range header. It reports partial as false
+// when the whole file should be served: without a Range header, with a stale If-Range, with a
+// malformed header or with several ranges. It returns errRangeNotSatisfiable if the range does
+// not overlap the file.
+func requestRange(ctx httpx.Context, etag string, modTime time.Time, size int64) (offset int64, length int64, partial bool, err error) {
+	header := ctx.Header("Range")
+	if header == "" {
+		return 0, 0, false, nil
+	}
+	if ifRange := ctx.Header("If-Range"); ifRange != "" && !matchIfRange(ifRange, etag, modTime) {
+		return 0, 0, false, nil
+	}
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, 0, false, nil
+	}
+	if first == "" {
+		// A suffix range: the last N bytes.
+		n, pErr := strconv.ParseInt(last, 10, 64)
+		if pErr != nil || n < 0 {
+			return 0, 0, false, nil
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, false, errRangeNotSatisfiable
+		}
+		n = min(n, size)
+		return size - n, n, true, nil
+	}
+	offset, pErr := strconv.ParseInt(first, 10, 64)
+	if pErr != nil || offset < 0 {
+		return 0, 0, false, nil
+	}
+	end := size - 1
+	if last != "" {
+		end, pErr = strconv.ParseInt(last, 10, 64)
+		if pErr != nil || end < offset {
+			return 0, 0, false, nil
+		}
+		end = min(end, size-1)
+	}
+	if offset >= size {
+		return 0, 0, false, errRangeNotSatisfiable
+	}
+	return offset, end - offset + 1, true, nil
+}
+
+// matchIfRange reports whether an If-Range validator, an entity tag or a date, still matches the file.
+func matchIfRange(ifRange string, etag string, modTime time.Time) bool {
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, `W/"`) {
+		return etag != "" && matchETag(ifRange, etag, true)
+	}
+	t, err := http.ParseTime(ifRange)
+	if err != nil || modTime.IsZero() {
+		return false
+	}
+	return modTime.Truncate(time.Second).Equal(t)
+}
+
+// quoteETag returns the ETag as an HTTP entity tag. Backends report some ETags without quotes.
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"io"
-	"maps"
 	"net/http"
 	"net/url"
 	"strings"
@@ -13,6 +12,7 @@ import (
 	"github.com/go-sphere/httpx"
 	"github.com/go-sphere/sphere/cache"
 	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/imageproc"
 	"github.com/go-sphere/sphere/storage/storageerr"
 	"github.com/go-sphere/sphere/storage/urlhandler"
 )
@@ -24,6 +24,12 @@ type Config struct {
 	KeyTTL       time.Duration                `json:"key_ttl" yaml:"key_ttl"`
 	Dir          string                       `json:"dir" yaml:"dir"`
 	UploadNaming storage.UploadNamingStrategy `json:"upload_naming" yaml:"upload_naming"`
+	// SigningKeys sign the download URLs of GenerateSignedURL. The first key signs new URLs and
+	// every key verifies them, so a key is rotated by prepending its successor and removing it
+	// once the URLs it signed have expired.
+	SigningKeys []SigningKey `json:"signing_keys" yaml:"signing_keys"`
+	// PrivateDownload makes RegisterFileDownloader serve signed URLs only.
+	PrivateDownload bool `json:"private_download" yaml:"private_download"`
 }
 
 // FileServer provides a caching layer and upload token generation for S3-compatible storage.
@@ -53,6 +59,12 @@ func NewCDNAdapter(conf Config, cache cache.ByteCache, store storage.Storage, op
 	if conf.KeyTTL == 0 {
 		conf.KeyTTL = time.Minute * 5
 	}
+	if err := validateSigningKeys(conf.SigningKeys); err != nil {
+		return nil, err
+	}
+	if conf.PrivateDownload && len(conf.SigningKeys) == 0 {
+		return nil, errors.New("signing_keys is required for private_download")
+	}
 	handler, err := urlhandler.NewHandler(conf.GetBase)
 	if err != nil {
 		return nil, err
@@ -111,8 +123,41 @@ func (a *FileServer) CopyFile(ctx context.Context, sourceKey string, destination
 	return a.store.CopyFile(ctx, sourceKey, destinationKey, overwrite)
 }
 
+// ListFiles lists files of the underlying store, which must implement storage.FileLister.
+func (a *FileServer) ListFiles(ctx context.Context, req storage.ListFilesRequest) (storage.ListFilesResult, error) {
+	lister, ok := a.store.(storage.FileLister)
+	if !ok {
+		return storage.ListFilesResult{}, errors.New("store does not support listing files")
+	}
+	return lister.ListFiles(ctx, req)
+}
+
+// UploadFileWithOptions uploads a file with metadata to the underlying store,
+// which must implement storage.FileOptionsUploader.
+func (a *FileServer) UploadFileWithOptions(ctx context.Context, file io.Reader, key string, opts storage.UploadOptions) (string, error) {
+	uploader, ok := a.store.(storage.FileOptionsUploader)
+	if !ok {
+		return "", errors.New("store does not support upload options")
+	}
+	return uploader.UploadFileWithOptions(ctx, file, key, opts)
+}
+
+// StatFile returns file metadata from the underlying store, which must implement storage.FileStatter.
+func (a *FileServer) StatFile(ctx context.Context, key string) (storage.FileStat, error) {
+	statter, ok := a.store.(storage.FileStatter)
+	if !ok {
+		return storage.FileStat{}, errors.New("store does not support stat")
+	}
+	return statter.StatFile(ctx, key)
+}
+
 // GenerateUploadAuth creates temporary upload authorization for client-side uploads.
+// The upload policy is cached with the token and enforced by RegisterFileUploader;
+// its Expires replaces KeyTTL for the token.
 func (a *FileServer) GenerateUploadAuth(ctx context.Context, req storage.UploadAuthRequest) (storage.UploadAuthResult, error) {
+	if err := storage.ValidateUploadPolicy(req.UploadPolicy); err != nil {
+		return storage.UploadAuthResult{}, err
+	}
 	fileName, err := storage.BuildUploadFileName(req.FileName, a.config.UploadNaming)
 	if err != nil {
 		return storage.UploadAuthResult{}, err
@@ -125,6 +170,9 @@ func (a *FileServer) GenerateUploadAuth(ctx context.Context, req storage.UploadA
 	if err != nil {
 		return storage.UploadAuthResult{}, err
 	}
+	if err = a.saveUploadPolicy(ctx, newToken, key, req.UploadPolicy); err != nil {
+		return storage.UploadAuthResult{}, err
+	}
 	uri, err := url.JoinPath(a.config.PutBase, newToken)
 	if err != nil {
 		return storage.UploadAuthResult{}, err
@@ -142,33 +190,49 @@ func (a *FileServer) GenerateUploadAuth(ctx context.Context, req storage.UploadA
 	}, nil
 }
 
+// RegisterFileDownloader mounts GET and HEAD /*filename. Files are served with the Content-Type,
+// ETag, Last-Modified, Cache-Control and Content-Disposition the store reports; a Cache-Control
+// stored with the file overrides the default set with options. Conditional requests are answered
+// with 304 Not Modified, and Range requests with 206 Partial Content when the store supports them.
+// URLs signed by GenerateSignedURL are verified, and with PrivateDownload no other URL is served.
+// With WithImageProcessor, requests with image query parameters get the processed image; the
+// parameters of signed URLs are part of the signature, see GenerateSignedImageURL.
 func (a *FileServer) RegisterFileDownloader(route httpx.Router) {
 	sharedHeaders := map[string]string{}
 	if a.opts.downloadCacheControl != "" {
 		sharedHeaders["Cache-Control"] = a.opts.downloadCacheControl
 	}
 	path, param := httpx.FixWildcardPathIfNeed(route, "/*filename")
-	route.Handle(http.MethodGet, path, func(ctx httpx.Context) error {
+	handler := func(ctx httpx.Context) error {
 		filename := normalizeWildcardParam(ctx.Param(param))
 		if filename == "" {
 			return httpx.NewNotFoundError("filename is required")
 		}
-		result, err := a.store.DownloadFile(ctx.Context(), filename)
-		if err != nil {
-			if errors.Is(err, storageerr.ErrorNotFound) {
-				return httpx.NotFoundError(err)
-			}
-			return httpx.InternalServerError(err)
+		if err := a.verifySignedURL(filename, ctx.Query, time.Now()); err != nil {
+			return err
 		}
-		headers := maps.Clone(sharedHeaders)
-		for k, v := range headers {
-			ctx.SetHeader(k, v)
+		if a.opts.imageProcessor != nil {
+			opts, err := imageproc.ParseOptions(ctx.Query)
+			if err != nil {
+				return httpx.BadRequestError(err)
+			}
+			if !opts.IsZero() {
+				return a.serveImage(ctx, filename, opts, sharedHeaders)
+			}
 		}
-		// result.Reader is expected to be closed by httpx.DataFromReader, so we don't close it here.
-		return ctx.DataFromReader(200, result.MIME, result.Reader, int(result.Size))
-	})
+		return a.serveFile(ctx, filename, sharedHeaders)
+	}
+	route.Handle(http.MethodGet, path, handler)
+	route.Handle(http.MethodHead, path, handler)
 }
 
+// RegisterFileUploader mounts the upload routes: PUT /:key for tokens from GenerateUploadAuth and
+// PUT /:key/:part for the part URLs from GenerateMultipartUploadAuth. Tokens are single use, and
+// uploads that break the token's upload policy are rejected with 413 for oversized bodies, 415 for
+// disallowed content types and 400 for existing keys under InsertOnly. A body that turns out to
+// exceed MaxSize while streaming fails the upload. The body is stored under a staging key and
+// moved to its key only once it is complete and the hooks registered with WithUploadCompletionHook
+// accept it, so a failed upload never replaces an existing file.
 func (a *FileServer) RegisterFileUploader(route httpx.Router) {
 	route.Handle(http.MethodPut, "/:key", func(ctx httpx.Context) error {
 		key := ctx.Param("key")
@@ -186,16 +250,54 @@ func (a *FileServer) RegisterFileUploader(route httpx.Router) {
 		if err != nil {
 			return httpx.InternalServerError(err)
 		}
+		policy, err := a.takeUploadPolicy(ctx.Context(), key)
+		if err != nil {
+			return httpx.InternalServerError(err)
+		}
+		if err = a.checkUploadPolicy(ctx, string(filename), policy); err != nil {
+			return err
+		}
 		data := ctx.BodyReader()
 		if data == nil {
 			return httpx.NewBadRequestError("empty request body")
 		}
-		uploadKey, err := a.UploadFile(ctx.Context(), data, string(filename))
+		var body io.Reader = data
+		if policy.MaxSize > 0 {
+			body = &maxSizeReader{reader: data, remaining: policy.MaxSize}
+		}
+		if a.opts.contentChecker != nil {
+			checked, _, cErr := a.opts.contentChecker.CheckStream(ctx.Context(), body, string(filename))
+			if cErr != nil {
+				return cErr
+			}
+			defer func() {
+				_ = checked.Close()
+			}()
+			body = checked
+		}
+		counter := &countingReader{reader: body}
+		stagingKey, err := a.UploadFile(ctx.Context(), counter, uploadStagingKey(string(filename)))
+		if errors.Is(err, storageerr.ErrorFileTooLarge) {
+			_ = a.DeleteFile(ctx.Context(), stagingKey)
+			return err
+		}
 		if err != nil {
 			return httpx.InternalServerError(err)
 		}
-		return a.opts.uploadSuccessWithData(ctx, uploadKey, a.GenerateURL(uploadKey))
+		if err = a.completeUpload(ctx, string(filename), stagingKey, counter.size); err != nil {
+			_ = a.DeleteFile(ctx.Context(), stagingKey)
+			return err
+		}
+		if err = a.MoveFile(ctx.Context(), stagingKey, string(filename), !policy.InsertOnly); err != nil {
+			_ = a.DeleteFile(ctx.Context(), stagingKey)
+			if errors.Is(err, storageerr.ErrorDistExisted) {
+				return err
+			}
+			return httpx.InternalServerError(err)
+		}
+		return a.opts.uploadSuccessWithData(ctx, string(filename), a.GenerateURL(string(filename)))
 	})
+	route.Handle(http.MethodPut, "/:key/:part", a.uploadPart)
 }
 
 func normalizeWildcardParam(raw string) string {
@@ -0,0 +1,226 @@
+package fileserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/server/httpz"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+	"github.com/google/uuid"
+)
+
+// multipartTokenPrefix keeps multipart upload tokens apart from single upload tokens in the cache,
+// so that neither can be used on the other's route.
+const multipartTokenPrefix = "multipart:"
+
+// multipartToken is the cached target of a multipart upload token.
+type multipartToken struct {
+	Key      string `json:"key"`
+	UploadID string `json:"upload_id"`
+	// Parts holds the part numbers the token was issued for.
+	Parts []int `json:"parts"`
+}
+
+// multipart returns the store's multipart capability.
+func (a *FileServer) multipart() (storage.MultipartUploader, error) {
+	uploader, ok := a.store.(storage.MultipartUploader)
+	if !ok {
+		return nil, errors.New("store does not support multipart upload")
+	}
+	return uploader, nil
+}
+
+func (a *FileServer) InitiateMultipartUpload(ctx context.Context, key string) (string, error) {
+	uploader, err := a.multipart()
+	if err != nil {
+		return "", err
+	}
+	return uploader.InitiateMultipartUpload(ctx, key)
+}
+
+func (a *FileServer) UploadPart(ctx context.Context, key string, uploadID string, partNumber int, part io.Reader, size int64) (storage.UploadedPart, error) {
+	uploader, err := a.multipart()
+	if err != nil {
+		return storage.UploadedPart{}, err
+	}
+	return uploader.UploadPart(ctx, key, uploadID, partNumber, part, size)
+}
+
+func (a *FileServer) ListParts(ctx context.Context, key string, uploadID string) ([]storage.UploadedPart, error) {
+	uploader, err := a.multipart()
+	if err != nil {
+		return nil, err
+	}
+	return uploader.ListParts(ctx, key, uploadID)
+}
+
+// CompleteMultipartUpload assembles the parts into the file. With WithUploadContentCheck, the
+// assembled file is checked and deleted if it fails the checks.
+func (a *FileServer) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []storage.UploadedPart) (string, error) {
+	uploader, err := a.multipart()
+	if err != nil {
+		return "", err
+	}
+	stored, err := uploader.CompleteMultipartUpload(ctx, key, uploadID, parts)
+	if err != nil {
+		return "", err
+	}
+	if err = a.checkStoredContent(ctx, stored); err != nil {
+		_ = a.DeleteFile(context.WithoutCancel(ctx), stored)
+		return "", err
+	}
+	return stored, nil
+}
+
+// checkStoredContent runs the content checker of WithUploadContentCheck over a stored file.
+func (a *FileServer) checkStoredContent(ctx context.Context, key string) error {
+	if a.opts.contentChecker == nil {
+		return nil
+	}
+	result, err := a.DownloadFile(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = result.Reader.Close()
+	}()
+	checked, _, err := a.opts.contentChecker.CheckStream(ctx, result.Reader, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = checked.Close()
+	}()
+	_, err = io.Copy(io.Discard, checked)
+	return err
+}
+
+func (a *FileServer) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	uploader, err := a.multipart()
+	if err != nil {
+		return err
+	}
+	return uploader.AbortMultipartUpload(ctx, key, uploadID)
+}
+
+// GenerateMultipartUploadAuth initiates a multipart upload in the underlying store and returns
+// a temporary PUT URL per part. The store must implement storage.MultipartUploader.
+func (a *FileServer) GenerateMultipartUploadAuth(ctx context.Context, req storage.MultipartUploadAuthRequest) (storage.MultipartUploadAuthResult, error) {
+	if !req.UploadPolicy.IsZero() {
+		return storage.MultipartUploadAuthResult{}, storageerr.ErrorUploadPolicyUnsupported
+	}
+	partNumbers, err := storage.PartNumbers(req.PartCount)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	fileName, err := storage.BuildUploadFileName(req.FileName, a.config.UploadNaming)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	key, err := storage.JoinUploadKey(a.config.Dir, req.Dir, fileName)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	uploadID, err := a.InitiateMultipartUpload(ctx, key)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	parts, err := a.GeneratePartUploadAuth(ctx, key, uploadID, partNumbers)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	return storage.MultipartUploadAuthResult{
+		UploadID: uploadID,
+		Parts:    parts,
+		File: storage.UploadFileInfo{
+			Key: key,
+			URL: a.GenerateURL(key),
+		},
+	}, nil
+}
+
+// GeneratePartUploadAuth creates temporary PUT URLs for parts of an existing multipart upload.
+// All URLs share one token, which stays valid for KeyTTL so that failed parts can be retried,
+// and which only accepts the requested part numbers.
+func (a *FileServer) GeneratePartUploadAuth(ctx context.Context, key string, uploadID string, partNumbers []int) ([]storage.PartUploadAuthorization, error) {
+	for _, partNumber := range partNumbers {
+		if err := storage.ValidatePartNumber(partNumber); err != nil {
+			return nil, err
+		}
+	}
+	raw, err := json.Marshal(multipartToken{Key: key, UploadID: uploadID, Parts: partNumbers})
+	if err != nil {
+		return nil, err
+	}
+	token := uuid.NewString()
+	err = a.cache.SetWithTTL(ctx, multipartTokenPrefix+token, raw, a.config.KeyTTL)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]storage.PartUploadAuthorization, 0, len(partNumbers))
+	for _, partNumber := range partNumbers {
+		uri, uErr := url.JoinPath(a.config.PutBase, token, strconv.Itoa(partNumber))
+		if uErr != nil {
+			return nil, uErr
+		}
+		parts = append(parts, storage.PartUploadAuthorization{
+			PartNumber: partNumber,
+			Authorization: storage.UploadAuthorization{
+				Type:   storage.UploadAuthorizationTypeURL,
+				Value:  uri,
+				Method: http.MethodPut,
+			},
+		})
+	}
+	return parts, nil
+}
+
+// uploadPart handles PUT /:key/:part, storing the request body as a part of the multipart upload
+// the token was issued for, if the token was issued for the part number. The part ETag is returned both as a header and in the JSON body.
+func (a *FileServer) uploadPart(ctx httpx.Context) error {
+	raw, found, err := a.cache.Get(ctx.Context(), multipartTokenPrefix+ctx.Param("key"))
+	if err != nil {
+		return httpx.InternalServerError(err)
+	}
+	if !found {
+		return httpx.NewBadRequestError("key expires or not found")
+	}
+	var token multipartToken
+	if err = json.Unmarshal(raw, &token); err != nil {
+		return httpx.InternalServerError(err)
+	}
+	partNumber, err := strconv.Atoi(ctx.Param("part"))
+	if err != nil || !slices.Contains(token.Parts, partNumber) {
+		return httpx.NewBadRequestError("invalid part number")
+	}
+	size := int64(-1)
+	if length := ctx.Header("Content-Length"); length != "" {
+		if size, err = strconv.ParseInt(length, 10, 64); err != nil {
+			return httpx.NewBadRequestError("invalid content length")
+		}
+	}
+	data := ctx.BodyReader()
+	if data == nil {
+		return httpx.NewBadRequestError("empty request body")
+	}
+	part, err := a.UploadPart(ctx.Context(), token.Key, token.UploadID, partNumber, data, size)
+	if err != nil {
+		if errors.Is(err, storageerr.ErrorUploadNotFound) {
+			return httpx.NotFoundError(err)
+		}
+		if errors.Is(err, storageerr.ErrorPartInvalid) {
+			return httpx.BadRequestError(err)
+		}
+		return httpx.InternalServerError(err)
+	}
+	ctx.SetHeader("ETag", part.ETag)
+	return ctx.JSON(200, httpz.DataResponse[storage.UploadedPart]{Data: part})
+}
@@ -5,7 +5,10 @@ import (
 	"strconv"
 
 	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/mq"
 	"github.com/go-sphere/sphere/server/httpz"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/imageproc"
 	"github.com/google/uuid"
 )
 
@@ -18,6 +21,9 @@ type options struct {
 	uploadSuccessWithData func(ctx httpx.Context, key, url string) error
 	createFileKey         func(ctx context.Context, server *FileServer, filename string) (string, error)
 	downloadCacheControl  string
+	uploadCompletionHooks []storage.UploadCompletionHook
+	imageProcessor        *imageproc.Processor
+	contentChecker        *httpz.ContentChecker
 }
 
 // Option configures file server behavior.
@@ -40,6 +46,43 @@ func WithCacheControl(maxAge uint64) Option {
 	}
 }
 
+// WithUploadCompletionHook registers a hook that RegisterFileUploader calls after a PUT upload is stored.
+// Hooks run in registration order; an error from a hook deletes the file and fails the request,
+// so hooks may validate uploads as well as record them. An httpx error keeps its status code.
+func WithUploadCompletionHook(hook storage.UploadCompletionHook) Option {
+	return func(o *options) {
+		if hook == nil {
+			return
+		}
+		o.uploadCompletionHooks = append(o.uploadCompletionHooks, hook)
+	}
+}
+
+// PublishUploadCompletions returns a hook that broadcasts upload completions to the topic.
+func PublishUploadCompletions(pubsub mq.PubSub[storage.UploadCompletion], topic string) storage.UploadCompletionHook {
+	return func(ctx context.Context, completion storage.UploadCompletion) error {
+		return pubsub.Broadcast(ctx, topic, completion)
+	}
+}
+
+// WithImageProcessor lets RegisterFileDownloader process images requested with the w, h, mode,
+// format and q query parameters of imageproc.ParseOptions. Public servers should restrict the
+// variants with imageproc.WithPresets, since every variant is processed and cached.
+func WithImageProcessor(processor *imageproc.Processor) Option {
+	return func(o *options) {
+		o.imageProcessor = processor
+	}
+}
+
+// WithUploadContentCheck validates the content of PUT uploads to RegisterFileUploader before it is
+// stored, with the same checks as httpz.WithFormContentCheck. Multipart uploads are checked once
+// CompleteMultipartUpload assembles them, and the file is deleted if it fails the checks.
+func WithUploadContentCheck(checks ...httpz.ContentCheckOption) Option {
+	return func(o *options) {
+		o.contentChecker = httpz.NewContentChecker(checks...)
+	}
+}
+
 func newOptions(opts ...Option) *options {
 	opt := &options{
 		uploadSuccessWithData: defaultUploadSuccessWithData,
@@ -0,0 +1,132 @@
+package fileserver
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/storage/imageproc"
+)
+
+// Query parameters of signed download URLs.
+const (
+	signedURLExpiresParam   = "expires"
+	signedURLKeyIDParam     = "key_id"
+	signedURLSignatureParam = "signature"
+)
+
+var (
+	errSignedURLRequired = httpx.NewForbiddenError("signed url required")
+	errSignatureInvalid  = httpx.NewForbiddenError("signature invalid")
+	errSignedURLExpired  = httpx.NewForbiddenError("signed url expired")
+)
+
+// SigningKey is a secret for signing download URLs. The ID is embedded in signed URLs
+// to select the verifying key, so it must be unique and must not be reused for another secret.
+type SigningKey struct {
+	ID     string `json:"id" yaml:"id"`
+	Secret string `json:"secret" yaml:"secret"`
+}
+
+func validateSigningKeys(keys []SigningKey) error {
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		if key.ID == "" || key.Secret == "" {
+			return errors.New("signing key id and secret are required")
+		}
+		if _, ok := seen[key.ID]; ok {
+			return errors.New("duplicate signing key id: " + key.ID)
+		}
+		seen[key.ID] = struct{}{}
+	}
+	return nil
+}
+
+// GenerateSignedURL creates a download URL signed with HMAC-SHA256 by the first signing key.
+// RegisterFileDownloader rejects it after it expires or if its key, expiry or signature is altered.
+func (a *FileServer) GenerateSignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return a.GenerateSignedImageURL(ctx, key, imageproc.Options{}, expires)
+}
+
+// GenerateSignedImageURL creates a signed download URL of the image processed with the options,
+// for servers with WithImageProcessor. The image query parameters are signed as well, so that
+// the URL cannot be altered to request other variants.
+func (a *FileServer) GenerateSignedImageURL(ctx context.Context, key string, opts imageproc.Options, expires time.Duration) (string, error) {
+	if len(a.config.SigningKeys) == 0 {
+		return "", errors.New("signing_keys is required for signed urls")
+	}
+	if expires <= 0 {
+		return "", errors.New("expires must be positive")
+	}
+	query := opts.Values()
+	// Sign the options as the downloader parses them.
+	image, err := imageproc.ParseOptions(query.Get)
+	if err != nil {
+		return "", err
+	}
+	signingKey := a.config.SigningKeys[0]
+	deadline := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query.Set(signedURLExpiresParam, deadline)
+	query.Set(signedURLKeyIDParam, signingKey.ID)
+	query.Set(signedURLSignatureParam, signURL(signingKey.Secret, strings.TrimPrefix(key, "/"), deadline, image))
+	uri := a.GenerateURL(key)
+	if uri == "" {
+		return "", errors.New("invalid key")
+	}
+	return uri + "?" + query.Encode(), nil
+}
+
+// verifySignedURL checks the signature of a download request. Requests without a signature
+// are only rejected with PrivateDownload, while a present but invalid signature is always rejected.
+func (a *FileServer) verifySignedURL(key string, query func(string) string, now time.Time) error {
+	signature := query(signedURLSignatureParam)
+	if signature == "" {
+		if a.config.PrivateDownload {
+			return errSignedURLRequired
+		}
+		return nil
+	}
+	deadline := query(signedURLExpiresParam)
+	expiresAt, err := strconv.ParseInt(deadline, 10, 64)
+	if err != nil {
+		return errSignatureInvalid
+	}
+	image, err := imageproc.ParseOptions(query)
+	if err != nil {
+		return errSignatureInvalid
+	}
+	keyID := query(signedURLKeyIDParam)
+	for _, signingKey := range a.config.SigningKeys {
+		if signingKey.ID != keyID {
+			continue
+		}
+		if !hmac.Equal([]byte(signature), []byte(signURL(signingKey.Secret, key, deadline, image))) {
+			return errSignatureInvalid
+		}
+		if now.Unix() >= expiresAt {
+			return errSignedURLExpired
+		}
+		return nil
+	}
+	return errSignatureInvalid
+}
+
+// signURL signs the key, expiry and image options; the key ID is not signed, since it only selects
+// the secret. URLs without image options keep the signature of the key and expiry alone.
+func signURL(secret string, key string, deadline string, image imageproc.Options) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(key))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(deadline))
+	if !image.IsZero() {
+		mac.Write([]byte{'\n'})
+		mac.Write([]byte(image.String()))
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
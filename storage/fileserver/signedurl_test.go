@@ -0,0 +1,168 @@
+package fileserver
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/cache/memory"
+	"github.com/go-sphere/sphere/storage/imageproc"
+)
+
+func newSigningServer(t *testing.T, keys ...SigningKey) *FileServer {
+	t.Helper()
+	server, err := NewCDNAdapter(Config{
+		PutBase:     "https://example.com/upload",
+		GetBase:     "https://example.com/files",
+		SigningKeys: keys,
+	}, memory.NewByteCache(), noopStorage{})
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	return server
+}
+
+func signedQuery(t *testing.T, server *FileServer, key string, expires time.Duration) url.Values {
+	t.Helper()
+	uri, err := server.GenerateSignedURL(context.Background(), key, expires)
+	if err != nil {
+		t.Fatalf("GenerateSignedURL() error = %v", err)
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("parse signed url: %v", err)
+	}
+	if !strings.HasPrefix(uri, "https://example.com/files/"+key+"?") {
+		t.Fatalf("signed url = %q, want the download url with a query", uri)
+	}
+	return parsed.Query()
+}
+
+func TestFileServer_verifySignedURL(t *testing.T) {
+	oldKey := SigningKey{ID: "2024", Secret: "old-secret"}
+	newKey := SigningKey{ID: "2025", Secret: "new-secret"}
+	server := newSigningServer(t, newKey, oldKey)
+	now := time.Now()
+	query := signedQuery(t, server, "docs/a.pdf", time.Hour)
+
+	t.Run("valid until expiry", func(t *testing.T) {
+		if err := server.verifySignedURL("docs/a.pdf", query.Get, now); err != nil {
+			t.Fatalf("verifySignedURL() error = %v", err)
+		}
+		if query.Get(signedURLKeyIDParam) != newKey.ID {
+			t.Fatalf("key_id = %q, want the first key %q", query.Get(signedURLKeyIDParam), newKey.ID)
+		}
+		if err := server.verifySignedURL("docs/a.pdf", query.Get, now.Add(2*time.Hour)); !errors.Is(err, errSignedURLExpired) {
+			t.Fatalf("verifySignedURL() after expiry error = %v, want %v", err, errSignedURLExpired)
+		}
+	})
+
+	t.Run("tampering", func(t *testing.T) {
+		if err := server.verifySignedURL("docs/b.pdf", query.Get, now); !errors.Is(err, errSignatureInvalid) {
+			t.Fatalf("other key error = %v, want %v", err, errSignatureInvalid)
+		}
+		for param, value := range map[string]string{
+			signedURLExpiresParam:   "99999999999",
+			signedURLSignatureParam: strings.Repeat("A", 43),
+			signedURLKeyIDParam:     oldKey.ID,
+		} {
+			tampered := url.Values{}
+			for k, v := range query {
+				tampered[k] = v
+			}
+			tampered.Set(param, value)
+			if err := server.verifySignedURL("docs/a.pdf", tampered.Get, now); !errors.Is(err, errSignatureInvalid) {
+				t.Fatalf("tampered %s error = %v, want %v", param, err, errSignatureInvalid)
+			}
+		}
+		tampered := url.Values{signedURLSignatureParam: query[signedURLSignatureParam], signedURLKeyIDParam: {"unknown"}, signedURLExpiresParam: query[signedURLExpiresParam]}
+		if err := server.verifySignedURL("docs/a.pdf", tampered.Get, now); !errors.Is(err, errSignatureInvalid) {
+			t.Fatalf("unknown key id error = %v, want %v", err, errSignatureInvalid)
+		}
+	})
+
+	t.Run("rotation", func(t *testing.T) {
+		previous := newSigningServer(t, oldKey)
+		oldQuery := signedQuery(t, previous, "docs/a.pdf", time.Hour)
+		if err := server.verifySignedURL("docs/a.pdf", oldQuery.Get, now); err != nil {
+			t.Fatalf("URL signed by the previous key error = %v", err)
+		}
+		rotated := newSigningServer(t, newKey)
+		if err := rotated.verifySignedURL("docs/a.pdf", oldQuery.Get, now); !errors.Is(err, errSignatureInvalid) {
+			t.Fatalf("URL signed by a removed key error = %v, want %v", err, errSignatureInvalid)
+		}
+	})
+
+	t.Run("image options", func(t *testing.T) {
+		uri, err := server.GenerateSignedImageURL(context.Background(), "photos/a.png", imageproc.Options{Width: 200, Format: "JPG"}, time.Hour)
+		if err != nil {
+			t.Fatalf("GenerateSignedImageURL() error = %v", err)
+		}
+		parsed, err := url.Parse(uri)
+		if err != nil {
+			t.Fatalf("parse signed url: %v", err)
+		}
+		imageQuery := parsed.Query()
+		if imageQuery.Get(imageproc.WidthParam) != "200" {
+			t.Fatalf("signed image url = %q, want the image parameters", uri)
+		}
+		if err = server.verifySignedURL("photos/a.png", imageQuery.Get, now); err != nil {
+			t.Fatalf("verifySignedURL() error = %v", err)
+		}
+		for param, value := range map[string]string{imageproc.WidthParam: "201", imageproc.HeightParam: "50", imageproc.QualityParam: "10"} {
+			tampered := url.Values{}
+			for k, v := range imageQuery {
+				tampered[k] = v
+			}
+			tampered.Set(param, value)
+			if err = server.verifySignedURL("photos/a.png", tampered.Get, now); !errors.Is(err, errSignatureInvalid) {
+				t.Fatalf("tampered %s error = %v, want %v", param, err, errSignatureInvalid)
+			}
+		}
+		withImage := url.Values{}
+		for k, v := range query {
+			withImage[k] = v
+		}
+		withImage.Set(imageproc.WidthParam, "4096")
+		if err = server.verifySignedURL("docs/a.pdf", withImage.Get, now); !errors.Is(err, errSignatureInvalid) {
+			t.Fatalf("image parameters added to a signed url error = %v, want %v", err, errSignatureInvalid)
+		}
+	})
+
+	t.Run("unsigned requests", func(t *testing.T) {
+		if err := server.verifySignedURL("docs/a.pdf", url.Values{}.Get, now); err != nil {
+			t.Fatalf("unsigned public request error = %v", err)
+		}
+		server.config.PrivateDownload = true
+		defer func() { server.config.PrivateDownload = false }()
+		if err := server.verifySignedURL("docs/a.pdf", url.Values{}.Get, now); !errors.Is(err, errSignedURLRequired) {
+			t.Fatalf("unsigned private request error = %v, want %v", err, errSignedURLRequired)
+		}
+	})
+}
+
+func TestNewCDNAdapter_ValidateSigningKeys(t *testing.T) {
+	base := Config{PutBase: "https://example.com", GetBase: "https://example.com"}
+	invalid := map[string]Config{
+		"private without keys": {PutBase: base.PutBase, GetBase: base.GetBase, PrivateDownload: true},
+		"empty secret":         {PutBase: base.PutBase, GetBase: base.GetBase, SigningKeys: []SigningKey{{ID: "a"}}},
+		"duplicate id": {PutBase: base.PutBase, GetBase: base.GetBase, SigningKeys: []SigningKey{
+			{ID: "a", Secret: "x"}, {ID: "a", Secret: "y"},
+		}},
+	}
+	for name, conf := range invalid {
+		if _, err := NewCDNAdapter(conf, memory.NewByteCache(), noopStorage{}); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+	server, err := NewCDNAdapter(base, memory.NewByteCache(), noopStorage{})
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	if _, err = server.GenerateSignedURL(context.Background(), "a.txt", time.Minute); err == nil {
+		t.Fatal("GenerateSignedURL() without signing keys expected error")
+	}
+}
@@ -0,0 +1,158 @@
+package fileserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+	"github.com/google/uuid"
+)
+
+// uploadPolicyPrefix keeps the upload policy of a token apart from the token in the cache.
+const uploadPolicyPrefix = "policy:"
+
+// saveUploadPolicy caches the policy of an upload token. When the policy sets Expires, the token
+// is stored again with that TTL, since WithCreateFileKey implementations store it with KeyTTL.
+func (a *FileServer) saveUploadPolicy(ctx context.Context, token string, key string, policy storage.UploadPolicy) error {
+	if policy.IsZero() {
+		return nil
+	}
+	ttl := a.config.KeyTTL
+	if policy.Expires > 0 {
+		ttl = policy.Expires
+		if err := a.cache.SetWithTTL(ctx, token, []byte(key), ttl); err != nil {
+			return err
+		}
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return a.cache.SetWithTTL(ctx, uploadPolicyPrefix+token, raw, ttl)
+}
+
+// takeUploadPolicy returns and removes the cached policy of an upload token.
+// Tokens without a cached policy have no constraints.
+func (a *FileServer) takeUploadPolicy(ctx context.Context, token string) (storage.UploadPolicy, error) {
+	raw, found, err := a.cache.Get(ctx, uploadPolicyPrefix+token)
+	if err != nil || !found {
+		return storage.UploadPolicy{}, err
+	}
+	if err = a.cache.Del(ctx, uploadPolicyPrefix+token); err != nil {
+		return storage.UploadPolicy{}, err
+	}
+	var policy storage.UploadPolicy
+	if err = json.Unmarshal(raw, &policy); err != nil {
+		return storage.UploadPolicy{}, err
+	}
+	return policy, nil
+}
+
+// checkUploadPolicy rejects an upload request that the policy does not allow before reading the body.
+// The content type is the request's Content-Type, or the one derived from the key extension without it.
+func (a *FileServer) checkUploadPolicy(ctx httpx.Context, key string, policy storage.UploadPolicy) error {
+	if len(policy.AllowedMIMETypes) > 0 {
+		contentType := ctx.Header("Content-Type")
+		if contentType == "" {
+			contentType = mime.TypeByExtension(path.Ext(key))
+		}
+		if !storage.MatchMIMEType(policy.AllowedMIMETypes, contentType) {
+			return storageerr.ErrorMIMETypeNotAllowed
+		}
+	}
+	if length := ctx.Header("Content-Length"); length != "" && policy.MaxSize > 0 {
+		size, err := strconv.ParseInt(length, 10, 64)
+		if err != nil {
+			return httpx.NewBadRequestError("invalid content length")
+		}
+		if size > policy.MaxSize {
+			return storageerr.ErrorFileTooLarge
+		}
+	}
+	if policy.InsertOnly {
+		exists, err := a.IsFileExists(ctx.Context(), key)
+		if err != nil {
+			return httpx.InternalServerError(err)
+		}
+		if exists {
+			return storageerr.ErrorDistExisted
+		}
+	}
+	return nil
+}
+
+// maxSizeReader fails with storageerr.ErrorFileTooLarge once more than remaining bytes are read,
+// for request bodies without a Content-Length or with a wrong one.
+type maxSizeReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *maxSizeReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, storageerr.ErrorFileTooLarge
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n, storageerr.ErrorFileTooLarge
+	}
+	return n, err
+}
+
+// uploadStagingPrefix holds PUT uploads until they are moved to their key.
+const uploadStagingPrefix = ".uploads/"
+
+// uploadStagingKey returns a unique staging key for an upload to key, keeping its extension.
+func uploadStagingKey(key string) string {
+	return uploadStagingPrefix + uuid.NewString() + path.Ext(key)
+}
+
+// completeUpload runs the upload completion hooks for a file stored by a PUT upload under stagingKey
+// and bound for key. The file information comes from the store when it implements storage.FileStatter,
+// and from the request otherwise.
+func (a *FileServer) completeUpload(ctx httpx.Context, key string, stagingKey string, size int64) error {
+	if len(a.opts.uploadCompletionHooks) == 0 {
+		return nil
+	}
+	var info storage.FileInfo
+	if statter, ok := a.store.(storage.FileStatter); ok {
+		stat, err := statter.StatFile(ctx.Context(), stagingKey)
+		if err != nil {
+			return httpx.InternalServerError(err)
+		}
+		info = stat.FileInfo
+		info.Key = key
+	} else {
+		info = storage.FileInfo{Key: key, Size: size, ModTime: time.Now(), MIME: ctx.Header("Content-Type")}
+		if info.MIME == "" {
+			info.MIME = mime.TypeByExtension(path.Ext(key))
+		}
+	}
+	completion := storage.UploadCompletion{FileInfo: info, URL: a.GenerateURL(key)}
+	for _, hook := range a.opts.uploadCompletionHooks {
+		if err := hook(ctx.Context(), completion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countingReader counts the bytes of a request body as it is stored.
+type countingReader struct {
+	reader io.Reader
+	size   int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.size += int64(n)
+	return n, err
+}
@@ -0,0 +1,346 @@
+// Package imageproc resizes, crops and converts stored images with the standard library codecs,
+// caching the results in a storage.Storage.
+package imageproc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+var (
+	// ErrInvalidOptions indicates malformed image options or a size above the dimension limit.
+	ErrInvalidOptions = errors.New("imageproc: invalid options")
+	// ErrImageTooLarge indicates a source image above the pixel or size limit.
+	ErrImageTooLarge = errors.New("imageproc: image too large")
+	// ErrUnsupportedImage indicates a source that is not a JPEG, PNG or GIF image.
+	ErrUnsupportedImage = errors.New("imageproc: unsupported image")
+)
+
+// Mode decides how an image is fitted to the requested width and height.
+type Mode string
+
+const (
+	// ModeFit scales the image down to fit within the width and height, keeping its aspect ratio.
+	// Images that already fit are not enlarged.
+	ModeFit Mode = "fit"
+	// ModeResize scales the image to exactly the width and height, ignoring its aspect ratio.
+	ModeResize Mode = "resize"
+	// ModeCrop scales the image to cover the width and height, keeping its aspect ratio,
+	// and cuts out the center.
+	ModeCrop Mode = "crop"
+)
+
+// Output formats.
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatGIF  = "gif"
+)
+
+// Query parameters read by ParseOptions.
+const (
+	WidthParam   = "w"
+	HeightParam  = "h"
+	ModeParam    = "mode"
+	FormatParam  = "format"
+	QualityParam = "q"
+)
+
+// Options describe the processing of an image. With only a width or a height,
+// the other side follows the aspect ratio in every mode.
+type Options struct {
+	Width   int
+	Height  int
+	Mode    Mode
+	Format  string // the source format when empty
+	Quality int    // JPEG quality from 1 to 100; 75 when zero
+}
+
+// IsZero reports whether the options request no processing.
+func (o Options) IsZero() bool {
+	return o.Width == 0 && o.Height == 0 && o.Format == "" && o.Quality == 0
+}
+
+// String returns the options in a canonical form, used in cache keys.
+func (o Options) String() string {
+	mode := o.Mode
+	if mode == "" {
+		mode = ModeFit
+	}
+	return "w=" + strconv.Itoa(o.Width) + ",h=" + strconv.Itoa(o.Height) + ",mode=" + string(mode) +
+		",format=" + o.Format + ",q=" + strconv.Itoa(o.Quality)
+}
+
+// ParseOptions reads options from the w, h, mode, format and q query parameters.
+func ParseOptions(query func(string) string) (Options, error) {
+	var opts Options
+	var err error
+	if opts.Width, err = parseParam(query(WidthParam)); err != nil {
+		return Options{}, err
+	}
+	if opts.Height, err = parseParam(query(HeightParam)); err != nil {
+		return Options{}, err
+	}
+	if opts.Quality, err = parseParam(query(QualityParam)); err != nil {
+		return Options{}, err
+	}
+	opts.Mode = Mode(query(ModeParam))
+	opts.Format = query(FormatParam)
+	opts = opts.normalize()
+	if err = opts.validate(0); err != nil {
+		return Options{}, err
+	}
+	return opts, nil
+}
+
+// Values returns the options as the query parameters read by ParseOptions.
+func (o Options) Values() url.Values {
+	values := url.Values{}
+	for param, value := range map[string]int{WidthParam: o.Width, HeightParam: o.Height, QualityParam: o.Quality} {
+		if value != 0 {
+			values.Set(param, strconv.Itoa(value))
+		}
+	}
+	if o.Mode != "" {
+		values.Set(ModeParam, string(o.Mode))
+	}
+	if o.Format != "" {
+		values.Set(FormatParam, o.Format)
+	}
+	return values
+}
+
+// normalize lower-cases the mode and format and spells the JPEG format as ParseOptions does.
+func (o Options) normalize() Options {
+	o.Mode = Mode(strings.ToLower(string(o.Mode)))
+	o.Format = strings.ToLower(o.Format)
+	if o.Format == "jpg" {
+		o.Format = FormatJPEG
+	}
+	return o
+}
+
+func parseParam(raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		return 0, ErrInvalidOptions
+	}
+	return value, nil
+}
+
+// validate checks the options; a positive maxDimension also bounds the width and height.
+func (o Options) validate(maxDimension int) error {
+	if o.Width < 0 || o.Height < 0 || o.Quality < 0 || o.Quality > 100 {
+		return ErrInvalidOptions
+	}
+	if maxDimension > 0 && (o.Width > maxDimension || o.Height > maxDimension) {
+		return ErrInvalidOptions
+	}
+	switch o.Mode {
+	case "", ModeFit, ModeResize, ModeCrop:
+	default:
+		return ErrInvalidOptions
+	}
+	switch o.Format {
+	case "", FormatJPEG, FormatPNG, FormatGIF:
+	default:
+		return ErrInvalidOptions
+	}
+	return nil
+}
+
+type options struct {
+	cacheDir string
+	limits   Limits
+	presets  map[string]struct{}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		cacheDir: "imageproc",
+		limits: Limits{
+			MaxDimension:    4096,
+			MaxSourcePixels: 25_000_000,
+			MaxSourceSize:   32 << 20, // 32 MiB
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Option configures a Processor.
+type Option func(*options)
+
+// WithCacheDir sets the key prefix of processed images in the cache storage.
+func WithCacheDir(dir string) Option {
+	return func(o *options) {
+		o.cacheDir = strings.Trim(dir, "/")
+	}
+}
+
+// WithMaxDimension sets the largest width or height that may be requested. The default is 4096.
+func WithMaxDimension(size int) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.limits.MaxDimension = size
+		}
+	}
+}
+
+// WithMaxSourcePixels sets the largest source image, in pixels, that is decoded. The default is 25 million.
+func WithMaxSourcePixels(pixels int64) Option {
+	return func(o *options) {
+		if pixels > 0 {
+			o.limits.MaxSourcePixels = pixels
+		}
+	}
+}
+
+// WithMaxSourceSize sets the largest encoded source image in bytes. The default is 32 MiB.
+func WithMaxSourceSize(size int64) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.limits.MaxSourceSize = size
+		}
+	}
+}
+
+// WithPresets restricts the processor to the given options, such as the sizes a site displays, so
+// that requests cannot fill the cache with arbitrary variants. Any options are processed by default.
+func WithPresets(presets ...Options) Option {
+	return func(o *options) {
+		if o.presets == nil {
+			o.presets = make(map[string]struct{}, len(presets))
+		}
+		for _, preset := range presets {
+			o.presets[preset.normalize().String()] = struct{}{}
+		}
+	}
+}
+
+// Processor processes images of a store and caches the results.
+type Processor struct {
+	opts  *options
+	cache storage.Storage
+}
+
+// NewProcessor creates a processor that caches processed images in cache.
+// A nil cache processes every request again.
+func NewProcessor(cache storage.Storage, options ...Option) *Processor {
+	return &Processor{
+		opts:  newOptions(options...),
+		cache: cache,
+	}
+}
+
+// Transform returns the image stored at key in store, processed with the options. Results are
+// cached under a key derived from the source ETag and modification time, so a changed source is
+// processed again; the returned ETag identifies the processed image in the same way.
+// With WithPresets, other options fail with ErrInvalidOptions.
+// The source is only downloaded on cache misses when the store implements storage.FileStatter.
+func (p *Processor) Transform(ctx context.Context, store storage.Storage, key string, opts Options) (storage.DownloadResult, error) {
+	opts = opts.normalize()
+	if err := opts.validate(p.opts.limits.MaxDimension); err != nil {
+		return storage.DownloadResult{}, err
+	}
+	if p.opts.presets != nil {
+		if _, ok := p.opts.presets[opts.String()]; !ok {
+			return storage.DownloadResult{}, ErrInvalidOptions
+		}
+	}
+	var source *storage.DownloadResult
+	var version string
+	if statter, ok := store.(storage.FileStatter); ok {
+		stat, err := statter.StatFile(ctx, key)
+		if err != nil {
+			return storage.DownloadResult{}, err
+		}
+		version = stat.ETag + "@" + strconv.FormatInt(stat.ModTime.UnixNano(), 10)
+	} else {
+		result, err := store.DownloadFile(ctx, key)
+		if err != nil {
+			return storage.DownloadResult{}, err
+		}
+		defer func() { _ = result.Reader.Close() }()
+		source = &result
+		version = result.ETag + "@" + strconv.FormatInt(result.ModTime.UnixNano(), 10)
+	}
+	sum := sha256.Sum256([]byte(key + "\n" + version + "\n" + opts.String()))
+	etag := hex.EncodeToString(sum[:16])
+	cacheKey := path.Join(p.opts.cacheDir, key, etag)
+
+	if p.cache != nil {
+		cached, err := p.cache.DownloadFile(ctx, cacheKey)
+		if err == nil {
+			cached.ETag = etag
+			if !strings.HasPrefix(cached.MIME, "image/") {
+				// Caches without upload options derive the type from the key, which has no extension.
+				reader := bufio.NewReader(cached.Reader)
+				head, _ := reader.Peek(512)
+				cached.MIME = http.DetectContentType(head)
+				cached.Reader = readCloser{Reader: reader, Closer: cached.Reader}
+			}
+			return cached, nil
+		}
+		if !errors.Is(err, storageerr.ErrorNotFound) {
+			return storage.DownloadResult{}, err
+		}
+	}
+
+	if source == nil {
+		result, err := store.DownloadFile(ctx, key)
+		if err != nil {
+			return storage.DownloadResult{}, err
+		}
+		defer func() { _ = result.Reader.Close() }()
+		source = &result
+	}
+	var buf bytes.Buffer
+	mimeType, err := Process(source.Reader, &buf, opts, p.opts.limits)
+	if err != nil {
+		return storage.DownloadResult{}, err
+	}
+	if p.cache != nil {
+		_, err = uploadCached(ctx, p.cache, cacheKey, buf.Bytes(), mimeType)
+		if err != nil {
+			return storage.DownloadResult{}, err
+		}
+	}
+	return storage.DownloadResult{
+		Reader: io.NopCloser(bytes.NewReader(buf.Bytes())),
+		MIME:   mimeType,
+		Size:   int64(buf.Len()),
+		ETag:   etag,
+	}, nil
+}
+
+// uploadCached stores a processed image, with its content type when the cache supports upload options.
+func uploadCached(ctx context.Context, cache storage.Storage, key string, data []byte, mimeType string) (string, error) {
+	if uploader, ok := cache.(storage.FileOptionsUploader); ok {
+		return uploader.UploadFileWithOptions(ctx, bytes.NewReader(data), key, storage.UploadOptions{ContentType: mimeType})
+	}
+	return cache.UploadFile(ctx, bytes.NewReader(data), key)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
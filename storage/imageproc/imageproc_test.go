@@ -0,0 +1,268 @@
+package imageproc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/go-sphere/sphere/cache/memory"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/kvcache"
+)
+
+func encodePNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+func defaultLimits() Limits {
+	return newOptions().limits
+}
+
+func TestParseOptions(t *testing.T) {
+	query := map[string]string{"w": "120", "h": "80", "mode": "CROP", "format": "jpg", "q": "90"}
+	opts, err := ParseOptions(func(key string) string { return query[key] })
+	if err != nil {
+		t.Fatalf("ParseOptions() error = %v", err)
+	}
+	want := Options{Width: 120, Height: 80, Mode: ModeCrop, Format: FormatJPEG, Quality: 90}
+	if opts != want {
+		t.Fatalf("ParseOptions() = %+v, want %+v", opts, want)
+	}
+	if opts, err = ParseOptions(func(string) string { return "" }); err != nil || !opts.IsZero() {
+		t.Fatalf("ParseOptions() without parameters = %+v, %v, want zero options", opts, err)
+	}
+	for _, invalid := range []map[string]string{
+		{"w": "0"},
+		{"w": "-5"},
+		{"h": "abc"},
+		{"q": "101"},
+		{"mode": "stretch"},
+		{"format": "webp"},
+	} {
+		if _, err = ParseOptions(func(key string) string { return invalid[key] }); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("ParseOptions(%v) error = %v, want %v", invalid, err, ErrInvalidOptions)
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	source := encodePNG(t, 200, 100, red)
+	tests := []struct {
+		name   string
+		opts   Options
+		width  int
+		height int
+		format string
+	}{
+		{"fit", Options{Width: 50, Height: 50}, 50, 25, "png"},
+		{"fit without enlarging", Options{Width: 400}, 200, 100, "png"},
+		{"resize", Options{Width: 30, Height: 40, Mode: ModeResize}, 30, 40, "png"},
+		{"resize by width", Options{Width: 40, Mode: ModeResize}, 40, 20, "png"},
+		{"crop", Options{Width: 50, Height: 50, Mode: ModeCrop}, 50, 50, "png"},
+		{"enlarge", Options{Width: 300, Height: 300, Mode: ModeCrop}, 300, 300, "png"},
+		{"jpeg", Options{Width: 20, Format: FormatJPEG, Quality: 60}, 20, 10, "jpeg"},
+		{"gif", Options{Format: FormatGIF}, 200, 100, "gif"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			mimeType, err := Process(bytes.NewReader(source), &out, tt.opts, defaultLimits())
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if mimeType != "image/"+tt.format {
+				t.Fatalf("Process() MIME = %q, want image/%s", mimeType, tt.format)
+			}
+			img, format, err := image.Decode(&out)
+			if err != nil {
+				t.Fatalf("decode result: %v", err)
+			}
+			if format != tt.format || img.Bounds().Dx() != tt.width || img.Bounds().Dy() != tt.height {
+				t.Fatalf("result = %s %dx%d, want %s %dx%d", format, img.Bounds().Dx(), img.Bounds().Dy(), tt.format, tt.width, tt.height)
+			}
+			// Resampling a solid image must keep its color everywhere, including the edges.
+			for _, p := range []image.Point{img.Bounds().Min, img.Bounds().Max.Sub(image.Pt(1, 1))} {
+				r, g, b, a := img.At(p.X, p.Y).RGBA()
+				if r>>8 < 250 || g>>8 > 5 || b>>8 > 5 || a>>8 != 255 {
+					t.Fatalf("pixel at %v = %d,%d,%d,%d, want red", p, r>>8, g>>8, b>>8, a>>8)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessLimits(t *testing.T) {
+	source := encodePNG(t, 100, 100, color.White)
+	limits := defaultLimits()
+	limits.MaxSourcePixels = 100 * 99
+	if _, err := Process(bytes.NewReader(source), io.Discard, Options{Width: 10}, limits); !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("Process() over the pixel limit error = %v, want %v", err, ErrImageTooLarge)
+	}
+	limits = defaultLimits()
+	limits.MaxSourceSize = int64(len(source) - 1)
+	if _, err := Process(bytes.NewReader(source), io.Discard, Options{Width: 10}, limits); !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("Process() over the size limit error = %v, want %v", err, ErrImageTooLarge)
+	}
+	limits = defaultLimits()
+	limits.MaxDimension = 64
+	if _, err := Process(bytes.NewReader(source), io.Discard, Options{Width: 65}, limits); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("Process() over the dimension limit error = %v, want %v", err, ErrInvalidOptions)
+	}
+	if _, err := Process(strings.NewReader("not an image"), io.Discard, Options{Width: 10}, defaultLimits()); !errors.Is(err, ErrUnsupportedImage) {
+		t.Fatalf("Process() of text error = %v, want %v", err, ErrUnsupportedImage)
+	}
+}
+
+func TestProcessExtremeAspectRatio(t *testing.T) {
+	// A thin source within the pixel limit must not scale into a huge image.
+	thin := encodePNG(t, 16000, 1, color.Black)
+	for _, opts := range []Options{
+		{Height: 512, Mode: ModeResize},
+		{Height: 512, Mode: ModeCrop},
+		{Height: 512},
+	} {
+		_, err := Process(bytes.NewReader(thin), io.Discard, opts, defaultLimits())
+		if opts.Mode == "" {
+			// Fit never enlarges.
+			if err != nil {
+				t.Fatalf("Process(%+v) error = %v", opts, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrImageTooLarge) {
+			t.Fatalf("Process(%+v) error = %v, want %v", opts, err, ErrImageTooLarge)
+		}
+	}
+
+	// Cropping scales only the part of the source that covers the box.
+	var buf bytes.Buffer
+	if _, err := Process(bytes.NewReader(thin), &buf, Options{Width: 512, Height: 512, Mode: ModeCrop, Format: FormatPNG}, defaultLimits()); err != nil {
+		t.Fatalf("Process() crop error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil || img.Bounds().Dx() != 512 || img.Bounds().Dy() != 512 {
+		t.Fatalf("crop result = %v, %v, want 512x512", img.Bounds(), err)
+	}
+}
+
+func newMemoryStorage(t *testing.T) *kvcache.Client {
+	t.Helper()
+	byteCache := memory.NewByteCache()
+	t.Cleanup(func() { _ = byteCache.Close() })
+	store, err := kvcache.NewClient(kvcache.Config{}, byteCache)
+	if err != nil {
+		t.Fatalf("new kvcache client: %v", err)
+	}
+	return store
+}
+
+// countingStorage counts downloads of the source image.
+type countingStorage struct {
+	*kvcache.Client
+	downloads int
+}
+
+func (s *countingStorage) DownloadFile(ctx context.Context, key string) (storage.DownloadResult, error) {
+	s.downloads++
+	return s.Client.DownloadFile(ctx, key)
+}
+
+func TestProcessor_Transform(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStorage{Client: newMemoryStorage(t)}
+	processor := NewProcessor(newMemoryStorage(t))
+	if _, err := store.UploadFile(ctx, bytes.NewReader(encodePNG(t, 64, 64, color.Black)), "photos/a.png"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+
+	opts := Options{Width: 16, Format: FormatJPEG}
+	first, err := processor.Transform(ctx, store, "photos/a.png", opts)
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	_ = first.Reader.Close()
+	second, err := processor.Transform(ctx, store, "photos/a.png", opts)
+	if err != nil {
+		t.Fatalf("Transform() cached error = %v", err)
+	}
+	data, _ := io.ReadAll(second.Reader)
+	_ = second.Reader.Close()
+	if store.downloads != 1 {
+		t.Fatalf("source downloads = %d, want 1 with a cached result", store.downloads)
+	}
+	if second.ETag != first.ETag || second.MIME != "image/jpeg" || int64(len(data)) != first.Size {
+		t.Fatalf("cached result = %+v, want the first result %+v", second, first)
+	}
+
+	other, err := processor.Transform(ctx, store, "photos/a.png", Options{Width: 8})
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	_ = other.Reader.Close()
+	if other.ETag == first.ETag {
+		t.Fatal("different options share an ETag")
+	}
+
+	if _, err = store.UploadFile(ctx, bytes.NewReader(encodePNG(t, 32, 32, color.White)), "photos/a.png"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	changed, err := processor.Transform(ctx, store, "photos/a.png", opts)
+	if err != nil {
+		t.Fatalf("Transform() after change error = %v", err)
+	}
+	_ = changed.Reader.Close()
+	if changed.ETag == first.ETag {
+		t.Fatal("a changed source kept the cached result")
+	}
+}
+
+func TestOptionsValues(t *testing.T) {
+	opts := Options{Width: 120, Mode: ModeCrop, Format: FormatPNG}
+	values := opts.Values()
+	if values.Encode() != "format=png&mode=crop&w=120" {
+		t.Fatalf("Values() = %q", values.Encode())
+	}
+	parsed, err := ParseOptions(values.Get)
+	if err != nil || parsed != opts {
+		t.Fatalf("ParseOptions(Values()) = %+v, %v, want %+v", parsed, err, opts)
+	}
+}
+
+func TestProcessor_TransformPresets(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStorage(t)
+	processor := NewProcessor(newMemoryStorage(t), WithPresets(Options{Width: 16, Format: "JPG"}, Options{Width: 64, Height: 64, Mode: ModeCrop}))
+	if _, err := store.UploadFile(ctx, bytes.NewReader(encodePNG(t, 64, 64, color.Black)), "photos/a.png"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	for _, opts := range []Options{{Width: 16, Format: FormatJPEG}, {Width: 64, Height: 64, Mode: ModeCrop}} {
+		result, err := processor.Transform(ctx, store, "photos/a.png", opts)
+		if err != nil {
+			t.Fatalf("Transform(%+v) error = %v", opts, err)
+		}
+		_ = result.Reader.Close()
+	}
+	for _, opts := range []Options{{Width: 17, Format: FormatJPEG}, {Width: 16}, {Width: 64, Height: 64}} {
+		if _, err := processor.Transform(ctx, store, "photos/a.png", opts); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("Transform(%+v) error = %v, want %v", opts, err, ErrInvalidOptions)
+		}
+	}
+}
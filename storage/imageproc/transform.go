@@ -0,0 +1,253 @@
+package imageproc
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+)
+
+// Limits bound the work of processing a single image.
+type Limits struct {
+	// MaxDimension is the largest width or height of a processed image.
+	MaxDimension int
+	// MaxSourcePixels is the largest width times height of a source image. It is checked
+	// from the image header before decoding, so oversized images never allocate their pixels.
+	MaxSourcePixels int64
+	// MaxSourceSize is the largest encoded source image in bytes.
+	MaxSourceSize int64
+}
+
+// Process decodes a JPEG, PNG or GIF image from r, applies the options and writes the result to w.
+// It returns the MIME type of the written image. GIF sources are processed by their first frame.
+func Process(r io.Reader, w io.Writer, opts Options, limits Limits) (string, error) {
+	if err := opts.validate(limits.MaxDimension); err != nil {
+		return "", err
+	}
+	raw, err := io.ReadAll(io.LimitReader(r, limits.MaxSourceSize+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(raw)) > limits.MaxSourceSize {
+		return "", ErrImageTooLarge
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return "", ErrUnsupportedImage
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > limits.MaxSourcePixels {
+		return "", ErrImageTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return "", ErrUnsupportedImage
+	}
+	if opts.Format != "" {
+		format = opts.Format
+	}
+	result, err := transform(src, opts, limits)
+	if err != nil {
+		return "", err
+	}
+	return encode(w, result, format, opts.Quality)
+}
+
+// transform scales and crops the image to the requested size. Sizes derived from the source aspect
+// ratio are bounded by the limits like requested ones, so extreme sources fail with ErrImageTooLarge.
+func transform(src image.Image, opts Options, limits Limits) (image.Image, error) {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if opts.Width == 0 && opts.Height == 0 {
+		return src, nil
+	}
+	var w, h int
+	switch opts.Mode {
+	case ModeResize:
+		w, h = scaledSize(sw, sh, opts.Width, opts.Height)
+	case ModeCrop:
+		if opts.Width == 0 || opts.Height == 0 {
+			w, h = scaledSize(sw, sh, opts.Width, opts.Height)
+			break
+		}
+		// Cut the part of the source that covers the box, then scale only that part.
+		w, h = opts.Width, opts.Height
+		scale := math.Max(float64(w)/float64(sw), float64(h)/float64(sh))
+		cw := min(sw, max(1, int(math.Round(float64(w)/scale))))
+		ch := min(sh, max(1, int(math.Round(float64(h)/scale))))
+		x, y := src.Bounds().Min.X+(sw-cw)/2, src.Bounds().Min.Y+(sh-ch)/2
+		src = subImage(src, image.Rect(x, y, x+cw, y+ch))
+	default:
+		// Fit within the box without enlarging.
+		scale := 1.0
+		if opts.Width > 0 {
+			scale = math.Min(scale, float64(opts.Width)/float64(sw))
+		}
+		if opts.Height > 0 {
+			scale = math.Min(scale, float64(opts.Height)/float64(sh))
+		}
+		if scale == 1 {
+			return src, nil
+		}
+		w = max(1, int(math.Round(float64(sw)*scale)))
+		h = max(1, int(math.Round(float64(sh)*scale)))
+	}
+	if (limits.MaxDimension > 0 && (w > limits.MaxDimension || h > limits.MaxDimension)) ||
+		(limits.MaxSourcePixels > 0 && int64(w)*int64(h) > limits.MaxSourcePixels) {
+		return nil, ErrImageTooLarge
+	}
+	return resample(src, w, h), nil
+}
+
+// subImage returns the part of the image within r, copying it if the image cannot share its pixels.
+func subImage(src image.Image, r image.Rectangle) image.Image {
+	if sub, ok := src.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(r)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, r.Min, draw.Src)
+	return dst
+}
+
+// scaledSize fills in a missing width or height from the source aspect ratio.
+func scaledSize(sw, sh, w, h int) (int, int) {
+	if w == 0 {
+		w = max(1, int(math.Round(float64(sw)*float64(h)/float64(sh))))
+	}
+	if h == 0 {
+		h = max(1, int(math.Round(float64(sh)*float64(w)/float64(sw))))
+	}
+	return w, h
+}
+
+// weights holds the contributions of source pixels start, start+1, ... to one destination pixel.
+type weights struct {
+	start  int
+	values []float32
+}
+
+// filterWeights computes a triangle filter from srcLen to dstLen pixels. When shrinking, the filter
+// widens with the scale so that every source pixel contributes, which avoids aliasing.
+func filterWeights(srcLen, dstLen int) []weights {
+	scale := float64(srcLen) / float64(dstLen)
+	support := math.Max(scale, 1)
+	result := make([]weights, dstLen)
+	for i := range result {
+		center := (float64(i)+0.5)*scale - 0.5
+		start := max(0, int(math.Ceil(center-support)))
+		end := min(srcLen-1, int(math.Floor(center+support)))
+		values := make([]float32, 0, end-start+1)
+		var sum float64
+		for j := start; j <= end; j++ {
+			weight := 1 - math.Abs(float64(j)-center)/support
+			if weight < 0 {
+				weight = 0
+			}
+			values = append(values, float32(weight))
+			sum += weight
+		}
+		if sum == 0 {
+			// The center falls exactly between pixels at an edge; take the nearest one.
+			start, values, sum = min(max(0, int(math.Round(center))), srcLen-1), []float32{1}, 1
+		}
+		for k := range values {
+			values[k] /= float32(sum)
+		}
+		result[i] = weights{start: start, values: values}
+	}
+	return result
+}
+
+// resample scales the image to w by h pixels in two separable passes over premultiplied RGBA.
+// The pass that yields the smaller intermediate image runs first, so the intermediate never
+// exceeds the larger of the source and the result.
+func resample(src image.Image, w, h int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	if int64(w)*int64(bounds.Dy()) <= int64(bounds.Dx())*int64(h) {
+		return scaleY(scaleX(rgba, w), h)
+	}
+	return scaleX(scaleY(rgba, h), w)
+}
+
+// scaleX scales the width of the image to w pixels.
+func scaleX(src *image.RGBA, w int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, sh))
+	xWeights := filterWeights(sw, w)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[y*src.Stride:]
+		out := dst.Pix[y*dst.Stride:]
+		for x, wt := range xWeights {
+			var acc [4]float32
+			for k, v := range wt.values {
+				p := row[(wt.start+k)*4:]
+				acc[0] += v * float32(p[0])
+				acc[1] += v * float32(p[1])
+				acc[2] += v * float32(p[2])
+				acc[3] += v * float32(p[3])
+			}
+			storePixel(out[x*4:], acc)
+		}
+	}
+	return dst
+}
+
+// scaleY scales the height of the image to h pixels.
+func scaleY(src *image.RGBA, h int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, sw, h))
+	yWeights := filterWeights(sh, h)
+	for y, wt := range yWeights {
+		out := dst.Pix[y*dst.Stride:]
+		for x := 0; x < sw; x++ {
+			var acc [4]float32
+			for k, v := range wt.values {
+				p := src.Pix[(wt.start+k)*src.Stride+x*4:]
+				acc[0] += v * float32(p[0])
+				acc[1] += v * float32(p[1])
+				acc[2] += v * float32(p[2])
+				acc[3] += v * float32(p[3])
+			}
+			storePixel(out[x*4:], acc)
+		}
+	}
+	return dst
+}
+
+func storePixel(p []uint8, acc [4]float32) {
+	for i, v := range acc {
+		p[i] = uint8(min(255, max(0, v+0.5)))
+	}
+	// Rounding must not leave a premultiplied channel above alpha.
+	for i := range 3 {
+		p[i] = min(p[i], p[3])
+	}
+}
+
+func encode(w io.Writer, img image.Image, format string, quality int) (string, error) {
+	switch format {
+	case FormatJPEG:
+		if quality == 0 {
+			quality = jpeg.DefaultQuality
+		}
+		// JPEG has no alpha channel, so transparent areas become white rather than black.
+		opaque := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(opaque, opaque.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		draw.Draw(opaque, opaque.Bounds(), img, img.Bounds().Min, draw.Over)
+		return "image/jpeg", jpeg.Encode(w, opaque, &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		return "image/png", png.Encode(w, img)
+	case FormatGIF:
+		return "image/gif", gif.Encode(w, img, &gif.Options{NumColors: 256})
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedImage, format)
+	}
+}
@@ -3,10 +3,16 @@ package kvcache
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"mime"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -36,23 +42,62 @@ func NewClient(conf Config, cache cache.ByteCache) (*Client, error) {
 	}, nil
 }
 
+// metadataKeyPrefix namespaces the metadata records of files uploaded with options.
+// Storage keys never start with a NUL byte, so the records cannot collide with files.
+const metadataKeyPrefix = "\x00metadata:"
+
 // keyPreprocess removes leading slash from storage keys to ensure cache key consistency.
 func (c *Client) keyPreprocess(key string) string {
 	return strings.TrimPrefix(key, "/")
 }
 
-// UploadFile stores file data in the cache with the specified key and expiration time.
+// setAll stores the values with the configured expiration time.
+func (c *Client) setAll(ctx context.Context, values map[string][]byte) error {
+	if c.config.Expires != nil {
+		return c.cache.MultiSetWithTTL(ctx, values, *c.config.Expires)
+	}
+	return c.cache.MultiSet(ctx, values)
+}
+
+// UploadFile stores file data in the cache with the specified key and expiration time,
+// and removes the metadata of a previous upload with options.
 func (c *Client) UploadFile(ctx context.Context, file io.Reader, key string) (string, error) {
 	key = c.keyPreprocess(key)
 	all, err := io.ReadAll(file)
 	if err != nil {
 		return "", err
 	}
-	if c.config.Expires != nil {
-		err = c.cache.SetWithTTL(ctx, key, all, *c.config.Expires)
-	} else {
-		err = c.cache.Set(ctx, key, all)
+	err = c.setAll(ctx, map[string][]byte{key: all})
+	if err != nil {
+		return "", err
+	}
+	err = c.cache.Del(ctx, metadataKeyPrefix+key)
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// UploadFileWithOptions stores file data like UploadFile, together with a metadata record
+// that expires with the file.
+func (c *Client) UploadFileWithOptions(ctx context.Context, file io.Reader, key string, opts storage.UploadOptions) (string, error) {
+	key = c.keyPreprocess(key)
+	opts, err := storage.NormalizeUploadOptions(key, opts)
+	if err != nil {
+		return "", err
+	}
+	metadata, err := json.Marshal(opts)
+	if err != nil {
+		return "", err
+	}
+	all, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
 	}
+	err = c.setAll(ctx, map[string][]byte{
+		key:                     all,
+		metadataKeyPrefix + key: metadata,
+	})
 	if err != nil {
 		return "", err
 	}
@@ -79,27 +124,79 @@ func (c *Client) IsFileExists(ctx context.Context, key string) (bool, error) {
 }
 
 // DownloadFile retrieves file data from the cache storage.
-// Returns the file content reader, MIME type based on file extension, and content size.
+// Returns the file content reader and size, the stored metadata, and the MIME type
+// stored on upload or based on file extension.
 func (c *Client) DownloadFile(ctx context.Context, key string) (storage.DownloadResult, error) {
-	key = c.keyPreprocess(key)
-	data, found, err := c.cache.Get(ctx, key)
+	stat, data, err := c.statFile(ctx, key)
 	if err != nil {
 		return storage.DownloadResult{}, err
 	}
-	if !found {
-		return storage.DownloadResult{}, storageerr.ErrorNotFound
+	return storage.DownloadResult{
+		Reader:       io.NopCloser(bytes.NewReader(data)),
+		MIME:         stat.MIME,
+		Size:         stat.Size,
+		ETag:         stat.ETag,
+		FileMetadata: stat.FileMetadata,
+	}, nil
+}
+
+// DownloadFileRange retrieves part of the file data from the cache storage.
+func (c *Client) DownloadFileRange(ctx context.Context, key string, offset int64, length int64) (storage.DownloadResult, error) {
+	stat, data, err := c.statFile(ctx, key)
+	if err != nil {
+		return storage.DownloadResult{}, err
+	}
+	length, err = storage.RangeLength(offset, length, stat.Size)
+	if err != nil {
+		return storage.DownloadResult{}, err
 	}
 	return storage.DownloadResult{
-		Reader: io.NopCloser(bytes.NewReader(data)),
-		MIME:   mime.TypeByExtension(filepath.Ext(key)),
-		Size:   int64(len(data)),
+		Reader:       io.NopCloser(bytes.NewReader(data[offset : offset+length])),
+		MIME:         stat.MIME,
+		Size:         length,
+		ETag:         stat.ETag,
+		FileMetadata: stat.FileMetadata,
 	}, nil
 }
 
-// DeleteFile removes a file from the cache storage.
+// StatFile returns the file information together with the metadata stored on upload.
+// The cache does not track modification times, so ModTime is always zero.
+func (c *Client) StatFile(ctx context.Context, key string) (storage.FileStat, error) {
+	stat, _, err := c.statFile(ctx, key)
+	return stat, err
+}
+
+func (c *Client) statFile(ctx context.Context, key string) (storage.FileStat, []byte, error) {
+	key = c.keyPreprocess(key)
+	values, err := c.cache.MultiGet(ctx, []string{key, metadataKeyPrefix + key})
+	if err != nil {
+		return storage.FileStat{}, nil, err
+	}
+	data, found := values[key]
+	if !found {
+		return storage.FileStat{}, nil, storageerr.ErrorNotFound
+	}
+	opts := storage.UploadOptions{ContentType: mime.TypeByExtension(filepath.Ext(key))}
+	if raw, ok := values[metadataKeyPrefix+key]; ok {
+		if err = json.Unmarshal(raw, &opts); err != nil {
+			return storage.FileStat{}, nil, fmt.Errorf("decode metadata of %q: %w", key, err)
+		}
+	}
+	return storage.FileStat{
+		FileInfo: storage.FileInfo{
+			Key:  key,
+			Size: int64(len(data)),
+			ETag: contentETag(data),
+			MIME: opts.ContentType,
+		},
+		FileMetadata: opts.FileMetadata,
+	}, data, nil
+}
+
+// DeleteFile removes a file and its metadata from the cache storage.
 func (c *Client) DeleteFile(ctx context.Context, key string) error {
 	key = c.keyPreprocess(key)
-	err := c.cache.Del(ctx, key)
+	err := c.cache.MultiDel(ctx, []string{key, metadataKeyPrefix + key})
 	if err != nil {
 		return err
 	}
@@ -115,14 +212,14 @@ func (c *Client) MoveFile(ctx context.Context, sourceKey string, destinationKey
 	if err != nil {
 		return err
 	}
-	err = c.cache.Del(ctx, sourceKey)
+	err = c.cache.MultiDel(ctx, []string{sourceKey, metadataKeyPrefix + sourceKey})
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// CopyFile duplicates a file from source to destination key within cache storage.
+// CopyFile duplicates a file and its metadata from source to destination key within cache storage.
 // Validates overwrite permissions and handles cache expiration settings.
 func (c *Client) CopyFile(ctx context.Context, sourceKey string, destinationKey string, overwrite bool) error {
 	sourceKey = c.keyPreprocess(sourceKey)
@@ -136,20 +233,74 @@ func (c *Client) CopyFile(ctx context.Context, sourceKey string, destinationKey
 			return storageerr.ErrorDistExisted
 		}
 	}
-	value, found, err := c.cache.Get(ctx, sourceKey)
+	values, err := c.cache.MultiGet(ctx, []string{sourceKey, metadataKeyPrefix + sourceKey})
 	if err != nil {
 		return err
 	}
+	value, found := values[sourceKey]
 	if !found {
 		return storageerr.ErrorNotFound
 	}
-	if c.config.Expires != nil {
-		err = c.cache.SetWithTTL(ctx, destinationKey, value, *c.config.Expires)
-	} else {
-		err = c.cache.Set(ctx, destinationKey, value)
+	copied := map[string][]byte{destinationKey: value}
+	if metadata, ok := values[metadataKeyPrefix+sourceKey]; ok {
+		copied[metadataKeyPrefix+destinationKey] = metadata
+	} else if err = c.cache.Del(ctx, metadataKeyPrefix+destinationKey); err != nil {
+		return err
 	}
+	err = c.setAll(ctx, copied)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// ListFiles lists the stored files when the cache implements cache.KeyLister; the in-memory
+// ristretto cache does not. The cache should hold only files, for example through an nscache namespace.
+// Sizes and ETags are computed from the cached contents, MIME types are based on file extension,
+// and modification times are not tracked.
+func (c *Client) ListFiles(ctx context.Context, req storage.ListFilesRequest) (storage.ListFilesResult, error) {
+	lister, ok := c.cache.(cache.KeyLister)
+	if !ok {
+		return storage.ListFilesResult{}, errors.New("cache does not support key listing")
+	}
+	keys, err := lister.Keys(ctx, req.Prefix)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	keys = slices.DeleteFunc(keys, func(key string) bool {
+		return strings.HasPrefix(key, metadataKeyPrefix)
+	})
+	slices.Sort(keys)
+	files, prefixes, next, err := storage.PageKeys(keys, req)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	values, err := c.cache.MultiGet(ctx, files)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	result := storage.ListFilesResult{
+		Files:      make([]storage.FileInfo, 0, len(files)),
+		Prefixes:   prefixes,
+		NextCursor: next,
+	}
+	for _, key := range files {
+		// Skip files that expired after the keys were listed.
+		data, found := values[key]
+		if !found {
+			continue
+		}
+		result.Files = append(result.Files, storage.FileInfo{
+			Key:  key,
+			Size: int64(len(data)),
+			ETag: contentETag(data),
+			MIME: mime.TypeByExtension(filepath.Ext(key)),
+		})
+	}
+	return result, nil
+}
+
+func contentETag(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
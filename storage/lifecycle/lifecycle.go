@@ -0,0 +1,360 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sphere/sphere/core/task"
+	"github.com/go-sphere/sphere/log"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+var ErrExecutorAlreadyStarted = errors.New("lifecycle executor already started")
+
+// Storage is the storage whose files the rules are applied to.
+type Storage interface {
+	storage.FileLister
+	storage.FileDeleter
+	storage.FileMoverCopier
+}
+
+// TagReader provides the tags of stored files, for backends with tags apart from user metadata.
+type TagReader interface {
+	// FileTags returns the tags of a file.
+	// Returns storageerr.ErrorNotFound if the file does not exist.
+	FileTags(ctx context.Context, key string) (map[string]string, error)
+}
+
+// NativeLifecycle is implemented by backends that enforce lifecycle rules themselves.
+type NativeLifecycle interface {
+	// SupportsLifecycleRule reports whether the backend can enforce the rule.
+	SupportsLifecycleRule(rule Rule) bool
+
+	// ApplyLifecycleRules installs the rules, all of them supported, in the backend. Rules installed
+	// before with the same IDs are replaced, while other rules of the backend are left untouched.
+	ApplyLifecycleRules(ctx context.Context, rules []Rule) error
+}
+
+// Match describes a file matched by a rule, and the outcome of its action.
+type Match struct {
+	RuleID  string    `json:"rule_id"`
+	Key     string    `json:"key"`
+	Action  Action    `json:"action"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// Destination is the key of an archived file.
+	Destination string `json:"destination,omitempty"`
+	// Error is set if the action failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Report describes one application of the rules.
+type Report struct {
+	DryRun bool `json:"dry_run"`
+	// Native lists the IDs of the rules enforced by the storage, which were not scanned.
+	Native []string `json:"native,omitempty"`
+	// Scanned counts the listed files in scope of a rule.
+	Scanned  int     `json:"scanned"`
+	Matches  []Match `json:"matches"`
+	Deleted  int     `json:"deleted"`
+	Archived int     `json:"archived"`
+	Failed   int     `json:"failed"`
+}
+
+// Executor applies lifecycle rules by listing the files of a storage. It is a task that applies
+// them periodically, and can apply them on demand with RunOnce. The age of a file is taken from
+// its listed modification time; files of backends that do not track it are never matched.
+type Executor struct {
+	storage  Storage
+	rules    []Rule
+	archives []string
+	opts     *options
+	now      func() time.Time
+
+	loop task.Loop
+	// mu guards the native rules, installed once by the first run.
+	mu      sync.Mutex
+	applied bool
+	native  map[string]bool
+}
+
+// NewExecutor creates a lifecycle executor applying the rules to the storage in order.
+// A file is handled by the first rule that matches it. Archive rules leave out the files under
+// the archive prefix of any rule, so that archived files are not archived again.
+func NewExecutor(s Storage, rules []Rule, opt ...Option) (*Executor, error) {
+	if s == nil {
+		return nil, errors.New("storage is required")
+	}
+	opts := newOptions(opt...)
+	if opts.interval <= 0 {
+		return nil, errors.New("lifecycle interval must be positive")
+	}
+	ids := make(map[string]struct{}, len(rules))
+	var archives []string
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := ids[rule.ID]; ok {
+			return nil, errors.New("duplicate lifecycle rule id: " + rule.ID)
+		}
+		ids[rule.ID] = struct{}{}
+		if rule.Action == ActionArchive {
+			archives = append(archives, rule.archivePrefix())
+		}
+	}
+	return &Executor{
+		storage:  s,
+		rules:    append([]Rule(nil), rules...),
+		archives: archives,
+		opts:     opts,
+		now:      time.Now,
+	}, nil
+}
+
+// Identifier returns the executor's identifier for logging and debugging purposes.
+func (e *Executor) Identifier() string {
+	return "storage_lifecycle"
+}
+
+// Start applies the rules until the context is cancelled or Stop is called.
+// The report of each run is passed to the report handler; runs that fail are logged and
+// retried on the next interval. A stopped executor can be started again.
+func (e *Executor) Start(ctx context.Context) error {
+	return e.loop.Run(ctx, ErrExecutorAlreadyStarted, func(ctx context.Context) error {
+		ticker := time.NewTicker(e.opts.interval)
+		defer ticker.Stop()
+		for {
+			report, err := e.RunOnce(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Warn("storage lifecycle run failed", log.Err(err))
+				}
+			} else {
+				e.opts.reportHandler(report)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop signals the loop to exit and waits for the in-flight run to finish.
+func (e *Executor) Stop(ctx context.Context) error {
+	return e.loop.Stop(ctx)
+}
+
+// RunOnce applies the rules once and reports the matched files. Failed actions are reported in
+// their matches without stopping the run; an error is returned if the storage cannot be listed
+// or the native rules cannot be installed.
+func (e *Executor) RunOnce(ctx context.Context) (Report, error) {
+	report := Report{DryRun: e.opts.dryRun, Matches: []Match{}}
+	native, err := e.applyNative(ctx)
+	if err != nil {
+		return report, err
+	}
+	now := e.now()
+	handled := make(map[string]struct{})
+	var nativeRules []Rule
+	for _, rule := range e.rules {
+		if native[rule.ID] {
+			report.Native = append(report.Native, rule.ID)
+			nativeRules = append(nativeRules, rule)
+			continue
+		}
+		if err = e.runRule(ctx, rule, now, nativeRules, handled, &report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// applyNative installs the natively supported rules once and returns their IDs.
+func (e *Executor) applyNative(ctx context.Context) (map[string]bool, error) {
+	backend, ok := e.storage.(NativeLifecycle)
+	if !ok || !e.opts.native || e.opts.dryRun {
+		return nil, nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.applied {
+		return e.native, nil
+	}
+	native := make(map[string]bool)
+	var rules []Rule
+	for i, rule := range e.rules {
+		if backend.SupportsLifecycleRule(rule) && !overlapsEarlier(e.rules[:i], rule) {
+			native[rule.ID] = true
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) > 0 {
+		if err := backend.ApplyLifecycleRules(ctx, rules); err != nil {
+			return nil, err
+		}
+	}
+	e.applied = true
+	e.native = native
+	return native, nil
+}
+
+// overlapsEarlier reports whether an earlier rule may match files in scope of the rule.
+func overlapsEarlier(earlier []Rule, rule Rule) bool {
+	for _, r := range earlier {
+		if strings.HasPrefix(r.Prefix, rule.Prefix) || strings.HasPrefix(rule.Prefix, r.Prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// runRule lists the files in scope of the rule and applies its action to the expired ones.
+// Files matched by an earlier native rule are left to the storage.
+func (e *Executor) runRule(ctx context.Context, rule Rule, now time.Time, nativeRules []Rule, handled map[string]struct{}, report *Report) error {
+	req := storage.ListFilesRequest{Prefix: rule.Prefix}
+	for {
+		page, err := e.storage.ListFiles(ctx, req)
+		if err != nil {
+			return err
+		}
+		for _, file := range page.Files {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if _, ok := handled[file.Key]; ok || e.archived(rule, file.Key) {
+				continue
+			}
+			report.Scanned++
+			native, mErr := e.matchesAny(ctx, nativeRules, file, now)
+			if mErr != nil {
+				return mErr
+			}
+			if native {
+				handled[file.Key] = struct{}{}
+				continue
+			}
+			ok, mErr := e.matches(ctx, rule, file, now)
+			if mErr != nil {
+				return mErr
+			}
+			if !ok {
+				continue
+			}
+			match := e.apply(ctx, rule, file, report)
+			handled[file.Key] = struct{}{}
+			if match.Destination != "" {
+				handled[match.Destination] = struct{}{}
+			}
+			report.Matches = append(report.Matches, match)
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		req.Cursor = page.NextCursor
+	}
+}
+
+// matches reports whether a listed file in scope of the rule has expired under it.
+// Files removed since they were listed do not match.
+func (e *Executor) matches(ctx context.Context, rule Rule, file storage.FileInfo, now time.Time) (bool, error) {
+	if file.ModTime.IsZero() || now.Sub(file.ModTime) < rule.MinAge {
+		return false, nil
+	}
+	if len(rule.Tags) == 0 {
+		return true, nil
+	}
+	tags, err := e.fileTags(ctx, file.Key)
+	if errors.Is(err, storageerr.ErrorNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return rule.matchesTags(tags), nil
+}
+
+// matchesAny reports whether a listed file has expired under any of the rules.
+func (e *Executor) matchesAny(ctx context.Context, rules []Rule, file storage.FileInfo, now time.Time) (bool, error) {
+	for _, rule := range rules {
+		if !strings.HasPrefix(file.Key, rule.Prefix) || e.archived(rule, file.Key) {
+			continue
+		}
+		ok, err := e.matches(ctx, rule, file, now)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// archived reports whether an archive rule must leave out an archived file.
+func (e *Executor) archived(rule Rule, key string) bool {
+	if rule.Action != ActionArchive {
+		return false
+	}
+	for _, prefix := range e.archives {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply performs the action of the rule on a file, unless in dry-run mode.
+func (e *Executor) apply(ctx context.Context, rule Rule, file storage.FileInfo, report *Report) Match {
+	match := Match{
+		RuleID:  rule.ID,
+		Key:     file.Key,
+		Action:  rule.Action,
+		Size:    file.Size,
+		ModTime: file.ModTime,
+	}
+	if rule.Action == ActionArchive {
+		match.Destination = rule.archiveKey(file.Key)
+	}
+	if e.opts.dryRun {
+		return match
+	}
+	var err error
+	switch rule.Action {
+	case ActionDelete:
+		err = e.storage.DeleteFile(ctx, file.Key)
+	case ActionArchive:
+		err = e.storage.MoveFile(ctx, file.Key, match.Destination, true)
+	}
+	switch {
+	case errors.Is(err, storageerr.ErrorNotFound):
+		// Removed since it was listed; there is nothing left to do.
+	case err != nil:
+		match.Error = err.Error()
+		report.Failed++
+	case rule.Action == ActionDelete:
+		report.Deleted++
+	default:
+		report.Archived++
+	}
+	return match
+}
+
+// fileTags returns the tags of a file, read from its user metadata without a TagReader.
+func (e *Executor) fileTags(ctx context.Context, key string) (map[string]string, error) {
+	if reader, ok := e.storage.(TagReader); ok {
+		return reader.FileTags(ctx, key)
+	}
+	statter, ok := e.storage.(storage.FileStatter)
+	if !ok {
+		return nil, errors.New("storage does not provide tags")
+	}
+	stat, err := statter.StatFile(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return stat.UserMetadata, nil
+}
@@ -0,0 +1,200 @@
+package lifecycle
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/local"
+)
+
+// nativeStorage enforces delete rules natively, like S3 bucket lifecycle rules.
+type nativeStorage struct {
+	*local.Client
+	applied []Rule
+}
+
+func (s *nativeStorage) SupportsLifecycleRule(rule Rule) bool {
+	return rule.Action == ActionDelete
+}
+
+func (s *nativeStorage) ApplyLifecycleRules(ctx context.Context, rules []Rule) error {
+	s.applied = append(s.applied, rules...)
+	return nil
+}
+
+func newLocalStorage(t *testing.T, now time.Time, ages map[string]time.Duration, tags map[string]map[string]string) *local.Client {
+	t.Helper()
+	root := t.TempDir()
+	client, err := local.NewClient(local.Config{RootDir: root})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	ctx := context.Background()
+	for key, age := range ages {
+		opts := storage.UploadOptions{}
+		opts.UserMetadata = tags[key]
+		if _, err = client.UploadFileWithOptions(ctx, strings.NewReader(key), key, opts); err != nil {
+			t.Fatalf("UploadFileWithOptions(%q) error = %v", key, err)
+		}
+		modTime := now.Add(-age)
+		if err = os.Chtimes(filepath.Join(root, key), modTime, modTime); err != nil {
+			t.Fatalf("Chtimes(%q) error = %v", key, err)
+		}
+	}
+	return client
+}
+
+func TestExecutor(t *testing.T) {
+	now := time.Now()
+	ages := map[string]time.Duration{
+		"tmp/old.bin":       48 * time.Hour,
+		"tmp/new.bin":       time.Hour,
+		"exports/old.csv":   10 * 24 * time.Hour,
+		"exports/keep.csv":  10 * 24 * time.Hour,
+		"exports/fresh.csv": 24 * time.Hour,
+		"avatars/a.png":     100 * 24 * time.Hour,
+	}
+	tags := map[string]map[string]string{
+		"exports/old.csv":  {"kind": "report"},
+		"exports/keep.csv": {"kind": "invoice"},
+	}
+	rules := []Rule{
+		{ID: "tmp", Prefix: "tmp/", MinAge: 24 * time.Hour, Action: ActionDelete},
+		{ID: "reports", Prefix: "exports/", MinAge: 7 * 24 * time.Hour, Tags: map[string]string{"Kind": "report"}, Action: ActionArchive, ArchivePrefix: "archive/"},
+		{ID: "archive", Prefix: "", MinAge: 7 * 24 * time.Hour, Action: ActionArchive, ArchivePrefix: "cold"},
+	}
+	ctx := context.Background()
+
+	t.Run("dry run", func(t *testing.T) {
+		store := newLocalStorage(t, now, ages, tags)
+		executor, err := NewExecutor(store, rules, WithDryRun())
+		if err != nil {
+			t.Fatalf("NewExecutor() error = %v", err)
+		}
+		report, err := executor.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce() error = %v", err)
+		}
+		if !report.DryRun || report.Deleted != 0 || report.Archived != 0 || len(report.Matches) != 4 {
+			t.Fatalf("report = %+v, want 4 matches and no actions", report)
+		}
+		for key := range ages {
+			if exists, _ := store.IsFileExists(ctx, key); !exists {
+				t.Fatalf("dry run removed %s", key)
+			}
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		store := newLocalStorage(t, now, ages, tags)
+		executor, err := NewExecutor(store, rules)
+		if err != nil {
+			t.Fatalf("NewExecutor() error = %v", err)
+		}
+		report, err := executor.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce() error = %v", err)
+		}
+		if report.Deleted != 1 || report.Archived != 3 || report.Failed != 0 {
+			t.Fatalf("report = %+v, want 1 deleted and 3 archived", report)
+		}
+		want := map[string]bool{
+			"tmp/old.bin":             false,
+			"tmp/new.bin":             true,
+			"exports/old.csv":         false,
+			"archive/exports/old.csv": true,
+			"exports/keep.csv":        false,
+			"cold/exports/keep.csv":   true,
+			"exports/fresh.csv":       true,
+			"cold/avatars/a.png":      true,
+		}
+		for key, exists := range want {
+			if got, _ := store.IsFileExists(ctx, key); got != exists {
+				t.Fatalf("IsFileExists(%q) = %v, want %v", key, got, exists)
+			}
+		}
+
+		report, err = executor.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("second RunOnce() error = %v", err)
+		}
+		if len(report.Matches) != 0 {
+			t.Fatalf("second run matches = %+v, want archived files left alone", report.Matches)
+		}
+	})
+
+	t.Run("native rules", func(t *testing.T) {
+		nativeAges := map[string]time.Duration{"tmp/stale.bin": 10 * 24 * time.Hour}
+		maps.Copy(nativeAges, ages)
+		store := &nativeStorage{Client: newLocalStorage(t, now, nativeAges, tags)}
+		executor, err := NewExecutor(store, rules, WithNativeRules())
+		if err != nil {
+			t.Fatalf("NewExecutor() error = %v", err)
+		}
+		for range 2 {
+			report, rErr := executor.RunOnce(ctx)
+			if rErr != nil {
+				t.Fatalf("RunOnce() error = %v", rErr)
+			}
+			if strings.Join(report.Native, ",") != "tmp" {
+				t.Fatalf("native rules = %v, want tmp", report.Native)
+			}
+		}
+		if len(store.applied) != 1 || store.applied[0].ID != "tmp" {
+			t.Fatalf("applied rules = %+v, want tmp installed once", store.applied)
+		}
+		if exists, _ := store.IsFileExists(ctx, "tmp/old.bin"); !exists {
+			t.Fatal("executor deleted a file of a native rule")
+		}
+		if exists, _ := store.IsFileExists(ctx, "tmp/stale.bin"); !exists {
+			t.Fatal("a later rule archived a file of a native rule")
+		}
+	})
+
+	t.Run("native rules after overlapping rules", func(t *testing.T) {
+		store := &nativeStorage{Client: newLocalStorage(t, now, ages, tags)}
+		overlapping := []Rule{
+			{ID: "exports", Prefix: "exports/", MinAge: 7 * 24 * time.Hour, Action: ActionArchive, ArchivePrefix: "archive/"},
+			{ID: "all", Prefix: "", MinAge: 24 * time.Hour, Action: ActionDelete},
+		}
+		executor, err := NewExecutor(store, overlapping, WithNativeRules())
+		if err != nil {
+			t.Fatalf("NewExecutor() error = %v", err)
+		}
+		report, err := executor.RunOnce(ctx)
+		if err != nil {
+			t.Fatalf("RunOnce() error = %v", err)
+		}
+		if len(report.Native) != 0 || len(store.applied) != 0 {
+			t.Fatalf("native rules = %v, want the overlapped rule scanned", report.Native)
+		}
+		if exists, _ := store.IsFileExists(ctx, "archive/exports/old.csv"); !exists {
+			t.Fatal("the first matching rule did not archive its file")
+		}
+	})
+}
+
+func TestNewExecutorValidatesRules(t *testing.T) {
+	store, err := local.NewClient(local.Config{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	invalid := [][]Rule{
+		{{Prefix: "tmp/", MinAge: time.Hour, Action: ActionDelete}},
+		{{ID: "a", MinAge: 0, Action: ActionDelete}},
+		{{ID: "a", MinAge: time.Hour, Action: ActionArchive}},
+		{{ID: "a", MinAge: time.Hour, Action: "shred"}},
+		{{ID: "a", MinAge: time.Hour, Action: ActionDelete}, {ID: "a", MinAge: time.Hour, Action: ActionDelete}},
+	}
+	for _, rules := range invalid {
+		if _, err = NewExecutor(store, rules); err == nil {
+			t.Fatalf("NewExecutor(%+v) accepted invalid rules", rules)
+		}
+	}
+}
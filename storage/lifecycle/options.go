@@ -0,0 +1,75 @@
+package lifecycle
+
+import (
+	"time"
+
+	"github.com/go-sphere/sphere/log"
+)
+
+// options holds configuration parameters for the lifecycle executor.
+type options struct {
+	interval      time.Duration
+	dryRun        bool
+	native        bool
+	reportHandler func(report Report)
+}
+
+func newOptions(opt ...Option) *options {
+	opts := &options{
+		interval: time.Hour,
+		reportHandler: func(report Report) {
+			if report.Failed > 0 {
+				log.Warn(
+					"storage lifecycle run had failures",
+					log.Int("scanned", report.Scanned),
+					log.Int("deleted", report.Deleted),
+					log.Int("archived", report.Archived),
+					log.Int("failed", report.Failed),
+				)
+			}
+		},
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	return opts
+}
+
+// Option defines a function type for configuring the lifecycle executor.
+type Option func(*options)
+
+// WithInterval sets how often the executor applies the rules when run as a task.
+// The default interval is one hour.
+func WithInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.interval = interval
+	}
+}
+
+// WithDryRun makes the executor report the files its rules match without deleting or moving them.
+func WithDryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// WithNativeRules hands the rules the storage can enforce itself over to it, when the storage
+// implements NativeLifecycle. The executor then only scans for the remaining rules, leaving the
+// files matched by an earlier native rule to the storage. To keep the first matching rule in
+// charge of a file, a rule is only handed over if no earlier rule overlaps its prefix. Native
+// rules are not installed in dry-run mode, where every rule is scanned.
+func WithNativeRules() Option {
+	return func(o *options) {
+		o.native = true
+	}
+}
+
+// WithReportHandler sets the function receiving the report of each run of the task.
+// By default runs with failures are logged.
+func WithReportHandler(handler func(report Report)) Option {
+	return func(o *options) {
+		if handler != nil {
+			o.reportHandler = handler
+		}
+	}
+}
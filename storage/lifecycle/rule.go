@@ -0,0 +1,80 @@
+package lifecycle
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Action is what a rule does with the files it matches.
+type Action string
+
+const (
+	// ActionDelete deletes matching files.
+	ActionDelete Action = "delete"
+	// ActionArchive moves matching files under the archive prefix of the rule.
+	ActionArchive Action = "archive"
+)
+
+// Rule selects files by key prefix, age and tags, and applies an action to them.
+type Rule struct {
+	// ID names the rule in reports and in native lifecycle configurations.
+	ID string `json:"id" yaml:"id"`
+	// Prefix restricts the rule to keys starting with it. Empty matches every key.
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// MinAge is how long after its last modification a file matches.
+	MinAge time.Duration `json:"min_age" yaml:"min_age"`
+	// Tags restricts the rule to files carrying all of these tags. Tags are read through
+	// TagReader when the storage implements it, and from the user metadata otherwise.
+	Tags   map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Action Action            `json:"action" yaml:"action"`
+	// ArchivePrefix is prepended to the key of archived files. Required by ActionArchive.
+	ArchivePrefix string `json:"archive_prefix,omitempty" yaml:"archive_prefix,omitempty"`
+}
+
+// Validate checks that the rule is complete.
+func (r Rule) Validate() error {
+	if r.ID == "" {
+		return errors.New("lifecycle rule id is required")
+	}
+	if r.MinAge <= 0 {
+		return errors.New("lifecycle rule " + r.ID + ": min age must be positive")
+	}
+	switch r.Action {
+	case ActionDelete:
+	case ActionArchive:
+		if strings.Trim(r.ArchivePrefix, "/") == "" {
+			return errors.New("lifecycle rule " + r.ID + ": archive prefix is required")
+		}
+	default:
+		return errors.New("lifecycle rule " + r.ID + ": unknown action " + string(r.Action))
+	}
+	return nil
+}
+
+// matchesTags reports whether the tags carry every tag of the rule.
+// Keys are compared case-insensitively, like user metadata keys.
+func (r Rule) matchesTags(tags map[string]string) bool {
+	for k, v := range r.Tags {
+		found := false
+		for tk, tv := range tags {
+			if strings.EqualFold(tk, k) && tv == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Rule) archivePrefix() string {
+	return strings.Trim(r.ArchivePrefix, "/") + "/"
+}
+
+// archiveKey returns the key a file is archived to.
+func (r Rule) archiveKey(key string) string {
+	return r.archivePrefix() + strings.TrimPrefix(key, "/")
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"io"
+	"io/fs"
 	"mime"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/go-sphere/sphere/storage"
@@ -16,6 +18,12 @@ import (
 // Config holds the configuration for local file storage operations.
 type Config struct {
 	RootDir string `json:"root_dir" yaml:"root_dir"`
+	// MultipartDir holds the part files of unfinished multipart uploads.
+	// Defaults to the ".multipart" directory inside RootDir.
+	MultipartDir string `json:"multipart_dir" yaml:"multipart_dir"`
+	// MetadataDir holds the JSON sidecar files with the metadata of files uploaded with options.
+	// Defaults to the ".metadata" directory inside RootDir.
+	MetadataDir string `json:"metadata_dir" yaml:"metadata_dir"`
 }
 
 // Client provides local filesystem storage operations.
@@ -35,13 +43,20 @@ func NewClient(conf Config) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	if conf.MultipartDir == "" {
+		conf.MultipartDir = filepath.Join(conf.RootDir, ".multipart")
+	}
+	if conf.MetadataDir == "" {
+		conf.MetadataDir = filepath.Join(conf.RootDir, ".metadata")
+	}
 	return &Client{
 		config: conf,
 	}, nil
 }
 
 // fixFilePath resolves and validates file paths to prevent directory traversal attacks.
-// It ensures that all file operations stay within the configured root directory.
+// It ensures that all file operations stay within the configured root directory and
+// outside of the internal directories kept there, the multipart staging and metadata directories.
 func (c *Client) fixFilePath(key string) (string, error) {
 	rootDir, err := filepath.Abs(c.config.RootDir)
 	if err != nil {
@@ -60,11 +75,35 @@ func (c *Client) fixFilePath(key string) (string, error) {
 	if rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
 		return "", storageerr.ErrorFileNameInvalid
 	}
+	for _, dir := range c.reservedDirs() {
+		reserved, rErr := filepath.Abs(dir)
+		if rErr != nil {
+			return "", rErr
+		}
+		if isWithin(reserved, filePath) {
+			return "", storageerr.ErrorFileNameInvalid
+		}
+	}
 	return filePath, nil
 }
 
+// reservedDirs returns the internal directories that keys must not address.
+func (c *Client) reservedDirs() []string {
+	return []string{c.config.MultipartDir, c.config.MetadataDir}
+}
+
+// isWithin reports whether path is dir or inside of it.
+func isWithin(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator)))
+}
+
 // UploadFile uploads data from a reader to the local filesystem with the specified key.
-// It creates the necessary directory structure and writes the file content.
+// It creates the necessary directory structure and writes the file content,
+// and removes the metadata of a previous upload with options.
 func (c *Client) UploadFile(ctx context.Context, file io.Reader, key string) (string, error) {
 	filePath, err := c.fixFilePath(key)
 	if err != nil {
@@ -85,6 +124,9 @@ func (c *Client) UploadFile(ctx context.Context, file io.Reader, key string) (st
 	if err != nil {
 		return "", err
 	}
+	if err = c.removeMetadata(filePath); err != nil {
+		return "", err
+	}
 	return key, nil
 }
 
@@ -118,31 +160,75 @@ func (c *Client) IsFileExists(ctx context.Context, key string) (bool, error) {
 }
 
 // DownloadFile retrieves a file from local filesystem storage.
-// Returns the file reader, MIME type based on file extension, and file size.
+// Returns the file reader and size, the stored metadata, and the MIME type
+// stored on upload or based on the file extension.
 func (c *Client) DownloadFile(ctx context.Context, key string) (storage.DownloadResult, error) {
-	filePath, err := c.fixFilePath(key)
+	file, result, err := c.openFile(key)
+	if err != nil {
+		return storage.DownloadResult{}, err
+	}
+	result.Reader = file
+	return result, nil
+}
+
+// DownloadFileRange retrieves part of a file from local filesystem storage by seeking in the file.
+func (c *Client) DownloadFileRange(ctx context.Context, key string, offset int64, length int64) (storage.DownloadResult, error) {
+	file, result, err := c.openFile(key)
 	if err != nil {
 		return storage.DownloadResult{}, err
 	}
+	length, err = storage.RangeLength(offset, length, result.Size)
+	if err != nil {
+		_ = file.Close()
+		return storage.DownloadResult{}, err
+	}
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		_ = file.Close()
+		return storage.DownloadResult{}, err
+	}
+	result.Reader = rangeReader{Reader: io.LimitReader(file, length), Closer: file}
+	result.Size = length
+	return result, nil
+}
+
+// openFile opens a file for download and describes it in a DownloadResult without a reader.
+func (c *Client) openFile(key string) (*os.File, storage.DownloadResult, error) {
+	filePath, err := c.fixFilePath(key)
+	if err != nil {
+		return nil, storage.DownloadResult{}, err
+	}
 	file, err := os.Open(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return storage.DownloadResult{}, storageerr.ErrorNotFound
+			return nil, storage.DownloadResult{}, storageerr.ErrorNotFound
 		}
-		return storage.DownloadResult{}, err
+		return nil, storage.DownloadResult{}, err
 	}
 	stat, err := file.Stat()
 	if err != nil {
 		_ = file.Close()
-		return storage.DownloadResult{}, err
+		return nil, storage.DownloadResult{}, err
 	}
-	return storage.DownloadResult{
-		Reader: file,
-		MIME:   mime.TypeByExtension(filepath.Ext(key)),
-		Size:   stat.Size(),
+	opts, err := c.readMetadata(key, filePath)
+	if err != nil {
+		_ = file.Close()
+		return nil, storage.DownloadResult{}, err
+	}
+	return file, storage.DownloadResult{
+		MIME:         opts.ContentType,
+		Size:         stat.Size(),
+		ETag:         fileETag(stat),
+		ModTime:      stat.ModTime(),
+		FileMetadata: opts.FileMetadata,
 	}, nil
 }
 
+// rangeReader reads a section of a file and closes the file.
+type rangeReader struct {
+	io.Reader
+	io.Closer
+}
+
 // DeleteFile removes a file from the local filesystem storage.
 func (c *Client) DeleteFile(ctx context.Context, key string) error {
 	filePath, err := c.fixFilePath(key)
@@ -156,7 +242,7 @@ func (c *Client) DeleteFile(ctx context.Context, key string) error {
 		}
 		return err
 	}
-	return nil
+	return c.removeMetadata(filePath)
 }
 
 // removeBeforeOverwrite handles file overwrite logic for move and copy operations.
@@ -205,7 +291,7 @@ func (c *Client) MoveFile(ctx context.Context, sourceKey string, destinationKey
 	if e := os.Rename(sourcePath, destinationPath); e != nil {
 		return e
 	}
-	return nil
+	return c.moveMetadata(sourcePath, destinationPath)
 }
 
 // CopyFile duplicates a file from source to destination key within local filesystem storage.
@@ -250,5 +336,84 @@ func (c *Client) CopyFile(ctx context.Context, sourceKey string, destinationKey
 	if err != nil {
 		return err
 	}
-	return nil
+	return c.copyMetadata(sourcePath, destinationPath)
+}
+
+// ListFiles lists the regular files under the root directory, using forward slashes in keys on
+// every platform. It walks the directories matching the prefix on every call, which suits file
+// browsers and maintenance jobs rather than hot paths. Parts of unfinished multipart uploads and
+// metadata sidecar files are not listed. ETags are derived from the modification time and size,
+// like http file servers do, and MIME types from the key extension.
+func (c *Client) ListFiles(ctx context.Context, req storage.ListFilesRequest) (storage.ListFilesResult, error) {
+	rootDir, err := filepath.Abs(c.config.RootDir)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	multipartDir, err := filepath.Abs(c.config.MultipartDir)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	metadataDir, err := filepath.Abs(c.config.MetadataDir)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	var keys []string
+	infos := make(map[string]fs.FileInfo)
+	err = filepath.WalkDir(rootDir, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		rel, relErr := filepath.Rel(rootDir, path)
+		if relErr != nil {
+			return relErr
+		}
+		key := filepath.ToSlash(rel)
+		if entry.IsDir() {
+			if path == multipartDir || path == metadataDir {
+				return filepath.SkipDir
+			}
+			dirKey := key + "/"
+			if key != "." && !strings.HasPrefix(dirKey, req.Prefix) && !strings.HasPrefix(req.Prefix, dirKey) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || !strings.HasPrefix(key, req.Prefix) {
+			return nil
+		}
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			return infoErr
+		}
+		keys = append(keys, key)
+		infos[key] = info
+		return nil
+	})
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	slices.Sort(keys)
+	files, prefixes, next, err := storage.PageKeys(keys, req)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	result := storage.ListFilesResult{
+		Files:      make([]storage.FileInfo, 0, len(files)),
+		Prefixes:   prefixes,
+		NextCursor: next,
+	}
+	for _, key := range files {
+		info := infos[key]
+		result.Files = append(result.Files, storage.FileInfo{
+			Key:     key,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			ETag:    fileETag(info),
+			MIME:    mime.TypeByExtension(filepath.Ext(key)),
+		})
+	}
+	return result, nil
 }
@@ -1,10 +1,14 @@
 package local
 
 import (
+	"context"
 	"errors"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/go-sphere/sphere/storage"
 	"github.com/go-sphere/sphere/storage/storageerr"
 )
 
@@ -36,4 +40,207 @@ func TestClient_fixFilePath(t *testing.T) {
 			t.Fatalf("fixFilePath() error = %v, want %v", fixErr, storageerr.ErrorFileNameInvalid)
 		}
 	})
+
+	t.Run("reject multipart directory", func(t *testing.T) {
+		for _, key := range []string{".multipart", ".multipart/id/target", "images/../.multipart/id/1"} {
+			if _, fixErr := client.fixFilePath(key); !errors.Is(fixErr, storageerr.ErrorFileNameInvalid) {
+				t.Fatalf("fixFilePath(%q) error = %v, want %v", key, fixErr, storageerr.ErrorFileNameInvalid)
+			}
+		}
+		if _, fixErr := client.fixFilePath(".multipart-notes.txt"); fixErr != nil {
+			t.Fatalf("fixFilePath() error = %v", fixErr)
+		}
+	})
+
+	t.Run("reject metadata directory", func(t *testing.T) {
+		for _, key := range []string{".metadata", ".metadata/page.html.json", "./.metadata/a/b.json"} {
+			if _, fixErr := client.fixFilePath(key); !errors.Is(fixErr, storageerr.ErrorFileNameInvalid) {
+				t.Fatalf("fixFilePath(%q) error = %v, want %v", key, fixErr, storageerr.ErrorFileNameInvalid)
+			}
+		}
+	})
+}
+
+func TestClient_MultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	rootDir := t.TempDir()
+	client, err := NewClient(Config{
+		RootDir: rootDir,
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	t.Run("complete assembles parts in order", func(t *testing.T) {
+		const key = "videos/movie.mp4"
+		uploadID, initErr := client.InitiateMultipartUpload(ctx, key)
+		if initErr != nil {
+			t.Fatalf("InitiateMultipartUpload() error = %v", initErr)
+		}
+		// Upload out of order and replace part 1, as a client resuming after a failure would.
+		for _, p := range []struct {
+			number int
+			data   string
+		}{{2, "world"}, {1, "stale"}, {1, "hello "}} {
+			if _, upErr := client.UploadPart(ctx, key, uploadID, p.number, strings.NewReader(p.data), int64(len(p.data))); upErr != nil {
+				t.Fatalf("UploadPart(%d) error = %v", p.number, upErr)
+			}
+		}
+		parts, listErr := client.ListParts(ctx, key, uploadID)
+		if listErr != nil {
+			t.Fatalf("ListParts() error = %v", listErr)
+		}
+		if len(parts) != 2 || parts[0].PartNumber != 1 || parts[0].Size != 6 || parts[1].PartNumber != 2 {
+			t.Fatalf("ListParts() = %+v, want parts 1 and 2", parts)
+		}
+		if _, compErr := client.CompleteMultipartUpload(ctx, key, uploadID, parts); compErr != nil {
+			t.Fatalf("CompleteMultipartUpload() error = %v", compErr)
+		}
+		content, readErr := os.ReadFile(filepath.Join(rootDir, key))
+		if readErr != nil {
+			t.Fatalf("read completed file: %v", readErr)
+		}
+		if string(content) != "hello world" {
+			t.Fatalf("completed content = %q, want %q", string(content), "hello world")
+		}
+		if _, listErr = client.ListParts(ctx, key, uploadID); !errors.Is(listErr, storageerr.ErrorUploadNotFound) {
+			t.Fatalf("ListParts() after complete error = %v, want %v", listErr, storageerr.ErrorUploadNotFound)
+		}
+	})
+
+	t.Run("reject mismatched parts", func(t *testing.T) {
+		const key = "videos/broken.mp4"
+		uploadID, initErr := client.InitiateMultipartUpload(ctx, key)
+		if initErr != nil {
+			t.Fatalf("InitiateMultipartUpload() error = %v", initErr)
+		}
+		if _, upErr := client.UploadPart(ctx, key, uploadID, 1, strings.NewReader("short"), 10); !errors.Is(upErr, storageerr.ErrorPartInvalid) {
+			t.Fatalf("UploadPart() with wrong size error = %v, want %v", upErr, storageerr.ErrorPartInvalid)
+		}
+		part, upErr := client.UploadPart(ctx, key, uploadID, 1, strings.NewReader("data"), -1)
+		if upErr != nil {
+			t.Fatalf("UploadPart() error = %v", upErr)
+		}
+		_, compErr := client.CompleteMultipartUpload(ctx, key, uploadID, []storage.UploadedPart{{PartNumber: 1, ETag: "bogus"}})
+		if !errors.Is(compErr, storageerr.ErrorPartInvalid) {
+			t.Fatalf("CompleteMultipartUpload() with wrong etag error = %v, want %v", compErr, storageerr.ErrorPartInvalid)
+		}
+		_, compErr = client.CompleteMultipartUpload(ctx, key, uploadID, []storage.UploadedPart{part, {PartNumber: 2}})
+		if !errors.Is(compErr, storageerr.ErrorPartInvalid) {
+			t.Fatalf("CompleteMultipartUpload() with missing part error = %v, want %v", compErr, storageerr.ErrorPartInvalid)
+		}
+		if _, listErr := client.ListParts(ctx, "videos/other.mp4", uploadID); !errors.Is(listErr, storageerr.ErrorUploadNotFound) {
+			t.Fatalf("ListParts() with other key error = %v, want %v", listErr, storageerr.ErrorUploadNotFound)
+		}
+	})
+
+	t.Run("abort removes parts", func(t *testing.T) {
+		const key = "videos/aborted.mp4"
+		uploadID, initErr := client.InitiateMultipartUpload(ctx, key)
+		if initErr != nil {
+			t.Fatalf("InitiateMultipartUpload() error = %v", initErr)
+		}
+		if _, upErr := client.UploadPart(ctx, key, uploadID, 1, strings.NewReader("data"), 4); upErr != nil {
+			t.Fatalf("UploadPart() error = %v", upErr)
+		}
+		if abortErr := client.AbortMultipartUpload(ctx, key, uploadID); abortErr != nil {
+			t.Fatalf("AbortMultipartUpload() error = %v", abortErr)
+		}
+		if _, statErr := os.Stat(filepath.Join(rootDir, ".multipart", uploadID)); !os.IsNotExist(statErr) {
+			t.Fatalf("staging directory still exists: %v", statErr)
+		}
+		if _, upErr := client.UploadPart(ctx, key, "../../etc", 1, strings.NewReader("data"), 4); !errors.Is(upErr, storageerr.ErrorUploadNotFound) {
+			t.Fatalf("UploadPart() with forged upload id error = %v, want %v", upErr, storageerr.ErrorUploadNotFound)
+		}
+	})
+}
+
+func TestClient_UploadFileWithOptions(t *testing.T) {
+	ctx := context.Background()
+	client, err := NewClient(Config{
+		RootDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	opts := storage.UploadOptions{
+		ContentType: "application/json",
+		FileMetadata: storage.FileMetadata{
+			CacheControl:       "no-cache",
+			ContentDisposition: `attachment; filename="report.json"`,
+			UserMetadata:       map[string]string{"Owner": "alice"},
+		},
+	}
+	if _, err = client.UploadFileWithOptions(ctx, strings.NewReader("{}"), "reports/a.txt", opts); err != nil {
+		t.Fatalf("UploadFileWithOptions() error = %v", err)
+	}
+
+	stat, err := client.StatFile(ctx, "reports/a.txt")
+	if err != nil {
+		t.Fatalf("StatFile() error = %v", err)
+	}
+	if stat.Size != 2 || stat.MIME != "application/json" || stat.ETag == "" || stat.ModTime.IsZero() {
+		t.Fatalf("StatFile() = %+v, want size, stored MIME, ETag and ModTime", stat)
+	}
+	if stat.CacheControl != "no-cache" || stat.ContentDisposition != opts.ContentDisposition || stat.UserMetadata["owner"] != "alice" {
+		t.Fatalf("StatFile() metadata = %+v, want stored metadata", stat.FileMetadata)
+	}
+
+	t.Run("copy and move carry metadata", func(t *testing.T) {
+		if err = client.CopyFile(ctx, "reports/a.txt", "reports/b.txt", false); err != nil {
+			t.Fatalf("CopyFile() error = %v", err)
+		}
+		if err = client.MoveFile(ctx, "reports/b.txt", "archive/c.txt", false); err != nil {
+			t.Fatalf("MoveFile() error = %v", err)
+		}
+		moved, statErr := client.StatFile(ctx, "archive/c.txt")
+		if statErr != nil {
+			t.Fatalf("StatFile() error = %v", statErr)
+		}
+		if moved.MIME != "application/json" || moved.CacheControl != "no-cache" {
+			t.Fatalf("moved metadata = %+v, want copied metadata", moved)
+		}
+		sidecar, _ := client.metadataPath(filepath.Join(client.config.RootDir, "reports", "b.txt"))
+		if _, statErr = os.Stat(sidecar); !os.IsNotExist(statErr) {
+			t.Fatalf("sidecar of moved file still exists: %v", statErr)
+		}
+	})
+
+	t.Run("plain upload clears metadata", func(t *testing.T) {
+		if _, err = client.UploadFile(ctx, strings.NewReader("plain"), "reports/a.txt"); err != nil {
+			t.Fatalf("UploadFile() error = %v", err)
+		}
+		plain, statErr := client.StatFile(ctx, "reports/a.txt")
+		if statErr != nil {
+			t.Fatalf("StatFile() error = %v", statErr)
+		}
+		if plain.MIME != "text/plain; charset=utf-8" || plain.CacheControl != "" || plain.UserMetadata != nil {
+			t.Fatalf("StatFile() = %+v, want metadata cleared", plain)
+		}
+	})
+
+	t.Run("sidecars are not listed", func(t *testing.T) {
+		result, listErr := client.ListFiles(ctx, storage.ListFilesRequest{})
+		if listErr != nil {
+			t.Fatalf("ListFiles() error = %v", listErr)
+		}
+		for _, file := range result.Files {
+			if strings.HasPrefix(file.Key, ".metadata/") {
+				t.Fatalf("ListFiles() listed sidecar %q", file.Key)
+			}
+		}
+	})
+
+	t.Run("delete removes metadata", func(t *testing.T) {
+		if err = client.DeleteFile(ctx, "archive/c.txt"); err != nil {
+			t.Fatalf("DeleteFile() error = %v", err)
+		}
+		if _, err = client.StatFile(ctx, "archive/c.txt"); !errors.Is(err, storageerr.ErrorNotFound) {
+			t.Fatalf("StatFile() error = %v, want %v", err, storageerr.ErrorNotFound)
+		}
+		sidecar, _ := client.metadataPath(filepath.Join(client.config.RootDir, "archive", "c.txt"))
+		if _, statErr := os.Stat(sidecar); !os.IsNotExist(statErr) {
+			t.Fatalf("sidecar of deleted file still exists: %v", statErr)
+		}
+	})
 }
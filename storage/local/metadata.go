@@ -0,0 +1,191 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+// UploadFileWithOptions uploads a file like UploadFile and writes its metadata to a JSON sidecar file
+// in MetadataDir, at the same relative path as the file.
+func (c *Client) UploadFileWithOptions(ctx context.Context, file io.Reader, key string, opts storage.UploadOptions) (string, error) {
+	opts, err := storage.NormalizeUploadOptions(key, opts)
+	if err != nil {
+		return "", err
+	}
+	filePath, err := c.fixFilePath(key)
+	if err != nil {
+		return "", err
+	}
+	key, err = c.UploadFile(ctx, file, key)
+	if err != nil {
+		return "", err
+	}
+	if err = c.writeMetadata(filePath, opts); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// StatFile returns the file information together with the metadata stored on upload.
+func (c *Client) StatFile(ctx context.Context, key string) (storage.FileStat, error) {
+	filePath, err := c.fixFilePath(key)
+	if err != nil {
+		return storage.FileStat{}, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return storage.FileStat{}, storageerr.ErrorNotFound
+		}
+		return storage.FileStat{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return storage.FileStat{}, storageerr.ErrorNotFound
+	}
+	opts, err := c.readMetadata(key, filePath)
+	if err != nil {
+		return storage.FileStat{}, err
+	}
+	return storage.FileStat{
+		FileInfo: storage.FileInfo{
+			Key:     key,
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			ETag:    fileETag(info),
+			MIME:    opts.ContentType,
+		},
+		FileMetadata: opts.FileMetadata,
+	}, nil
+}
+
+// metadataPath returns the sidecar file of the file at filePath, which must be inside RootDir.
+func (c *Client) metadataPath(filePath string) (string, error) {
+	rootDir, err := filepath.Abs(c.config.RootDir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(rootDir, filePath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(c.config.MetadataDir, rel+".json"), nil
+}
+
+// readMetadata returns the stored metadata of a file. Without a sidecar file,
+// the content type is derived from the key extension.
+func (c *Client) readMetadata(key string, filePath string) (storage.UploadOptions, error) {
+	sidecar, err := c.metadataPath(filePath)
+	if err != nil {
+		return storage.UploadOptions{}, err
+	}
+	raw, err := os.ReadFile(sidecar)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return storage.UploadOptions{ContentType: mime.TypeByExtension(filepath.Ext(key))}, nil
+		}
+		return storage.UploadOptions{}, err
+	}
+	var opts storage.UploadOptions
+	if err = json.Unmarshal(raw, &opts); err != nil {
+		return storage.UploadOptions{}, fmt.Errorf("decode metadata of %q: %w", key, err)
+	}
+	return opts, nil
+}
+
+// writeMetadata replaces the sidecar file atomically, so readers never see a partial file.
+func (c *Client) writeMetadata(filePath string, opts storage.UploadOptions) error {
+	sidecar, err := c.metadataPath(filePath)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(sidecar), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(sidecar), ".metadata-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	_, err = tmp.Write(raw)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), sidecar)
+}
+
+func (c *Client) removeMetadata(filePath string) error {
+	sidecar, err := c.metadataPath(filePath)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(sidecar); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// moveMetadata moves the sidecar file along with a moved file, replacing the destination's metadata.
+func (c *Client) moveMetadata(sourcePath string, destinationPath string) error {
+	source, err := c.metadataPath(sourcePath)
+	if err != nil {
+		return err
+	}
+	destination, err := c.metadataPath(destinationPath)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(source); err != nil {
+		if os.IsNotExist(err) {
+			return c.removeMetadata(destinationPath)
+		}
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(destination), 0o750); err != nil {
+		return err
+	}
+	return os.Rename(source, destination)
+}
+
+// copyMetadata copies the sidecar file along with a copied file, replacing the destination's metadata.
+func (c *Client) copyMetadata(sourcePath string, destinationPath string) error {
+	source, err := c.metadataPath(sourcePath)
+	if err != nil {
+		return err
+	}
+	raw, err := os.ReadFile(source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c.removeMetadata(destinationPath)
+		}
+		return err
+	}
+	var opts storage.UploadOptions
+	if err = json.Unmarshal(raw, &opts); err != nil {
+		return err
+	}
+	return c.writeMetadata(destinationPath, opts)
+}
+
+// fileETag derives a weak validator from the modification time and size, like http file servers do.
+// It is marked weak, so that If-Range requests, which need a strong validator, never match it.
+func fileETag(info fs.FileInfo) string {
+	return fmt.Sprintf(`W/"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
@@ -0,0 +1,247 @@
+package local
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+	"github.com/google/uuid"
+)
+
+// uploadTargetFile records the resolved destination path inside a multipart upload directory.
+const uploadTargetFile = "target"
+
+// InitiateMultipartUpload creates a staging directory for the parts of a new upload.
+func (c *Client) InitiateMultipartUpload(ctx context.Context, key string) (string, error) {
+	filePath, err := c.fixFilePath(key)
+	if err != nil {
+		return "", err
+	}
+	uploadID := uuid.NewString()
+	dir := filepath.Join(c.config.MultipartDir, uploadID)
+	if err = os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+	if err = os.WriteFile(filepath.Join(dir, uploadTargetFile), []byte(filePath), 0o640); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+	return uploadID, nil
+}
+
+// uploadDir resolves the staging directory of a multipart upload and verifies that it targets the key.
+func (c *Client) uploadDir(key string, uploadID string) (string, string, error) {
+	filePath, err := c.fixFilePath(key)
+	if err != nil {
+		return "", "", err
+	}
+	// The upload ID becomes a path element, so only accept IDs this client could have issued.
+	if _, err = uuid.Parse(uploadID); err != nil {
+		return "", "", storageerr.ErrorUploadNotFound
+	}
+	dir := filepath.Join(c.config.MultipartDir, uploadID)
+	target, err := os.ReadFile(filepath.Join(dir, uploadTargetFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", storageerr.ErrorUploadNotFound
+		}
+		return "", "", err
+	}
+	if string(target) != filePath {
+		return "", "", storageerr.ErrorUploadNotFound
+	}
+	return dir, filePath, nil
+}
+
+// UploadPart writes a part to a temporary file and then renames it into place,
+// so an interrupted transfer never leaves a partial part behind.
+// The part file name carries the part number and the MD5 ETag of its content.
+func (c *Client) UploadPart(ctx context.Context, key string, uploadID string, partNumber int, part io.Reader, size int64) (storage.UploadedPart, error) {
+	if err := storage.ValidatePartNumber(partNumber); err != nil {
+		return storage.UploadedPart{}, err
+	}
+	dir, _, err := c.uploadDir(key, uploadID)
+	if err != nil {
+		return storage.UploadedPart{}, err
+	}
+	tmp, err := os.CreateTemp(dir, "part-*.tmp")
+	if err != nil {
+		return storage.UploadedPart{}, err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	hash := md5.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hash), part)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return storage.UploadedPart{}, err
+	}
+	if size >= 0 && written != size {
+		return storage.UploadedPart{}, storageerr.ErrorPartInvalid
+	}
+
+	etag := hex.EncodeToString(hash.Sum(nil))
+	previous, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%05d-*.part", partNumber)))
+	if err != nil {
+		return storage.UploadedPart{}, err
+	}
+	for _, name := range previous {
+		if e := os.Remove(name); e != nil && !os.IsNotExist(e) {
+			return storage.UploadedPart{}, e
+		}
+	}
+	if err = os.Rename(tmp.Name(), filepath.Join(dir, partFileName(partNumber, etag))); err != nil {
+		return storage.UploadedPart{}, err
+	}
+	return storage.UploadedPart{
+		PartNumber: partNumber,
+		ETag:       etag,
+		Size:       written,
+	}, nil
+}
+
+// ListParts returns the parts stored in the upload's staging directory.
+func (c *Client) ListParts(ctx context.Context, key string, uploadID string) ([]storage.UploadedPart, error) {
+	dir, _, err := c.uploadDir(key, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return listPartFiles(dir)
+}
+
+// CompleteMultipartUpload concatenates the parts into a temporary file next to the destination
+// and renames it over the destination, then removes the staging directory.
+func (c *Client) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []storage.UploadedPart) (string, error) {
+	sorted, err := storage.SortParts(parts)
+	if err != nil {
+		return "", err
+	}
+	dir, filePath, err := c.uploadDir(key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := listPartFiles(dir)
+	if err != nil {
+		return "", err
+	}
+	etags := make(map[int]string, len(stored))
+	for _, part := range stored {
+		etags[part.PartNumber] = part.ETag
+	}
+	for _, part := range sorted {
+		etag, ok := etags[part.PartNumber]
+		if !ok || (part.ETag != "" && strings.Trim(part.ETag, `"`) != etag) {
+			return "", storageerr.ErrorPartInvalid
+		}
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil {
+		return "", err
+	}
+	out, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.Remove(out.Name())
+	}()
+	for _, part := range sorted {
+		if err = appendPartFile(out, filepath.Join(dir, partFileName(part.PartNumber, etags[part.PartNumber]))); err != nil {
+			_ = out.Close()
+			return "", err
+		}
+	}
+	if err = out.Sync(); err != nil {
+		_ = out.Close()
+		return "", err
+	}
+	if err = out.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Rename(out.Name(), filePath); err != nil {
+		return "", err
+	}
+	if err = c.removeMetadata(filePath); err != nil {
+		return "", err
+	}
+	if err = os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// AbortMultipartUpload removes the staging directory together with all uploaded parts.
+func (c *Client) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	dir, _, err := c.uploadDir(key, uploadID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+func partFileName(partNumber int, etag string) string {
+	return fmt.Sprintf("%05d-%s.part", partNumber, etag)
+}
+
+// listPartFiles parses the part files of a staging directory. os.ReadDir sorts by name,
+// and the zero-padded part numbers make that the part number order.
+func listPartFiles(dir string) ([]storage.UploadedPart, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, storageerr.ErrorUploadNotFound
+		}
+		return nil, err
+	}
+	var parts []storage.UploadedPart
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".part")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		number, etag, ok := strings.Cut(name, "-")
+		if !ok {
+			continue
+		}
+		partNumber, convErr := strconv.Atoi(number)
+		if convErr != nil {
+			continue
+		}
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			return nil, infoErr
+		}
+		parts = append(parts, storage.UploadedPart{
+			PartNumber: partNumber,
+			ETag:       etag,
+			Size:       info.Size(),
+		})
+	}
+	return parts, nil
+}
+
+func appendPartFile(out io.Writer, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return storageerr.ErrorPartInvalid
+		}
+		return err
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	_, err = io.Copy(out, in)
+	return err
+}
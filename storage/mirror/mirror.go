@@ -0,0 +1,335 @@
+// Package mirror combines a primary and a secondary storage into one storage.Storage that writes
+// to both and reads from the primary, falling back to the secondary. It suits migrations between
+// backends: new writes reach both, reads find files not yet copied, and a reconciliation job
+// copies the missing files using listing.
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+// BackendError reports the failure of an operation on one of the mirrored storages.
+type BackendError struct {
+	Backend string
+	Op      string
+	Key     string
+	Err     error
+}
+
+func (e *BackendError) Error() string {
+	return fmt.Sprintf("mirror: %s %s %q: %v", e.Backend, e.Op, e.Key, e.Err)
+}
+
+func (e *BackendError) Unwrap() error {
+	return e.Err
+}
+
+// backend is one of the mirrored storages with its name.
+type backend struct {
+	name  string
+	store storage.Storage
+}
+
+// wrap returns the error as a *BackendError of the backend, or nil.
+func (b backend) wrap(op, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &BackendError{Backend: b.name, Op: op, Key: key, Err: err}
+}
+
+// Storage mirrors writes to two storages and reads from the primary, falling back to the secondary
+// when the primary fails or lacks a file. Failures of a single storage are reported as
+// *BackendError values, joined when both fail.
+//
+// Deleting, moving and copying a file that only one storage has succeeds; after a move or copy,
+// the destination is copied to the storage that lacked the source. The operation fails with
+// storageerr.ErrorNotFound only when neither storage has the file.
+type Storage struct {
+	primary   backend
+	secondary backend
+	opts      *options
+
+	sem chan struct{}
+	wg  sync.WaitGroup
+	// mu guards tails, the last pending background operation of each key. Operations on a key run
+	// after the previous one, so that a late copy cannot bring back a file deleted after it.
+	mu    sync.Mutex
+	tails map[string]chan struct{}
+}
+
+// NewStorage creates a storage mirroring writes to primary and secondary.
+func NewStorage(primary, secondary storage.Storage, opt ...Option) (*Storage, error) {
+	if primary == nil || secondary == nil {
+		return nil, errors.New("primary and secondary are required")
+	}
+	opts := newOptions(opt...)
+	if opts.mode != ModeWriteAll && opts.mode != ModeWritePrimaryAsync {
+		return nil, fmt.Errorf("unknown mirror mode %q", opts.mode)
+	}
+	if opts.concurrency <= 0 {
+		return nil, errors.New("concurrency must be positive")
+	}
+	if opts.errorHandler == nil {
+		return nil, errors.New("error handler is required")
+	}
+	return &Storage{
+		primary:   backend{name: opts.primaryName, store: primary},
+		secondary: backend{name: opts.secondaryName, store: secondary},
+		opts:      opts,
+		sem:       make(chan struct{}, opts.concurrency),
+		tails:     make(map[string]chan struct{}),
+	}, nil
+}
+
+// UploadFile uploads to the primary storage and, depending on the mode, streams the same data to
+// the secondary storage at once or copies the file from the primary storage afterwards.
+func (s *Storage) UploadFile(ctx context.Context, file io.Reader, key string) (string, error) {
+	if s.opts.mode == ModeWritePrimaryAsync {
+		if _, err := s.primary.store.UploadFile(ctx, file, key); err != nil {
+			return "", s.primary.wrap("upload", key, err)
+		}
+		s.replicate(ctx, s.primary, s.secondary, key)
+		return key, nil
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.secondary.store.UploadFile(ctx, pr, key)
+		// Keep draining so that a failed secondary does not block the primary upload.
+		_, _ = io.Copy(io.Discard, pr)
+		done <- err
+	}()
+	_, pErr := s.primary.store.UploadFile(ctx, io.TeeReader(file, pw), key)
+	if pErr != nil {
+		_ = pw.CloseWithError(pErr)
+	} else {
+		_ = pw.Close()
+	}
+	sErr := <-done
+	if err := errors.Join(s.primary.wrap("upload", key, pErr), s.secondary.wrap("upload", key, sErr)); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// UploadLocalFile uploads a local file like UploadFile.
+func (s *Storage) UploadLocalFile(ctx context.Context, file string, key string) (string, error) {
+	if s.opts.mode == ModeWritePrimaryAsync {
+		if _, err := s.primary.store.UploadLocalFile(ctx, file, key); err != nil {
+			return "", s.primary.wrap("upload", key, err)
+		}
+		// The local file may be gone by the time the replication runs, so it copies from the primary.
+		s.replicate(ctx, s.primary, s.secondary, key)
+		return key, nil
+	}
+	_, pErr := s.primary.store.UploadLocalFile(ctx, file, key)
+	_, sErr := s.secondary.store.UploadLocalFile(ctx, file, key)
+	if err := errors.Join(s.primary.wrap("upload", key, pErr), s.secondary.wrap("upload", key, sErr)); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// IsFileExists reports whether either storage has the file.
+func (s *Storage) IsFileExists(ctx context.Context, key string) (bool, error) {
+	exists, pErr := s.primary.store.IsFileExists(ctx, key)
+	if pErr == nil && exists {
+		return true, nil
+	}
+	exists, sErr := s.secondary.store.IsFileExists(ctx, key)
+	if sErr == nil && (exists || pErr == nil) {
+		return exists, nil
+	}
+	return false, errors.Join(s.primary.wrap("exists", key, pErr), s.secondary.wrap("exists", key, sErr))
+}
+
+// DownloadFile retrieves the file from the primary storage, or from the secondary storage if that fails.
+func (s *Storage) DownloadFile(ctx context.Context, key string) (storage.DownloadResult, error) {
+	result, pErr := s.primary.store.DownloadFile(ctx, key)
+	if pErr == nil {
+		return result, nil
+	}
+	result, sErr := s.secondary.store.DownloadFile(ctx, key)
+	if sErr == nil {
+		return result, nil
+	}
+	if errors.Is(pErr, storageerr.ErrorNotFound) && errors.Is(sErr, storageerr.ErrorNotFound) {
+		return storage.DownloadResult{}, storageerr.ErrorNotFound
+	}
+	return storage.DownloadResult{}, errors.Join(s.primary.wrap("download", key, pErr), s.secondary.wrap("download", key, sErr))
+}
+
+// DeleteFile deletes the file from both storages.
+func (s *Storage) DeleteFile(ctx context.Context, key string) error {
+	return s.write(ctx, "delete", key, "", func(ctx context.Context, store storage.Storage) error {
+		return store.DeleteFile(ctx, key)
+	})
+}
+
+// MoveFile moves the file in both storages.
+func (s *Storage) MoveFile(ctx context.Context, sourceKey string, destinationKey string, overwrite bool) error {
+	return s.write(ctx, "move", sourceKey, destinationKey, func(ctx context.Context, store storage.Storage) error {
+		return store.MoveFile(ctx, sourceKey, destinationKey, overwrite)
+	})
+}
+
+// CopyFile copies the file in both storages.
+func (s *Storage) CopyFile(ctx context.Context, sourceKey string, destinationKey string, overwrite bool) error {
+	return s.write(ctx, "copy", sourceKey, destinationKey, func(ctx context.Context, store storage.Storage) error {
+		return store.CopyFile(ctx, sourceKey, destinationKey, overwrite)
+	})
+}
+
+// Wait blocks until the pending background replications finish or the context is done.
+func (s *Storage) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// write applies an operation on key to both storages according to the mode. A storage lacking
+// the source does not fail the operation; the destination key, if any, is then copied to it.
+func (s *Storage) write(ctx context.Context, op, key, destinationKey string, apply func(context.Context, storage.Storage) error) error {
+	if s.opts.mode == ModeWritePrimaryAsync {
+		pErr := apply(ctx, s.primary.store)
+		if errors.Is(pErr, storageerr.ErrorNotFound) {
+			// Only the secondary has the file, so it is written synchronously.
+			sErr := apply(ctx, s.secondary.store)
+			if errors.Is(sErr, storageerr.ErrorNotFound) {
+				return storageerr.ErrorNotFound
+			}
+			if sErr != nil {
+				return s.secondary.wrap(op, key, sErr)
+			}
+			if destinationKey != "" {
+				s.replicate(ctx, s.secondary, s.primary, destinationKey)
+			}
+			return nil
+		}
+		if pErr != nil {
+			return s.primary.wrap(op, key, pErr)
+		}
+		keys := []string{key}
+		if destinationKey != "" {
+			keys = append(keys, destinationKey)
+		}
+		s.async(ctx, keys, func(ctx context.Context) error {
+			return s.settle(ctx, s.secondary, s.primary, op, key, destinationKey, apply(ctx, s.secondary.store))
+		})
+		return nil
+	}
+
+	pErr := apply(ctx, s.primary.store)
+	sErr := apply(ctx, s.secondary.store)
+	if errors.Is(pErr, storageerr.ErrorNotFound) && errors.Is(sErr, storageerr.ErrorNotFound) {
+		return storageerr.ErrorNotFound
+	}
+	// A storage is only repaired from the other one if the operation succeeded there.
+	primaryDestination, secondaryDestination := destinationKey, destinationKey
+	if sErr != nil {
+		primaryDestination = ""
+	}
+	if pErr != nil {
+		secondaryDestination = ""
+	}
+	return errors.Join(
+		s.settle(ctx, s.primary, s.secondary, op, key, primaryDestination, pErr),
+		s.settle(ctx, s.secondary, s.primary, op, key, secondaryDestination, sErr),
+	)
+}
+
+// settle turns the result of an operation on one storage into its error. If the storage lacked
+// the source, the destination key, if any, is copied to it from the other storage instead.
+func (s *Storage) settle(ctx context.Context, to, from backend, op, key, destinationKey string, err error) error {
+	if !errors.Is(err, storageerr.ErrorNotFound) {
+		return to.wrap(op, key, err)
+	}
+	if destinationKey == "" {
+		return nil
+	}
+	return to.wrap("replicate", destinationKey, copyFile(ctx, from.store, to.store, destinationKey))
+}
+
+// replicate copies a file between the storages in the background.
+func (s *Storage) replicate(ctx context.Context, from, to backend, key string) {
+	s.async(ctx, []string{key}, func(ctx context.Context) error {
+		return to.wrap("replicate", key, copyFile(ctx, from.store, to.store, key))
+	})
+}
+
+// async runs a background operation on the keys after the pending operations on them,
+// reporting its failure to the error handler.
+func (s *Storage) async(ctx context.Context, keys []string, fn func(ctx context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	done := make(chan struct{})
+	var prev []chan struct{}
+	s.mu.Lock()
+	for _, key := range keys {
+		if tail, ok := s.tails[key]; ok {
+			prev = append(prev, tail)
+		}
+		s.tails[key] = done
+	}
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			for _, key := range keys {
+				if s.tails[key] == done {
+					delete(s.tails, key)
+				}
+			}
+			s.mu.Unlock()
+			close(done)
+		}()
+		for _, tail := range prev {
+			<-tail
+		}
+		s.sem <- struct{}{}
+		defer func() { <-s.sem }()
+		if err := fn(ctx); err != nil {
+			s.opts.errorHandler(err)
+		}
+	}()
+}
+
+// copyFile copies a file between storages, keeping its content type and metadata
+// when the destination supports upload options.
+func copyFile(ctx context.Context, from, to storage.Storage, key string) error {
+	result, err := from.DownloadFile(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = result.Reader.Close()
+	}()
+	if uploader, ok := to.(storage.FileOptionsUploader); ok {
+		_, err = uploader.UploadFileWithOptions(ctx, result.Reader, key, storage.UploadOptions{
+			ContentType:  result.MIME,
+			FileMetadata: result.FileMetadata,
+		})
+		return err
+	}
+	_, err = to.UploadFile(ctx, result.Reader, key)
+	return err
+}
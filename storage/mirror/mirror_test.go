@@ -0,0 +1,301 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/cache/mcache"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/kvcache"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+func newMemoryStorage(t *testing.T) *kvcache.Client {
+	t.Helper()
+	store, err := kvcache.NewClient(kvcache.Config{}, mcache.NewByteCache())
+	if err != nil {
+		t.Fatalf("new kvcache client: %v", err)
+	}
+	return store
+}
+
+// failingStorage fails uploads with and without options with err.
+type failingStorage struct {
+	*kvcache.Client
+	err error
+}
+
+func (s *failingStorage) UploadFile(ctx context.Context, file io.Reader, key string) (string, error) {
+	// Read part of the stream, like a backend failing mid-upload.
+	_, _ = io.CopyN(io.Discard, file, 1)
+	return "", s.err
+}
+
+func (s *failingStorage) UploadFileWithOptions(ctx context.Context, file io.Reader, key string, _ storage.UploadOptions) (string, error) {
+	return s.UploadFile(ctx, file, key)
+}
+
+func readFile(t *testing.T, store storage.Storage, key string) string {
+	t.Helper()
+	result, err := store.DownloadFile(context.Background(), key)
+	if err != nil {
+		t.Fatalf("DownloadFile(%q) error = %v", key, err)
+	}
+	defer func() { _ = result.Reader.Close() }()
+	data, err := io.ReadAll(result.Reader)
+	if err != nil {
+		t.Fatalf("read %q: %v", key, err)
+	}
+	return string(data)
+}
+
+func upload(t *testing.T, store storage.Storage, key, content string) {
+	t.Helper()
+	if _, err := store.UploadFile(context.Background(), strings.NewReader(content), key); err != nil {
+		t.Fatalf("UploadFile(%q) error = %v", key, err)
+	}
+}
+
+func TestStorage_WriteAll(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newMemoryStorage(t), newMemoryStorage(t)
+	s, err := NewStorage(primary, secondary)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	content := strings.Repeat("mirrored ", 10000)
+	upload(t, s, "a.txt", content)
+	for name, store := range map[string]storage.Storage{"primary": primary, "secondary": secondary} {
+		if got := readFile(t, store, "a.txt"); got != content {
+			t.Fatalf("%s has %d bytes, want %d", name, len(got), len(content))
+		}
+	}
+
+	if err = s.CopyFile(ctx, "a.txt", "b.txt", false); err != nil {
+		t.Fatalf("CopyFile() error = %v", err)
+	}
+	if err = s.MoveFile(ctx, "b.txt", "c.txt", false); err != nil {
+		t.Fatalf("MoveFile() error = %v", err)
+	}
+	if err = s.DeleteFile(ctx, "a.txt"); err != nil {
+		t.Fatalf("DeleteFile() error = %v", err)
+	}
+	for name, store := range map[string]storage.Storage{"primary": primary, "secondary": secondary} {
+		for key, want := range map[string]bool{"a.txt": false, "b.txt": false, "c.txt": true} {
+			if exists, _ := store.IsFileExists(ctx, key); exists != want {
+				t.Fatalf("%s has %s = %v, want %v", name, key, exists, want)
+			}
+		}
+	}
+}
+
+func TestStorage_WriteAllFailure(t *testing.T) {
+	primary := newMemoryStorage(t)
+	boom := errors.New("boom")
+	s, err := NewStorage(primary, &failingStorage{Client: newMemoryStorage(t), err: boom}, WithNames("s3", "qiniu"))
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	_, err = s.UploadFile(context.Background(), strings.NewReader(strings.Repeat("x", 1<<20)), "a.txt")
+	var backendErr *BackendError
+	if !errors.As(err, &backendErr) || backendErr.Backend != "qiniu" || backendErr.Op != "upload" || !errors.Is(err, boom) {
+		t.Fatalf("UploadFile() error = %v, want a qiniu upload error", err)
+	}
+	// The primary received the whole file even though the secondary stopped reading.
+	if got := readFile(t, primary, "a.txt"); len(got) != 1<<20 {
+		t.Fatalf("primary has %d bytes, want %d", len(got), 1<<20)
+	}
+}
+
+func TestStorage_ReadFallback(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newMemoryStorage(t), newMemoryStorage(t)
+	s, err := NewStorage(primary, secondary)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	upload(t, secondary, "legacy.txt", "old backend")
+	if exists, err := s.IsFileExists(ctx, "legacy.txt"); err != nil || !exists {
+		t.Fatalf("IsFileExists() = %v, %v, want true", exists, err)
+	}
+	if got := readFile(t, s, "legacy.txt"); got != "old backend" {
+		t.Fatalf("DownloadFile() = %q", got)
+	}
+	if _, err = s.DownloadFile(ctx, "missing.txt"); !errors.Is(err, storageerr.ErrorNotFound) {
+		t.Fatalf("DownloadFile() of a missing key error = %v, want %v", err, storageerr.ErrorNotFound)
+	}
+
+	// Moving a file only the secondary has leaves the destination in both storages.
+	if err = s.MoveFile(ctx, "legacy.txt", "moved.txt", false); err != nil {
+		t.Fatalf("MoveFile() error = %v", err)
+	}
+	if got := readFile(t, primary, "moved.txt"); got != "old backend" {
+		t.Fatalf("primary moved.txt = %q", got)
+	}
+	if exists, _ := secondary.IsFileExists(ctx, "legacy.txt"); exists {
+		t.Fatal("secondary still has the moved source")
+	}
+	if err = s.CopyFile(ctx, "missing.txt", "other.txt", false); !errors.Is(err, storageerr.ErrorNotFound) {
+		t.Fatalf("CopyFile() of a missing key error = %v, want %v", err, storageerr.ErrorNotFound)
+	}
+}
+
+func TestStorage_WritePrimaryAsync(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newMemoryStorage(t), newMemoryStorage(t)
+	var mu sync.Mutex
+	var failures []error
+	s, err := NewStorage(primary, secondary, WithMode(ModeWritePrimaryAsync), WithErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, err)
+	}))
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	upload(t, s, "a.txt", "async")
+	if err = s.CopyFile(ctx, "a.txt", "b.txt", false); err != nil {
+		t.Fatalf("CopyFile() error = %v", err)
+	}
+	if err = s.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	for _, key := range []string{"a.txt", "b.txt"} {
+		if got := readFile(t, secondary, key); got != "async" {
+			t.Fatalf("secondary %s = %q", key, got)
+		}
+	}
+
+	// A replication failure is reported without failing the write.
+	failing := &failingStorage{Client: newMemoryStorage(t), err: errors.New("boom")}
+	s, err = NewStorage(primary, failing, WithMode(ModeWritePrimaryAsync), WithErrorHandler(func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, err)
+	}))
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	upload(t, s, "c.txt", "primary only")
+	if err = s.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	var backendErr *BackendError
+	if len(failures) != 1 || !errors.As(failures[0], &backendErr) || backendErr.Backend != "secondary" || backendErr.Key != "c.txt" {
+		t.Fatalf("replication failures = %v, want one for c.txt on the secondary", failures)
+	}
+}
+
+// blockingStorage reports uploads with options on started and holds them until release is closed.
+type blockingStorage struct {
+	*kvcache.Client
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStorage) UploadFileWithOptions(ctx context.Context, file io.Reader, key string, opts storage.UploadOptions) (string, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return s.Client.UploadFileWithOptions(ctx, file, key, opts)
+}
+
+func TestStorage_WritePrimaryAsyncOrdersKeys(t *testing.T) {
+	ctx := context.Background()
+	secondary := &blockingStorage{Client: newMemoryStorage(t), started: make(chan struct{}, 1), release: make(chan struct{})}
+	s, err := NewStorage(newMemoryStorage(t), secondary, WithMode(ModeWritePrimaryAsync))
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	upload(t, s, "a.txt", "async")
+	// The replication has read the file from the primary when the delete comes in.
+	<-secondary.started
+	if err = s.DeleteFile(ctx, "a.txt"); err != nil {
+		t.Fatalf("DeleteFile() error = %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(secondary.release)
+	if err = s.Wait(ctx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if exists, _ := secondary.IsFileExists(ctx, "a.txt"); exists {
+		t.Fatal("a replication finishing after the delete brought the file back")
+	}
+}
+
+func TestStorage_Reconcile(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := newMemoryStorage(t), newMemoryStorage(t)
+	s, err := NewStorage(primary, secondary)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	upload(t, primary, "docs/both.txt", "both")
+	upload(t, secondary, "docs/both.txt", "both")
+	upload(t, primary, "docs/new.txt", "new")
+	upload(t, secondary, "docs/old-1.txt", "old 1")
+	upload(t, secondary, "docs/old-2.txt", "old 2")
+	upload(t, secondary, "other/skip.txt", "skip")
+
+	result, err := s.Reconcile(ctx, "docs/")
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if result.Scanned != 4 || result.CopiedToPrimary != 2 || result.CopiedToSecondary != 1 || len(result.Errors) != 0 {
+		t.Fatalf("Reconcile() = %+v", result)
+	}
+	if got := readFile(t, primary, "docs/old-2.txt"); got != "old 2" {
+		t.Fatalf("primary docs/old-2.txt = %q", got)
+	}
+	if got := readFile(t, secondary, "docs/new.txt"); got != "new" {
+		t.Fatalf("secondary docs/new.txt = %q", got)
+	}
+	if exists, _ := primary.IsFileExists(ctx, "other/skip.txt"); exists {
+		t.Fatal("reconciled a key outside the prefix")
+	}
+	if result, err = s.Reconcile(ctx, "docs/"); err != nil || result.CopiedToPrimary+result.CopiedToSecondary != 0 {
+		t.Fatalf("second Reconcile() = %+v, %v, want nothing copied", result, err)
+	}
+}
+
+func TestReconciler_StartStop(t *testing.T) {
+	s, err := NewStorage(newMemoryStorage(t), newMemoryStorage(t))
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	reconciler, err := NewReconciler(s, WithReconcileInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewReconciler() error = %v", err)
+	}
+	started := make(chan error, 1)
+	go func() { started <- reconciler.Start(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	if err = reconciler.Start(context.Background()); !errors.Is(err, ErrReconcilerAlreadyStarted) {
+		t.Fatalf("second Start() error = %v, want %v", err, ErrReconcilerAlreadyStarted)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err = reconciler.Stop(ctx); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if err = <-started; err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	// A stopped reconciler can be started again.
+	go func() { started <- reconciler.Start(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	if err = reconciler.Stop(ctx); err != nil {
+		t.Fatalf("Stop() after restart error = %v", err)
+	}
+	if err = <-started; err != nil {
+		t.Fatalf("Start() after restart error = %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package mirror
+
+import (
+	"time"
+
+	"github.com/go-sphere/sphere/log"
+)
+
+// Mode decides how writes reach the secondary storage.
+type Mode string
+
+const (
+	// ModeWriteAll writes to both storages before returning, streaming uploads to both at once.
+	// A write fails if either storage fails it.
+	ModeWriteAll Mode = "write_all"
+	// ModeWritePrimaryAsync returns once the primary storage is written and replicates the write
+	// to the secondary storage in the background, in the order of the writes to each key.
+	// Replication failures go to the error handler.
+	ModeWritePrimaryAsync Mode = "write_primary_async"
+)
+
+// options holds configuration parameters for the mirrored storage.
+type options struct {
+	mode          Mode
+	primaryName   string
+	secondaryName string
+	concurrency   int
+	errorHandler  func(err error)
+}
+
+func newOptions(opt ...Option) *options {
+	opts := &options{
+		mode:          ModeWriteAll,
+		primaryName:   "primary",
+		secondaryName: "secondary",
+		concurrency:   4,
+		errorHandler: func(err error) {
+			log.Warn("mirror replication failed", log.Err(err))
+		},
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	return opts
+}
+
+// Option defines a function type for configuring the mirrored storage.
+type Option func(*options)
+
+// WithMode sets how writes reach the secondary storage. The default mode is ModeWriteAll.
+func WithMode(mode Mode) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+// WithNames sets the names identifying the storages in a BackendError.
+// The default names are "primary" and "secondary".
+func WithNames(primary, secondary string) Option {
+	return func(o *options) {
+		o.primaryName = primary
+		o.secondaryName = secondary
+	}
+}
+
+// WithConcurrency sets the maximum number of background replications running at once
+// in ModeWritePrimaryAsync. The default concurrency is 4.
+func WithConcurrency(n int) Option {
+	return func(o *options) {
+		o.concurrency = n
+	}
+}
+
+// WithErrorHandler sets the function receiving background replication failures,
+// each a *BackendError. By default they are logged.
+func WithErrorHandler(handler func(err error)) Option {
+	return func(o *options) {
+		o.errorHandler = handler
+	}
+}
+
+// reconcilerOptions holds configuration parameters for the reconciliation task.
+type reconcilerOptions struct {
+	interval time.Duration
+	prefix   string
+}
+
+func newReconcilerOptions(opt ...ReconcilerOption) *reconcilerOptions {
+	opts := &reconcilerOptions{
+		interval: time.Hour,
+	}
+	for _, o := range opt {
+		o(opts)
+	}
+	return opts
+}
+
+// ReconcilerOption defines a function type for configuring the reconciliation task.
+type ReconcilerOption func(*reconcilerOptions)
+
+// WithReconcileInterval sets how often the storages are reconciled.
+// The default interval is one hour.
+func WithReconcileInterval(interval time.Duration) ReconcilerOption {
+	return func(o *reconcilerOptions) {
+		o.interval = interval
+	}
+}
+
+// WithReconcilePrefix restricts reconciliation to keys starting with the prefix.
+func WithReconcilePrefix(prefix string) ReconcilerOption {
+	return func(o *reconcilerOptions) {
+		o.prefix = prefix
+	}
+}
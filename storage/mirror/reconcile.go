@@ -0,0 +1,162 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-sphere/sphere/core/task"
+	"github.com/go-sphere/sphere/log"
+	"github.com/go-sphere/sphere/storage"
+)
+
+var ErrReconcilerAlreadyStarted = errors.New("mirror reconciler already started")
+
+// ReconcileResult summarizes a reconciliation of the mirrored storages.
+type ReconcileResult struct {
+	// Scanned is the number of distinct keys compared.
+	Scanned int
+	// CopiedToPrimary and CopiedToSecondary count the files copied to the storage that lacked them.
+	CopiedToPrimary   int
+	CopiedToSecondary int
+	// Errors holds a *BackendError for every file that could not be copied.
+	Errors []error
+}
+
+// Reconcile copies the files under the prefix that only one storage has to the other storage.
+// Both storages must implement storage.FileLister. Files present in both are left alone, as backends
+// compute ETags differently. Copy failures are collected in the result; the returned error only
+// reports listing failures.
+func (s *Storage) Reconcile(ctx context.Context, prefix string) (ReconcileResult, error) {
+	primaryLister, ok := s.primary.store.(storage.FileLister)
+	if !ok {
+		return ReconcileResult{}, s.primary.wrap("list", prefix, errors.New("listing not supported"))
+	}
+	secondaryLister, ok := s.secondary.store.(storage.FileLister)
+	if !ok {
+		return ReconcileResult{}, s.secondary.wrap("list", prefix, errors.New("listing not supported"))
+	}
+	primary := &listCursor{lister: primaryLister, req: storage.ListFilesRequest{Prefix: prefix}}
+	secondary := &listCursor{lister: secondaryLister, req: storage.ListFilesRequest{Prefix: prefix}}
+
+	var result ReconcileResult
+	for {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		pFile, pOK, err := primary.peek(ctx)
+		if err != nil {
+			return result, s.primary.wrap("list", prefix, err)
+		}
+		sFile, sOK, err := secondary.peek(ctx)
+		if err != nil {
+			return result, s.secondary.wrap("list", prefix, err)
+		}
+		switch {
+		case !pOK && !sOK:
+			return result, nil
+		case pOK && (!sOK || pFile.Key < sFile.Key):
+			if cErr := copyFile(ctx, s.primary.store, s.secondary.store, pFile.Key); cErr != nil {
+				result.Errors = append(result.Errors, s.secondary.wrap("replicate", pFile.Key, cErr))
+			} else {
+				result.CopiedToSecondary++
+			}
+			primary.next()
+		case sOK && (!pOK || sFile.Key < pFile.Key):
+			if cErr := copyFile(ctx, s.secondary.store, s.primary.store, sFile.Key); cErr != nil {
+				result.Errors = append(result.Errors, s.primary.wrap("replicate", sFile.Key, cErr))
+			} else {
+				result.CopiedToPrimary++
+			}
+			secondary.next()
+		default:
+			primary.next()
+			secondary.next()
+		}
+		result.Scanned++
+	}
+}
+
+// listCursor walks a listing one file at a time, fetching pages as needed.
+type listCursor struct {
+	lister storage.FileLister
+	req    storage.ListFilesRequest
+	files  []storage.FileInfo
+	done   bool
+}
+
+func (c *listCursor) peek(ctx context.Context) (storage.FileInfo, bool, error) {
+	for len(c.files) == 0 && !c.done {
+		page, err := c.lister.ListFiles(ctx, c.req)
+		if err != nil {
+			return storage.FileInfo{}, false, err
+		}
+		c.files = page.Files
+		c.req.Cursor = page.NextCursor
+		c.done = page.NextCursor == ""
+	}
+	if len(c.files) == 0 {
+		return storage.FileInfo{}, false, nil
+	}
+	return c.files[0], true, nil
+}
+
+func (c *listCursor) next() {
+	c.files = c.files[1:]
+}
+
+// Reconciler is a task that periodically reconciles the mirrored storages.
+type Reconciler struct {
+	storage *Storage
+	opts    *reconcilerOptions
+
+	loop task.Loop
+}
+
+// NewReconciler creates a reconciliation task for the storage.
+func NewReconciler(s *Storage, opt ...ReconcilerOption) (*Reconciler, error) {
+	if s == nil {
+		return nil, errors.New("storage is required")
+	}
+	opts := newReconcilerOptions(opt...)
+	if opts.interval <= 0 {
+		return nil, errors.New("reconcile interval must be positive")
+	}
+	return &Reconciler{
+		storage: s,
+		opts:    opts,
+	}, nil
+}
+
+// Identifier returns the reconciler's identifier for logging and debugging purposes.
+func (r *Reconciler) Identifier() string {
+	return "mirror_reconciler"
+}
+
+// Start reconciles the storages until the context is cancelled or Stop is called.
+// Failures are logged and retried on the next run. A stopped reconciler can be started again.
+func (r *Reconciler) Start(ctx context.Context) error {
+	return r.loop.Run(ctx, ErrReconcilerAlreadyStarted, func(ctx context.Context) error {
+		ticker := time.NewTicker(r.opts.interval)
+		defer ticker.Stop()
+		for {
+			result, err := r.storage.Reconcile(ctx, r.opts.prefix)
+			if err != nil && ctx.Err() == nil {
+				log.Warn("mirror reconciliation failed", log.String("prefix", r.opts.prefix), log.Err(err))
+			}
+			for _, cErr := range result.Errors {
+				log.Warn("mirror reconciliation copy failed", log.String("prefix", r.opts.prefix), log.Err(cErr))
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop signals the reconciliation loop to exit and waits for the in-flight run to finish.
+func (r *Reconciler) Stop(ctx context.Context) error {
+	return r.loop.Stop(ctx)
+}
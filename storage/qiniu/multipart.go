@@ -0,0 +1,213 @@
+package qiniu
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+	qiniuStorage "github.com/qiniu/go-sdk/v7/storage"
+	"github.com/qiniu/go-sdk/v7/storagev2/apis"
+	httpclient "github.com/qiniu/go-sdk/v7/storagev2/http_client"
+	"github.com/qiniu/go-sdk/v7/storagev2/uptoken"
+)
+
+// keyUploadToken creates an upload token scoped to the key, as required by every step of a multipart upload.
+func (n *Client) keyUploadToken(key string) string {
+	put := &qiniuStorage.PutPolicy{
+		Scope: n.config.Bucket + ":" + key,
+	}
+	return put.UploadToken(n.mac)
+}
+
+// InitiateMultipartUpload starts a Qiniu resumable upload (v2) for the specified key.
+func (n *Client) InitiateMultipartUpload(ctx context.Context, key string) (string, error) {
+	key = n.keyPreprocess(key)
+	uploader := qiniuStorage.NewResumeUploaderV2(&qiniuStorage.Config{})
+	ret := qiniuStorage.InitPartsRet{}
+	err := uploader.InitParts(ctx, n.keyUploadToken(key), "", n.config.Bucket, key, true, &ret)
+	if err != nil {
+		return "", err
+	}
+	return ret.UploadID, nil
+}
+
+// UploadPart uploads a single part of a resumable upload.
+// Parts of unknown size are buffered in memory, since Qiniu requires the length up front.
+func (n *Client) UploadPart(ctx context.Context, key string, uploadID string, partNumber int, part io.Reader, size int64) (storage.UploadedPart, error) {
+	if err := storage.ValidatePartNumber(partNumber); err != nil {
+		return storage.UploadedPart{}, err
+	}
+	key = n.keyPreprocess(key)
+	if size < 0 {
+		all, err := io.ReadAll(part)
+		if err != nil {
+			return storage.UploadedPart{}, err
+		}
+		part = bytes.NewReader(all)
+		size = int64(len(all))
+	}
+	uploader := qiniuStorage.NewResumeUploaderV2(&qiniuStorage.Config{})
+	ret := qiniuStorage.UploadPartsRet{}
+	err := uploader.UploadParts(ctx, n.keyUploadToken(key), "", n.config.Bucket, key, true, uploadID, int64(partNumber), "", &ret, part, int(size))
+	if err != nil {
+		return storage.UploadedPart{}, convertMultipartError(err)
+	}
+	return storage.UploadedPart{
+		PartNumber: partNumber,
+		ETag:       ret.Etag,
+		Size:       size,
+	}, nil
+}
+
+// ListParts returns the parts uploaded so far, following Qiniu pagination.
+func (n *Client) ListParts(ctx context.Context, key string, uploadID string) ([]storage.UploadedPart, error) {
+	key = n.keyPreprocess(key)
+	client := apis.NewStorage(&httpclient.Options{})
+	var parts []storage.UploadedPart
+	var marker int64
+	for {
+		resp, err := client.ResumableUploadV2ListParts(ctx, &apis.ResumableUploadV2ListPartsRequest{
+			BucketName:       n.config.Bucket,
+			ObjectName:       &key,
+			UploadId:         uploadID,
+			PartNumberMarker: marker,
+			UpToken:          uptoken.NewParser(n.keyUploadToken(key)),
+		}, nil)
+		if err != nil {
+			return nil, convertMultipartError(err)
+		}
+		for _, part := range resp.Parts {
+			parts = append(parts, storage.UploadedPart{
+				PartNumber: int(part.PartNumber),
+				ETag:       part.Etag,
+				Size:       part.Size,
+			})
+		}
+		if resp.PartNumberMarker == 0 {
+			return parts, nil
+		}
+		marker = resp.PartNumberMarker
+	}
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the object.
+func (n *Client) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []storage.UploadedPart) (string, error) {
+	sorted, err := storage.SortParts(parts)
+	if err != nil {
+		return "", err
+	}
+	key = n.keyPreprocess(key)
+	progresses := make([]qiniuStorage.UploadPartInfo, 0, len(sorted))
+	for _, part := range sorted {
+		progresses = append(progresses, qiniuStorage.UploadPartInfo{
+			Etag:       part.ETag,
+			PartNumber: int64(part.PartNumber),
+		})
+	}
+	uploader := qiniuStorage.NewResumeUploaderV2(&qiniuStorage.Config{})
+	ret := qiniuStorage.PutRet{}
+	err = uploader.CompleteParts(ctx, n.keyUploadToken(key), "", &ret, n.config.Bucket, key, true, uploadID, &qiniuStorage.RputV2Extra{
+		Progresses: progresses,
+	})
+	if err != nil {
+		return "", convertMultipartError(err)
+	}
+	return ret.Key, nil
+}
+
+// AbortMultipartUpload aborts the upload and lets Qiniu discard its parts.
+func (n *Client) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	key = n.keyPreprocess(key)
+	client := apis.NewStorage(&httpclient.Options{})
+	_, err := client.ResumableUploadV2AbortMultipartUpload(ctx, &apis.ResumableUploadV2AbortMultipartUploadRequest{
+		BucketName: n.config.Bucket,
+		ObjectName: &key,
+		UploadId:   uploadID,
+		UpToken:    uptoken.NewParser(n.keyUploadToken(key)),
+	}, nil)
+	if err != nil {
+		return convertMultipartError(err)
+	}
+	return nil
+}
+
+// GenerateMultipartUploadAuth initiates a resumable upload and returns the Qiniu part upload URL
+// of each part together with the UpToken authorization header.
+// Clients keep the etag returned for each part; the server can also recover them with ListParts.
+func (n *Client) GenerateMultipartUploadAuth(ctx context.Context, req storage.MultipartUploadAuthRequest) (storage.MultipartUploadAuthResult, error) {
+	if !req.UploadPolicy.IsZero() {
+		return storage.MultipartUploadAuthResult{}, storageerr.ErrorUploadPolicyUnsupported
+	}
+	partNumbers, err := storage.PartNumbers(req.PartCount)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	fileName, err := storage.BuildUploadFileName(req.FileName, n.config.UploadNaming)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	key, err := storage.JoinUploadKey(n.config.Dir, req.Dir, fileName)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	key = n.keyPreprocess(key)
+	uploadID, err := n.InitiateMultipartUpload(ctx, key)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	parts, err := n.GeneratePartUploadAuth(ctx, key, uploadID, partNumbers)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	return storage.MultipartUploadAuthResult{
+		UploadID: uploadID,
+		Parts:    parts,
+		File: storage.UploadFileInfo{
+			Key: key,
+			URL: n.GenerateURL(key),
+		},
+	}, nil
+}
+
+// GeneratePartUploadAuth creates fresh part upload authorizations for an existing resumable upload.
+// The upload tokens expire after 1 hour.
+func (n *Client) GeneratePartUploadAuth(ctx context.Context, key string, uploadID string, partNumbers []int) ([]storage.PartUploadAuthorization, error) {
+	key = n.keyPreprocess(key)
+	uploader := qiniuStorage.NewResumeUploaderV2(&qiniuStorage.Config{})
+	upHost, err := uploader.UpHost(n.config.AccessKey, n.config.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{
+		"Authorization": "UpToken " + n.keyUploadToken(key),
+	}
+	encodedKey := base64.URLEncoding.EncodeToString([]byte(key))
+	parts := make([]storage.PartUploadAuthorization, 0, len(partNumbers))
+	for _, partNumber := range partNumbers {
+		if err = storage.ValidatePartNumber(partNumber); err != nil {
+			return nil, err
+		}
+		parts = append(parts, storage.PartUploadAuthorization{
+			PartNumber: partNumber,
+			Authorization: storage.UploadAuthorization{
+				Type:    storage.UploadAuthorizationTypeURL,
+				Value:   fmt.Sprintf("%s/buckets/%s/objects/%s/uploads/%s/%d", upHost, n.config.Bucket, encodedKey, uploadID, partNumber),
+				Method:  http.MethodPut,
+				Headers: headers,
+			},
+		})
+	}
+	return parts, nil
+}
+
+func convertMultipartError(err error) error {
+	if isNotFoundError(err) {
+		return storageerr.ErrorUploadNotFound
+	}
+	return err
+}
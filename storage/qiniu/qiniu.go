@@ -1,13 +1,17 @@
 package qiniu
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-sphere/sphere/storage"
 	"github.com/go-sphere/sphere/storage/storageerr"
@@ -26,6 +30,21 @@ type Config struct {
 	UploadNaming storage.UploadNamingStrategy `json:"upload_naming" yaml:"upload_naming"` // Upload file naming strategy
 
 	PublicBase string `json:"public_base" yaml:"public_base"` // Public base URL for file access
+
+	// CallbackURL receives an upload callback from Qiniu after each upload authorized by
+	// GenerateUploadAuth; its handler verifies the callback with VerifyUploadCallback.
+	CallbackURL string `json:"callback_url" yaml:"callback_url"`
+}
+
+// uploadCallbackBody is the JSON body of upload callbacks, filled in by Qiniu's magic variables.
+const uploadCallbackBody = `{"key":"$(key)","size":$(fsize),"mime":"$(mimeType)","etag":"$(etag)"}`
+
+// uploadCallback is the decoded body of an upload callback.
+type uploadCallback struct {
+	Key  string `json:"key"`
+	Size int64  `json:"size"`
+	MIME string `json:"mime"`
+	ETag string `json:"etag"`
 }
 
 // Client provides Qiniu Cloud Object Storage operations with URL handling capabilities.
@@ -71,8 +90,15 @@ func (n *Client) keyPreprocess(key string) string {
 
 // GenerateUploadAuth creates a secure upload token for direct client uploads to Qiniu.
 // It generates the storage key using configured naming strategy and returns token, key, and public URL.
-// The token includes restrictions for image and video MIME types only.
+// The upload policy maps to the PutPolicy: MaxSize to fsizeLimit, AllowedMIMETypes to mimeLimit,
+// which Qiniu checks against the detected content type, Expires to the deadline, and InsertOnly
+// to insertOnly. Without a policy, the token only accepts images and videos and never
+// overwrites an existing file. The token expires after 1 hour by default. With CallbackURL configured,
+// Qiniu posts the uploaded file's information there once the upload completes.
 func (n *Client) GenerateUploadAuth(_ context.Context, req storage.UploadAuthRequest) (storage.UploadAuthResult, error) {
+	if err := storage.ValidateUploadPolicy(req.UploadPolicy); err != nil {
+		return storage.UploadAuthResult{}, err
+	}
 	fileName, err := storage.BuildUploadFileName(req.FileName, n.config.UploadNaming)
 	if err != nil {
 		return storage.UploadAuthResult{}, err
@@ -82,10 +108,11 @@ func (n *Client) GenerateUploadAuth(_ context.Context, req storage.UploadAuthReq
 		return storage.UploadAuthResult{}, err
 	}
 	key = n.keyPreprocess(key)
-	put := &qiniuStorage.PutPolicy{
-		Scope:      n.config.Bucket + ":" + key,
-		InsertOnly: 1,
-		MimeLimit:  "image/*;video/*",
+	put := n.putPolicy(key, req.UploadPolicy)
+	if n.config.CallbackURL != "" {
+		put.CallbackURL = n.config.CallbackURL
+		put.CallbackBody = uploadCallbackBody
+		put.CallbackBodyType = "application/json"
 	}
 	return storage.UploadAuthResult{
 		Authorization: storage.UploadAuthorization{
@@ -100,6 +127,80 @@ func (n *Client) GenerateUploadAuth(_ context.Context, req storage.UploadAuthReq
 	}, nil
 }
 
+// defaultUploadPolicy applies to upload authorizations requested without a policy.
+var defaultUploadPolicy = storage.UploadPolicy{
+	AllowedMIMETypes: []string{"image/*", "video/*"},
+	InsertOnly:       true,
+}
+
+// putPolicy returns the PutPolicy of a client upload to the key, with the default policy if the
+// given one is zero.
+func (n *Client) putPolicy(key string, policy storage.UploadPolicy) *qiniuStorage.PutPolicy {
+	if policy.IsZero() {
+		policy = defaultUploadPolicy
+	}
+	put := &qiniuStorage.PutPolicy{
+		Scope:      n.config.Bucket + ":" + key,
+		FsizeLimit: policy.MaxSize,
+		MimeLimit:  strings.Join(policy.AllowedMIMETypes, ";"),
+	}
+	if policy.Expires > 0 {
+		// PutPolicy counts whole seconds, so round up to keep short expiries valid.
+		put.Expires = uint64((policy.Expires + time.Second - 1) / time.Second)
+	}
+	if policy.InsertOnly {
+		put.InsertOnly = 1
+	}
+	return put
+}
+
+// VerifyUploadCallback checks that an upload callback request was signed by Qiniu with the
+// client's credentials and returns the completed upload it reports. It returns
+// storageerr.ErrorUploadCallbackInvalid for requests that are not genuine callbacks.
+func (n *Client) VerifyUploadCallback(req *http.Request) (storage.UploadCompletion, error) {
+	if req.Body == nil {
+		return storage.UploadCompletion{}, storageerr.ErrorUploadCallbackInvalid
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return storage.UploadCompletion{}, err
+	}
+	// The signature covers the body, so it must be readable again for the verification.
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	ok, err := n.mac.VerifyCallback(req)
+	if err != nil {
+		return storage.UploadCompletion{}, err
+	}
+	if !ok {
+		return storage.UploadCompletion{}, storageerr.ErrorUploadCallbackInvalid
+	}
+	var callback uploadCallback
+	if err = json.Unmarshal(body, &callback); err != nil || callback.Key == "" {
+		return storage.UploadCompletion{}, storageerr.ErrorUploadCallbackInvalid
+	}
+	return storage.UploadCompletion{
+		FileInfo: storage.FileInfo{
+			Key:     callback.Key,
+			Size:    callback.Size,
+			ModTime: time.Now(),
+			ETag:    callback.ETag,
+			MIME:    callback.MIME,
+		},
+		URL: n.GenerateURL(callback.Key),
+	}, nil
+}
+
+// GenerateSignedURL creates a private download URL on the public base URL, signed with a
+// download token that expires after the given duration.
+func (n *Client) GenerateSignedURL(_ context.Context, key string, expires time.Duration) (string, error) {
+	if expires <= 0 {
+		return "", errors.New("expires must be positive")
+	}
+	key = n.keyPreprocess(key)
+	deadline := time.Now().Add(expires).Unix()
+	return qiniuStorage.MakePrivateURLv2(n.mac, n.config.PublicBase, key, deadline), nil
+}
+
 // UploadFile uploads data from a reader to Qiniu Cloud Object Storage with the specified key.
 func (n *Client) UploadFile(ctx context.Context, file io.Reader, key string) (string, error) {
 	key = n.keyPreprocess(key)
@@ -134,6 +235,56 @@ func (n *Client) UploadLocalFile(ctx context.Context, file string, key string) (
 	return ret.Key, nil
 }
 
+// UploadFileWithOptions uploads data to Qiniu Cloud Object Storage with the content type and
+// user metadata of the options. Qiniu has no per-file Cache-Control or Content-Disposition,
+// so they are stored as the reserved user metadata entries cache-control and content-disposition.
+func (n *Client) UploadFileWithOptions(ctx context.Context, file io.Reader, key string, opts storage.UploadOptions) (string, error) {
+	key = n.keyPreprocess(key)
+	opts, err := storage.NormalizeUploadOptions(key, opts)
+	if err != nil {
+		return "", err
+	}
+	put := &qiniuStorage.PutPolicy{
+		Scope: n.config.Bucket,
+	}
+	upToken := put.UploadToken(n.mac)
+	cfg := qiniuStorage.Config{}
+	ret := qiniuStorage.PutRet{}
+	formUploader := qiniuStorage.NewFormUploader(&cfg)
+	err = formUploader.Put(ctx, &ret, upToken, key, file, -1, &qiniuStorage.PutExtra{
+		MimeType: opts.ContentType,
+		Params:   metadataParams(opts.FileMetadata),
+	})
+	if err != nil {
+		return "", err
+	}
+	return ret.Key, nil
+}
+
+// StatFile returns the file information and metadata from the Qiniu stat API.
+// Qiniu reports the file hash as the ETag.
+func (n *Client) StatFile(ctx context.Context, key string) (storage.FileStat, error) {
+	key = n.keyPreprocess(key)
+	manager := qiniuStorage.NewBucketManager(n.mac, &qiniuStorage.Config{})
+	info, err := manager.Stat(n.config.Bucket, key)
+	if err != nil {
+		if isNotFoundError(err) {
+			return storage.FileStat{}, storageerr.ErrorNotFound
+		}
+		return storage.FileStat{}, err
+	}
+	return storage.FileStat{
+		FileInfo: storage.FileInfo{
+			Key:     key,
+			Size:    info.Fsize,
+			ModTime: time.Unix(0, info.PutTime*100),
+			ETag:    info.Hash,
+			MIME:    info.MimeType,
+		},
+		FileMetadata: parseMetadata(info.MetaData),
+	}, nil
+}
+
 // IsFileExists checks whether a file exists in the Qiniu Cloud Object Storage bucket.
 func (n *Client) IsFileExists(ctx context.Context, key string) (bool, error) {
 	key = n.keyPreprocess(key)
@@ -149,7 +300,7 @@ func (n *Client) IsFileExists(ctx context.Context, key string) (bool, error) {
 }
 
 // DownloadFile retrieves a file from Qiniu Cloud Object Storage.
-// Returns the file reader, content type, and content length.
+// Returns the file reader, content type, content length, and the file metadata.
 func (n *Client) DownloadFile(ctx context.Context, key string) (storage.DownloadResult, error) {
 	key = n.keyPreprocess(key)
 	manager := qiniuStorage.NewBucketManager(n.mac, &qiniuStorage.Config{})
@@ -161,9 +312,43 @@ func (n *Client) DownloadFile(ctx context.Context, key string) (storage.Download
 		return storage.DownloadResult{}, err
 	}
 	return storage.DownloadResult{
-		Reader: object.Body,
-		MIME:   object.ContentType,
-		Size:   object.ContentLength,
+		Reader:       object.Body,
+		MIME:         object.ContentType,
+		Size:         object.ContentLength,
+		ETag:         object.ETag,
+		ModTime:      object.LastModified,
+		FileMetadata: parseMetadata(object.Metadata),
+	}, nil
+}
+
+// DownloadFileRange retrieves part of a file from Qiniu Cloud Object Storage with a ranged GET.
+func (n *Client) DownloadFileRange(ctx context.Context, key string, offset int64, length int64) (storage.DownloadResult, error) {
+	if offset < 0 || length == 0 {
+		return storage.DownloadResult{}, storageerr.ErrorRangeInvalid
+	}
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length > 0 {
+		byteRange += strconv.FormatInt(offset+length-1, 10)
+	}
+	key = n.keyPreprocess(key)
+	manager := qiniuStorage.NewBucketManager(n.mac, &qiniuStorage.Config{})
+	object, err := manager.Get(n.config.Bucket, key, &qiniuStorage.GetObjectInput{Context: ctx, Range: byteRange})
+	if err != nil {
+		if isNotFoundError(err) {
+			return storage.DownloadResult{}, storageerr.ErrorNotFound
+		}
+		if isRangeNotSatisfiableError(err) {
+			return storage.DownloadResult{}, storageerr.ErrorRangeInvalid
+		}
+		return storage.DownloadResult{}, err
+	}
+	return storage.DownloadResult{
+		Reader:       object.Body,
+		MIME:         object.ContentType,
+		Size:         object.ContentLength,
+		ETag:         object.ETag,
+		ModTime:      object.LastModified,
+		FileMetadata: parseMetadata(object.Metadata),
 	}, nil
 }
 
@@ -214,6 +399,87 @@ func (n *Client) CopyFile(ctx context.Context, sourceKey string, destinationKey
 	return nil
 }
 
+// ListFiles lists files with the Qiniu list API; the cursor is the Qiniu marker.
+// Qiniu reports the file hash as the ETag.
+func (n *Client) ListFiles(ctx context.Context, req storage.ListFilesRequest) (storage.ListFilesResult, error) {
+	limit, err := storage.ListLimit(req.Limit)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	manager := qiniuStorage.NewBucketManager(n.mac, &qiniuStorage.Config{})
+	ret, hasNext, err := manager.ListFilesWithContext(ctx, n.config.Bucket,
+		qiniuStorage.ListInputOptionsPrefix(n.keyPreprocess(req.Prefix)),
+		qiniuStorage.ListInputOptionsDelimiter(req.Delimiter),
+		qiniuStorage.ListInputOptionsMarker(req.Cursor),
+		qiniuStorage.ListInputOptionsLimit(limit),
+	)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	result := storage.ListFilesResult{
+		Files:    make([]storage.FileInfo, 0, len(ret.Items)),
+		Prefixes: ret.CommonPrefixes,
+	}
+	for _, item := range ret.Items {
+		result.Files = append(result.Files, storage.FileInfo{
+			Key:     item.Key,
+			Size:    item.Fsize,
+			ModTime: time.Unix(0, item.PutTime*100),
+			ETag:    item.Hash,
+			MIME:    item.MimeType,
+		})
+	}
+	if hasNext {
+		result.NextCursor = ret.Marker
+	}
+	return result, nil
+}
+
+const (
+	metaKeyPrefix          = "x-qn-meta-"
+	cacheControlMeta       = "cache-control"
+	contentDispositionMeta = "content-disposition"
+)
+
+// metadataParams converts file metadata into the x-qn-meta- upload parameters.
+// Qiniu rejects empty values, so they are left out.
+func metadataParams(metadata storage.FileMetadata) map[string]string {
+	params := make(map[string]string, len(metadata.UserMetadata)+2)
+	for k, v := range metadata.UserMetadata {
+		if v != "" {
+			params[metaKeyPrefix+k] = v
+		}
+	}
+	if metadata.CacheControl != "" {
+		params[metaKeyPrefix+cacheControlMeta] = metadata.CacheControl
+	}
+	if metadata.ContentDisposition != "" {
+		params[metaKeyPrefix+contentDispositionMeta] = metadata.ContentDisposition
+	}
+	return params
+}
+
+// parseMetadata converts Qiniu metadata, with or without the x-qn-meta- prefix, into file metadata.
+func parseMetadata(raw map[string]string) storage.FileMetadata {
+	var metadata storage.FileMetadata
+	for k, v := range raw {
+		k = strings.ToLower(k)
+		k = strings.TrimPrefix(k, metaKeyPrefix)
+		switch k {
+		case cacheControlMeta:
+			metadata.CacheControl = v
+		case contentDispositionMeta:
+			metadata.ContentDisposition = v
+		default:
+			if metadata.UserMetadata == nil {
+				metadata.UserMetadata = make(map[string]string)
+			}
+			metadata.UserMetadata[k] = v
+		}
+	}
+	return metadata
+}
+
 func isNotFoundError(err error) bool {
 	if errors.Is(err, qiniuStorage.ErrNoSuchFile) {
 		return true
@@ -225,6 +491,14 @@ func isNotFoundError(err error) bool {
 	return respErr != nil && respErr.Code == 612
 }
 
+func isRangeNotSatisfiableError(err error) bool {
+	var respErr *qiniuStorage.ErrorInfo
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	return respErr != nil && respErr.Code == http.StatusRequestedRangeNotSatisfiable
+}
+
 func isDestinationExistsError(err error) bool {
 	var respErr *qiniuStorage.ErrorInfo
 	if !errors.As(err, &respErr) {
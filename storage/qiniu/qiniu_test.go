@@ -0,0 +1,29 @@
+package qiniu
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/storage"
+)
+
+func TestClient_putPolicy(t *testing.T) {
+	client, err := NewClient(Config{AccessKey: "ak", SecretKey: "sk", Bucket: "bucket", PublicBase: "https://cdn.example.com"})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	put := client.putPolicy("a.png", storage.UploadPolicy{})
+	if put.Scope != "bucket:a.png" || put.InsertOnly != 1 || put.MimeLimit != "image/*;video/*" || put.FsizeLimit != 0 {
+		t.Fatalf("default putPolicy() = %+v, want insert only images and videos", put)
+	}
+
+	put = client.putPolicy("a.pdf", storage.UploadPolicy{
+		MaxSize:          1024,
+		AllowedMIMETypes: []string{"application/pdf"},
+		Expires:          1500 * time.Millisecond,
+	})
+	if put.InsertOnly != 0 || put.MimeLimit != "application/pdf" || put.FsizeLimit != 1024 || put.Expires != 2 {
+		t.Fatalf("putPolicy() = %+v, want the requested policy", put)
+	}
+}
@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/go-sphere/sphere/storage/lifecycle"
+	"github.com/go-sphere/sphere/storage/storageerr"
+	"github.com/minio/minio-go/v7"
+	s3lifecycle "github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+const day = 24 * time.Hour
+
+// FileTags returns the S3 object tags of a file, which lifecycle rules match in S3.
+func (s *Client) FileTags(ctx context.Context, key string) (map[string]string, error) {
+	key = s.keyPreprocess(key)
+	objectTags, err := s.client.GetObjectTagging(ctx, s.config.Bucket, key, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		if isNoSuchKeyError(err) {
+			return nil, storageerr.ErrorNotFound
+		}
+		return nil, err
+	}
+	return objectTags.ToMap(), nil
+}
+
+// SupportsLifecycleRule reports whether S3 can enforce the rule as an expiration rule of the bucket.
+// S3 expires objects in whole days and cannot move them to another key, so only delete rules
+// with a minimum age of whole days are supported.
+func (s *Client) SupportsLifecycleRule(rule lifecycle.Rule) bool {
+	return rule.Action == lifecycle.ActionDelete && rule.MinAge >= day && rule.MinAge%day == 0
+}
+
+// ApplyLifecycleRules installs the rules in the lifecycle configuration of the bucket, replacing
+// the bucket rules with the same IDs and keeping the others. Rule tags match S3 object tags.
+// S3 evaluates the age from the object creation, rounded to the next midnight UTC.
+func (s *Client) ApplyLifecycleRules(ctx context.Context, rules []lifecycle.Rule) error {
+	config, err := s.client.GetBucketLifecycle(ctx, s.config.Bucket)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code != "NoSuchLifecycleConfiguration" {
+			return err
+		}
+		config = s3lifecycle.NewConfiguration()
+	}
+	config.Rules = mergeLifecycleRules(config.Rules, s.lifecycleRules(rules))
+	return s.client.SetBucketLifecycle(ctx, s.config.Bucket, config)
+}
+
+func (s *Client) lifecycleRules(rules []lifecycle.Rule) []s3lifecycle.Rule {
+	result := make([]s3lifecycle.Rule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, s3lifecycle.Rule{
+			ID:         rule.ID,
+			Status:     "Enabled",
+			RuleFilter: lifecycleFilter(s.keyPreprocess(rule.Prefix), rule.Tags),
+			Expiration: s3lifecycle.Expiration{
+				Days: s3lifecycle.ExpirationDays(rule.MinAge / day),
+			},
+		})
+	}
+	return result
+}
+
+// lifecycleFilter builds the filter of a bucket rule, which must use And to combine conditions.
+func lifecycleFilter(prefix string, tags map[string]string) s3lifecycle.Filter {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	filterTags := make([]s3lifecycle.Tag, 0, len(keys))
+	for _, k := range keys {
+		filterTags = append(filterTags, s3lifecycle.Tag{Key: k, Value: tags[k]})
+	}
+	switch {
+	case len(filterTags) == 0:
+		return s3lifecycle.Filter{Prefix: prefix}
+	case len(filterTags) == 1 && prefix == "":
+		return s3lifecycle.Filter{Tag: filterTags[0]}
+	default:
+		return s3lifecycle.Filter{And: s3lifecycle.And{Prefix: prefix, Tags: filterTags}}
+	}
+}
+
+// mergeLifecycleRules replaces the existing rules with the IDs of the applied rules.
+func mergeLifecycleRules(existing, applied []s3lifecycle.Rule) []s3lifecycle.Rule {
+	merged := make([]s3lifecycle.Rule, 0, len(existing)+len(applied))
+	for _, rule := range existing {
+		if !slices.ContainsFunc(applied, func(r s3lifecycle.Rule) bool { return r.ID == rule.ID }) {
+			merged = append(merged, rule)
+		}
+	}
+	return append(merged, applied...)
+}
@@ -0,0 +1,53 @@
+package s3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/storage/lifecycle"
+	s3lifecycle "github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+func TestLifecycleRules(t *testing.T) {
+	client, err := NewClient(Config{Endpoint: "localhost:9000", Bucket: "bucket"})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	rules := []lifecycle.Rule{
+		{ID: "tmp", Prefix: "/tmp/", MinAge: 2 * day, Action: lifecycle.ActionDelete},
+		{ID: "tagged", MinAge: day, Tags: map[string]string{"kind": "export"}, Action: lifecycle.ActionDelete},
+		{ID: "both", Prefix: "exports/", MinAge: 30 * day, Tags: map[string]string{"b": "2", "a": "1"}, Action: lifecycle.ActionDelete},
+	}
+	for _, rule := range rules {
+		if !client.SupportsLifecycleRule(rule) {
+			t.Fatalf("SupportsLifecycleRule(%s) = false, want true", rule.ID)
+		}
+	}
+	unsupported := []lifecycle.Rule{
+		{ID: "hours", MinAge: 36 * time.Hour, Action: lifecycle.ActionDelete},
+		{ID: "archive", MinAge: day, Action: lifecycle.ActionArchive, ArchivePrefix: "cold/"},
+	}
+	for _, rule := range unsupported {
+		if client.SupportsLifecycleRule(rule) {
+			t.Fatalf("SupportsLifecycleRule(%s) = true, want false", rule.ID)
+		}
+	}
+
+	got := client.lifecycleRules(rules)
+	if got[0].RuleFilter.Prefix != "tmp/" || got[0].Expiration.Days != 2 || got[0].Status != "Enabled" {
+		t.Fatalf("prefix rule = %+v", got[0])
+	}
+	if got[1].RuleFilter.Tag.Key != "kind" || got[1].RuleFilter.Tag.Value != "export" {
+		t.Fatalf("tag rule filter = %+v", got[1].RuleFilter)
+	}
+	and := got[2].RuleFilter.And
+	if and.Prefix != "exports/" || len(and.Tags) != 2 || and.Tags[0].Key != "a" || and.Tags[1].Key != "b" {
+		t.Fatalf("combined rule filter = %+v", got[2].RuleFilter)
+	}
+
+	existing := []s3lifecycle.Rule{{ID: "other"}, {ID: "tmp", Status: "Disabled"}}
+	merged := mergeLifecycleRules(existing, got)
+	if len(merged) != 4 || merged[0].ID != "other" || merged[1].ID != "tmp" || merged[1].Status != "Enabled" {
+		t.Fatalf("merged rules = %+v", merged)
+	}
+}
@@ -0,0 +1,174 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+	"github.com/minio/minio-go/v7"
+)
+
+// core exposes the low-level S3 multipart API of the underlying MinIO client.
+func (s *Client) core() minio.Core {
+	return minio.Core{Client: s.client}
+}
+
+// InitiateMultipartUpload starts a native S3 multipart upload for the specified key.
+func (s *Client) InitiateMultipartUpload(ctx context.Context, key string) (string, error) {
+	key = s.keyPreprocess(key)
+	return s.core().NewMultipartUpload(ctx, s.config.Bucket, key, minio.PutObjectOptions{})
+}
+
+// UploadPart uploads a single part of a multipart upload.
+// S3 requires every part except the last to be at least 5 MiB.
+func (s *Client) UploadPart(ctx context.Context, key string, uploadID string, partNumber int, part io.Reader, size int64) (storage.UploadedPart, error) {
+	if err := storage.ValidatePartNumber(partNumber); err != nil {
+		return storage.UploadedPart{}, err
+	}
+	key = s.keyPreprocess(key)
+	info, err := s.core().PutObjectPart(ctx, s.config.Bucket, key, uploadID, partNumber, part, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return storage.UploadedPart{}, convertMultipartError(err)
+	}
+	return storage.UploadedPart{
+		PartNumber: info.PartNumber,
+		ETag:       info.ETag,
+		Size:       info.Size,
+	}, nil
+}
+
+// ListParts returns the parts uploaded so far, following S3 pagination.
+func (s *Client) ListParts(ctx context.Context, key string, uploadID string) ([]storage.UploadedPart, error) {
+	key = s.keyPreprocess(key)
+	var parts []storage.UploadedPart
+	marker := 0
+	for {
+		result, err := s.core().ListObjectParts(ctx, s.config.Bucket, key, uploadID, marker, 1000)
+		if err != nil {
+			return nil, convertMultipartError(err)
+		}
+		for _, part := range result.ObjectParts {
+			parts = append(parts, storage.UploadedPart{
+				PartNumber: part.PartNumber,
+				ETag:       part.ETag,
+				Size:       part.Size,
+			})
+		}
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the object.
+func (s *Client) CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []storage.UploadedPart) (string, error) {
+	sorted, err := storage.SortParts(parts)
+	if err != nil {
+		return "", err
+	}
+	key = s.keyPreprocess(key)
+	completeParts := make([]minio.CompletePart, 0, len(sorted))
+	for _, part := range sorted {
+		completeParts = append(completeParts, minio.CompletePart{
+			PartNumber: part.PartNumber,
+			ETag:       part.ETag,
+		})
+	}
+	info, err := s.core().CompleteMultipartUpload(ctx, s.config.Bucket, key, uploadID, completeParts, minio.PutObjectOptions{})
+	if err != nil {
+		return "", convertMultipartError(err)
+	}
+	return info.Key, nil
+}
+
+// AbortMultipartUpload aborts the upload and lets S3 discard its parts.
+func (s *Client) AbortMultipartUpload(ctx context.Context, key string, uploadID string) error {
+	key = s.keyPreprocess(key)
+	err := s.core().AbortMultipartUpload(ctx, s.config.Bucket, key, uploadID)
+	if err != nil {
+		return convertMultipartError(err)
+	}
+	return nil
+}
+
+// GenerateMultipartUploadAuth initiates a multipart upload and returns a presigned PUT URL per part.
+// Clients read each part's ETag from the response header; the server can also recover them with ListParts.
+// The presigned URLs expire after 1 hour.
+func (s *Client) GenerateMultipartUploadAuth(ctx context.Context, req storage.MultipartUploadAuthRequest) (storage.MultipartUploadAuthResult, error) {
+	if !req.UploadPolicy.IsZero() {
+		return storage.MultipartUploadAuthResult{}, storageerr.ErrorUploadPolicyUnsupported
+	}
+	partNumbers, err := storage.PartNumbers(req.PartCount)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	fileName, err := storage.BuildUploadFileName(req.FileName, s.config.UploadNaming)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	key, err := storage.JoinUploadKey(s.config.Dir, req.Dir, fileName)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	key = s.keyPreprocess(key)
+	uploadID, err := s.InitiateMultipartUpload(ctx, key)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	parts, err := s.GeneratePartUploadAuth(ctx, key, uploadID, partNumbers)
+	if err != nil {
+		return storage.MultipartUploadAuthResult{}, err
+	}
+	return storage.MultipartUploadAuthResult{
+		UploadID: uploadID,
+		Parts:    parts,
+		File: storage.UploadFileInfo{
+			Key: key,
+			URL: s.GenerateURL(key),
+		},
+	}, nil
+}
+
+// GeneratePartUploadAuth creates fresh presigned PUT URLs for parts of an existing multipart upload.
+func (s *Client) GeneratePartUploadAuth(ctx context.Context, key string, uploadID string, partNumbers []int) ([]storage.PartUploadAuthorization, error) {
+	key = s.keyPreprocess(key)
+	parts := make([]storage.PartUploadAuthorization, 0, len(partNumbers))
+	for _, partNumber := range partNumbers {
+		if err := storage.ValidatePartNumber(partNumber); err != nil {
+			return nil, err
+		}
+		preSignedURL, err := s.client.Presign(ctx, http.MethodPut, s.config.Bucket, key, time.Hour, url.Values{
+			"partNumber": {strconv.Itoa(partNumber)},
+			"uploadId":   {uploadID},
+		})
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, storage.PartUploadAuthorization{
+			PartNumber: partNumber,
+			Authorization: storage.UploadAuthorization{
+				Type:   storage.UploadAuthorizationTypeURL,
+				Value:  preSignedURL.String(),
+				Method: http.MethodPut,
+			},
+		})
+	}
+	return parts, nil
+}
+
+func convertMultipartError(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case minio.NoSuchUpload:
+		return storageerr.ErrorUploadNotFound
+	case minio.InvalidPart, minio.InvalidPartOrder:
+		return storageerr.ErrorPartInvalid
+	default:
+		return err
+	}
+}
@@ -2,8 +2,13 @@ package s3
 
 import (
 	"context"
+	"errors"
 	"io"
+	"mime"
 	"net/http"
+	"path"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +32,12 @@ type Config struct {
 	UploadNaming    storage.UploadNamingStrategy `json:"upload_naming" yaml:"upload_naming"`
 }
 
+// User metadata recording the upload policy of objects uploaded with a POST policy, for ConfirmUpload.
+const (
+	uploadMaxSizeMetadata   = "upload-max-size"
+	uploadMIMETypesMetadata = "upload-mime-types"
+)
+
 // Client provides S3-compatible object storage operations with URL handling capabilities.
 // It uses the MinIO client library to interact with S3 or S3-compatible services.
 type Client struct {
@@ -69,11 +80,23 @@ func (s *Client) keyPreprocess(key string) string {
 	return strings.TrimPrefix(key, "/")
 }
 
-// GenerateUploadAuth creates a presigned PUT URL for direct client uploads to S3.
+// GenerateUploadAuth creates a presigned URL for direct client uploads to S3.
 // It generates the storage key using configured naming strategy and returns
-// the presigned URL, storage key, and public access URL. The presigned URL
-// expires after 1 hour.
+// the presigned URL, storage key, and public access URL. The URL expires after
+// the policy's Expires, or 1 hour by default.
+//
+// Without size or MIME constraints it presigns a PUT. With them it returns a presigned
+// POST policy form, where MaxSize becomes a content-length-range condition and a single
+// allowed MIME type a Content-Type condition. For a "type/*" wildcard the Content-Type field
+// holds a prefix that clients replace with the file's content type. Several allowed types cannot
+// be expressed as a condition and fail with storageerr.ErrorUploadPolicyUnsupported. ConfirmUpload
+// checks the upload again against the constraints the policy records as object metadata.
+// InsertOnly signs an If-None-Match: * header into the PUT, which S3 rejects for existing
+// keys; POST policies cannot express it, so it cannot be combined with the other constraints.
 func (s *Client) GenerateUploadAuth(ctx context.Context, req storage.UploadAuthRequest) (storage.UploadAuthResult, error) {
+	if err := storage.ValidateUploadPolicy(req.UploadPolicy); err != nil {
+		return storage.UploadAuthResult{}, err
+	}
 	fileName, err := storage.BuildUploadFileName(req.FileName, s.config.UploadNaming)
 	if err != nil {
 		return storage.UploadAuthResult{}, err
@@ -83,20 +106,22 @@ func (s *Client) GenerateUploadAuth(ctx context.Context, req storage.UploadAuthR
 		return storage.UploadAuthResult{}, err
 	}
 	key = s.keyPreprocess(key)
+	expires := req.Expires
+	if expires == 0 {
+		expires = time.Hour
+	}
 
-	preSignedURL, err := s.client.PresignedPutObject(ctx,
-		s.config.Bucket,
-		key,
-		time.Hour)
+	var authorization storage.UploadAuthorization
+	if req.MaxSize > 0 || len(req.AllowedMIMETypes) > 0 {
+		authorization, err = s.presignPostPolicy(ctx, key, expires, req.UploadPolicy)
+	} else {
+		authorization, err = s.presignPut(ctx, key, expires, req.InsertOnly)
+	}
 	if err != nil {
 		return storage.UploadAuthResult{}, err
 	}
 	return storage.UploadAuthResult{
-		Authorization: storage.UploadAuthorization{
-			Type:   storage.UploadAuthorizationTypeURL,
-			Value:  preSignedURL.String(),
-			Method: http.MethodPut,
-		},
+		Authorization: authorization,
 		File: storage.UploadFileInfo{
 			Key: key,
 			URL: s.GenerateURL(key),
@@ -104,6 +129,145 @@ func (s *Client) GenerateUploadAuth(ctx context.Context, req storage.UploadAuthR
 	}, nil
 }
 
+func (s *Client) presignPut(ctx context.Context, key string, expires time.Duration, insertOnly bool) (storage.UploadAuthorization, error) {
+	var headers http.Header
+	if insertOnly {
+		headers = http.Header{"If-None-Match": {"*"}}
+	}
+	preSignedURL, err := s.client.PresignHeader(ctx, http.MethodPut, s.config.Bucket, key, expires, nil, headers)
+	if err != nil {
+		return storage.UploadAuthorization{}, err
+	}
+	authorization := storage.UploadAuthorization{
+		Type:   storage.UploadAuthorizationTypeURL,
+		Value:  preSignedURL.String(),
+		Method: http.MethodPut,
+	}
+	if insertOnly {
+		authorization.Headers = map[string]string{"If-None-Match": "*"}
+	}
+	return authorization, nil
+}
+
+func (s *Client) presignPostPolicy(ctx context.Context, key string, expires time.Duration, policy storage.UploadPolicy) (storage.UploadAuthorization, error) {
+	if policy.InsertOnly {
+		return storage.UploadAuthorization{}, storageerr.ErrorUploadPolicyUnsupported
+	}
+	post := minio.NewPostPolicy()
+	if err := post.SetBucket(s.config.Bucket); err != nil {
+		return storage.UploadAuthorization{}, err
+	}
+	if err := post.SetKey(key); err != nil {
+		return storage.UploadAuthorization{}, err
+	}
+	if err := post.SetExpires(time.Now().UTC().Add(expires)); err != nil {
+		return storage.UploadAuthorization{}, err
+	}
+	if policy.MaxSize > 0 {
+		if err := post.SetContentLengthRange(0, policy.MaxSize); err != nil {
+			return storage.UploadAuthorization{}, err
+		}
+		if err := post.SetUserMetadata(uploadMaxSizeMetadata, strconv.FormatInt(policy.MaxSize, 10)); err != nil {
+			return storage.UploadAuthorization{}, err
+		}
+	}
+	if len(policy.AllowedMIMETypes) > 0 {
+		prefix, exact, err := contentTypeCondition(policy.AllowedMIMETypes)
+		if err != nil {
+			return storage.UploadAuthorization{}, err
+		}
+		if exact {
+			err = post.SetContentType(prefix)
+		} else {
+			err = post.SetContentTypeStartsWith(prefix)
+		}
+		if err != nil {
+			return storage.UploadAuthorization{}, err
+		}
+		err = post.SetUserMetadata(uploadMIMETypesMetadata, strings.Join(policy.AllowedMIMETypes, ","))
+		if err != nil {
+			return storage.UploadAuthorization{}, err
+		}
+	}
+	postURL, fields, err := s.client.PresignedPostPolicy(ctx, post)
+	if err != nil {
+		return storage.UploadAuthorization{}, err
+	}
+	return storage.UploadAuthorization{
+		Type:   storage.UploadAuthorizationTypeForm,
+		Value:  postURL.String(),
+		Method: http.MethodPost,
+		Fields: fields,
+	}, nil
+}
+
+// contentTypeCondition returns the Content-Type condition of a POST policy for the allowed MIME
+// types: an exact type, or a prefix for a "type/*" wildcard. Several allowed types cannot be
+// expressed as one condition, so they are rejected with storageerr.ErrorUploadPolicyUnsupported
+// rather than relaxed to any type; "*/*" allows any type and needs no condition.
+func contentTypeCondition(types []string) (string, bool, error) {
+	if slices.Contains(types, "*/*") {
+		return "", false, nil
+	}
+	if len(types) > 1 {
+		return "", false, storageerr.ErrorUploadPolicyUnsupported
+	}
+	pattern := strings.ToLower(types[0])
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.TrimSuffix(pattern, "*"), false, nil
+	}
+	return pattern, true, nil
+}
+
+// ConfirmUpload checks an upload authorized by GenerateUploadAuth once the client reports it
+// finished. It stats the object, checks the size and MIME constraints that the POST policy
+// recorded as object metadata, and deletes the object if it breaks them.
+func (s *Client) ConfirmUpload(ctx context.Context, key string) (storage.UploadCompletion, error) {
+	stat, err := s.StatFile(ctx, key)
+	if err != nil {
+		return storage.UploadCompletion{}, err
+	}
+	if err = checkUploadMetadata(stat); err != nil {
+		if dErr := s.DeleteFile(ctx, key); dErr != nil {
+			return storage.UploadCompletion{}, errors.Join(err, dErr)
+		}
+		return storage.UploadCompletion{}, err
+	}
+	return storage.UploadCompletion{FileInfo: stat.FileInfo, URL: s.GenerateURL(stat.Key)}, nil
+}
+
+func checkUploadMetadata(stat storage.FileStat) error {
+	if raw := stat.UserMetadata[uploadMaxSizeMetadata]; raw != "" {
+		maxSize, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		if stat.Size > maxSize {
+			return storageerr.ErrorFileTooLarge
+		}
+	}
+	if raw := stat.UserMetadata[uploadMIMETypesMetadata]; raw != "" {
+		if !storage.MatchMIMEType(strings.Split(raw, ","), stat.MIME) {
+			return storageerr.ErrorMIMETypeNotAllowed
+		}
+	}
+	return nil
+}
+
+// GenerateSignedURL creates a presigned GET URL on the S3 endpoint, valid for at most 7 days.
+// Unlike GenerateURL, it does not use the public base URL, since the signature covers the host.
+func (s *Client) GenerateSignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if expires <= 0 {
+		return "", errors.New("expires must be positive")
+	}
+	key = s.keyPreprocess(key)
+	preSignedURL, err := s.client.PresignedGetObject(ctx, s.config.Bucket, key, expires, nil)
+	if err != nil {
+		return "", err
+	}
+	return preSignedURL.String(), nil
+}
+
 // UploadFile uploads data from a reader to S3-compatible storage with the specified key.
 func (s *Client) UploadFile(ctx context.Context, file io.Reader, key string) (string, error) {
 	key = s.keyPreprocess(key)
@@ -124,6 +288,26 @@ func (s *Client) UploadLocalFile(ctx context.Context, file string, key string) (
 	return info.Key, nil
 }
 
+// UploadFileWithOptions uploads data to S3-compatible storage with the content type,
+// Cache-Control and Content-Disposition headers and user metadata of the options.
+func (s *Client) UploadFileWithOptions(ctx context.Context, file io.Reader, key string, opts storage.UploadOptions) (string, error) {
+	key = s.keyPreprocess(key)
+	opts, err := storage.NormalizeUploadOptions(key, opts)
+	if err != nil {
+		return "", err
+	}
+	info, err := s.client.PutObject(ctx, s.config.Bucket, key, file, -1, minio.PutObjectOptions{
+		ContentType:        opts.ContentType,
+		CacheControl:       opts.CacheControl,
+		ContentDisposition: opts.ContentDisposition,
+		UserMetadata:       opts.UserMetadata,
+	})
+	if err != nil {
+		return "", err
+	}
+	return info.Key, nil
+}
+
 // IsFileExists checks whether a file exists in the S3-compatible storage bucket.
 func (s *Client) IsFileExists(ctx context.Context, key string) (bool, error) {
 	key = s.keyPreprocess(key)
@@ -138,7 +322,7 @@ func (s *Client) IsFileExists(ctx context.Context, key string) (bool, error) {
 }
 
 // DownloadFile retrieves a file from S3-compatible storage.
-// Returns the file reader, content type, and content size.
+// Returns the file reader, content type, content size, and the object metadata.
 func (s *Client) DownloadFile(ctx context.Context, key string) (storage.DownloadResult, error) {
 	key = s.keyPreprocess(key)
 	object, err := s.client.GetObject(ctx, s.config.Bucket, key, minio.GetObjectOptions{})
@@ -156,13 +340,67 @@ func (s *Client) DownloadFile(ctx context.Context, key string) (storage.Download
 		}
 		return storage.DownloadResult{}, err
 	}
+	stat := objectStat(info)
+	return storage.DownloadResult{
+		Reader:       object,
+		MIME:         stat.MIME,
+		Size:         stat.Size,
+		ETag:         stat.ETag,
+		ModTime:      stat.ModTime,
+		FileMetadata: stat.FileMetadata,
+	}, nil
+}
+
+// DownloadFileRange retrieves part of a file from S3-compatible storage with a ranged GET.
+func (s *Client) DownloadFileRange(ctx context.Context, key string, offset int64, length int64) (storage.DownloadResult, error) {
+	if offset < 0 || length == 0 {
+		return storage.DownloadResult{}, storageerr.ErrorRangeInvalid
+	}
+	key = s.keyPreprocess(key)
+	opts := minio.GetObjectOptions{}
+	var err error
+	switch {
+	case length > 0:
+		err = opts.SetRange(offset, offset+length-1)
+	case offset > 0:
+		err = opts.SetRange(offset, 0)
+	}
+	if err != nil {
+		return storage.DownloadResult{}, err
+	}
+	object, err := s.client.GetObject(ctx, s.config.Bucket, key, opts)
+	if err != nil {
+		return storage.DownloadResult{}, convertDownloadError(err)
+	}
+	info, err := object.Stat()
+	if err != nil {
+		_ = object.Close()
+		return storage.DownloadResult{}, convertDownloadError(err)
+	}
+	stat := objectStat(info)
 	return storage.DownloadResult{
-		Reader: object,
-		MIME:   info.ContentType,
-		Size:   info.Size,
+		Reader:       object,
+		MIME:         stat.MIME,
+		Size:         stat.Size,
+		ETag:         stat.ETag,
+		ModTime:      stat.ModTime,
+		FileMetadata: stat.FileMetadata,
 	}, nil
 }
 
+// StatFile returns the object information and metadata with a HEAD request.
+func (s *Client) StatFile(ctx context.Context, key string) (storage.FileStat, error) {
+	key = s.keyPreprocess(key)
+	info, err := s.client.StatObject(ctx, s.config.Bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if isNoSuchKeyError(err) {
+			return storage.FileStat{}, storageerr.ErrorNotFound
+		}
+		return storage.FileStat{}, err
+	}
+	return objectStat(info), nil
+}
+
 // DeleteFile removes a file from the S3-compatible storage bucket.
 func (s *Client) DeleteFile(ctx context.Context, key string) error {
 	key = s.keyPreprocess(key)
@@ -217,6 +455,75 @@ func (s *Client) CopyFile(ctx context.Context, sourceKey string, destinationKey
 	return nil
 }
 
+// ListFiles lists objects with ListObjectsV2; the cursor is the S3 continuation token.
+// S3 listings carry no content type, so MIME is derived from the key extension.
+// The MinIO listing API takes no context, so the request cannot be cancelled.
+func (s *Client) ListFiles(ctx context.Context, req storage.ListFilesRequest) (storage.ListFilesResult, error) {
+	limit, err := storage.ListLimit(req.Limit)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	prefix := s.keyPreprocess(req.Prefix)
+	res, err := s.core().ListObjectsV2(s.config.Bucket, prefix, "", req.Cursor, req.Delimiter, limit)
+	if err != nil {
+		return storage.ListFilesResult{}, err
+	}
+	result := storage.ListFilesResult{
+		Files: make([]storage.FileInfo, 0, len(res.Contents)),
+	}
+	for _, object := range res.Contents {
+		result.Files = append(result.Files, storage.FileInfo{
+			Key:     object.Key,
+			Size:    object.Size,
+			ModTime: object.LastModified,
+			ETag:    object.ETag,
+			MIME:    mime.TypeByExtension(path.Ext(object.Key)),
+		})
+	}
+	for _, commonPrefix := range res.CommonPrefixes {
+		result.Prefixes = append(result.Prefixes, commonPrefix.Prefix)
+	}
+	if res.IsTruncated {
+		result.NextCursor = res.NextContinuationToken
+	}
+	return result, nil
+}
+
+func objectStat(info minio.ObjectInfo) storage.FileStat {
+	var userMetadata map[string]string
+	if len(info.UserMetadata) > 0 {
+		userMetadata = make(map[string]string, len(info.UserMetadata))
+		for k, v := range info.UserMetadata {
+			userMetadata[strings.ToLower(k)] = v
+		}
+	}
+	return storage.FileStat{
+		FileInfo: storage.FileInfo{
+			Key:     info.Key,
+			Size:    info.Size,
+			ModTime: info.LastModified,
+			ETag:    info.ETag,
+			MIME:    info.ContentType,
+		},
+		FileMetadata: storage.FileMetadata{
+			CacheControl:       info.Metadata.Get("Cache-Control"),
+			ContentDisposition: info.Metadata.Get("Content-Disposition"),
+			UserMetadata:       userMetadata,
+		},
+	}
+}
+
+func convertDownloadError(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case minio.NoSuchKey:
+		return storageerr.ErrorNotFound
+	case minio.InvalidRange:
+		return storageerr.ErrorRangeInvalid
+	default:
+		return err
+	}
+}
+
 func isNoSuchKeyError(err error) bool {
 	return minio.ToErrorResponse(err).Code == minio.NoSuchKey
 }
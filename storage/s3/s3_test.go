@@ -0,0 +1,45 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+func TestContentTypeCondition(t *testing.T) {
+	tests := []struct {
+		types   []string
+		prefix  string
+		exact   bool
+		wantErr error
+	}{
+		{types: []string{"Image/PNG"}, prefix: "image/png", exact: true},
+		{types: []string{"image/*"}, prefix: "image/"},
+		{types: []string{"*/*"}, prefix: ""},
+		{types: []string{"image/png", "*/*"}, prefix: ""},
+		{types: []string{"image/png", "application/pdf"}, wantErr: storageerr.ErrorUploadPolicyUnsupported},
+	}
+	for _, tt := range tests {
+		prefix, exact, err := contentTypeCondition(tt.types)
+		if !errors.Is(err, tt.wantErr) || prefix != tt.prefix || exact != tt.exact {
+			t.Errorf("contentTypeCondition(%v) = %q, %v, %v, want %q, %v, %v", tt.types, prefix, exact, err, tt.prefix, tt.exact, tt.wantErr)
+		}
+	}
+}
+
+func TestGenerateUploadAuthRejectsSeveralMIMETypes(t *testing.T) {
+	client, err := NewClient(Config{Endpoint: "localhost:9000", Bucket: "bucket"})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	_, err = client.GenerateUploadAuth(context.Background(), storage.UploadAuthRequest{
+		FileName:     "a.png",
+		UploadPolicy: storage.UploadPolicy{AllowedMIMETypes: []string{"image/png", "image/jpeg"}},
+	})
+	if !errors.Is(err, storageerr.ErrorUploadPolicyUnsupported) {
+		t.Fatalf("GenerateUploadAuth() error = %v, want %v", err, storageerr.ErrorUploadPolicyUnsupported)
+	}
+}
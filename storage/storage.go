@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"net/url"
+	"time"
 )
 
 // URLHandler provides URL generation and key extraction capabilities for storage backends.
@@ -26,12 +27,21 @@ type URLHandler interface {
 	ExtractKeyFromURLWithMode(uri string, strict bool) (string, error)
 }
 
+// SignedURLGenerator provides time-limited download URLs for files in private buckets.
+type SignedURLGenerator interface {
+	// GenerateSignedURL creates a URL that allows downloading the file until it expires.
+	// Backends cap the expiry; S3, for example, allows at most 7 days.
+	GenerateSignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
 // UploadAuthorizationType indicates how upload authorization should be interpreted by clients.
 type UploadAuthorizationType string
 
 const (
 	UploadAuthorizationTypeURL   UploadAuthorizationType = "url"
 	UploadAuthorizationTypeToken UploadAuthorizationType = "token"
+	// UploadAuthorizationTypeForm is a multipart/form-data POST to Value, sending Fields before the file field.
+	UploadAuthorizationTypeForm UploadAuthorizationType = "form"
 )
 
 // UploadAuthorization carries the upload authorization data for client-side uploads.
@@ -40,6 +50,7 @@ type UploadAuthorization struct {
 	Value   string                  `json:"value" yaml:"value"`
 	Method  string                  `json:"method" yaml:"method"`
 	Headers map[string]string       `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Fields  map[string]string       `json:"fields,omitempty" yaml:"fields,omitempty"`
 }
 
 // UploadFileInfo contains the finalized storage information for an upload.
@@ -63,10 +74,29 @@ const (
 	UploadNamingStrategyOriginal  UploadNamingStrategy = "original"
 )
 
+// UploadPolicy constrains what a client may upload with an upload authorization.
+// The zero value imposes no constraints and uses the backend's default expiry.
+type UploadPolicy struct {
+	// MaxSize is the largest file size in bytes.
+	MaxSize int64 `json:"max_size,omitempty" yaml:"max_size,omitempty"`
+	// AllowedMIMETypes lists the accepted content types, such as "image/png" or "image/*".
+	AllowedMIMETypes []string `json:"allowed_mime_types,omitempty" yaml:"allowed_mime_types,omitempty"`
+	// Expires is how long the authorization stays valid.
+	Expires time.Duration `json:"expires,omitempty" yaml:"expires,omitempty"`
+	// InsertOnly rejects uploads to a key that already holds a file.
+	InsertOnly bool `json:"insert_only,omitempty" yaml:"insert_only,omitempty"`
+}
+
+// IsZero reports whether the policy imposes no constraints.
+func (p UploadPolicy) IsZero() bool {
+	return p.MaxSize == 0 && len(p.AllowedMIMETypes) == 0 && p.Expires == 0 && !p.InsertOnly
+}
+
 // UploadAuthRequest describes the input for upload authorization generation.
 type UploadAuthRequest struct {
-	FileName string `json:"file_name" yaml:"file_name"`
-	Dir      string `json:"dir,omitempty" yaml:"dir,omitempty"`
+	FileName     string `json:"file_name" yaml:"file_name"`
+	Dir          string `json:"dir,omitempty" yaml:"dir,omitempty"`
+	UploadPolicy `yaml:",inline"`
 }
 
 // UploadAuthorizer provides secure upload authorization generation for client-side uploads.
@@ -76,6 +106,55 @@ type UploadAuthorizer interface {
 	GenerateUploadAuth(ctx context.Context, req UploadAuthRequest) (UploadAuthResult, error)
 }
 
+// UploadCompletion describes a client-side upload that finished and was validated.
+type UploadCompletion struct {
+	FileInfo `yaml:",inline"`
+	URL      string `json:"url" yaml:"url"`
+}
+
+// UploadCompletionHook is notified of completed client-side uploads.
+type UploadCompletionHook func(ctx context.Context, completion UploadCompletion) error
+
+// UploadConfirmer validates client-side uploads that clients sent to the backend directly,
+// for backends that cannot notify the server when an upload finishes.
+type UploadConfirmer interface {
+	// ConfirmUpload checks that the file exists and satisfies the upload policy it was authorized with.
+	// Files that break the policy are deleted.
+	ConfirmUpload(ctx context.Context, key string) (UploadCompletion, error)
+}
+
+// MultipartUploadAuthRequest describes the input for multipart upload authorization.
+// Upload policies are not supported for multipart uploads and are rejected.
+type MultipartUploadAuthRequest struct {
+	UploadAuthRequest `yaml:",inline"`
+	PartCount         int `json:"part_count" yaml:"part_count"`
+}
+
+// PartUploadAuthorization carries the upload authorization for a single part of a multipart upload.
+type PartUploadAuthorization struct {
+	PartNumber    int                 `json:"part_number" yaml:"part_number"`
+	Authorization UploadAuthorization `json:"authorization" yaml:"authorization"`
+}
+
+// MultipartUploadAuthResult is the structured result for generating multipart upload authorization.
+type MultipartUploadAuthResult struct {
+	UploadID string                    `json:"upload_id" yaml:"upload_id"`
+	Parts    []PartUploadAuthorization `json:"parts" yaml:"parts"`
+	File     UploadFileInfo            `json:"file" yaml:"file"`
+}
+
+// MultipartUploadAuthorizer provides upload authorization for resumable client-side uploads of large files.
+// Clients upload each part to its authorization and report back when all parts are sent; the server
+// then finishes the upload through MultipartUploader.
+type MultipartUploadAuthorizer interface {
+	// GenerateMultipartUploadAuth initiates a multipart upload and authorizes parts 1 to PartCount.
+	GenerateMultipartUploadAuth(ctx context.Context, req MultipartUploadAuthRequest) (MultipartUploadAuthResult, error)
+
+	// GeneratePartUploadAuth authorizes the given parts of an existing multipart upload again,
+	// typically to resume an interrupted upload after the earlier authorizations expired.
+	GeneratePartUploadAuth(ctx context.Context, key string, uploadID string, partNumbers []int) ([]PartUploadAuthorization, error)
+}
+
 // FileUploader provides file upload capabilities to the storage backend.
 type FileUploader interface {
 	// UploadFile uploads data from a reader to the storage backend with the specified key.
@@ -87,11 +166,68 @@ type FileUploader interface {
 	UploadLocalFile(ctx context.Context, file string, key string) (string, error)
 }
 
+// FileMetadata holds the HTTP headers and custom metadata stored with a file.
+type FileMetadata struct {
+	CacheControl       string `json:"cache_control,omitempty" yaml:"cache_control,omitempty"`
+	ContentDisposition string `json:"content_disposition,omitempty" yaml:"content_disposition,omitempty"`
+	// UserMetadata holds custom key-value pairs. Keys are case-insensitive and reported in lower case.
+	UserMetadata map[string]string `json:"user_metadata,omitempty" yaml:"user_metadata,omitempty"`
+}
+
+// UploadOptions describes the metadata to store with an uploaded file.
+type UploadOptions struct {
+	// ContentType is the MIME type served for the file.
+	// Empty means the type derived from the key extension.
+	ContentType  string `json:"content_type,omitempty" yaml:"content_type,omitempty"`
+	FileMetadata `yaml:",inline"`
+}
+
+// FileOptionsUploader provides uploads that store metadata with the file.
+type FileOptionsUploader interface {
+	// UploadFileWithOptions uploads data from a reader like UploadFile and stores the metadata with it.
+	// Uploading to the key again without options clears the metadata.
+	UploadFileWithOptions(ctx context.Context, file io.Reader, key string, opts UploadOptions) (string, error)
+}
+
+// UploadedPart describes a part stored in a multipart upload.
+type UploadedPart struct {
+	PartNumber int    `json:"part_number" yaml:"part_number"`
+	ETag       string `json:"etag" yaml:"etag"`
+	Size       int64  `json:"size" yaml:"size"`
+}
+
+// MultipartUploader provides resumable uploads of large files in separately uploaded parts.
+// Part numbers range from 1 to MaxPartNumber. Parts may be uploaded in any order, and uploading
+// a part again replaces it. Backends may impose a minimum size on every part but the last.
+type MultipartUploader interface {
+	// InitiateMultipartUpload starts a multipart upload to the specified key and returns its upload ID.
+	InitiateMultipartUpload(ctx context.Context, key string) (string, error)
+
+	// UploadPart stores data from a reader as the given part of the upload.
+	// Size is the length of the part, or -1 if unknown; backends that require it may reject -1.
+	UploadPart(ctx context.Context, key string, uploadID string, partNumber int, part io.Reader, size int64) (UploadedPart, error)
+
+	// ListParts returns the parts uploaded so far ordered by part number,
+	// so that an interrupted upload can resume with the missing ones.
+	ListParts(ctx context.Context, key string, uploadID string) ([]UploadedPart, error)
+
+	// CompleteMultipartUpload assembles the parts in part number order into the file.
+	// Returns the storage key or an error if a part is missing or its ETag does not match.
+	CompleteMultipartUpload(ctx context.Context, key string, uploadID string, parts []UploadedPart) (string, error)
+
+	// AbortMultipartUpload discards the upload and all of its uploaded parts.
+	AbortMultipartUpload(ctx context.Context, key string, uploadID string) error
+}
+
 // DownloadResult is the structured output for download operations.
+// ETag, ModTime and the metadata are left empty by backends that do not track them.
 type DownloadResult struct {
-	Reader io.ReadCloser
-	MIME   string
-	Size   int64
+	Reader  io.ReadCloser
+	MIME    string
+	Size    int64
+	ETag    string
+	ModTime time.Time
+	FileMetadata
 }
 
 // FileDownloader provides file download and existence checking capabilities.
@@ -104,12 +240,72 @@ type FileDownloader interface {
 	DownloadFile(ctx context.Context, key string) (DownloadResult, error)
 }
 
+// RangeDownloader provides partial downloads, for serving HTTP range requests.
+type RangeDownloader interface {
+	// DownloadFileRange retrieves length bytes of a file starting at offset; a negative length reads
+	// to the end of the file. DownloadResult.Size is the number of bytes returned, which is less than
+	// length if the file ends earlier. Returns storageerr.ErrorRangeInvalid if offset is not within the file.
+	// The caller is responsible for closing DownloadResult.Reader.
+	DownloadFileRange(ctx context.Context, key string, offset int64, length int64) (DownloadResult, error)
+}
+
 // FileDeleter provides file deletion capabilities.
 type FileDeleter interface {
 	// DeleteFile removes a file from the storage backend.
 	DeleteFile(ctx context.Context, key string) error
 }
 
+// FileInfo describes a stored file.
+type FileInfo struct {
+	Key     string    `json:"key" yaml:"key"`
+	Size    int64     `json:"size" yaml:"size"`
+	ModTime time.Time `json:"mod_time" yaml:"mod_time"`
+	ETag    string    `json:"etag,omitempty" yaml:"etag,omitempty"`
+	MIME    string    `json:"mime,omitempty" yaml:"mime,omitempty"`
+}
+
+// ListFilesRequest describes the input for listing files.
+type ListFilesRequest struct {
+	// Prefix restricts the listing to keys starting with it.
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// Delimiter groups keys that contain it after the prefix into Prefixes, like directories.
+	// For example, with Prefix "docs/" and Delimiter "/", "docs/a/b.txt" is reported as "docs/a/".
+	Delimiter string `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
+	// Cursor continues a listing from the NextCursor of the previous page.
+	Cursor string `json:"cursor,omitempty" yaml:"cursor,omitempty"`
+	// Limit caps the number of files and prefixes returned, between 1 and MaxListLimit.
+	// Zero means MaxListLimit.
+	Limit int `json:"limit,omitempty" yaml:"limit,omitempty"`
+}
+
+// ListFilesResult is one page of a file listing.
+type ListFilesResult struct {
+	Files    []FileInfo `json:"files" yaml:"files"`
+	Prefixes []string   `json:"prefixes,omitempty" yaml:"prefixes,omitempty"`
+	// NextCursor is empty on the last page. Cursors are opaque and backend specific.
+	NextCursor string `json:"next_cursor,omitempty" yaml:"next_cursor,omitempty"`
+}
+
+// FileLister provides paginated listing of stored files.
+type FileLister interface {
+	// ListFiles returns a page of the files matching the request in ascending key order.
+	// A page may hold fewer entries than the limit even when more follow.
+	ListFiles(ctx context.Context, req ListFilesRequest) (ListFilesResult, error)
+}
+
+// FileStat describes a stored file together with its metadata.
+type FileStat struct {
+	FileInfo     `yaml:",inline"`
+	FileMetadata `yaml:",inline"`
+}
+
+// FileStatter provides file metadata lookup without downloading the content.
+type FileStatter interface {
+	// StatFile returns the size, MIME type, ETag, modification time and metadata of a file.
+	// Returns storageerr.ErrorNotFound if the file does not exist.
+	StatFile(ctx context.Context, key string) (FileStat, error)
+}
+
 // FileMoverCopier provides file moving and copying operations within the storage backend.
 type FileMoverCopier interface {
 	// MoveFile relocates a file from source to destination key.
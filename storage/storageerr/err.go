@@ -2,6 +2,7 @@ package storageerr
 
 import (
 	"errors"
+	"net/http"
 
 	"github.com/go-sphere/httpx"
 )
@@ -16,4 +17,28 @@ var (
 
 	// ErrorFileNameInvalid indicates that the provided file name or path is invalid or unsafe.
 	ErrorFileNameInvalid = httpx.BadRequestError(errors.New("file name invalid"))
+
+	// ErrorMetadataInvalid indicates that an upload content type, header or user metadata entry is malformed.
+	ErrorMetadataInvalid = httpx.BadRequestError(errors.New("file metadata invalid"))
+
+	// ErrorFileTooLarge indicates that an upload exceeds the maximum size of its upload policy.
+	ErrorFileTooLarge = httpx.NewWithStatus(http.StatusRequestEntityTooLarge, "file too large")
+
+	// ErrorMIMETypeNotAllowed indicates that the content type of an upload is not allowed by its upload policy.
+	ErrorMIMETypeNotAllowed = httpx.NewWithStatus(http.StatusUnsupportedMediaType, "mime type not allowed")
+
+	// ErrorUploadPolicyUnsupported indicates that a backend cannot enforce an upload policy constraint.
+	ErrorUploadPolicyUnsupported = httpx.BadRequestError(errors.New("upload policy not supported"))
+
+	// ErrorUploadCallbackInvalid indicates that an upload callback is not signed by the storage provider.
+	ErrorUploadCallbackInvalid = httpx.NewForbiddenError("upload callback invalid")
+
+	// ErrorRangeInvalid indicates that a requested byte range does not overlap the file.
+	ErrorRangeInvalid = httpx.NewWithStatus(http.StatusRequestedRangeNotSatisfiable, "range not satisfiable")
+
+	// ErrorUploadNotFound indicates that the multipart upload does not exist, or was completed or aborted.
+	ErrorUploadNotFound = httpx.NotFoundError(errors.New("multipart upload not found"))
+
+	// ErrorPartInvalid indicates that a multipart part number is out of range, or a part is missing or mismatched.
+	ErrorPartInvalid = httpx.BadRequestError(errors.New("multipart part invalid"))
 )
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/storage/archive"
+)
+
+func TestArchiveResponseOverHTTP(t *testing.T) {
+	router := newMiniRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx := context.Background()
+	store := newInMemoryStorage(t)
+	entries := []archive.Entry{
+		{Key: "attachments/a.txt", Name: "a.txt"},
+		{Key: "attachments/b.txt", Name: "b.txt"},
+	}
+	for _, entry := range entries {
+		if _, err := store.UploadFile(ctx, strings.NewReader("content of "+entry.Name), entry.Key); err != nil {
+			t.Fatalf("UploadFile(%q) error = %v", entry.Key, err)
+		}
+	}
+	router.GET("/attachments.zip", func(ctx httpx.Context) error {
+		return archive.WriteResponse(ctx, store, "attachments.zip", entries)
+	})
+
+	resp, err := server.Client().Get(server.URL + "/attachments.zip")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("status = %d, content type = %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if _, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); params["filename"] != "attachments.zip" {
+		t.Fatalf("Content-Disposition = %q", resp.Header.Get("Content-Disposition"))
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() error = %v", err)
+	}
+	if len(reader.File) != 2 {
+		t.Fatalf("archive entries = %d, want 2", len(reader.File))
+	}
+	for _, file := range reader.File {
+		f, oErr := file.Open()
+		if oErr != nil {
+			t.Fatalf("open %s: %v", file.Name, oErr)
+		}
+		data, _ := io.ReadAll(f)
+		_ = f.Close()
+		if string(data) != "content of "+file.Name {
+			t.Fatalf("content of %s = %q", file.Name, data)
+		}
+	}
+}
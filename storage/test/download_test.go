@@ -0,0 +1,345 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/cache/memory"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/fileserver"
+	"github.com/go-sphere/sphere/storage/kvcache"
+	"github.com/go-sphere/sphere/storage/local"
+	"github.com/go-sphere/sphere/storage/qiniu"
+	"github.com/go-sphere/sphere/storage/s3"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+var _ storage.RangeDownloader = (*s3.Client)(nil)
+var _ storage.RangeDownloader = (*qiniu.Client)(nil)
+var _ storage.RangeDownloader = (*local.Client)(nil)
+var _ storage.RangeDownloader = (*kvcache.Client)(nil)
+var _ storage.RangeDownloader = (*fileserver.FileServer)(nil)
+
+const rangeContent = "0123456789abcdef"
+
+func TestRangeDownloaderContract(t *testing.T) {
+	stores := map[string]metadataStorage{
+		"kvcache memory": newInMemoryStorage(t),
+	}
+	for name, store := range listableStorages(t) {
+		stores[name] = store.(metadataStorage)
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if _, err := store.UploadFile(ctx, bytes.NewBufferString(rangeContent), "media/clip.txt"); err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+			ranger := store.(storage.RangeDownloader)
+			tests := []struct {
+				offset, length int64
+				want           string
+			}{
+				{0, 4, "0123"},
+				{10, -1, "abcdef"},
+				{14, 10, "ef"},
+			}
+			for _, tt := range tests {
+				result, err := ranger.DownloadFileRange(ctx, "media/clip.txt", tt.offset, tt.length)
+				if err != nil {
+					t.Fatalf("DownloadFileRange(%d, %d) error = %v", tt.offset, tt.length, err)
+				}
+				got, err := io.ReadAll(result.Reader)
+				_ = result.Reader.Close()
+				if err != nil {
+					t.Fatalf("read range: %v", err)
+				}
+				if string(got) != tt.want || result.Size != int64(len(tt.want)) {
+					t.Fatalf("DownloadFileRange(%d, %d) = %q (size %d), want %q", tt.offset, tt.length, got, result.Size, tt.want)
+				}
+			}
+			if _, err := ranger.DownloadFileRange(ctx, "media/clip.txt", 16, 1); !errors.Is(err, storageerr.ErrorRangeInvalid) {
+				t.Fatalf("DownloadFileRange() past the end error = %v, want %v", err, storageerr.ErrorRangeInvalid)
+			}
+			if _, err := ranger.DownloadFileRange(ctx, "media/missing.txt", 0, 1); !errors.Is(err, storageerr.ErrorNotFound) {
+				t.Fatalf("DownloadFileRange() missing error = %v, want %v", err, storageerr.ErrorNotFound)
+			}
+		})
+	}
+}
+
+func TestFileServerRangeAndConditionalRequests(t *testing.T) {
+	localStore, err := local.NewClient(local.Config{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("new local client: %v", err)
+	}
+	server, fileServer := newDownloadServer(t, localStore)
+	ctx := context.Background()
+	if _, err = fileServer.UploadFile(ctx, bytes.NewBufferString(rangeContent), "media/clip.txt"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	fileURL := fileServer.GenerateURL("media/clip.txt")
+
+	full := doRequest(t, server, http.MethodGet, fileURL, nil)
+	if full.status != http.StatusOK || full.body != rangeContent {
+		t.Fatalf("GET = %d %q, want 200 with the whole file", full.status, full.body)
+	}
+	if full.header.Get("Accept-Ranges") != "bytes" {
+		t.Fatalf("Accept-Ranges = %q, want bytes", full.header.Get("Accept-Ranges"))
+	}
+	etag := full.header.Get("ETag")
+	lastModified := full.header.Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("ETag = %q, Last-Modified = %q, want both set", etag, lastModified)
+	}
+
+	t.Run("ranges", func(t *testing.T) {
+		tests := []struct {
+			rangeHeader  string
+			want         string
+			contentRange string
+		}{
+			{"bytes=2-5", "2345", "bytes 2-5/16"},
+			{"bytes=12-", "cdef", "bytes 12-15/16"},
+			{"bytes=-3", "def", "bytes 13-15/16"},
+			{"bytes=10-100", "abcdef", "bytes 10-15/16"},
+		}
+		for _, tt := range tests {
+			resp := doRequest(t, server, http.MethodGet, fileURL, map[string]string{"Range": tt.rangeHeader})
+			if resp.status != http.StatusPartialContent || resp.body != tt.want {
+				t.Fatalf("Range %s = %d %q, want 206 %q", tt.rangeHeader, resp.status, resp.body, tt.want)
+			}
+			if got := resp.header.Get("Content-Range"); got != tt.contentRange {
+				t.Fatalf("Range %s Content-Range = %q, want %q", tt.rangeHeader, got, tt.contentRange)
+			}
+		}
+
+		unsatisfiable := doRequest(t, server, http.MethodGet, fileURL, map[string]string{"Range": "bytes=16-"})
+		if unsatisfiable.status != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatalf("unsatisfiable range status = %d, want 416", unsatisfiable.status)
+		}
+		if got := unsatisfiable.header.Get("Content-Range"); got != "bytes */16" {
+			t.Fatalf("unsatisfiable Content-Range = %q, want %q", got, "bytes */16")
+		}
+
+		for _, header := range []string{"bytes=0-1,4-5", "items=0-1", "bytes=5-2"} {
+			resp := doRequest(t, server, http.MethodGet, fileURL, map[string]string{"Range": header})
+			if resp.status != http.StatusOK || resp.body != rangeContent {
+				t.Fatalf("Range %s = %d %q, want the whole file", header, resp.status, resp.body)
+			}
+		}
+	})
+
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("ETag = %q, want a weak validator", etag)
+	}
+
+	t.Run("if-range", func(t *testing.T) {
+		resp := doRequest(t, server, http.MethodGet, fileURL, map[string]string{"Range": "bytes=0-1", "If-Range": lastModified})
+		if resp.status != http.StatusPartialContent || resp.body != "01" {
+			t.Fatalf("matching If-Range = %d %q, want 206", resp.status, resp.body)
+		}
+		// If-Range needs a strong validator, so the weak ETag never matches.
+		resp = doRequest(t, server, http.MethodGet, fileURL, map[string]string{"Range": "bytes=0-1", "If-Range": etag})
+		if resp.status != http.StatusOK || resp.body != rangeContent {
+			t.Fatalf("weak If-Range = %d %q, want the whole file", resp.status, resp.body)
+		}
+		resp = doRequest(t, server, http.MethodGet, fileURL, map[string]string{"Range": "bytes=0-1", "If-Range": `"stale"`})
+		if resp.status != http.StatusOK || resp.body != rangeContent {
+			t.Fatalf("stale If-Range = %d %q, want the whole file", resp.status, resp.body)
+		}
+	})
+
+	t.Run("conditional", func(t *testing.T) {
+		for _, header := range []map[string]string{
+			{"If-None-Match": etag},
+			{"If-None-Match": `"other", ` + strings.TrimPrefix(etag, "W/")},
+			{"If-None-Match": "*"},
+			{"If-Modified-Since": lastModified},
+			{"If-Modified-Since": time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)},
+		} {
+			resp := doRequest(t, server, http.MethodGet, fileURL, header)
+			if resp.status != http.StatusNotModified || resp.body != "" {
+				t.Fatalf("%v = %d %q, want 304", header, resp.status, resp.body)
+			}
+			if resp.header.Get("ETag") != etag {
+				t.Fatalf("%v ETag = %q, want %q", header, resp.header.Get("ETag"), etag)
+			}
+		}
+		for _, header := range []map[string]string{
+			{"If-None-Match": `"other"`},
+			{"If-Modified-Since": time.Unix(0, 0).UTC().Format(http.TimeFormat)},
+			// If-None-Match takes precedence over If-Modified-Since.
+			{"If-None-Match": `"other"`, "If-Modified-Since": lastModified},
+		} {
+			resp := doRequest(t, server, http.MethodGet, fileURL, header)
+			if resp.status != http.StatusOK || resp.body != rangeContent {
+				t.Fatalf("%v = %d %q, want 200", header, resp.status, resp.body)
+			}
+		}
+	})
+
+	t.Run("head", func(t *testing.T) {
+		resp := doRequest(t, server, http.MethodHead, fileURL, nil)
+		if resp.status != http.StatusOK || resp.body != "" {
+			t.Fatalf("HEAD = %d %q, want 200 without body", resp.status, resp.body)
+		}
+		if got := resp.header.Get("Content-Length"); got != strconv.Itoa(len(rangeContent)) {
+			t.Fatalf("HEAD Content-Length = %q, want %d", got, len(rangeContent))
+		}
+		if got := resp.header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+			t.Fatalf("HEAD Content-Type = %q", got)
+		}
+		partial := doRequest(t, server, http.MethodHead, fileURL, map[string]string{"Range": "bytes=0-3"})
+		if partial.status != http.StatusPartialContent || partial.header.Get("Content-Length") != "4" {
+			t.Fatalf("ranged HEAD = %d, Content-Length %q, want 206 and 4", partial.status, partial.header.Get("Content-Length"))
+		}
+		missing := doRequest(t, server, http.MethodHead, fileServer.GenerateURL("media/missing.txt"), nil)
+		if missing.status != http.StatusNotFound {
+			t.Fatalf("HEAD missing status = %d, want 404", missing.status)
+		}
+	})
+}
+
+// plainStorage hides every optional capability of the wrapped store.
+type plainStorage struct {
+	storage.Storage
+}
+
+func TestFileServerConditionalRequestsWithoutStat(t *testing.T) {
+	localStore, err := local.NewClient(local.Config{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("new local client: %v", err)
+	}
+	server, fileServer := newDownloadServer(t, plainStorage{Storage: localStore})
+	if _, err = fileServer.UploadFile(context.Background(), bytes.NewBufferString(rangeContent), "clip.txt"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	fileURL := fileServer.GenerateURL("clip.txt")
+
+	resp := doRequest(t, server, http.MethodGet, fileURL, map[string]string{"Range": "bytes=0-1"})
+	if resp.status != http.StatusOK || resp.body != rangeContent {
+		t.Fatalf("Range without range support = %d %q, want the whole file", resp.status, resp.body)
+	}
+	if resp.header.Get("Accept-Ranges") != "" {
+		t.Fatalf("Accept-Ranges = %q, want none", resp.header.Get("Accept-Ranges"))
+	}
+	notModified := doRequest(t, server, http.MethodGet, fileURL, map[string]string{"If-None-Match": resp.header.Get("ETag")})
+	if notModified.status != http.StatusNotModified {
+		t.Fatalf("If-None-Match without StatFile status = %d, want 304", notModified.status)
+	}
+	head := doRequest(t, server, http.MethodHead, fileURL, nil)
+	if head.status != http.StatusOK || head.header.Get("Content-Length") != strconv.Itoa(len(rangeContent)) {
+		t.Fatalf("HEAD without StatFile = %d, Content-Length %q", head.status, head.header.Get("Content-Length"))
+	}
+}
+
+func newDownloadServer(t *testing.T, store storage.Storage, options ...fileserver.Option) (*httptest.Server, *fileserver.FileServer) {
+	t.Helper()
+	router := newMiniRouter()
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	tokenCache := memory.NewByteCache()
+	t.Cleanup(func() { _ = tokenCache.Close() })
+	fileServer, err := fileserver.NewCDNAdapter(
+		fileserver.Config{
+			PutBase: server.URL + "/upload",
+			GetBase: server.URL + "/files",
+		},
+		tokenCache,
+		store,
+		options...,
+	)
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	fileServer.RegisterFileDownloader(router.Group("/files"))
+	return server, fileServer
+}
+
+type downloadResponse struct {
+	status int
+	header http.Header
+	body   string
+}
+
+func doRequest(t *testing.T, server *httptest.Server, method string, url string, headers map[string]string) downloadResponse {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("new %s request: %v", method, err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read %s body: %v", method, err)
+	}
+	return downloadResponse{status: resp.StatusCode, header: resp.Header, body: string(body)}
+}
+
+func TestFileServerSignedDownloadOverHTTP(t *testing.T) {
+	router := newMiniRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+	tokenCache := memory.NewByteCache()
+	t.Cleanup(func() { _ = tokenCache.Close() })
+	fileServer, err := fileserver.NewCDNAdapter(
+		fileserver.Config{
+			PutBase:         server.URL + "/upload",
+			GetBase:         server.URL + "/files",
+			SigningKeys:     []fileserver.SigningKey{{ID: "k1", Secret: "secret"}},
+			PrivateDownload: true,
+		},
+		tokenCache,
+		newInMemoryStorage(t),
+	)
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	fileServer.RegisterFileDownloader(router.Group("/files"))
+	ctx := context.Background()
+	if _, err = fileServer.UploadFile(ctx, bytes.NewBufferString("secret report"), "private/report.txt"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+
+	signedURL, err := fileServer.GenerateSignedURL(ctx, "private/report.txt", time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateSignedURL() error = %v", err)
+	}
+	resp := doRequest(t, server, http.MethodGet, signedURL, nil)
+	if resp.status != http.StatusOK || resp.body != "secret report" {
+		t.Fatalf("signed GET = %d %q, want 200", resp.status, resp.body)
+	}
+
+	unsigned := doRequest(t, server, http.MethodGet, fileServer.GenerateURL("private/report.txt"), nil)
+	if unsigned.status != http.StatusForbidden {
+		t.Fatalf("unsigned GET status = %d, want 403", unsigned.status)
+	}
+	otherFile := strings.Replace(signedURL, "report.txt", "other.txt", 1)
+	if resp = doRequest(t, server, http.MethodGet, otherFile, nil); resp.status != http.StatusForbidden {
+		t.Fatalf("signature reused for another key status = %d, want 403", resp.status)
+	}
+	expired, err := fileServer.GenerateSignedURL(ctx, "private/report.txt", time.Nanosecond)
+	if err != nil {
+		t.Fatalf("GenerateSignedURL() error = %v", err)
+	}
+	time.Sleep(time.Second)
+	if resp = doRequest(t, server, http.MethodGet, expired, nil); resp.status != http.StatusForbidden {
+		t.Fatalf("expired GET status = %d, want 403", resp.status)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/go-sphere/sphere/server/httpz"
 	"github.com/go-sphere/sphere/storage"
 	"github.com/go-sphere/sphere/storage/fileserver"
+	"github.com/go-sphere/sphere/storage/local"
 )
 
 func TestFileServerUploadAndDownloadOverHTTP(t *testing.T) {
@@ -100,6 +101,143 @@ func TestFileServerUploadAndDownloadOverHTTP(t *testing.T) {
 	}
 }
 
+func TestFileServerMultipartUploadOverHTTP(t *testing.T) {
+	ctx := context.Background()
+	router := newMiniRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	tokenCache := memory.NewByteCache()
+	t.Cleanup(func() { _ = tokenCache.Close() })
+	localStorage, err := local.NewClient(local.Config{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("new local client: %v", err)
+	}
+	fileServer, err := fileserver.NewCDNAdapter(
+		fileserver.Config{
+			PutBase:      server.URL + "/upload",
+			GetBase:      server.URL + "/files",
+			UploadNaming: storage.UploadNamingStrategyOriginal,
+		},
+		tokenCache,
+		localStorage,
+	)
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	fileServer.RegisterFileUploader(router.Group("/upload"))
+	fileServer.RegisterFileDownloader(router.Group("/files"))
+
+	auth, err := fileServer.GenerateMultipartUploadAuth(ctx, storage.MultipartUploadAuthRequest{
+		UploadAuthRequest: storage.UploadAuthRequest{FileName: "movie.txt", Dir: "videos"},
+		PartCount:         3,
+	})
+	if err != nil {
+		t.Fatalf("GenerateMultipartUploadAuth() error = %v", err)
+	}
+	if auth.File.Key != "videos/movie.txt" || len(auth.Parts) != 3 {
+		t.Fatalf("GenerateMultipartUploadAuth() = %+v, want 3 parts for videos/movie.txt", auth)
+	}
+
+	putPart := func(uri string, data string) *http.Response {
+		t.Helper()
+		req, reqErr := http.NewRequest(http.MethodPut, uri, strings.NewReader(data))
+		if reqErr != nil {
+			t.Fatalf("new PUT request: %v", reqErr)
+		}
+		resp, doErr := server.Client().Do(req)
+		if doErr != nil {
+			t.Fatalf("PUT part request failed: %v", doErr)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	chunks := []string{"part one|", "part two|", "part three"}
+	// Send only the first two parts, as if the connection dropped before the third.
+	for i, part := range auth.Parts[:2] {
+		resp := putPart(part.Authorization.Value, chunks[i])
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			t.Fatalf("part %d status = %d, body = %s", part.PartNumber, resp.StatusCode, string(body))
+		}
+		var result httpz.DataResponse[storage.UploadedPart]
+		if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("decode part response: %v", err)
+		}
+		if result.Data.PartNumber != part.PartNumber || resp.Header.Get("ETag") != result.Data.ETag {
+			t.Fatalf("part response = %+v with ETag %q", result.Data, resp.Header.Get("ETag"))
+		}
+	}
+
+	// Resume: find the missing parts and authorize them again.
+	uploaded, err := fileServer.ListParts(ctx, auth.File.Key, auth.UploadID)
+	if err != nil {
+		t.Fatalf("ListParts() error = %v", err)
+	}
+	if len(uploaded) != 2 {
+		t.Fatalf("ListParts() = %+v, want 2 parts", uploaded)
+	}
+	resumed, err := fileServer.GeneratePartUploadAuth(ctx, auth.File.Key, auth.UploadID, []int{3})
+	if err != nil {
+		t.Fatalf("GeneratePartUploadAuth() error = %v", err)
+	}
+	if resp := putPart(resumed[0].Authorization.Value, chunks[2]); resp.StatusCode != http.StatusOK {
+		t.Fatalf("resumed part status = %d", resp.StatusCode)
+	}
+	if resp := putPart(strings.Replace(resumed[0].Authorization.Value, "/3", "/0", 1), "x"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid part number status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	// The resumed token only covers part 3, so it cannot overwrite the uploaded parts.
+	if resp := putPart(strings.Replace(resumed[0].Authorization.Value, "/3", "/1", 1), "forged"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unissued part number status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	uploaded, err = fileServer.ListParts(ctx, auth.File.Key, auth.UploadID)
+	if err != nil {
+		t.Fatalf("ListParts() error = %v", err)
+	}
+	if _, err = fileServer.CompleteMultipartUpload(ctx, auth.File.Key, auth.UploadID, uploaded); err != nil {
+		t.Fatalf("CompleteMultipartUpload() error = %v", err)
+	}
+
+	getResp, err := server.Client().Get(auth.File.URL)
+	if err != nil {
+		t.Fatalf("GET download request failed: %v", err)
+	}
+	defer func() { _ = getResp.Body.Close() }()
+	all, err := io.ReadAll(getResp.Body)
+	if err != nil {
+		t.Fatalf("read download body: %v", err)
+	}
+	if want := strings.Join(chunks, ""); string(all) != want {
+		t.Fatalf("download body = %q, want %q", string(all), want)
+	}
+}
+
+func TestFileServerMultipartRequiresCapableStore(t *testing.T) {
+	tokenCache := memory.NewByteCache()
+	t.Cleanup(func() { _ = tokenCache.Close() })
+	fileServer, err := fileserver.NewCDNAdapter(
+		fileserver.Config{
+			PutBase: "https://cdn.example.com",
+			GetBase: "https://cdn.example.com",
+		},
+		tokenCache,
+		newInMemoryStorage(t),
+	)
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	_, err = fileServer.GenerateMultipartUploadAuth(context.Background(), storage.MultipartUploadAuthRequest{
+		UploadAuthRequest: storage.UploadAuthRequest{FileName: "movie.mp4"},
+		PartCount:         2,
+	})
+	if err == nil {
+		t.Fatal("expected GenerateMultipartUploadAuth to fail for a store without multipart support")
+	}
+}
+
 type miniRoute struct {
 	method  string
 	pattern string
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-sphere/sphere/storage/fileserver"
+	"github.com/go-sphere/sphere/storage/imageproc"
+)
+
+func TestFileServerImageProcessingOverHTTP(t *testing.T) {
+	store := newInMemoryStorage(t)
+	processor := imageproc.NewProcessor(newInMemoryStorage(t), imageproc.WithMaxDimension(1000))
+	server, fileServer := newDownloadServer(t, store, fileserver.WithImageProcessor(processor))
+
+	src := image.NewNRGBA(image.Rect(0, 0, 80, 40))
+	for y := range 40 {
+		for x := range 80 {
+			src.Set(x, y, color.NRGBA{B: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatalf("png.Encode() error = %v", err)
+	}
+	original := buf.String()
+	ctx := context.Background()
+	if _, err := store.UploadFile(ctx, strings.NewReader(original), "images/banner.png"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if _, err := store.UploadFile(ctx, strings.NewReader("plain text"), "docs/readme.png"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	url := fileServer.GenerateURL("images/banner.png")
+
+	resp := doRequest(t, server, http.MethodGet, url+"?w=20&format=jpg&q=80", nil)
+	if resp.status != http.StatusOK || resp.header.Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("processed image status = %d, Content-Type = %q", resp.status, resp.header.Get("Content-Type"))
+	}
+	img, format, err := image.Decode(strings.NewReader(resp.body))
+	if err != nil {
+		t.Fatalf("decode processed image: %v", err)
+	}
+	if format != "jpeg" || img.Bounds().Dx() != 20 || img.Bounds().Dy() != 10 {
+		t.Fatalf("processed image = %s %dx%d, want jpeg 20x10", format, img.Bounds().Dx(), img.Bounds().Dy())
+	}
+	etag := resp.header.Get("ETag")
+	if etag == "" {
+		t.Fatal("processed image has no ETag")
+	}
+
+	resp = doRequest(t, server, http.MethodGet, url+"?w=20&format=jpg&q=80", map[string]string{"If-None-Match": etag})
+	if resp.status != http.StatusNotModified {
+		t.Fatalf("conditional request status = %d, want %d", resp.status, http.StatusNotModified)
+	}
+	resp = doRequest(t, server, http.MethodHead, url+"?w=30&h=30&mode=crop", nil)
+	if resp.status != http.StatusOK || resp.header.Get("Content-Type") != "image/png" || resp.body != "" {
+		t.Fatalf("HEAD status = %d, Content-Type = %q", resp.status, resp.header.Get("Content-Type"))
+	}
+	if resp = doRequest(t, server, http.MethodGet, url, nil); resp.status != http.StatusOK || resp.body != original {
+		t.Fatalf("unprocessed download status = %d, want the original file", resp.status)
+	}
+
+	for name, uri := range map[string]string{
+		"malformed width":    url + "?w=wide",
+		"too large":          url + "?w=1001",
+		"unsupported format": url + "?format=webp",
+		"not an image":       fileServer.GenerateURL("docs/readme.png") + "?w=10",
+	} {
+		if resp = doRequest(t, server, http.MethodGet, uri, nil); resp.status != http.StatusBadRequest {
+			t.Fatalf("%s status = %d, want %d", name, resp.status, http.StatusBadRequest)
+		}
+	}
+	if resp = doRequest(t, server, http.MethodGet, fileServer.GenerateURL("images/missing.png")+"?w=10", nil); resp.status != http.StatusNotFound {
+		t.Fatalf("missing image status = %d, want %d", resp.status, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/go-sphere/sphere/cache/mcache"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/fileserver"
+	"github.com/go-sphere/sphere/storage/kvcache"
+	"github.com/go-sphere/sphere/storage/local"
+	"github.com/go-sphere/sphere/storage/qiniu"
+	"github.com/go-sphere/sphere/storage/s3"
+)
+
+var _ storage.FileLister = (*s3.Client)(nil)
+var _ storage.FileLister = (*qiniu.Client)(nil)
+var _ storage.FileLister = (*local.Client)(nil)
+var _ storage.FileLister = (*kvcache.Client)(nil)
+var _ storage.FileLister = (*fileserver.FileServer)(nil)
+
+type listableStorage interface {
+	storage.Storage
+	storage.FileLister
+}
+
+func listableStorages(t *testing.T) map[string]listableStorage {
+	t.Helper()
+	localStore, err := local.NewClient(local.Config{RootDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("new local client: %v", err)
+	}
+	kvStore, err := kvcache.NewClient(kvcache.Config{}, mcache.NewByteCache())
+	if err != nil {
+		t.Fatalf("new kvcache client: %v", err)
+	}
+	return map[string]listableStorage{
+		"local":   localStore,
+		"kvcache": kvStore,
+	}
+}
+
+var listedFiles = map[string]string{
+	"a.txt":              "a",
+	"docs/readme.txt":    "readme",
+	"docs/guide.md":      "# guide",
+	"docs/img/logo.png":  "png",
+	"docs/img/photo.jpg": "jpeg",
+	"videos/intro.mp4":   "mp4",
+}
+
+func TestFileListerContract(t *testing.T) {
+	for name, store := range listableStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for key, content := range listedFiles {
+				if _, err := store.UploadFile(ctx, bytes.NewBufferString(content), key); err != nil {
+					t.Fatalf("UploadFile(%q) error = %v", key, err)
+				}
+			}
+
+			t.Run("prefix", func(t *testing.T) {
+				result, err := store.ListFiles(ctx, storage.ListFilesRequest{Prefix: "docs/img/"})
+				if err != nil {
+					t.Fatalf("ListFiles() error = %v", err)
+				}
+				want := []string{"docs/img/logo.png", "docs/img/photo.jpg"}
+				if got := fileKeys(result.Files); !slices.Equal(got, want) {
+					t.Fatalf("keys = %v, want %v", got, want)
+				}
+				if result.NextCursor != "" {
+					t.Fatalf("NextCursor = %q, want empty", result.NextCursor)
+				}
+			})
+
+			t.Run("delimiter", func(t *testing.T) {
+				result, err := store.ListFiles(ctx, storage.ListFilesRequest{Prefix: "docs/", Delimiter: "/"})
+				if err != nil {
+					t.Fatalf("ListFiles() error = %v", err)
+				}
+				wantFiles := []string{"docs/guide.md", "docs/readme.txt"}
+				if got := fileKeys(result.Files); !slices.Equal(got, wantFiles) {
+					t.Fatalf("keys = %v, want %v", got, wantFiles)
+				}
+				if want := []string{"docs/img/"}; !slices.Equal(result.Prefixes, want) {
+					t.Fatalf("prefixes = %v, want %v", result.Prefixes, want)
+				}
+
+				root, err := store.ListFiles(ctx, storage.ListFilesRequest{Delimiter: "/"})
+				if err != nil {
+					t.Fatalf("ListFiles() error = %v", err)
+				}
+				if want := []string{"a.txt"}; !slices.Equal(fileKeys(root.Files), want) {
+					t.Fatalf("root keys = %v, want %v", fileKeys(root.Files), want)
+				}
+				if want := []string{"docs/", "videos/"}; !slices.Equal(root.Prefixes, want) {
+					t.Fatalf("root prefixes = %v, want %v", root.Prefixes, want)
+				}
+			})
+
+			t.Run("pagination", func(t *testing.T) {
+				var seen []string
+				req := storage.ListFilesRequest{Limit: 2}
+				for page := 0; ; page++ {
+					if page > len(listedFiles) {
+						t.Fatal("pagination did not terminate")
+					}
+					result, err := store.ListFiles(ctx, req)
+					if err != nil {
+						t.Fatalf("ListFiles() error = %v", err)
+					}
+					if len(result.Files) > req.Limit {
+						t.Fatalf("page has %d files, limit %d", len(result.Files), req.Limit)
+					}
+					seen = append(seen, fileKeys(result.Files)...)
+					if result.NextCursor == "" {
+						break
+					}
+					req.Cursor = result.NextCursor
+				}
+				want := make([]string, 0, len(listedFiles))
+				for key := range listedFiles {
+					want = append(want, key)
+				}
+				slices.Sort(want)
+				if !slices.Equal(seen, want) {
+					t.Fatalf("paginated keys = %v, want %v", seen, want)
+				}
+			})
+
+			t.Run("metadata", func(t *testing.T) {
+				result, err := store.ListFiles(ctx, storage.ListFilesRequest{Prefix: "docs/readme"})
+				if err != nil {
+					t.Fatalf("ListFiles() error = %v", err)
+				}
+				if len(result.Files) != 1 {
+					t.Fatalf("files = %v, want 1", fileKeys(result.Files))
+				}
+				file := result.Files[0]
+				if file.Size != int64(len("readme")) {
+					t.Fatalf("size = %d, want %d", file.Size, len("readme"))
+				}
+				if file.MIME != "text/plain; charset=utf-8" {
+					t.Fatalf("mime = %q, want %q", file.MIME, "text/plain; charset=utf-8")
+				}
+				if file.ETag == "" {
+					t.Fatal("etag is empty")
+				}
+			})
+
+			t.Run("invalid limit", func(t *testing.T) {
+				if _, err := store.ListFiles(ctx, storage.ListFilesRequest{Limit: -1}); err == nil {
+					t.Fatal("ListFiles() with negative limit error = nil")
+				}
+			})
+		})
+	}
+}
+
+func TestFileListerWithoutKeyListingCache(t *testing.T) {
+	store := newInMemoryStorage(t)
+	if _, err := store.ListFiles(context.Background(), storage.ListFilesRequest{}); err == nil {
+		t.Fatal("ListFiles() over a cache without key listing error = nil")
+	}
+}
+
+func fileKeys(files []storage.FileInfo) []string {
+	keys := make([]string, 0, len(files))
+	for _, file := range files {
+		keys = append(keys, file.Key)
+	}
+	return keys
+}
@@ -0,0 +1,192 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-sphere/sphere/cache/memory"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/fileserver"
+	"github.com/go-sphere/sphere/storage/kvcache"
+	"github.com/go-sphere/sphere/storage/local"
+	"github.com/go-sphere/sphere/storage/qiniu"
+	"github.com/go-sphere/sphere/storage/s3"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+var _ storage.FileStatter = (*s3.Client)(nil)
+var _ storage.FileStatter = (*qiniu.Client)(nil)
+var _ storage.FileStatter = (*local.Client)(nil)
+var _ storage.FileStatter = (*kvcache.Client)(nil)
+var _ storage.FileStatter = (*fileserver.FileServer)(nil)
+var _ storage.FileOptionsUploader = (*s3.Client)(nil)
+var _ storage.FileOptionsUploader = (*qiniu.Client)(nil)
+var _ storage.FileOptionsUploader = (*local.Client)(nil)
+var _ storage.FileOptionsUploader = (*kvcache.Client)(nil)
+var _ storage.FileOptionsUploader = (*fileserver.FileServer)(nil)
+
+type metadataStorage interface {
+	storage.Storage
+	storage.FileStatter
+	storage.FileOptionsUploader
+}
+
+func TestFileMetadataContract(t *testing.T) {
+	stores := map[string]metadataStorage{
+		"kvcache memory": newInMemoryStorage(t),
+	}
+	for name, store := range listableStorages(t) {
+		stores[name] = store.(metadataStorage)
+	}
+	opts := storage.UploadOptions{
+		ContentType: "application/pdf",
+		FileMetadata: storage.FileMetadata{
+			CacheControl:       "private, max-age=60",
+			ContentDisposition: `attachment; filename="invoice.pdf"`,
+			UserMetadata:       map[string]string{"Order-ID": "1001"},
+		},
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			const key = "invoices/2024/0001.bin"
+			if _, err := store.UploadFileWithOptions(ctx, bytes.NewBufferString("%PDF"), key, opts); err != nil {
+				t.Fatalf("UploadFileWithOptions() error = %v", err)
+			}
+
+			stat, err := store.StatFile(ctx, key)
+			if err != nil {
+				t.Fatalf("StatFile() error = %v", err)
+			}
+			if stat.Key != key || stat.Size != 4 || stat.MIME != opts.ContentType || stat.ETag == "" {
+				t.Fatalf("StatFile() = %+v, want key, size, stored MIME and ETag", stat.FileInfo)
+			}
+			if stat.CacheControl != opts.CacheControl || stat.ContentDisposition != opts.ContentDisposition {
+				t.Fatalf("StatFile() headers = %+v, want stored headers", stat.FileMetadata)
+			}
+			if len(stat.UserMetadata) != 1 || stat.UserMetadata["order-id"] != "1001" {
+				t.Fatalf("StatFile() user metadata = %v, want lower-cased keys", stat.UserMetadata)
+			}
+
+			download, err := store.DownloadFile(ctx, key)
+			if err != nil {
+				t.Fatalf("DownloadFile() error = %v", err)
+			}
+			_ = download.Reader.Close()
+			if download.MIME != stat.MIME || download.ETag != stat.ETag || download.CacheControl != stat.CacheControl {
+				t.Fatalf("DownloadFile() = %+v, want the metadata of StatFile()", download)
+			}
+
+			if err = store.CopyFile(ctx, key, "copies/0001.bin", false); err != nil {
+				t.Fatalf("CopyFile() error = %v", err)
+			}
+			copied, err := store.StatFile(ctx, "copies/0001.bin")
+			if err != nil {
+				t.Fatalf("StatFile() copy error = %v", err)
+			}
+			if copied.MIME != opts.ContentType || copied.ContentDisposition != opts.ContentDisposition {
+				t.Fatalf("copied metadata = %+v, want the source metadata", copied)
+			}
+
+			if _, err = store.UploadFile(ctx, bytes.NewBufferString("plain"), key); err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+			plain, err := store.StatFile(ctx, key)
+			if err != nil {
+				t.Fatalf("StatFile() error = %v", err)
+			}
+			if plain.MIME != "application/octet-stream" || plain.CacheControl != "" || plain.UserMetadata != nil {
+				t.Fatalf("StatFile() after plain upload = %+v, want metadata cleared", plain)
+			}
+
+			if _, err = store.StatFile(ctx, "missing.txt"); !errors.Is(err, storageerr.ErrorNotFound) {
+				t.Fatalf("StatFile() missing error = %v, want %v", err, storageerr.ErrorNotFound)
+			}
+			if _, err = store.UploadFileWithOptions(ctx, bytes.NewBufferString("x"), "bad.txt", storage.UploadOptions{
+				FileMetadata: storage.FileMetadata{UserMetadata: map[string]string{"bad key": "v"}},
+			}); !errors.Is(err, storageerr.ErrorMetadataInvalid) {
+				t.Fatalf("UploadFileWithOptions() invalid metadata error = %v, want %v", err, storageerr.ErrorMetadataInvalid)
+			}
+		})
+	}
+}
+
+func TestFileServerDownloadServesMetadataHeaders(t *testing.T) {
+	router := newMiniRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	tokenCache := memory.NewByteCache()
+	t.Cleanup(func() { _ = tokenCache.Close() })
+	fileServer, err := fileserver.NewCDNAdapter(
+		fileserver.Config{
+			PutBase: server.URL + "/upload",
+			GetBase: server.URL + "/files",
+		},
+		tokenCache,
+		newInMemoryStorage(t),
+		fileserver.WithCacheControl(3600),
+	)
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	fileServer.RegisterFileDownloader(router.Group("/files"))
+
+	ctx := context.Background()
+	_, err = fileServer.UploadFileWithOptions(ctx, bytes.NewBufferString("a,b"), "exports/data", storage.UploadOptions{
+		ContentType: "text/csv",
+		FileMetadata: storage.FileMetadata{
+			ContentDisposition: `attachment; filename="data.csv"`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("UploadFileWithOptions() error = %v", err)
+	}
+	_, err = fileServer.UploadFileWithOptions(ctx, bytes.NewBufferString("{}"), "private/config.json", storage.UploadOptions{
+		FileMetadata: storage.FileMetadata{CacheControl: "no-store"},
+	})
+	if err != nil {
+		t.Fatalf("UploadFileWithOptions() error = %v", err)
+	}
+
+	resp := getFile(t, server, fileServer.GenerateURL("exports/data"))
+	if got := resp.Header.Get("Content-Type"); got != "text/csv" {
+		t.Fatalf("Content-Type = %q, want %q", got, "text/csv")
+	}
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="data.csv"` {
+		t.Fatalf("Content-Disposition = %q", got)
+	}
+	if got := resp.Header.Get("Cache-Control"); got != "max-age=3600" {
+		t.Fatalf("Cache-Control = %q, want the default", got)
+	}
+	if got := resp.Header.Get("ETag"); len(got) < 3 || got[0] != '"' {
+		t.Fatalf("ETag = %q, want a quoted entity tag", got)
+	}
+
+	resp = getFile(t, server, fileServer.GenerateURL("private/config.json"))
+	if got := resp.Header.Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("Cache-Control = %q, want the stored value", got)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type = %q, want %q", got, "application/json")
+	}
+}
+
+func getFile(t *testing.T, server *httptest.Server, url string) *http.Response {
+	t.Helper()
+	resp, err := server.Client().Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("GET %s status = %d, body = %s", url, resp.StatusCode, string(body))
+	}
+	return resp
+}
@@ -0,0 +1,176 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/server/httpz"
+)
+
+func TestStreamMultipartUploadOverHTTP(t *testing.T) {
+	router := newMiniRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	store := newInMemoryStorage(t)
+	router.POST("/upload", httpz.WithMultipartUpload(store,
+		func(ctx httpx.Context, result httpz.MultipartUploadResult) (httpz.MultipartUploadResult, error) {
+			return result, nil
+		},
+		httpz.WithMultipartMaxFileSize(1024),
+		httpz.WithMultipartMaxTotalSize(8*1024),
+		httpz.WithMultipartMaxFiles(3),
+		httpz.WithMultipartMaxFieldSize(16),
+		httpz.WithMultipartFileKeys("files"),
+		httpz.WithMultipartAllowExtensions(".txt", ".png"),
+		httpz.WithMultipartContentCheck(httpz.WithContentMatchExtension()),
+		httpz.WithMultipartFileKey(func(ctx httpx.Context, field, filename string) (string, error) {
+			return "uploads/" + filename, nil
+		}),
+	))
+
+	type part struct {
+		field, filename, content string
+	}
+	post := func(t *testing.T, parts ...part) (int, httpz.MultipartUploadResult) {
+		t.Helper()
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for _, p := range parts {
+			var w io.Writer
+			var err error
+			if p.filename == "" {
+				w, err = writer.CreateFormField(p.field)
+			} else {
+				w, err = writer.CreateFormFile(p.field, p.filename)
+			}
+			if err != nil {
+				t.Fatalf("create part: %v", err)
+			}
+			_, _ = io.WriteString(w, p.content)
+		}
+		_ = writer.Close()
+		resp, err := server.Client().Post(server.URL+"/upload", writer.FormDataContentType(), &body)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var decoded httpz.DataResponse[httpz.MultipartUploadResult]
+		if resp.StatusCode == http.StatusOK {
+			if err = json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+		}
+		return resp.StatusCode, decoded.Data
+	}
+	ctx := context.Background()
+
+	t.Run("files and fields", func(t *testing.T) {
+		status, result := post(t,
+			part{field: "title", content: "holiday"},
+			part{field: "files", filename: "a.txt", content: "first file"},
+			part{field: "files", filename: "big.txt", content: strings.Repeat("x", 2048)},
+			part{field: "files", filename: "page.png", content: "<html></html>"},
+			part{field: "tags", content: "one"},
+			part{field: "tags", content: "two"},
+		)
+		if status != http.StatusOK {
+			t.Fatalf("status = %d, want %d", status, http.StatusOK)
+		}
+		if result.Fields["title"][0] != "holiday" || strings.Join(result.Fields["tags"], ",") != "one,two" {
+			t.Fatalf("fields = %v", result.Fields)
+		}
+		if len(result.Files) != 3 {
+			t.Fatalf("files = %+v, want 3 results", result.Files)
+		}
+		first := result.Files[0]
+		if first.Error != "" || first.Key != "uploads/a.txt" || first.Size != int64(len("first file")) || first.ContentType != "text/plain" {
+			t.Fatalf("first file = %+v", first)
+		}
+		if exists, _ := store.IsFileExists(ctx, "uploads/a.txt"); !exists {
+			t.Fatal("accepted file was not stored")
+		}
+		for _, rejected := range result.Files[1:] {
+			if rejected.Error == "" || rejected.Key != "" {
+				t.Fatalf("rejected file = %+v, want an error", rejected)
+			}
+			if exists, _ := store.IsFileExists(ctx, "uploads/"+rejected.Filename); exists {
+				t.Fatalf("rejected file %s was stored", rejected.Filename)
+			}
+		}
+	})
+
+	t.Run("existing file kept", func(t *testing.T) {
+		if _, err := store.UploadFile(ctx, strings.NewReader("kept"), "uploads/keep.txt"); err != nil {
+			t.Fatalf("UploadFile() error = %v", err)
+		}
+		status, result := post(t, part{field: "files", filename: "keep.txt", content: strings.Repeat("x", 2048)})
+		if status != http.StatusOK || len(result.Files) != 1 || result.Files[0].Error == "" {
+			t.Fatalf("status = %d, files = %+v, want a rejected file", status, result.Files)
+		}
+		if exists, _ := store.IsFileExists(ctx, "uploads/keep.txt"); !exists {
+			t.Fatal("existing file was deleted by a failed upload")
+		}
+	})
+
+	t.Run("rejected names", func(t *testing.T) {
+		status, result := post(t,
+			part{field: "avatar", filename: "b.txt", content: "wrong field"},
+			part{field: "files", filename: "run.exe", content: "MZ"},
+		)
+		if status != http.StatusOK || len(result.Files) != 2 {
+			t.Fatalf("status = %d, files = %+v", status, result.Files)
+		}
+		for _, file := range result.Files {
+			if file.Error == "" {
+				t.Fatalf("file %s was accepted", file.Filename)
+			}
+		}
+	})
+
+	t.Run("request limits", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			parts []part
+			keys  []string
+		}{
+			{"too many files", []part{
+				{field: "files", filename: "1.txt", content: "1"},
+				{field: "files", filename: "2.txt", content: "2"},
+				{field: "files", filename: "3.txt", content: "3"},
+				{field: "files", filename: "4.txt", content: "4"},
+			}, []string{"1.txt", "2.txt", "3.txt", "4.txt"}},
+			{"field too large", []part{{field: "title", content: strings.Repeat("t", 17)}}, nil},
+			{"total too large", []part{
+				{field: "files", filename: "x.txt", content: strings.Repeat("x", 1000)},
+				{field: "files", filename: "y.txt", content: strings.Repeat("y", 8000)},
+			}, []string{"x.txt", "y.txt"}},
+		}
+		for _, tt := range tests {
+			if status, _ := post(t, tt.parts...); status != http.StatusBadRequest {
+				t.Fatalf("%s: status = %d, want %d", tt.name, status, http.StatusBadRequest)
+			}
+			for _, key := range tt.keys {
+				if exists, _ := store.IsFileExists(ctx, "uploads/"+key); exists {
+					t.Fatalf("%s: file %s of a failed request was kept", tt.name, key)
+				}
+			}
+		}
+		resp, err := server.Client().Post(server.URL+"/upload", "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("non-multipart status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+		}
+	})
+}
@@ -24,6 +24,14 @@ var _ storage.CDNStorage = (*fileserver.FileServer)(nil)
 var _ storage.Storage = (*local.Client)(nil)
 var _ storage.Storage = (*kvcache.Client)(nil)
 
+var _ storage.MultipartUploader = (*s3.Client)(nil)
+var _ storage.MultipartUploader = (*qiniu.Client)(nil)
+var _ storage.MultipartUploader = (*local.Client)(nil)
+var _ storage.MultipartUploader = (*fileserver.FileServer)(nil)
+var _ storage.MultipartUploadAuthorizer = (*s3.Client)(nil)
+var _ storage.MultipartUploadAuthorizer = (*qiniu.Client)(nil)
+var _ storage.MultipartUploadAuthorizer = (*fileserver.FileServer)(nil)
+
 func TestFileServerGenerateUploadAuthWithMemoryImplementations(t *testing.T) {
 	ctx := context.Background()
 	tokenCache := memory.NewByteCache()
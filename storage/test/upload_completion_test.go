@@ -0,0 +1,165 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/cache/memory"
+	mqmemory "github.com/go-sphere/sphere/mq/memory"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/fileserver"
+	"github.com/go-sphere/sphere/storage/qiniu"
+	"github.com/go-sphere/sphere/storage/s3"
+	"github.com/go-sphere/sphere/storage/storageerr"
+	"github.com/qiniu/go-sdk/v7/auth/qbox"
+)
+
+var _ storage.UploadConfirmer = (*s3.Client)(nil)
+
+func TestFileServerUploadCompletionHooks(t *testing.T) {
+	router := newMiniRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	tokenCache := memory.NewByteCache()
+	t.Cleanup(func() { _ = tokenCache.Close() })
+	pubsub := mqmemory.NewPubSub[storage.UploadCompletion]()
+	t.Cleanup(func() { _ = pubsub.Close() })
+	published := make(chan storage.UploadCompletion, 1)
+	if err := pubsub.Subscribe(context.Background(), "uploads", func(data storage.UploadCompletion) error {
+		published <- data
+		return nil
+	}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	var completions []storage.UploadCompletion
+	store := newInMemoryStorage(t)
+	fileServer, err := fileserver.NewCDNAdapter(
+		fileserver.Config{
+			PutBase:      server.URL + "/upload",
+			GetBase:      server.URL + "/files",
+			UploadNaming: storage.UploadNamingStrategyOriginal,
+		},
+		tokenCache,
+		store,
+		fileserver.WithUploadCompletionHook(func(ctx context.Context, completion storage.UploadCompletion) error {
+			if strings.HasPrefix(completion.Key, "rejected/") {
+				return httpx.NewBadRequestError("upload rejected")
+			}
+			completions = append(completions, completion)
+			return nil
+		}),
+		fileserver.WithUploadCompletionHook(fileserver.PublishUploadCompletions(pubsub, "uploads")),
+	)
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	fileServer.RegisterFileUploader(router.Group("/upload"))
+
+	upload := func(t *testing.T, dir string) int {
+		t.Helper()
+		auth, aErr := fileServer.GenerateUploadAuth(context.Background(), storage.UploadAuthRequest{FileName: "report.csv", Dir: dir})
+		if aErr != nil {
+			t.Fatalf("GenerateUploadAuth() error = %v", aErr)
+		}
+		req, rErr := http.NewRequest(http.MethodPut, auth.Authorization.Value, strings.NewReader("a,b\n1,2\n"))
+		if rErr != nil {
+			t.Fatalf("new PUT request: %v", rErr)
+		}
+		resp, rErr := server.Client().Do(req)
+		if rErr != nil {
+			t.Fatalf("PUT upload request failed: %v", rErr)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if got := upload(t, "accepted"); got != http.StatusOK {
+		t.Fatalf("upload status = %d, want %d", got, http.StatusOK)
+	}
+	if len(completions) != 1 {
+		t.Fatalf("hook calls = %d, want 1", len(completions))
+	}
+	completion := completions[0]
+	if completion.Key != "accepted/report.csv" || completion.Size != 8 || completion.ETag == "" ||
+		completion.URL != fileServer.GenerateURL("accepted/report.csv") {
+		t.Fatalf("completion = %+v, want the stored file", completion)
+	}
+	select {
+	case got := <-published:
+		if got.Key != completion.Key {
+			t.Fatalf("published completion key = %q, want %q", got.Key, completion.Key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("completion was not published")
+	}
+
+	if _, err = store.UploadFile(context.Background(), strings.NewReader("kept"), "rejected/report.csv"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if got := upload(t, "rejected"); got != http.StatusBadRequest {
+		t.Fatalf("rejected upload status = %d, want %d", got, http.StatusBadRequest)
+	}
+	download, err := store.DownloadFile(context.Background(), "rejected/report.csv")
+	if err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+	content, err := io.ReadAll(download.Reader)
+	_ = download.Reader.Close()
+	if err != nil || string(content) != "kept" {
+		t.Fatalf("file after a rejected upload = %q, %v, want the existing file", content, err)
+	}
+	if len(completions) != 1 {
+		t.Fatalf("hook calls = %d, want no completion for the rejected upload", len(completions))
+	}
+}
+
+func TestQiniuVerifyUploadCallback(t *testing.T) {
+	client, err := qiniu.NewClient(qiniu.Config{
+		AccessKey:   "access",
+		SecretKey:   "secret",
+		Bucket:      "bucket",
+		PublicBase:  "https://cdn.example.com",
+		CallbackURL: "https://api.example.com/uploads/callback",
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	body := `{"key":"images/a.png","size":42,"mime":"image/png","etag":"Fh8xVqod2MQ1mocfI4S4KpRL6D98"}`
+	newCallback := func(t *testing.T, secret string) *http.Request {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "https://api.example.com/uploads/callback", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		token, sErr := qbox.NewMac("access", secret).SignRequest(req)
+		if sErr != nil {
+			t.Fatalf("SignRequest() error = %v", sErr)
+		}
+		req.Header.Set("Authorization", "QBox "+token)
+		return req
+	}
+
+	completion, err := client.VerifyUploadCallback(newCallback(t, "secret"))
+	if err != nil {
+		t.Fatalf("VerifyUploadCallback() error = %v", err)
+	}
+	if completion.Key != "images/a.png" || completion.Size != 42 || completion.MIME != "image/png" ||
+		completion.URL != "https://cdn.example.com/images/a.png" {
+		t.Fatalf("VerifyUploadCallback() = %+v, want the reported upload", completion)
+	}
+
+	if _, err = client.VerifyUploadCallback(newCallback(t, "forged")); !errors.Is(err, storageerr.ErrorUploadCallbackInvalid) {
+		t.Fatalf("forged callback error = %v, want %v", err, storageerr.ErrorUploadCallbackInvalid)
+	}
+	unsigned := httptest.NewRequest(http.MethodPost, "https://api.example.com/uploads/callback", strings.NewReader(body))
+	if _, err = client.VerifyUploadCallback(unsigned); !errors.Is(err, storageerr.ErrorUploadCallbackInvalid) {
+		t.Fatalf("unsigned callback error = %v, want %v", err, storageerr.ErrorUploadCallbackInvalid)
+	}
+}
@@ -0,0 +1,221 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-sphere/httpx"
+	"github.com/go-sphere/sphere/cache/memory"
+	"github.com/go-sphere/sphere/server/httpz"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/fileserver"
+	"github.com/go-sphere/sphere/storage/local"
+)
+
+func TestFileServerUploadContentCheckOverHTTP(t *testing.T) {
+	router := newMiniRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	var scanned []string
+	scanner := httpz.FileScannerFunc(func(ctx context.Context, file io.Reader, filename string) error {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		scanned = append(scanned, filename)
+		if bytes.Contains(data, []byte("EICAR")) {
+			return httpz.ErrMaliciousFile
+		}
+		return nil
+	})
+
+	tokenCache := memory.NewByteCache()
+	t.Cleanup(func() { _ = tokenCache.Close() })
+	store := newInMemoryStorage(t)
+	fileServer, err := fileserver.NewCDNAdapter(
+		fileserver.Config{
+			PutBase:      server.URL + "/upload",
+			GetBase:      server.URL + "/files",
+			UploadNaming: storage.UploadNamingStrategyOriginal,
+		},
+		tokenCache,
+		store,
+		fileserver.WithUploadContentCheck(
+			httpz.WithContentMatchExtension(),
+			httpz.WithContentMaxImageSize(100, 100),
+		),
+	)
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	fileServer.RegisterFileUploader(router.Group("/upload"))
+	scanningServer, err := fileserver.NewCDNAdapter(
+		fileserver.Config{
+			PutBase:      server.URL + "/scanned",
+			GetBase:      server.URL + "/files",
+			UploadNaming: storage.UploadNamingStrategyOriginal,
+		},
+		tokenCache,
+		store,
+		fileserver.WithUploadContentCheck(httpz.WithContentScanner(scanner)),
+	)
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	scanningServer.RegisterFileUploader(router.Group("/scanned"))
+
+	ctx := context.Background()
+	put := func(t *testing.T, fs *fileserver.FileServer, fileName string, body []byte) int {
+		t.Helper()
+		auth, aErr := fs.GenerateUploadAuth(ctx, storage.UploadAuthRequest{FileName: fileName})
+		if aErr != nil {
+			t.Fatalf("GenerateUploadAuth() error = %v", aErr)
+		}
+		req, rErr := http.NewRequest(http.MethodPut, auth.Authorization.Value, bytes.NewReader(body))
+		if rErr != nil {
+			t.Fatalf("new PUT request: %v", rErr)
+		}
+		resp, rErr := server.Client().Do(req)
+		if rErr != nil {
+			t.Fatalf("PUT %s failed: %v", fileName, rErr)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	encodePNG := func(t *testing.T, w, h int) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		if pErr := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); pErr != nil {
+			t.Fatalf("png.Encode() error = %v", pErr)
+		}
+		return buf.Bytes()
+	}
+	stored := func(t *testing.T, key string) []byte {
+		t.Helper()
+		result, dErr := store.DownloadFile(ctx, key)
+		if dErr != nil {
+			t.Fatalf("DownloadFile(%q) error = %v", key, dErr)
+		}
+		defer func() { _ = result.Reader.Close() }()
+		data, _ := io.ReadAll(result.Reader)
+		return data
+	}
+
+	t.Run("image", func(t *testing.T) {
+		photo := encodePNG(t, 80, 60)
+		if got := put(t, fileServer, "photo.png", photo); got != http.StatusOK {
+			t.Fatalf("valid image status = %d, want %d", got, http.StatusOK)
+		}
+		if !bytes.Equal(stored(t, "photo.png"), photo) {
+			t.Fatal("stored image differs from the upload")
+		}
+		if got := put(t, fileServer, "huge.png", encodePNG(t, 101, 10)); got != http.StatusBadRequest {
+			t.Fatalf("oversized image status = %d, want %d", got, http.StatusBadRequest)
+		}
+		if got := put(t, fileServer, "broken.png", []byte("\x89PNG\r\n\x1a\nnot really")); got != http.StatusBadRequest {
+			t.Fatalf("corrupt image status = %d, want %d", got, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("extension mismatch", func(t *testing.T) {
+		if got := put(t, fileServer, "photo.jpg", []byte("<html><script>alert(1)</script></html>")); got != http.StatusBadRequest {
+			t.Fatalf("HTML named .jpg status = %d, want %d", got, http.StatusBadRequest)
+		}
+		if exists, _ := store.IsFileExists(ctx, "photo.jpg"); exists {
+			t.Fatal("rejected upload was stored")
+		}
+		if got := put(t, fileServer, "notes.txt", []byte(strings.Repeat("plain text ", 100))); got != http.StatusOK {
+			t.Fatalf("text file status = %d, want %d", got, http.StatusOK)
+		}
+	})
+
+	t.Run("scanner", func(t *testing.T) {
+		clean := bytes.Repeat([]byte("clean content "), 10000)
+		if got := put(t, scanningServer, "clean.bin", clean); got != http.StatusOK {
+			t.Fatalf("clean upload status = %d, want %d", got, http.StatusOK)
+		}
+		if !bytes.Equal(stored(t, "clean.bin"), clean) {
+			t.Fatal("stored upload differs after scanning")
+		}
+		if got := put(t, scanningServer, "infected.bin", []byte("X5O!P%@AP EICAR test")); got != http.StatusBadRequest {
+			t.Fatalf("infected upload status = %d, want %d", got, http.StatusBadRequest)
+		}
+		if exists, _ := store.IsFileExists(ctx, "infected.bin"); exists {
+			t.Fatal("infected upload was stored")
+		}
+		if strings.Join(scanned, ",") != "clean.bin,infected.bin" {
+			t.Fatalf("scanned files = %v", scanned)
+		}
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		localStorage, lErr := local.NewClient(local.Config{RootDir: t.TempDir()})
+		if lErr != nil {
+			t.Fatalf("new local client: %v", lErr)
+		}
+		multipartServer, mErr := fileserver.NewCDNAdapter(
+			fileserver.Config{
+				PutBase:      server.URL + "/multipart",
+				GetBase:      server.URL + "/files",
+				UploadNaming: storage.UploadNamingStrategyOriginal,
+			},
+			tokenCache,
+			localStorage,
+			fileserver.WithUploadContentCheck(httpz.WithContentScanner(scanner)),
+		)
+		if mErr != nil {
+			t.Fatalf("NewCDNAdapter() error = %v", mErr)
+		}
+		multipartServer.RegisterFileUploader(router.Group("/multipart"))
+
+		upload := func(t *testing.T, fileName string, chunks ...string) error {
+			t.Helper()
+			auth, aErr := multipartServer.GenerateMultipartUploadAuth(ctx, storage.MultipartUploadAuthRequest{
+				UploadAuthRequest: storage.UploadAuthRequest{FileName: fileName},
+				PartCount:         len(chunks),
+			})
+			if aErr != nil {
+				t.Fatalf("GenerateMultipartUploadAuth() error = %v", aErr)
+			}
+			for i, part := range auth.Parts {
+				req, rErr := http.NewRequest(http.MethodPut, part.Authorization.Value, strings.NewReader(chunks[i]))
+				if rErr != nil {
+					t.Fatalf("new PUT request: %v", rErr)
+				}
+				resp, rErr := server.Client().Do(req)
+				if rErr != nil {
+					t.Fatalf("PUT part failed: %v", rErr)
+				}
+				_ = resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("part %d status = %d", part.PartNumber, resp.StatusCode)
+				}
+			}
+			parts, lErr := multipartServer.ListParts(ctx, auth.File.Key, auth.UploadID)
+			if lErr != nil {
+				t.Fatalf("ListParts() error = %v", lErr)
+			}
+			_, cErr := multipartServer.CompleteMultipartUpload(ctx, auth.File.Key, auth.UploadID, parts)
+			return cErr
+		}
+
+		if err := upload(t, "parts.bin", "clean ", "content"); err != nil {
+			t.Fatalf("CompleteMultipartUpload() error = %v", err)
+		}
+		err := upload(t, "infected-parts.bin", "X5O!P%@AP ", "EICAR test")
+		if _, status, _ := httpx.ParseError(err); err == nil || status != http.StatusBadRequest {
+			t.Fatalf("CompleteMultipartUpload() error = %v, want a rejected file", err)
+		}
+		if exists, _ := localStorage.IsFileExists(ctx, "infected-parts.bin"); exists {
+			t.Fatal("infected multipart upload was kept")
+		}
+	})
+}
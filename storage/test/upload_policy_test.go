@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sphere/sphere/cache/memory"
+	"github.com/go-sphere/sphere/storage"
+	"github.com/go-sphere/sphere/storage/fileserver"
+	"github.com/go-sphere/sphere/storage/storageerr"
+)
+
+func TestFileServerUploadPolicyOverHTTP(t *testing.T) {
+	router := newMiniRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	tokenCache := memory.NewByteCache()
+	t.Cleanup(func() { _ = tokenCache.Close() })
+	store := newInMemoryStorage(t)
+	fileServer, err := fileserver.NewCDNAdapter(
+		fileserver.Config{
+			PutBase:      server.URL + "/upload",
+			GetBase:      server.URL + "/files",
+			UploadNaming: storage.UploadNamingStrategyOriginal,
+		},
+		tokenCache,
+		store,
+	)
+	if err != nil {
+		t.Fatalf("NewCDNAdapter() error = %v", err)
+	}
+	fileServer.RegisterFileUploader(router.Group("/upload"))
+
+	ctx := context.Background()
+	authorize := func(t *testing.T, fileName string, policy storage.UploadPolicy) storage.UploadAuthResult {
+		t.Helper()
+		result, aErr := fileServer.GenerateUploadAuth(ctx, storage.UploadAuthRequest{FileName: fileName, UploadPolicy: policy})
+		if aErr != nil {
+			t.Fatalf("GenerateUploadAuth() error = %v", aErr)
+		}
+		return result
+	}
+	put := func(t *testing.T, uri string, contentType string, body io.Reader) int {
+		t.Helper()
+		req, rErr := http.NewRequest(http.MethodPut, uri, body)
+		if rErr != nil {
+			t.Fatalf("new PUT request: %v", rErr)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, rErr := server.Client().Do(req)
+		if rErr != nil {
+			t.Fatalf("PUT %s failed: %v", uri, rErr)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	t.Run("max size", func(t *testing.T) {
+		policy := storage.UploadPolicy{MaxSize: 4}
+		auth := authorize(t, "small.bin", policy)
+		if got := put(t, auth.Authorization.Value, "", strings.NewReader("12345")); got != http.StatusRequestEntityTooLarge {
+			t.Fatalf("oversized upload status = %d, want %d", got, http.StatusRequestEntityTooLarge)
+		}
+		auth = authorize(t, "streamed.bin", policy)
+		// An io.Reader without a known length is sent chunked, so the limit applies while streaming.
+		if got := put(t, auth.Authorization.Value, "", io.MultiReader(strings.NewReader("123"), strings.NewReader("45"))); got != http.StatusRequestEntityTooLarge {
+			t.Fatalf("oversized streamed upload status = %d, want %d", got, http.StatusRequestEntityTooLarge)
+		}
+		if exists, _ := store.IsFileExists(ctx, "streamed.bin"); exists {
+			t.Fatal("oversized streamed upload was kept")
+		}
+		auth = authorize(t, "small.bin", policy)
+		if got := put(t, auth.Authorization.Value, "", strings.NewReader("1234")); got != http.StatusOK {
+			t.Fatalf("upload within limit status = %d, want %d", got, http.StatusOK)
+		}
+	})
+
+	t.Run("allowed mime types", func(t *testing.T) {
+		policy := storage.UploadPolicy{AllowedMIMETypes: []string{"image/*"}}
+		auth := authorize(t, "photo.png", policy)
+		if got := put(t, auth.Authorization.Value, "application/pdf", strings.NewReader("%PDF")); got != http.StatusUnsupportedMediaType {
+			t.Fatalf("disallowed type status = %d, want %d", got, http.StatusUnsupportedMediaType)
+		}
+		auth = authorize(t, "photo.png", policy)
+		if got := put(t, auth.Authorization.Value, "", strings.NewReader("png")); got != http.StatusOK {
+			t.Fatalf("type from extension status = %d, want %d", got, http.StatusOK)
+		}
+		auth = authorize(t, "photo.jpg", policy)
+		if got := put(t, auth.Authorization.Value, "image/jpeg", strings.NewReader("jpg")); got != http.StatusOK {
+			t.Fatalf("allowed type status = %d, want %d", got, http.StatusOK)
+		}
+	})
+
+	t.Run("insert only", func(t *testing.T) {
+		auth := authorize(t, "once.txt", storage.UploadPolicy{InsertOnly: true})
+		if got := put(t, auth.Authorization.Value, "", strings.NewReader("first")); got != http.StatusOK {
+			t.Fatalf("first upload status = %d, want %d", got, http.StatusOK)
+		}
+		auth = authorize(t, "once.txt", storage.UploadPolicy{InsertOnly: true})
+		if got := put(t, auth.Authorization.Value, "", strings.NewReader("second")); got != http.StatusBadRequest {
+			t.Fatalf("second upload status = %d, want %d", got, http.StatusBadRequest)
+		}
+		auth = authorize(t, "once.txt", storage.UploadPolicy{})
+		if got := put(t, auth.Authorization.Value, "", strings.NewReader("third")); got != http.StatusOK {
+			t.Fatalf("overwrite status = %d, want %d", got, http.StatusOK)
+		}
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		auth := authorize(t, "late.txt", storage.UploadPolicy{Expires: 50 * time.Millisecond})
+		time.Sleep(200 * time.Millisecond)
+		if got := put(t, auth.Authorization.Value, "", strings.NewReader("late")); got != http.StatusBadRequest {
+			t.Fatalf("expired upload status = %d, want %d", got, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("invalid policy", func(t *testing.T) {
+		_, err := fileServer.GenerateUploadAuth(ctx, storage.UploadAuthRequest{
+			FileName:     "a.txt",
+			UploadPolicy: storage.UploadPolicy{AllowedMIMETypes: []string{"image"}},
+		})
+		if err == nil {
+			t.Fatal("GenerateUploadAuth() with an invalid mime type expected error")
+		}
+		_, err = fileServer.GenerateMultipartUploadAuth(ctx, storage.MultipartUploadAuthRequest{
+			UploadAuthRequest: storage.UploadAuthRequest{FileName: "movie.mp4", UploadPolicy: storage.UploadPolicy{MaxSize: 1}},
+			PartCount:         2,
+		})
+		if !errors.Is(err, storageerr.ErrorUploadPolicyUnsupported) {
+			t.Fatalf("GenerateMultipartUploadAuth() with a policy error = %v, want %v", err, storageerr.ErrorUploadPolicyUnsupported)
+		}
+	})
+}
@@ -5,11 +5,14 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"mime"
 	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/go-sphere/sphere/storage/storageerr"
 	"github.com/google/uuid"
 )
 
@@ -103,6 +106,207 @@ func JoinUploadKey(prefixDir string, bizDir string, fileName string) (string, er
 	return key, nil
 }
 
+// MaxPartNumber is the largest part number of a multipart upload.
+const MaxPartNumber = 10000
+
+// PartNumbers returns the part numbers 1 to count for authorizing a new multipart upload.
+func PartNumbers(count int) ([]int, error) {
+	if count < 1 || count > MaxPartNumber {
+		return nil, fmt.Errorf("part_count must be between 1 and %d", MaxPartNumber)
+	}
+	numbers := make([]int, count)
+	for i := range numbers {
+		numbers[i] = i + 1
+	}
+	return numbers, nil
+}
+
+// ValidatePartNumber checks that a multipart part number is within 1 to MaxPartNumber.
+func ValidatePartNumber(partNumber int) error {
+	if partNumber < 1 || partNumber > MaxPartNumber {
+		return storageerr.ErrorPartInvalid
+	}
+	return nil
+}
+
+// SortParts returns a copy of the parts ordered by part number for completing a multipart upload.
+// It rejects empty lists, invalid part numbers and duplicates.
+func SortParts(parts []UploadedPart) ([]UploadedPart, error) {
+	if len(parts) == 0 {
+		return nil, storageerr.ErrorPartInvalid
+	}
+	sorted := slices.Clone(parts)
+	slices.SortFunc(sorted, func(a, b UploadedPart) int {
+		return a.PartNumber - b.PartNumber
+	})
+	for i, part := range sorted {
+		if err := ValidatePartNumber(part.PartNumber); err != nil {
+			return nil, err
+		}
+		if i > 0 && sorted[i-1].PartNumber == part.PartNumber {
+			return nil, storageerr.ErrorPartInvalid
+		}
+	}
+	return sorted, nil
+}
+
+// MaxListLimit is the largest page size of a file listing.
+const MaxListLimit = 1000
+
+// ListLimit resolves the page size of a listing request.
+func ListLimit(limit int) (int, error) {
+	if limit == 0 {
+		return MaxListLimit, nil
+	}
+	if limit < 0 || limit > MaxListLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", MaxListLimit)
+	}
+	return limit, nil
+}
+
+// PageKeys applies the prefix, delimiter, cursor and limit of a listing request to keys sorted
+// in ascending order, for backends without native listing. It returns the keys to report as files,
+// the rolled up prefixes, and the next cursor, which is the last key or prefix of the page.
+func PageKeys(keys []string, req ListFilesRequest) ([]string, []string, string, error) {
+	limit, err := ListLimit(req.Limit)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var files, prefixes []string
+	var last string
+	for _, key := range keys {
+		if !strings.HasPrefix(key, req.Prefix) || (req.Cursor != "" && key <= req.Cursor) {
+			continue
+		}
+		entry, isPrefix := key, false
+		if req.Delimiter != "" {
+			if i := strings.Index(key[len(req.Prefix):], req.Delimiter); i >= 0 {
+				entry, isPrefix = key[:len(req.Prefix)+i+len(req.Delimiter)], true
+			}
+		}
+		// Keys under one prefix are contiguous, and a prefix ending the previous page is the cursor.
+		if isPrefix && (entry == last || entry == req.Cursor) {
+			continue
+		}
+		if len(files)+len(prefixes) == limit {
+			return files, prefixes, last, nil
+		}
+		if isPrefix {
+			prefixes = append(prefixes, entry)
+		} else {
+			files = append(files, entry)
+		}
+		last = entry
+	}
+	return files, prefixes, "", nil
+}
+
+// RangeLength returns the number of bytes that a range download of a file of the given size returns.
+// A negative length reads to the end of the file, and a length past the end is truncated.
+func RangeLength(offset int64, length int64, size int64) (int64, error) {
+	if offset < 0 || offset >= size || length == 0 {
+		return 0, storageerr.ErrorRangeInvalid
+	}
+	if length < 0 || length > size-offset {
+		length = size - offset
+	}
+	return length, nil
+}
+
+// NormalizeUploadOptions validates upload options and fills in what backends need to store them:
+// the content type derived from the key extension when empty, and lower-cased user metadata keys.
+// Metadata keys may hold letters, digits, '-' and '_', and values must not contain control characters,
+// since backends send them as HTTP headers.
+func NormalizeUploadOptions(key string, opts UploadOptions) (UploadOptions, error) {
+	if opts.ContentType == "" {
+		opts.ContentType = mime.TypeByExtension(path.Ext(key))
+	} else if _, _, err := mime.ParseMediaType(opts.ContentType); err != nil {
+		return UploadOptions{}, storageerr.ErrorMetadataInvalid
+	}
+	if !isHeaderValue(opts.CacheControl) || !isHeaderValue(opts.ContentDisposition) {
+		return UploadOptions{}, storageerr.ErrorMetadataInvalid
+	}
+	if len(opts.UserMetadata) == 0 {
+		opts.UserMetadata = nil
+		return opts, nil
+	}
+	metadata := make(map[string]string, len(opts.UserMetadata))
+	for k, v := range opts.UserMetadata {
+		if !isMetadataKey(k) || !isHeaderValue(v) {
+			return UploadOptions{}, storageerr.ErrorMetadataInvalid
+		}
+		metadata[strings.ToLower(k)] = v
+	}
+	opts.UserMetadata = metadata
+	return opts, nil
+}
+
+// ValidateUploadPolicy checks that an upload policy has no negative limits
+// and that its MIME types are media types or "type/*" wildcards.
+func ValidateUploadPolicy(policy UploadPolicy) error {
+	if policy.MaxSize < 0 {
+		return errors.New("max_size must not be negative")
+	}
+	if policy.Expires < 0 {
+		return errors.New("expires must not be negative")
+	}
+	for _, pattern := range policy.AllowedMIMETypes {
+		mainType, subType, ok := strings.Cut(pattern, "/")
+		if !ok || mainType == "" || subType == "" || strings.ContainsAny(pattern, ";, ") {
+			return fmt.Errorf("invalid allowed mime type: %q", pattern)
+		}
+		if mainType == "*" && subType != "*" {
+			return fmt.Errorf("invalid allowed mime type: %q", pattern)
+		}
+	}
+	return nil
+}
+
+// MatchMIMEType reports whether a content type, ignoring its parameters, matches one of the
+// patterns, which are media types or "type/*" wildcards. An empty pattern list matches any type.
+func MatchMIMEType(patterns []string, contentType string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*/*" || pattern == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isMetadataKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isHeaderValue(value string) bool {
+	for _, r := range value {
+		if (r < ' ' && r != '\t') || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
 func normalizeUploadDir(raw string, rejectAbs bool, field string) (string, error) {
 	value := strings.TrimSpace(raw)
 	if value == "" {
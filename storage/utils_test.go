@@ -3,6 +3,7 @@ package storage
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestBuildUploadFileName(t *testing.T) {
@@ -98,3 +99,184 @@ func TestJoinUploadKey(t *testing.T) {
 		t.Fatal("expected error for traversal biz dir, got nil")
 	}
 }
+
+func TestSortParts(t *testing.T) {
+	sorted, err := SortParts([]UploadedPart{{PartNumber: 3}, {PartNumber: 1}, {PartNumber: 2}})
+	if err != nil {
+		t.Fatalf("SortParts() error = %v", err)
+	}
+	for i, part := range sorted {
+		if part.PartNumber != i+1 {
+			t.Fatalf("SortParts() = %+v, want ascending part numbers", sorted)
+		}
+	}
+	invalid := [][]UploadedPart{
+		nil,
+		{{PartNumber: 0}},
+		{{PartNumber: MaxPartNumber + 1}},
+		{{PartNumber: 1}, {PartNumber: 1}},
+	}
+	for _, parts := range invalid {
+		if _, err = SortParts(parts); err == nil {
+			t.Fatalf("SortParts(%+v) expected error", parts)
+		}
+	}
+}
+
+func TestPartNumbers(t *testing.T) {
+	numbers, err := PartNumbers(3)
+	if err != nil {
+		t.Fatalf("PartNumbers() error = %v", err)
+	}
+	if len(numbers) != 3 || numbers[0] != 1 || numbers[2] != 3 {
+		t.Fatalf("PartNumbers(3) = %v, want [1 2 3]", numbers)
+	}
+	if _, err = PartNumbers(0); err == nil {
+		t.Fatal("PartNumbers(0) expected error")
+	}
+	if _, err = PartNumbers(MaxPartNumber + 1); err == nil {
+		t.Fatal("PartNumbers() above MaxPartNumber expected error")
+	}
+}
+
+func TestPageKeys(t *testing.T) {
+	keys := []string{"a.txt", "docs/a/1.txt", "docs/a/2.txt", "docs/b.txt", "docs/c/1.txt", "z.txt"}
+
+	var files, prefixes []string
+	req := ListFilesRequest{Prefix: "docs/", Delimiter: "/", Limit: 1}
+	for page := 0; ; page++ {
+		if page > len(keys) {
+			t.Fatal("pagination did not terminate")
+		}
+		pageFiles, pagePrefixes, next, err := PageKeys(keys, req)
+		if err != nil {
+			t.Fatalf("PageKeys() error = %v", err)
+		}
+		if len(pageFiles)+len(pagePrefixes) > 1 {
+			t.Fatalf("page %d exceeds limit: %v %v", page, pageFiles, pagePrefixes)
+		}
+		files = append(files, pageFiles...)
+		prefixes = append(prefixes, pagePrefixes...)
+		if next == "" {
+			break
+		}
+		req.Cursor = next
+	}
+	if strings.Join(files, ",") != "docs/b.txt" {
+		t.Fatalf("files = %v, want [docs/b.txt]", files)
+	}
+	if strings.Join(prefixes, ",") != "docs/a/,docs/c/" {
+		t.Fatalf("prefixes = %v, want [docs/a/ docs/c/]", prefixes)
+	}
+
+	files, prefixes, next, err := PageKeys(keys, ListFilesRequest{})
+	if err != nil {
+		t.Fatalf("PageKeys() error = %v", err)
+	}
+	if len(files) != len(keys) || len(prefixes) != 0 || next != "" {
+		t.Fatalf("PageKeys() without options = %v, %v, %q", files, prefixes, next)
+	}
+	if _, _, _, err = PageKeys(keys, ListFilesRequest{Limit: MaxListLimit + 1}); err == nil {
+		t.Fatal("PageKeys() above MaxListLimit expected error")
+	}
+}
+
+func TestNormalizeUploadOptions(t *testing.T) {
+	opts, err := NormalizeUploadOptions("docs/readme.txt", UploadOptions{
+		FileMetadata: FileMetadata{
+			CacheControl: "max-age=60",
+			UserMetadata: map[string]string{"Owner-ID": "42"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NormalizeUploadOptions() error = %v", err)
+	}
+	if opts.ContentType != "text/plain; charset=utf-8" {
+		t.Fatalf("ContentType = %q, want type from extension", opts.ContentType)
+	}
+	if opts.UserMetadata["owner-id"] != "42" || len(opts.UserMetadata) != 1 {
+		t.Fatalf("UserMetadata = %v, want lower-cased keys", opts.UserMetadata)
+	}
+
+	invalid := []UploadOptions{
+		{ContentType: "not a type"},
+		{FileMetadata: FileMetadata{CacheControl: "max-age=60\r\nX-Injected: 1"}},
+		{FileMetadata: FileMetadata{ContentDisposition: "attachment\n"}},
+		{FileMetadata: FileMetadata{UserMetadata: map[string]string{"": "v"}}},
+		{FileMetadata: FileMetadata{UserMetadata: map[string]string{"bad key": "v"}}},
+		{FileMetadata: FileMetadata{UserMetadata: map[string]string{"key": "v\x00"}}},
+	}
+	for _, o := range invalid {
+		if _, err = NormalizeUploadOptions("a.txt", o); err == nil {
+			t.Fatalf("NormalizeUploadOptions(%+v) expected error", o)
+		}
+	}
+}
+
+func TestRangeLength(t *testing.T) {
+	tests := []struct {
+		offset, length, size, want int64
+	}{
+		{0, -1, 10, 10},
+		{2, 3, 10, 3},
+		{8, 5, 10, 2},
+		{9, -1, 10, 1},
+	}
+	for _, tt := range tests {
+		got, err := RangeLength(tt.offset, tt.length, tt.size)
+		if err != nil || got != tt.want {
+			t.Fatalf("RangeLength(%d, %d, %d) = %d, %v, want %d", tt.offset, tt.length, tt.size, got, err, tt.want)
+		}
+	}
+	for _, tt := range [][3]int64{{10, 1, 10}, {-1, 1, 10}, {0, 0, 10}, {0, -1, 0}} {
+		if _, err := RangeLength(tt[0], tt[1], tt[2]); err == nil {
+			t.Fatalf("RangeLength(%v) expected error", tt)
+		}
+	}
+}
+
+func TestValidateUploadPolicy(t *testing.T) {
+	valid := UploadPolicy{MaxSize: 1 << 20, AllowedMIMETypes: []string{"image/png", "video/*", "*/*"}, Expires: time.Minute}
+	if err := ValidateUploadPolicy(valid); err != nil {
+		t.Fatalf("ValidateUploadPolicy() error = %v", err)
+	}
+	invalid := []UploadPolicy{
+		{MaxSize: -1},
+		{Expires: -time.Second},
+		{AllowedMIMETypes: []string{"image"}},
+		{AllowedMIMETypes: []string{"image/"}},
+		{AllowedMIMETypes: []string{"*/png"}},
+		{AllowedMIMETypes: []string{"image/png;q=1"}},
+		{AllowedMIMETypes: []string{"image/png, image/gif"}},
+	}
+	for _, policy := range invalid {
+		if err := ValidateUploadPolicy(policy); err == nil {
+			t.Fatalf("ValidateUploadPolicy(%+v) expected error", policy)
+		}
+	}
+}
+
+func TestMatchMIMEType(t *testing.T) {
+	tests := []struct {
+		patterns    []string
+		contentType string
+		want        bool
+	}{
+		{nil, "application/x-anything", true},
+		{[]string{"image/png"}, "image/png", true},
+		{[]string{"image/png"}, "IMAGE/PNG", true},
+		{[]string{"image/png"}, "image/jpeg", false},
+		{[]string{"image/*"}, "image/jpeg", true},
+		{[]string{"image/*"}, "imagex/jpeg", false},
+		{[]string{"text/*"}, "text/plain; charset=utf-8", true},
+		{[]string{"*/*"}, "application/pdf", true},
+		{[]string{"image/*", "video/mp4"}, "video/mp4", true},
+		{[]string{"image/*"}, "", false},
+		{[]string{"image/*"}, "not a type", false},
+	}
+	for _, tt := range tests {
+		if got := MatchMIMEType(tt.patterns, tt.contentType); got != tt.want {
+			t.Fatalf("MatchMIMEType(%v, %q) = %v, want %v", tt.patterns, tt.contentType, got, tt.want)
+		}
+	}
+}